@@ -0,0 +1,341 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// AddressLength is the size of an address in bytes.
+const AddressLength = 20
+
+// Address is a typed account address: 20 bytes, written as 0x and 40 hex
+// digits. Most of the chain still passes addresses around as strings; this
+// is what they are checked and migrated against.
+type Address [AddressLength]byte
+
+// ErrAddressChecksum is returned for a mixed-case address whose case
+// doesn't match its EIP-55 checksum, most likely because a digit was
+// mistyped.
+var ErrAddressChecksum = errors.New("address checksum mismatch")
+
+// ParseAddress parses a 0x-prefixed, 40-hex-digit address. Case is ignored,
+// as it always has been for addresses already on chain; addresses a person
+// typed go through ParseCheckedAddress.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return a, fmt.Errorf("address %q: missing 0x prefix", s)
+	}
+	if len(s) != 2+2*AddressLength {
+		return a, fmt.Errorf("address %q: %d hex digits, want %d", s, len(s)-2, 2*AddressLength)
+	}
+	if _, err := hex.Decode(a[:], []byte(s[2:])); err != nil {
+		return a, fmt.Errorf("address %q: %w", s, err)
+	}
+	return a, nil
+}
+
+// ParseCheckedAddress parses s like ParseAddress and, if its hex digits
+// mix upper and lower case, checks them against the EIP-55 checksum. An
+// address in a single case carries no checksum and is accepted as it is.
+func ParseCheckedAddress(s string) (Address, error) {
+	a, err := ParseAddress(s)
+	if err != nil {
+		return a, err
+	}
+	digits := s[2:]
+	if digits == strings.ToLower(digits) || digits == strings.ToUpper(digits) {
+		return a, nil
+	}
+	if digits != a.Checksum()[2:] {
+		return Address{}, fmt.Errorf("%w: %s", ErrAddressChecksum, s)
+	}
+	return a, nil
+}
+
+// String returns the address as 0x and lowercase hex. Transaction hashes
+// cover addresses in this form, so it doesn't carry the checksum.
+func (a Address) String() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// Checksum returns the address in EIP-55 mixed case: each letter among
+// the hex digits is upper case where the matching nibble of the
+// Keccak-256 of the lowercase digits is 8 or more. Mistyping a digit
+// changes the hash, so the case no longer matches.
+func (a Address) Checksum() string {
+	digits := []byte(hex.EncodeToString(a[:]))
+	hash := keccak256(digits)
+	for i, c := range digits {
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			digits[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(digits)
+}
+
+// canonicalAddress checks s, if it is written as a hex address, with
+// ParseCheckedAddress, or if it has DefaultAddressHRP's bech32 prefix, with
+// ParseBech32Address, and returns it as lowercase hex, the form balances
+// are keyed by; a checksummed address would otherwise be a different
+// account. Legacy identifiers are returned as they are, since the chain
+// still accepts them.
+func canonicalAddress(s string) (string, error) {
+	var a Address
+	var err error
+	switch {
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X"):
+		a, err = ParseCheckedAddress(s)
+	case strings.HasPrefix(strings.ToLower(s), DefaultAddressHRP+"1"):
+		a, err = ParseBech32Address(DefaultAddressHRP, s)
+	default:
+		return s, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return a.String(), nil
+}
+
+// MarshalText encodes the address as its string form.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText parses an address written by MarshalText.
+func (a *Address) UnmarshalText(text []byte) error {
+	parsed, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// aliasDomain separates alias derivation from any other hash of the same
+// identifier.
+const aliasDomain = "go-principals legacy alias\x00"
+
+// LegacyAlias derives the address that stands in for a legacy identifier
+// such as "alice" or the 39-digit "0xC0Ffee...3" older demos paid. It is
+// the first 20 bytes of a domain-separated SHA-256 of the identifier, so
+// every tool maps the same identifier to the same address without
+// coordinating. No one holds a key for it.
+func LegacyAlias(id string) Address {
+	sum := sha256.Sum256([]byte(aliasDomain + id))
+	var a Address
+	copy(a[:], sum[:AddressLength])
+	return a
+}
+
+// AddressFromPubKey derives the address controlled by pub: the last 20
+// bytes of the SHA-256 of its uncompressed encoding, as Ethereum takes them
+// from Keccak-256. Unlike a LegacyAlias, someone holds its key.
+func AddressFromPubKey(pub *ecdsa.PublicKey) (Address, error) {
+	raw, err := pub.Bytes()
+	if err != nil {
+		return Address{}, err
+	}
+	sum := sha256.Sum256(raw)
+	var a Address
+	copy(a[:], sum[len(sum)-AddressLength:])
+	return a, nil
+}
+
+// AliasBook records which legacy identifiers were mapped to which
+// addresses, so a migration can be explained and repeated.
+type AliasBook struct {
+	Aliases map[string]Address `json:"aliases"`
+}
+
+// NewAliasBook creates an empty book.
+func NewAliasBook() *AliasBook {
+	return &AliasBook{Aliases: make(map[string]Address)}
+}
+
+// Resolve returns the typed address for id. A well-formed address parses
+// as itself; anything else is a legacy identifier and gets its LegacyAlias,
+// which is recorded in the book. The empty string is not an address.
+func (b *AliasBook) Resolve(id string) (Address, error) {
+	if id == "" {
+		return Address{}, errors.New("empty address")
+	}
+	if a, err := ParseAddress(id); err == nil {
+		return a, nil
+	}
+	if a, ok := b.Aliases[id]; ok {
+		return a, nil
+	}
+	a := LegacyAlias(id)
+	b.Aliases[id] = a
+	return a, nil
+}
+
+// Legacy returns the identifier an aliased address stands in for.
+func (b *AliasBook) Legacy(a Address) (string, bool) {
+	for id, alias := range b.Aliases {
+		if alias == a {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// LoadAliasBook reads a book written by Save. A missing file is an empty
+// book.
+func LoadAliasBook(path string) (*AliasBook, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewAliasBook(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := NewAliasBook()
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+	if b.Aliases == nil {
+		b.Aliases = make(map[string]Address)
+	}
+	return b, nil
+}
+
+// Save writes the book as JSON.
+func (b *AliasBook) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// AliasChain resolves every address in a stored chain's blocks and
+// allocations, recording legacy identifiers in b.
+func (b *AliasBook) AliasChain(blocks []Block, alloc map[string]Amount) error {
+	for addr := range alloc {
+		if _, err := b.Resolve(addr); err != nil {
+			return fmt.Errorf("allocation: %w", err)
+		}
+	}
+	for _, blk := range blocks {
+		for _, id := range []string{blk.Coinbase, blk.Proposer} {
+			if id == "" {
+				continue
+			}
+			if _, err := b.Resolve(id); err != nil {
+				return fmt.Errorf("block %d: %w", blk.Index, err)
+			}
+		}
+		for _, tx := range blk.Transactions {
+			if _, err := b.Resolve(tx.From); err != nil {
+				return fmt.Errorf("block %d: tx %s sender: %w", blk.Index, tx.Hash, err)
+			}
+			for _, l := range tx.Legs() {
+				if _, err := b.Resolve(l.To); err != nil {
+					return fmt.Errorf("block %d: tx %s recipient: %w", blk.Index, tx.Hash, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RewriteTx returns tx with its sender and recipients replaced by their
+// typed addresses, in canonical lowercase form, and its hash recomputed.
+func (b *AliasBook) RewriteTx(tx Transaction) (Transaction, error) {
+	from, err := b.Resolve(tx.From)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("tx %s sender: %w", tx.Hash, err)
+	}
+	tx.From = from.String()
+	if len(tx.Transfers) == 0 {
+		to, err := b.Resolve(tx.To)
+		if err != nil {
+			return Transaction{}, fmt.Errorf("tx %s recipient: %w", tx.Hash, err)
+		}
+		tx.To = to.String()
+	}
+	tx.Transfers = append([]Transfer(nil), tx.Transfers...)
+	for i, l := range tx.Transfers {
+		to, err := b.Resolve(l.To)
+		if err != nil {
+			return Transaction{}, fmt.Errorf("tx %s recipient: %w", tx.Hash, err)
+		}
+		tx.Transfers[i].To = to.String()
+	}
+	tx.Hash = computeTxHash(tx)
+	return tx, nil
+}
+
+// runAlias implements the "alias" command:
+//
+//	alias [-chain chain.json] [-book aliases.json]   map a chain's legacy identifiers
+//	alias resolve <id> [-book aliases.json]          show the address for one identifier
+func runAlias(args []string) error {
+	if len(args) > 0 && args[0] == "resolve" {
+		fs := flag.NewFlagSet("alias resolve", flag.ContinueOnError)
+		path := fs.String("book", "aliases.json", "alias book to record the mapping in")
+		if len(args) < 2 {
+			return errors.New("usage: alias resolve <id>")
+		}
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		book, err := LoadAliasBook(*path)
+		if err != nil {
+			return err
+		}
+		a, err := book.Resolve(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(a)
+		return book.Save(*path)
+	}
+
+	fs := flag.NewFlagSet("alias", flag.ContinueOnError)
+	chainPath := fs.String("chain", "chain.json", "stored chain to map")
+	path := fs.String("book", "aliases.json", "alias book to record the mapping in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	blocks, err := LoadBlocks(*chainPath)
+	if err != nil {
+		return err
+	}
+	alloc, err := LoadAlloc(*chainPath)
+	if err != nil {
+		return err
+	}
+	book, err := LoadAliasBook(*path)
+	if err != nil {
+		return err
+	}
+	if err := book.AliasChain(blocks, alloc); err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(book.Aliases))
+	for id := range book.Aliases {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	fmt.Printf("%d legacy identifiers in %s:\n", len(ids), *path)
+	for _, id := range ids {
+		fmt.Printf("  %-44s -> %s\n", id, book.Aliases[id])
+	}
+	return book.Save(*path)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha3"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKeccak256Vectors(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	} {
+		if got := hex.EncodeToString(keccak256([]byte(tc.in))); got != tc.want {
+			t.Errorf("keccak256(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+// TestKeccakSpongeMatchesSHA3 checks the permutation and the absorbing of
+// inputs longer than a block, which the vectors above don't reach, against
+// crypto/sha3: SHA3-256 is the same sponge with 0x06 padding instead of
+// 0x01.
+func TestKeccakSpongeMatchesSHA3(t *testing.T) {
+	const rate = 136
+	sha3Sponge := func(msg []byte) []byte {
+		var st [25]uint64
+		for len(msg) >= rate {
+			keccakAbsorb(&st, msg[:rate])
+			msg = msg[rate:]
+		}
+		last := make([]byte, rate)
+		copy(last, msg)
+		last[len(msg)] ^= 0x06
+		last[rate-1] ^= 0x80
+		keccakAbsorb(&st, last)
+		out := make([]byte, 32)
+		for i := 0; i < 4; i++ {
+			binary.LittleEndian.PutUint64(out[i*8:], st[i])
+		}
+		return out
+	}
+	msg := make([]byte, 3*rate+1)
+	for i := range msg {
+		msg[i] = byte(i * 7)
+	}
+	for n := 0; n <= len(msg); n++ {
+		want := sha3.Sum256(msg[:n])
+		if got := sha3Sponge(msg[:n]); !bytes.Equal(got, want[:]) {
+			t.Fatalf("%d bytes: %x, want %x", n, got, want)
+		}
+	}
+}
+
+// The checksummed addresses from EIP-55.
+var eip55Vectors = []string{
+	"0x52908400098527886E0F7030069857D2E4169EE7",
+	"0x8617E340B3D01FA5F11F306F4090FD50E238070D",
+	"0xde709f2102306220921060314715629080e2fb77",
+	"0x27b1fdb04752bbc536007a920d24acb045561c26",
+	"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+	"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+	"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+}
+
+func TestChecksumVectors(t *testing.T) {
+	for _, want := range eip55Vectors {
+		a, err := ParseCheckedAddress(want)
+		if err != nil {
+			t.Errorf("%s: %v", want, err)
+			continue
+		}
+		if got := a.Checksum(); got != want {
+			t.Errorf("Checksum() = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestParseCheckedAddressRejectsBadCase(t *testing.T) {
+	good := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	// Flip the case of one letter.
+	bad := good[:4] + strings.ToLower(good[4:5]) + good[5:]
+	if _, err := ParseCheckedAddress(bad); !errors.Is(err, ErrAddressChecksum) {
+		t.Errorf("%s: err = %v, want ErrAddressChecksum", bad, err)
+	}
+	if _, err := ParseCheckedAddress(strings.ToLower(good)); err != nil {
+		t.Errorf("lowercase address: %v", err)
+	}
+	if _, err := ParseAddress(bad); err != nil {
+		t.Errorf("ParseAddress should ignore case: %v", err)
+	}
+}
+
+// TestAddressFromPubKeyVector pins the address of RFC 6979's P-256 key:
+// the last 20 bytes of the SHA-256 of its uncompressed encoding. The key
+// and signing demos derive the same one.
+func TestAddressFromPubKeyVector(t *testing.T) {
+	a, err := AddressFromPubKey(&rfc6979Key(t).PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := a.String(), "0x131ce83c1160fa33c087ab15b863574d31d8ff3c"; got != want {
+		t.Errorf("address %s, want %s", got, want)
+	}
+}
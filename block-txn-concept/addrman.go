@@ -0,0 +1,269 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Address manager sizing. Addresses are spread over many small buckets so a
+// single source can only ever fill a few of them.
+const (
+	NewBucketCount   = 64
+	TriedBucketCount = 16
+	BucketSize       = 32
+)
+
+// PeerAddr is what the address manager knows about one peer address.
+type PeerAddr struct {
+	Addr      string    `json:"addr"`   // host:port
+	Source    string    `json:"source"` // who told us about it
+	LastSeen  time.Time `json:"lastSeen"`
+	LastTried time.Time `json:"lastTried,omitempty"`
+	Attempts  int       `json:"attempts"`
+}
+
+// AddrManager remembers peer addresses across restarts. Addresses we have
+// only heard about live in "new" buckets; addresses we have successfully
+// connected to graduate to "tried" buckets. Which bucket an address lands in
+// depends on a secret key and on the network group of both the address and
+// its source, so a flood of addresses from one peer competes only with
+// itself. Full buckets evict a random entry.
+type AddrManager struct {
+	mu    sync.Mutex
+	key   [32]byte
+	new   [NewBucketCount]map[string]*PeerAddr
+	tried [TriedBucketCount]map[string]*PeerAddr
+	index map[string]*PeerAddr // every known address, in either table
+}
+
+// NewAddrManager creates an empty address manager with a random bucketing key.
+func NewAddrManager() (*AddrManager, error) {
+	var key [32]byte
+	if _, err := crand.Read(key[:]); err != nil {
+		return nil, err
+	}
+	return newAddrManager(key), nil
+}
+
+func newAddrManager(key [32]byte) *AddrManager {
+	m := &AddrManager{key: key, index: make(map[string]*PeerAddr)}
+	for i := range m.new {
+		m.new[i] = make(map[string]*PeerAddr)
+	}
+	for i := range m.tried {
+		m.tried[i] = make(map[string]*PeerAddr)
+	}
+	return m
+}
+
+// Add records addresses learned from source. Known addresses only have
+// their last-seen time refreshed.
+func (m *AddrManager) Add(addrs []string, source string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, addr := range addrs {
+		if p, ok := m.index[addr]; ok {
+			p.LastSeen = now
+			continue
+		}
+		m.insertNew(&PeerAddr{Addr: addr, Source: source, LastSeen: now})
+	}
+}
+
+// Attempt records a connection attempt to addr.
+func (m *AddrManager) Attempt(addr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.index[addr]; ok {
+		p.LastTried = time.Now()
+		p.Attempts++
+	}
+}
+
+// Good marks addr as successfully connected, moving it to the tried table.
+func (m *AddrManager) Good(addr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.index[addr]
+	if !ok {
+		return
+	}
+	p.LastSeen = time.Now()
+	p.Attempts = 0
+
+	nb := m.newBucket(p)
+	if _, inNew := m.new[nb][addr]; !inNew {
+		return // already tried
+	}
+	delete(m.new[nb], addr)
+
+	tb := m.tried[m.triedBucket(addr)]
+	if len(tb) >= BucketSize {
+		// Demote a random tried entry back to the new table.
+		victim := randomEntry(tb)
+		delete(tb, victim.Addr)
+		delete(m.index, victim.Addr)
+		m.insertNew(victim)
+	}
+	tb[addr] = p
+}
+
+// Select returns a random known address, preferring tried and new equally.
+func (m *AddrManager) Select() (PeerAddr, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tables := [][]map[string]*PeerAddr{m.tried[:], m.new[:]}
+	if rand.IntN(2) == 1 {
+		tables[0], tables[1] = tables[1], tables[0]
+	}
+	for _, table := range tables {
+		var candidates []*PeerAddr
+		for _, bucket := range table {
+			for _, p := range bucket {
+				candidates = append(candidates, p)
+			}
+		}
+		if len(candidates) > 0 {
+			return *candidates[rand.IntN(len(candidates))], true
+		}
+	}
+	return PeerAddr{}, false
+}
+
+// Len returns how many addresses are known.
+func (m *AddrManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.index)
+}
+
+// insertNew places p in its new bucket, evicting a random entry if full.
+func (m *AddrManager) insertNew(p *PeerAddr) {
+	bucket := m.new[m.newBucket(p)]
+	if len(bucket) >= BucketSize {
+		victim := randomEntry(bucket)
+		delete(bucket, victim.Addr)
+		delete(m.index, victim.Addr)
+	}
+	bucket[p.Addr] = p
+	m.index[p.Addr] = p
+}
+
+func (m *AddrManager) newBucket(p *PeerAddr) int {
+	return m.bucketHash(netGroup(p.Source), netGroup(p.Addr)) % NewBucketCount
+}
+
+func (m *AddrManager) triedBucket(addr string) int {
+	return m.bucketHash(netGroup(addr), addr) % TriedBucketCount
+}
+
+func (m *AddrManager) bucketHash(parts ...string) int {
+	h := sha256.New()
+	h.Write(m.key[:])
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return int(binary.BigEndian.Uint32(h.Sum(nil)[:4]) & 0x7fffffff)
+}
+
+// netGroup returns the network an address belongs to: the /16 for IPv4,
+// the /32 for IPv6, or the host name itself.
+func netGroup(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return host
+	case ip.To4() != nil:
+		return ip.To4().Mask(net.CIDRMask(16, 32)).String()
+	default:
+		return ip.Mask(net.CIDRMask(32, 128)).String()
+	}
+}
+
+func randomEntry(bucket map[string]*PeerAddr) *PeerAddr {
+	n := rand.IntN(len(bucket))
+	for _, p := range bucket {
+		if n == 0 {
+			return p
+		}
+		n--
+	}
+	return nil
+}
+
+// addrFile is the on-disk format of an address manager.
+type addrFile struct {
+	Key   []byte      `json:"key"`
+	New   []*PeerAddr `json:"new"`
+	Tried []*PeerAddr `json:"tried"`
+}
+
+// Save writes the address manager to path.
+func (m *AddrManager) Save(path string) error {
+	m.mu.Lock()
+	f := addrFile{Key: m.key[:]}
+	for _, bucket := range m.new {
+		for _, p := range bucket {
+			f.New = append(f.New, p)
+		}
+	}
+	for _, bucket := range m.tried {
+		for _, p := range bucket {
+			f.Tried = append(f.Tried, p)
+		}
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// LoadAddrManager reads an address manager written by Save. Entries are
+// re-bucketed with the stored key, so bucket limits still apply.
+func LoadAddrManager(path string) (*AddrManager, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f addrFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if len(f.Key) != 32 {
+		return nil, errors.New("address file has an invalid bucketing key")
+	}
+
+	var key [32]byte
+	copy(key[:], f.Key)
+	m := newAddrManager(key)
+	for _, p := range f.New {
+		m.insertNew(p)
+	}
+	for _, p := range f.Tried {
+		lastSeen := p.LastSeen
+		m.insertNew(p)
+		m.Good(p.Addr)
+		p.LastSeen = lastSeen
+	}
+	return m, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenesisAllocFundsAccounts(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	alloc := map[string]Amount{alice.Addr: 10 * Coin, bob.Addr: 3 * Coin}
+	c := newTestChain(t, testConfig(alloc))
+
+	snap, err := c.Snapshot(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLedger()
+	if err := l.ApplyBlock(c, c.Tip()); err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range alloc {
+		if got := snap.Balance(addr); got != want {
+			t.Errorf("%s: genesis snapshot has %s, want %s", addr, got, want)
+		}
+		if got := l.Account(addr).Balance; got != want {
+			t.Errorf("%s: ledger has %s, want %s", addr, got, want)
+		}
+	}
+
+	// Allocated coins can be spent; an account without one can't spend.
+	if err := mineTxs(t, c, signedTx(t, c, alice, Transaction{To: carol.Addr, Amount: 9 * Coin})); err != nil {
+		t.Fatal(err)
+	}
+	if err := mineTxs(t, c, signedTx(t, c, bob, Transaction{To: carol.Addr, Amount: 4 * Coin})); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("overspending an allocation: err = %v, want ErrInsufficientFunds", err)
+	}
+	if got := tipBalance(t, c, carol.Addr); got != 9*Coin {
+		t.Errorf("carol has %s, want 9", got)
+	}
+}
+
+func TestGenesisAllocIsStoredWithTheChain(t *testing.T) {
+	alice := newTestAccount(t)
+	alloc := map[string]Amount{alice.Addr: 7 * Coin}
+	c := newTestChain(t, testConfig(alloc))
+	path := filepath.Join(t.TempDir(), "chain.json")
+	if err := SaveChain(path, c); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadAlloc(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[alice.Addr] != 7*Coin {
+		t.Errorf("loaded alloc %v, want %v", got, alloc)
+	}
+
+	if err := SaveBlocks(path, c.BestChain()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := LoadAlloc(path); err != nil || got != nil {
+		t.Errorf("blocks-only file: alloc %v, err %v; want none", got, err)
+	}
+}
+
+func TestNegativeGenesisAllocRejected(t *testing.T) {
+	cfg := testConfig(map[string]Amount{newTestAccount(t).Addr: -Coin})
+	genesis := NewGenesisBlock(BitsForLeadingZeros(1))
+	if _, err := NewChain(cfg, genesis); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Errorf("a negative allocation: err = %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// AmountDecimals is the number of decimal places an Amount holds.
+const AmountDecimals = 8
+
+// Coin is one whole coin in minor units.
+const Coin Amount = 100_000_000
+
+// Amount is a quantity of coins, counted in minor units (1e-8 of a coin)
+// so that sums and differences are exact. Repeatedly adding 4.50 and 25.00
+// as float64s drifts; adding Amounts doesn't.
+//
+// In JSON an Amount is a plain decimal number, as amounts were before it
+// existed, and with fmt it formats like a float64 (%.2f, %10.2f, %g), so
+// stored chains and output look the same as they did.
+type Amount int64
+
+// ErrAmountRange is returned for an amount too large to represent.
+var ErrAmountRange = errors.New("amount out of range")
+
+// ParseAmount parses a decimal such as "4.50", "25", or "-0.00000001". It
+// rejects more than AmountDecimals decimal places rather than round.
+func ParseAmount(s string) (Amount, error) {
+	a, exact, err := parseDecimal(s)
+	if err != nil {
+		return 0, err
+	}
+	if !exact {
+		return 0, fmt.Errorf("amount %q: more than %d decimal places", s, AmountDecimals)
+	}
+	return a, nil
+}
+
+// parseDecimal parses a decimal, with an optional exponent as JSON allows,
+// rounding half away from zero to the nearest minor unit. exact reports
+// whether no rounding was needed.
+func parseDecimal(s string) (a Amount, exact bool, err error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || strings.ContainsAny(s, "/") {
+		return 0, false, fmt.Errorf("amount %q: not a decimal number", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(int64(Coin)))
+	exact = r.IsInt()
+
+	// Round half away from zero: add or subtract one half, then truncate.
+	half := big.NewRat(1, 2)
+	if r.Sign() < 0 {
+		half.Neg(half)
+	}
+	r.Add(r, half)
+	n := new(big.Int).Quo(r.Num(), r.Denom())
+	if !n.IsInt64() {
+		return 0, false, fmt.Errorf("amount %q: %w", s, ErrAmountRange)
+	}
+	return Amount(n.Int64()), exact, nil
+}
+
+// MustParseAmount is ParseAmount for literals known to be valid; it panics
+// on error.
+func MustParseAmount(s string) Amount {
+	a, err := ParseAmount(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// AmountFromFloat converts a float64 coin value, rounding to the nearest
+// minor unit. It is for legacy data and command-line math; new code should
+// stay in Amounts.
+func AmountFromFloat(f float64) (Amount, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("amount %v is not a number", f)
+	}
+	a, _, err := parseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+	return a, err
+}
+
+// MaxAmount is the most a single leg, fee, or output may carry: 21 million
+// coins, Bitcoin's cap on all money. A transaction's legs and fee can then
+// be summed without coming near the limit of an int64.
+const MaxAmount Amount = 21_000_000 * Coin
+
+// Add returns a + b, or ErrAmountRange if the sum doesn't fit. Sums of
+// amounts someone else chose, such as a transaction's legs, must use it: a
+// sum that wraps around turns a huge payment into a small or negative one.
+func (a Amount) Add(b Amount) (Amount, error) {
+	s := a + b
+	if (b > 0 && s < a) || (b < 0 && s > a) {
+		return 0, fmt.Errorf("%v + %v: %w", a, b, ErrAmountRange)
+	}
+	return s, nil
+}
+
+// Sub returns a - b, or ErrAmountRange if the difference doesn't fit.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	s := a - b
+	if (b > 0 && s > a) || (b < 0 && s < a) {
+		return 0, fmt.Errorf("%v - %v: %w", a, b, ErrAmountRange)
+	}
+	return s, nil
+}
+
+// Coins returns a as a float64 number of coins, for ratios and display
+// only; converting back is lossy.
+func (a Amount) Coins() float64 {
+	return float64(a) / float64(Coin)
+}
+
+// MulDiv returns a*mul/div, truncated toward zero, without overflowing in
+// between. It returns ErrAmountRange if the result doesn't fit in an
+// Amount, or div is zero.
+func (a Amount) MulDiv(mul, div int64) (Amount, error) {
+	if div == 0 {
+		return 0, fmt.Errorf("%v * %d / 0: %w", a, mul, ErrAmountRange)
+	}
+	n := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(mul))
+	if n.Quo(n, big.NewInt(div)); !n.IsInt64() {
+		return 0, fmt.Errorf("%v * %d / %d: %w", a, mul, div, ErrAmountRange)
+	}
+	return Amount(n.Int64()), nil
+}
+
+// decimal writes a with exactly places decimal places, rounding half away
+// from zero if places is below AmountDecimals.
+func (a Amount) decimal(places int) string {
+	neg := a < 0
+	u := new(big.Int).Abs(big.NewInt(int64(a)))
+	if places < AmountDecimals {
+		unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(AmountDecimals-places)), nil)
+		u.Add(u, new(big.Int).Quo(unit, big.NewInt(2)))
+		u.Quo(u, unit)
+	} else if places > AmountDecimals {
+		u.Mul(u, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places-AmountDecimals)), nil))
+	}
+	digits := u.String()
+	if places > 0 {
+		if len(digits) <= places {
+			digits = strings.Repeat("0", places-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+	}
+	if neg && strings.Trim(digits, "0.") != "" {
+		digits = "-" + digits
+	}
+	return digits
+}
+
+// String returns a as a decimal with no trailing zeros, e.g. "4.5" or "25".
+func (a Amount) String() string {
+	s := a.decimal(AmountDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// hashString is the form hashes commit to. Amounts with at most six
+// decimal places write six, exactly as hashes wrote float64 amounts with
+// %f before Amount existed, so old transactions keep their hashes; finer
+// amounts write all eight.
+func (a Amount) hashString() string {
+	if a%100 == 0 {
+		return a.decimal(6)
+	}
+	return a.decimal(AmountDecimals)
+}
+
+// Format implements fmt.Formatter, so Amounts print like float64s: %f
+// and %F take a precision (default 6), %v, %s, and %g print String, and %d
+// prints minor units. Width and the '+', '-', and '0' flags work as usual.
+func (a Amount) Format(f fmt.State, verb rune) {
+	var s string
+	switch verb {
+	case 'f', 'F':
+		places, ok := f.Precision()
+		if !ok {
+			places = 6
+		}
+		s = a.decimal(places)
+	case 'v', 's', 'g', 'G':
+		s = a.String()
+	case 'd':
+		s = strconv.FormatInt(int64(a), 10)
+	default:
+		fmt.Fprintf(f, "%%!%c(Amount=%s)", verb, a.String())
+		return
+	}
+	if f.Flag('+') && !strings.HasPrefix(s, "-") {
+		s = "+" + s
+	}
+	if width, ok := f.Width(); ok && len(s) < width {
+		pad := width - len(s)
+		switch {
+		case f.Flag('-'):
+			s += strings.Repeat(" ", pad)
+		case f.Flag('0'):
+			sign := ""
+			if s[0] == '-' || s[0] == '+' {
+				sign, s = s[:1], s[1:]
+			}
+			s = sign + strings.Repeat("0", pad) + s
+		default:
+			s = strings.Repeat(" ", pad) + s
+		}
+	}
+	f.Write([]byte(s))
+}
+
+// MarshalJSON writes a as a JSON number.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number, rounding to the nearest minor unit.
+// Chains written before Amount existed hold float64s, which may carry
+// noise below a minor unit (0.30000000000000004); rounding recovers the
+// intended amount.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if s == "" || strings.ContainsAny(s, "\"/") {
+		return fmt.Errorf("amount %s: not a JSON number", s)
+	}
+	v, _, err := parseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Set implements flag.Value.
+func (a *Amount) Set(s string) error {
+	v, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// amountFlag defines an Amount flag with the given default.
+func amountFlag(fs *flag.FlagSet, name string, value Amount, usage string) *Amount {
+	a := &value
+	fs.Var(a, name, usage)
+	return a
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMulDivBounds(t *testing.T) {
+	for _, tc := range []struct {
+		a         Amount
+		mul, div  int64
+		want      Amount
+		overflows bool
+	}{
+		{7 * Coin, 7, 8, 612_500_000, false},
+		{-7, 1, 2, -3, false}, // truncates toward zero
+		{math.MaxInt64, 3, 3, math.MaxInt64, false},
+		{math.MaxInt64, 2, 2, math.MaxInt64, false}, // the product alone would overflow
+		{math.MinInt64, 1, 1, math.MinInt64, false},
+		{math.MaxInt64, 2, 1, 0, true},
+		{math.MaxInt64/2 + 1, 2, 1, 0, true},
+		{math.MinInt64, -1, 1, 0, true},
+		{Coin, 1, 0, 0, true},
+	} {
+		got, err := tc.a.MulDiv(tc.mul, tc.div)
+		if tc.overflows {
+			if !errors.Is(err, ErrAmountRange) {
+				t.Errorf("%d * %d / %d: err = %v, want ErrAmountRange", tc.a, tc.mul, tc.div, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%d * %d / %d = %d, %v, want %d", tc.a, tc.mul, tc.div, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Asset names a token a ledger tracks besides the chain's own coin, e.g.
+// "USD" or "GOLD".
+type Asset string
+
+// NativeAsset is the chain's own coin. Fees are always paid in it, and it is
+// the only asset blocks carry.
+const NativeAsset Asset = ""
+
+// ErrAssetMismatch is returned where amounts of different assets would be
+// combined, such as a transaction naming an asset the chain doesn't carry.
+var ErrAssetMismatch = errors.New("asset mismatch")
+
+// Validate checks that a is the native coin or a symbol of 1 to 12
+// uppercase letters and digits starting with a letter.
+func (a Asset) Validate() error {
+	if a == NativeAsset {
+		return nil
+	}
+	if len(a) > 12 {
+		return fmt.Errorf("asset %q: longer than 12 characters", string(a))
+	}
+	for i, r := range a {
+		if !(r >= 'A' && r <= 'Z') && (i == 0 || !(r >= '0' && r <= '9')) {
+			return fmt.Errorf("asset %q: want uppercase letters and digits, starting with a letter", string(a))
+		}
+	}
+	return nil
+}
+
+// String returns the symbol, or "native" for the chain's coin.
+func (a Asset) String() string {
+	if a == NativeAsset {
+		return "native"
+	}
+	return string(a)
+}
+
+// change returns t's signed effect on its account's balance of asset: a
+// credit adds Amount if t moves asset, a debit takes Total if it moves
+// asset and Fee if asset is native. Every balance update goes through it,
+// so no amount is ever added to a balance of another asset.
+func (t Transaction) change(asset Asset) Amount {
+	var d Amount
+	switch t.Type {
+	case Credit:
+		if t.Asset == asset {
+			d += t.Amount
+		}
+	case Debit:
+		if t.Asset == asset {
+			total, _ := t.Total() // debits pass checkLegs before they apply
+			d -= total
+		}
+		if asset == NativeAsset {
+			d -= t.Fee
+		}
+	}
+	return d
+}
+
+// BalanceOf returns a's balance of asset.
+func (a *Account) BalanceOf(asset Asset) Amount {
+	if asset == NativeAsset {
+		return a.Balance
+	}
+	return a.Assets[asset]
+}
+
+// assetSymbols returns the tokens a holds, sorted.
+func (a *Account) assetSymbols() []Asset {
+	list := make([]Asset, 0, len(a.Assets))
+	for asset := range a.Assets {
+		list = append(list, asset)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// adjust adds t's effect, or with undo takes it away, on both a's native
+// balance and its balance of t's asset.
+func (a *Account) adjust(t Transaction, undo bool) {
+	sign := Amount(1)
+	if undo {
+		sign = -1
+	}
+	a.Balance += sign * t.change(NativeAsset)
+	if t.Asset == NativeAsset {
+		return
+	}
+	if a.Assets == nil {
+		a.Assets = make(map[Asset]Amount)
+	}
+	a.Assets[t.Asset] += sign * t.change(t.Asset)
+	if a.Assets[t.Asset] == 0 {
+		delete(a.Assets, t.Asset)
+	}
+}
+
+// MintAsset credits newly issued units of asset, which must not be the
+// native coin, to addr. Use Mint for the native coin.
+func (l *Ledger) MintAsset(addr string, asset Asset, amount Amount) error {
+	if err := asset.Validate(); err != nil {
+		return err
+	}
+	if asset == NativeAsset {
+		return fmt.Errorf("%w: MintAsset mints tokens; use Mint for the native coin", ErrAssetMismatch)
+	}
+	a := l.Account(addr)
+	a.adjust(Transaction{Type: Credit, Asset: asset, Amount: amount}, false)
+	return nil
+}
+
+// TotalOf is the sum of every balance of asset; for the native coin it is
+// Total.
+func (l *Ledger) TotalOf(asset Asset) Amount {
+	if asset == NativeAsset {
+		return l.Total()
+	}
+	var total Amount
+	for _, a := range l.accounts {
+		total += a.Assets[asset]
+	}
+	return total
+}
+
+// checkAsset keeps tokens off the chain: blocks and snapshots track only
+// the native coin, so a transaction naming another asset would be applied
+// as native coins.
+func checkAsset(ctx *TxContext, tx Transaction) error {
+	if tx.Asset != NativeAsset {
+		return fmt.Errorf("%w: tx %s moves %s; the chain carries only its native coin", ErrAssetMismatch, tx.Hash, tx.Asset)
+	}
+	return nil
+}
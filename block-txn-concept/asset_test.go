@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAssetValidate(t *testing.T) {
+	for _, ok := range []Asset{NativeAsset, "USD", "GOLD2", "ABCDEFGHIJKL"} {
+		if err := ok.Validate(); err != nil {
+			t.Errorf("%q: %v", string(ok), err)
+		}
+	}
+	for _, bad := range []Asset{"usd", "2X", "US-D", "ABCDEFGHIJKLM"} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%q: accepted", string(bad))
+		}
+	}
+}
+
+func TestTokenTransfers(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	l := NewLedger()
+	if err := l.ApplyBlock(c, c.Tip()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.MintAsset(alice.Addr, "USD", 100*Coin); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.MintAsset(alice.Addr, NativeAsset, Coin); !errors.Is(err, ErrAssetMismatch) {
+		t.Errorf("minting the native coin: err = %v, want ErrAssetMismatch", err)
+	}
+
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 40 * Coin, Fee: Coin, Asset: "USD"})
+	if err := l.Apply(tx); err != nil {
+		t.Fatal(err)
+	}
+	a, b := l.Account(alice.Addr), l.Account(bob.Addr)
+	if a.BalanceOf("USD") != 60*Coin || b.BalanceOf("USD") != 40*Coin {
+		t.Errorf("USD balances %v and %v, want 60 and 40", a.BalanceOf("USD"), b.BalanceOf("USD"))
+	}
+	if a.Balance != 9*Coin || b.Balance != 0 {
+		t.Errorf("native balances %v and %v, want 9 and 0: the fee is native", a.Balance, b.Balance)
+	}
+	if got := l.TotalOf("USD"); got != 100*Coin {
+		t.Errorf("USD total %v, want 100", got)
+	}
+
+	tooMuch := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 61 * Coin, Asset: "USD", Description: "more"})
+	tooMuch.Nonce = 1
+	if err := l.Apply(resign(t, tooMuch, alice)); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("overspending USD: err = %v, want ErrInsufficientFunds", err)
+	}
+
+	// The chain itself only carries the native coin.
+	if err := mineTxs(t, c, tx); !errors.Is(err, ErrAssetMismatch) {
+		t.Errorf("token on chain: err = %v, want ErrAssetMismatch", err)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// ErrNotAttested is returned by NewChain when the config names a genesis key
+// but has no attestation to check against it.
+var ErrNotAttested = errors.New("genesis is not attested")
+
+// ErrBadAttestation is returned by NewChain when the attestation wasn't
+// signed by the genesis key, or doesn't match the chain being started.
+var ErrBadAttestation = errors.New("genesis attestation does not match")
+
+// attestationDomain separates attestation signatures from any other use of
+// the same key.
+const attestationDomain = "go-principals genesis attestation v1\n"
+
+// GenesisAttestation is an operator's signed statement of what a chain is:
+// its genesis block, allocations, and consensus parameters, as a ChainSpec.
+// A node configured with the operator's public key refuses to start unless
+// its own genesis and config produce exactly this spec.
+type GenesisAttestation struct {
+	Spec      ChainSpec `json:"spec"`
+	PubKey    string    `json:"pubKey"`    // hex, uncompressed P-256
+	Signature string    `json:"signature"` // hex, ASN.1 ECDSA over the domain-separated spec hash
+}
+
+// attestationDigest hashes the spec as it is signed. The fork ID is left
+// out, since it depends on the tip rather than the genesis.
+func attestationDigest(spec ChainSpec) ([]byte, error) {
+	spec.ForkID = ""
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte(attestationDomain), data...))
+	return sum[:], nil
+}
+
+// Attest signs c's genesis and config with key.
+func (c *Chain) Attest(key *ecdsa.PrivateKey) (*GenesisAttestation, error) {
+	spec, err := c.Spec()
+	if err != nil {
+		return nil, err
+	}
+	digest, err := attestationDigest(spec)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := SignDeterministic(key, digest)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return &GenesisAttestation{Spec: spec, PubKey: hex.EncodeToString(pub), Signature: hex.EncodeToString(sig)}, nil
+}
+
+// Verify checks that a was signed by pub.
+func (a *GenesisAttestation) Verify(pub *ecdsa.PublicKey) error {
+	want, err := pub.Bytes()
+	if err != nil {
+		return err
+	}
+	if a.PubKey != hex.EncodeToString(want) {
+		return fmt.Errorf("%w: signed by %s, not the configured key", ErrBadAttestation, a.PubKey)
+	}
+	sig, err := hex.DecodeString(a.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature: %v", ErrBadAttestation, err)
+	}
+	digest, err := attestationDigest(a.Spec)
+	if err != nil {
+		return err
+	}
+	if !ecdsa.VerifyASN1(pub, digest, sig) {
+		return fmt.Errorf("%w: bad signature", ErrBadAttestation)
+	}
+	return nil
+}
+
+// checkAttestation verifies the configured attestation against the genesis
+// key and the chain's own spec.
+func (c *Chain) checkAttestation() error {
+	a := c.config.Attestation
+	if a == nil {
+		return ErrNotAttested
+	}
+	if err := a.Verify(c.config.GenesisKey); err != nil {
+		return err
+	}
+	spec, err := c.Spec()
+	if err != nil {
+		return err
+	}
+	got, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	want, err := json.Marshal(a.Spec)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		if a.Spec.Genesis == nil || spec.Genesis.Hash != a.Spec.Genesis.Hash {
+			return fmt.Errorf("%w: genesis block differs from the attested one", ErrBadAttestation)
+		}
+		return fmt.Errorf("%w: allocations or consensus parameters differ from the attested ones", ErrBadAttestation)
+	}
+	return nil
+}
+
+// ParsePublicKey decodes a hex-encoded uncompressed P-256 public key.
+func ParsePublicKey(s string) (*ecdsa.PublicKey, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), raw)
+}
+
+// writeKeyFile stores key as a PEM "EC PRIVATE KEY" readable only by its
+// owner.
+func writeKeyFile(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	return os.WriteFile(path, data, 0o600)
+}
+
+// readKeyFile loads a key written by writeKeyFile.
+func readKeyFile(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: no EC PRIVATE KEY block", path)
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// runGenesis implements the "genesis" command:
+//
+//	genesis keygen -out operator.pem
+//	genesis sign -chain chain.json -key operator.pem [config flags]
+//	genesis verify -chain chain.json [-pubkey HEX]
+//
+// sign takes the same config flags as spec dump and stores the attestation
+// in the chain file.
+func runGenesis(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: genesis keygen|sign|verify [flags]")
+	}
+	fs := flag.NewFlagSet("genesis "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "keygen":
+		out := fs.String("out", "operator.pem", "file to write the private key to")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return err
+		}
+		if err := writeKeyFile(*out, key); err != nil {
+			return err
+		}
+		pub, err := key.PublicKey.Bytes()
+		if err != nil {
+			return err
+		}
+		addr, err := AddressFromPubKey(&key.PublicKey)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Private key written to %s\nPublic key: %s\nAddress: %s\n", *out, hex.EncodeToString(pub), addr.Checksum())
+		return nil
+
+	case "sign":
+		path := fs.String("chain", "chain.json", "stored chain whose genesis to attest")
+		keyPath := fs.String("key", "operator.pem", "operator private key")
+		flags := addConfigFlags(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		cfg, err := flags.config()
+		if err != nil {
+			return err
+		}
+		key, err := readKeyFile(*keyPath)
+		if err != nil {
+			return err
+		}
+		f, err := readChainFile(*path)
+		if err != nil {
+			return err
+		}
+		cfg.Alloc, cfg.Upgrades = f.Alloc, f.Upgrades
+		chain, err := NewChain(cfg, f.Blocks[0])
+		if err != nil {
+			return err
+		}
+		if f.Attestation, err = chain.Attest(key); err != nil {
+			return err
+		}
+		if err := writeChainFile(*path, f); err != nil {
+			return err
+		}
+		fmt.Printf("Attested genesis %s in %s\n", f.Blocks[0].Hash, *path)
+		return nil
+
+	case "verify":
+		path := fs.String("chain", "chain.json", "stored chain to check")
+		pubHex := fs.String("pubkey", "", "operator public key the attestation must be signed with")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		f, err := readChainFile(*path)
+		if err != nil {
+			return err
+		}
+		a := f.Attestation
+		if a == nil {
+			return fmt.Errorf("%s: %w", *path, ErrNotAttested)
+		}
+		pub, err := ParsePublicKey(a.PubKey)
+		if err != nil {
+			return fmt.Errorf("attestation public key: %w", err)
+		}
+		if *pubHex != "" {
+			if pub, err = ParsePublicKey(*pubHex); err != nil {
+				return fmt.Errorf("-pubkey: %w", err)
+			}
+		}
+		fmt.Printf("Signed by : %s\n", a.PubKey)
+		if a.Spec.Genesis != nil {
+			fmt.Printf("Genesis   : %s\n", a.Spec.Genesis.Hash)
+		}
+		fmt.Printf("Chain ID  : %q\n", a.Spec.ChainID)
+		fmt.Printf("Engine    : %s, reward %g, fork choice %s\n", a.Spec.Consensus.Engine, a.Spec.Rewards.BlockReward, a.Spec.ForkChoice.Rule)
+		fmt.Printf("Alloc     : %d accounts\n", len(a.Spec.Alloc))
+		if err := a.Verify(pub); err != nil {
+			return err
+		}
+		if a.Spec.Genesis == nil || a.Spec.Genesis.Hash != f.Blocks[0].Hash {
+			return fmt.Errorf("%w: the file's genesis block is not the attested one", ErrBadAttestation)
+		}
+		got, _ := json.Marshal(allocSpec(f.Alloc))
+		want, _ := json.Marshal(a.Spec.Alloc)
+		if !bytes.Equal(got, want) {
+			return fmt.Errorf("%w: the file's allocations are not the attested ones", ErrBadAttestation)
+		}
+		if *pubHex == "" {
+			fmt.Println("Signature valid (pass -pubkey to check who signed it)")
+		} else {
+			fmt.Println("Signature valid")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown genesis command %q", args[0])
+	}
+}
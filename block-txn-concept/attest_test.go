@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGenesisAttestation(t *testing.T) {
+	operator, mallory, alice := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	genesis := c.Tip()
+	a, err := c.Attest(operator.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := c.Attest(mallory.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := *a
+	tampered.Spec.Rewards.BlockReward = 100 * Coin
+
+	start := func(cfg ChainConfig, a *GenesisAttestation) error {
+		cfg.GenesisKey = &operator.Key.PublicKey
+		cfg.Attestation = a
+		_, err := NewChain(cfg, genesis)
+		return err
+	}
+	if err := start(cfg, a); err != nil {
+		t.Fatalf("attested chain: %v", err)
+	}
+	if err := start(cfg, nil); !errors.Is(err, ErrNotAttested) {
+		t.Errorf("no attestation: err = %v, want ErrNotAttested", err)
+	}
+	for name, a := range map[string]*GenesisAttestation{"mallory's": forged, "tampered": &tampered} {
+		if err := start(cfg, a); !errors.Is(err, ErrBadAttestation) {
+			t.Errorf("%s attestation: err = %v, want ErrBadAttestation", name, err)
+		}
+	}
+
+	// The operator's signature is good, but this node's chain isn't the
+	// one it describes.
+	richer := cfg
+	richer.Alloc = map[string]Amount{alice.Addr: 20 * Coin}
+	if err := start(richer, a); !errors.Is(err, ErrBadAttestation) {
+		t.Errorf("different allocation: err = %v, want ErrBadAttestation", err)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// DefaultAddressHRP is the human-readable prefix addresses are written
+// with in bech32m unless a caller picks another, as Bitcoin uses "bc" on
+// mainnet and "tb" on testnet.
+const DefaultAddressHRP = "gp"
+
+// Bech32Encoding is one of the two checksum constants of the bech32
+// family. They differ only in the constant the checksum is XORed with.
+type Bech32Encoding uint32
+
+const (
+	// Bech32 is the original encoding from BIP-173.
+	Bech32 Bech32Encoding = 1
+	// Bech32m is BIP-350's fix for bech32's one weakness: a final "p" can
+	// be inserted or deleted in front of a "q" without breaking the
+	// checksum. Addresses here use it.
+	Bech32m Bech32Encoding = 0x2bc830a3
+)
+
+// String returns "bech32" or "bech32m".
+func (e Bech32Encoding) String() string {
+	switch e {
+	case Bech32:
+		return "bech32"
+	case Bech32m:
+		return "bech32m"
+	}
+	return fmt.Sprintf("Bech32Encoding(%#x)", uint32(e))
+}
+
+// ErrBech32Checksum is returned for a string whose checksum matches neither
+// encoding: a mistyped, swapped, or missing character.
+var ErrBech32Checksum = errors.New("bech32 checksum mismatch")
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Polymod is the BCH code both encodings' checksums come from; it
+// detects any error in up to four characters.
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if top>>i&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32HRPExpand feeds the prefix into the checksum: the high bits of
+// each character, a zero, then the low bits.
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// EncodeBech32 writes data under hrp in enc: the prefix, a "1", the data
+// regrouped into 5-bit characters, and a 6-character checksum, all lower
+// case.
+func EncodeBech32(enc Bech32Encoding, hrp string, data []byte) (string, error) {
+	if enc != Bech32 && enc != Bech32m {
+		return "", fmt.Errorf("unknown bech32 encoding %v", enc)
+	}
+	if err := checkBech32HRP(hrp); err != nil {
+		return "", err
+	}
+	hrp = strings.ToLower(hrp)
+	values := convertBits(data, 8, 5, true)
+	if len(hrp)+1+len(values)+6 > 90 {
+		return "", fmt.Errorf("bech32 string for %d bytes would pass 90 characters", len(data))
+	}
+	poly := bech32Polymod(append(append(bech32HRPExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ uint32(enc)
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, v := range values {
+		b.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[poly>>(5*(5-i))&31])
+	}
+	return b.String(), nil
+}
+
+// DecodeBech32 reads a string EncodeBech32 wrote, in either encoding, and
+// reports which it was. The string may be all upper or all lower case,
+// not a mix; the prefix is returned in lower case.
+func DecodeBech32(s string) (hrp string, data []byte, enc Bech32Encoding, err error) {
+	if len(s) > 90 {
+		return "", nil, 0, fmt.Errorf("bech32 string is %d characters, more than 90", len(s))
+	}
+	if s != strings.ToLower(s) && s != strings.ToUpper(s) {
+		return "", nil, 0, fmt.Errorf("bech32 string %q mixes upper and lower case", s)
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || len(s)-sep-1 < 6 {
+		return "", nil, 0, fmt.Errorf("bech32 string %q has no prefix or no checksum", s)
+	}
+	hrp = s[:sep]
+	if err := checkBech32HRP(hrp); err != nil {
+		return "", nil, 0, err
+	}
+	values := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, 0, fmt.Errorf("bech32 string %q: invalid character %q at %d", s, s[i], i)
+		}
+		values = append(values, byte(v))
+	}
+	switch c := Bech32Encoding(bech32Polymod(append(bech32HRPExpand(hrp), values...))); c {
+	case Bech32, Bech32m:
+		enc = c
+	default:
+		return "", nil, 0, fmt.Errorf("%w: %s", ErrBech32Checksum, s)
+	}
+	values = values[:len(values)-6]
+	data, ok := convertBitsStrict(values)
+	if !ok {
+		return "", nil, 0, fmt.Errorf("bech32 string %q has leftover bits", s)
+	}
+	return hrp, data, enc, nil
+}
+
+// checkBech32HRP checks that hrp is 1 to 83 printable ASCII characters.
+func checkBech32HRP(hrp string) error {
+	if len(hrp) < 1 || len(hrp) > 83 {
+		return fmt.Errorf("bech32 prefix %q must be 1 to 83 characters", hrp)
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return fmt.Errorf("bech32 prefix %q has a character outside printable ASCII", hrp)
+		}
+	}
+	return nil
+}
+
+// convertBits regroups data from groups of from bits into groups of to
+// bits, padding the last group with zeros if pad is set.
+func convertBits(data []byte, from, to uint, pad bool) []byte {
+	var acc uint32
+	var bits uint
+	var out []byte
+	maxv := uint32(1)<<to - 1
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad && bits > 0 {
+		out = append(out, byte(acc<<(to-bits)&maxv))
+	}
+	return out
+}
+
+// convertBitsStrict regroups 5-bit values back into bytes, failing if the
+// padding is longer than a group or isn't zero, as it is for any string
+// EncodeBech32 didn't write.
+func convertBitsStrict(values []byte) ([]byte, bool) {
+	out := convertBits(values, 5, 8, false)
+	rest := len(values)*5 - len(out)*8
+	if rest >= 5 || (len(values) > 0 && values[len(values)-1]&(1<<rest-1) != 0) {
+		return nil, false
+	}
+	return out, true
+}
+
+// Bech32 returns the address in bech32m under hrp, e.g. "gp1…".
+func (a Address) Bech32(hrp string) (string, error) {
+	return EncodeBech32(Bech32m, hrp, a[:])
+}
+
+// ParseBech32Address parses an address written by Address.Bech32 under
+// hrp. A string under another prefix, in plain bech32, or of the wrong
+// length is refused, so an address for another network isn't mistaken
+// for one here.
+func ParseBech32Address(hrp, s string) (Address, error) {
+	got, data, enc, err := DecodeBech32(s)
+	if err != nil {
+		return Address{}, err
+	}
+	var a Address
+	switch {
+	case got != strings.ToLower(hrp):
+		return a, fmt.Errorf("address %q is for prefix %q, not %q", s, got, hrp)
+	case enc != Bech32m:
+		return a, fmt.Errorf("address %q is %v, want %v", s, enc, Bech32m)
+	case len(data) != AddressLength:
+		return a, fmt.Errorf("address %q holds %d bytes, want %d", s, len(data), AddressLength)
+	}
+	copy(a[:], data)
+	return a, nil
+}
+
+// runAddress implements the "address" command: it parses an address in
+// hex, checksummed or not, or in bech32m, and prints it in every form.
+func runAddress(args []string) error {
+	fs := flag.NewFlagSet("address", flag.ContinueOnError)
+	hrp := fs.String("hrp", DefaultAddressHRP, "bech32m human-readable prefix")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: address [-hrp PREFIX] <0x address or bech32m address>")
+	}
+	s := fs.Arg(0)
+	var a Address
+	var err error
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		a, err = ParseCheckedAddress(s)
+	} else {
+		a, err = ParseBech32Address(*hrp, s)
+	}
+	if err != nil {
+		return err
+	}
+	b, err := a.Bech32(*hrp)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Hex:     ", a)
+	fmt.Println("EIP-55:  ", a.Checksum())
+	fmt.Println("Bech32m: ", b)
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// The valid checksum vectors from BIP-173 and BIP-350.
+var (
+	bech32Valid = []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"11" + strings.Repeat("q", 82) + "c8247j",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"?1ezyfcl",
+	}
+	bech32mValid = []string{
+		"A1LQFN3A",
+		"a1lqfn3a",
+		"an83characterlonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11sg7hg6",
+		"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx",
+		"split1checkupstagehandshakeupstreamerranterredcaperredlc445v",
+		"?1v759aa",
+	}
+)
+
+func TestDecodeBech32Vectors(t *testing.T) {
+	for enc, vectors := range map[Bech32Encoding][]string{Bech32: bech32Valid, Bech32m: bech32mValid} {
+		for _, s := range vectors {
+			hrp, data, got, err := DecodeBech32(s)
+			if err != nil {
+				t.Errorf("%s: %v", s, err)
+				continue
+			}
+			if got != enc {
+				t.Errorf("%s: decoded as %v, want %v", s, got, enc)
+			}
+			again, err := EncodeBech32(enc, hrp, data)
+			if err != nil || again != strings.ToLower(s) {
+				t.Errorf("%s: re-encoded as %q, %v", s, again, err)
+			}
+		}
+	}
+
+	// BIP-350's vector of 82 "l"s leaves nonzero bits over when regrouped
+	// into bytes, so DecodeBech32 refuses it; its checksum is still good.
+	var values []byte
+	for _, c := range strings.Repeat("l", 82) + "ludsr8" {
+		values = append(values, byte(strings.IndexRune(bech32Charset, c)))
+	}
+	if got := Bech32Encoding(bech32Polymod(append(bech32HRPExpand("1"), values...))); got != Bech32m {
+		t.Errorf("82 l's: checksum gives %v, want %v", got, Bech32m)
+	}
+}
+
+func TestDecodeBech32RejectsInvalid(t *testing.T) {
+	for _, s := range []string{
+		"pzry9x0s0muk",  // no separator
+		"1pzry9x0s0muk", // empty prefix
+		"x1b4n0q5v",     // "b" isn't in the alphabet
+		"li1dgmt3",      // checksum too short
+		"10a06t8",       // empty prefix
+		"1qzzfhee",      // empty prefix
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", // too long
+		"mm1crxm3i", // "i" in the checksum
+		"au1s5cgom", // "o" in the checksum
+		"A12uEL5L",  // mixed case
+		"a12uel5m",  // last character changed
+		"a12uel5lq", // character appended
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxx",
+	} {
+		if _, _, _, err := DecodeBech32(s); err == nil {
+			t.Errorf("%s: decoded", s)
+		}
+	}
+	// Checksums computed over the upper-case prefix.
+	for _, s := range []string{"A1G7SGD8", "M1VUXWEZ"} {
+		if _, _, _, err := DecodeBech32(s); !errors.Is(err, ErrBech32Checksum) {
+			t.Errorf("%s: err = %v, want ErrBech32Checksum", s, err)
+		}
+	}
+}
+
+func TestBech32AddressRoundTrip(t *testing.T) {
+	a, err := ParseAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "gp1t2htvpfl862vnwdqnuekd9p4ulh3h6hduderpx"
+	s, err := a.Bech32(DefaultAddressHRP)
+	if err != nil || s != want {
+		t.Fatalf("Bech32 = %q, %v, want %q", s, err, want)
+	}
+	if got, err := ParseBech32Address(DefaultAddressHRP, strings.ToUpper(s)); err != nil || got != a {
+		t.Errorf("ParseBech32Address = %v, %v", got, err)
+	}
+
+	plain, err := EncodeBech32(Bech32, DefaultAddressHRP, a[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := a.Bech32("tb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	short, err := EncodeBech32(Bech32m, DefaultAddressHRP, a[:19])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{plain, other, short} {
+		if _, err := ParseBech32Address(DefaultAddressHRP, bad); err == nil {
+			t.Errorf("%s: parsed as an address", bad)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// Bloom filter parameters. 2048 bits with three probes keeps the false
+// positive rate around 1% for a block touching 150 distinct items.
+const (
+	BloomBytes  = 256
+	bloomProbes = 3
+)
+
+// blockBloom builds the bloom filter for a block's transactions: every
+// transaction hash plus every sender and recipient address. A block with no
+// transactions has a nil filter.
+func blockBloom(txs []Transaction) []byte {
+	if len(txs) == 0 {
+		return nil
+	}
+	bloom := make([]byte, BloomBytes)
+	for _, tx := range txs {
+		bloomAdd(bloom, tx.Hash)
+		bloomAdd(bloom, tx.From)
+		for _, l := range tx.Legs() {
+			bloomAdd(bloom, l.To)
+		}
+	}
+	return bloom
+}
+
+func bloomAdd(bloom []byte, item string) {
+	for _, bit := range bloomBits(item) {
+		bloom[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// bloomBits derives the probe positions for item from its SHA-256 hash.
+func bloomBits(item string) [bloomProbes]uint {
+	sum := sha256.Sum256([]byte(item))
+	var bits [bloomProbes]uint
+	for i := range bits {
+		bits[i] = uint(binary.BigEndian.Uint16(sum[2*i:])) % (BloomBytes * 8)
+	}
+	return bits
+}
+
+// MayContain reports whether the block might include a transaction with the
+// given hash, or one sent from or to the given address. False means it
+// certainly doesn't; true means the body is worth fetching to find out.
+// It needs only the header, so it works on pruned blocks too.
+func (b Block) MayContain(item string) bool {
+	if len(b.Bloom) != BloomBytes {
+		return false
+	}
+	for _, bit := range bloomBits(item) {
+		if b.Bloom[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyBloom checks that a block's bloom filter matches its transactions.
+// Pruned blocks were checked when they arrived.
+func verifyBloom(b Block) error {
+	if b.IsPruned() {
+		return nil
+	}
+	if !bytes.Equal(b.Bloom, blockBloom(b.Transactions)) {
+		return fmt.Errorf("block %d: bloom filter does not match its transactions", b.Index)
+	}
+	return nil
+}
+
+// FilterBlocks returns the blocks among headers whose bloom filters match
+// item. A light client holding only headers can use it to decide which
+// block bodies to request.
+func FilterBlocks(headers []Block, item string) []Block {
+	var matches []Block
+	for _, b := range headers {
+		if b.MayContain(item) {
+			matches = append(matches, b)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestBlockBloom(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	tx := signedTx(t, c, alice, Transaction{Transfers: []Transfer{{To: bob.Addr, Amount: Coin}, {To: carol.Addr, Amount: Coin}}})
+	b, err := c.buildBlock("", []Transaction{tx}, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range []string{tx.Hash, alice.Addr, bob.Addr, carol.Addr} {
+		if !b.MayContain(item) {
+			t.Errorf("bloom misses %s", item)
+		}
+	}
+	if (Block{}).MayContain(alice.Addr) {
+		t.Error("a block without a bloom filter matched")
+	}
+
+	// A light client only fetches the block that paid bob.
+	empty, err := c.buildBlock("", nil, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FilterBlocks([]Block{empty, b}, bob.Addr); len(got) != 1 || got[0].Hash != b.Hash {
+		t.Errorf("FilterBlocks matched %d blocks, want just the payment", len(got))
+	}
+
+	lying := b
+	lying.Bloom = blockBloom(nil)
+	if err := c.config.Engine.Seal(&lying); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(lying); err == nil {
+		t.Error("block with the wrong bloom filter was accepted")
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TxBuilder assembles a debit step by step:
+//
+//	tx, err := NewTxBuilder().
+//		From(alice).To(bob).Amount(MustParseAmount("2.50")).Fee(fee).
+//		NonceFrom(ledger).Description("lunch").SignWith(key).
+//		Build()
+//
+// Each setter returns the builder; Build checks the result, fills in what
+// was left to it, and returns the transaction hashed and, given a key,
+// signed.
+type TxBuilder struct {
+	tx     Transaction
+	ledger *Ledger
+	pool   *Mempool
+	key    *ecdsa.PrivateKey
+}
+
+// NewTxBuilder starts a debit in CurrentTxVersion with no other fields set.
+func NewTxBuilder() *TxBuilder {
+	return &TxBuilder{tx: Transaction{Version: CurrentTxVersion, Type: Debit}}
+}
+
+// ID sets the transaction's statement ID.
+func (b *TxBuilder) ID(id int) *TxBuilder {
+	b.tx.ID = id
+	return b
+}
+
+// From sets the sender.
+func (b *TxBuilder) From(addr string) *TxBuilder {
+	b.tx.From = addr
+	return b
+}
+
+// To sets the recipient of an ordinary transaction.
+func (b *TxBuilder) To(addr string) *TxBuilder {
+	b.tx.To = addr
+	return b
+}
+
+// Amount sets what an ordinary transaction pays To.
+func (b *TxBuilder) Amount(a Amount) *TxBuilder {
+	b.tx.Amount = a
+	return b
+}
+
+// Transfer adds a leg, making the transaction a multi-transfer; To and
+// Amount must then be left unset.
+func (b *TxBuilder) Transfer(to string, amount Amount) *TxBuilder {
+	b.tx.Transfers = append(b.tx.Transfers, Transfer{To: to, Amount: amount})
+	return b
+}
+
+// Fee sets the fee.
+func (b *TxBuilder) Fee(fee Amount) *TxBuilder {
+	b.tx.Fee = fee
+	return b
+}
+
+// Description sets the note shown on statements.
+func (b *TxBuilder) Description(s string) *TxBuilder {
+	b.tx.Description = s
+	return b
+}
+
+// Category sets the reporting category.
+func (b *TxBuilder) Category(c string) *TxBuilder {
+	b.tx.Category = c
+	return b
+}
+
+// Tag adds reporting tags.
+func (b *TxBuilder) Tag(tags ...string) *TxBuilder {
+	b.tx.Tags = append(b.tx.Tags, tags...)
+	return b
+}
+
+// Time sets the transaction's time. Build uses the current time otherwise.
+func (b *TxBuilder) Time(t time.Time) *TxBuilder {
+	b.tx.Time = t
+	return b
+}
+
+// Nonce sets the nonce explicitly.
+func (b *TxBuilder) Nonce(n uint64) *TxBuilder {
+	b.tx.Nonce = n
+	return b
+}
+
+// NonceFrom makes Build take the sender's next nonce from l, overriding
+// Nonce, and check there that the sender can afford the transaction.
+func (b *TxBuilder) NonceFrom(l *Ledger) *TxBuilder {
+	b.ledger = l
+	return b
+}
+
+// FeeFrom makes Build set the fee to m's estimate for the finished
+// transaction, overriding Fee; see Mempool.EstimateCost.
+func (b *TxBuilder) FeeFrom(m *Mempool) *TxBuilder {
+	b.pool = m
+	return b
+}
+
+// Version sets the transaction's format, e.g. TxVersion0 for a node that
+// predates TxVersion1.
+func (b *TxBuilder) Version(v uint32) *TxBuilder {
+	b.tx.Version = v
+	return b
+}
+
+// Asset makes the transaction move a token instead of the native coin; the
+// fee is still paid in the native coin.
+func (b *TxBuilder) Asset(a Asset) *TxBuilder {
+	b.tx.Asset = a
+	return b
+}
+
+// When adds conditions that must all hold in the block that includes the
+// transaction.
+func (b *TxBuilder) When(conds ...Condition) *TxBuilder {
+	b.tx.Conditions = append(b.tx.Conditions, conds...)
+	return b
+}
+
+// Expires makes the transaction invalid in blocks above height or
+// timestamped after at; a zero value sets no bound on that side.
+func (b *TxBuilder) Expires(height int, at time.Time) *TxBuilder {
+	b.tx.Expires = nil
+	if height != 0 || !at.IsZero() {
+		b.tx.Expires = &Expiry{Height: height, Time: at}
+	}
+	return b
+}
+
+// Bind binds the transaction to a chain ID and, if forkID isn't empty, a
+// fork. Fork IDs need TxVersion1 or later.
+func (b *TxBuilder) Bind(chainID, forkID string) *TxBuilder {
+	b.tx.ChainID, b.tx.ForkID = chainID, forkID
+	return b
+}
+
+// OnChain binds the transaction to c's chain ID and to the fork the next
+// block will be on.
+func (b *TxBuilder) OnChain(c *Chain) *TxBuilder {
+	return b.Bind(c.config.ChainID, c.ForkID(c.Tip().Index+1))
+}
+
+// Multisig makes the transaction spend from p's address; SignWith then
+// adds a cosignature.
+func (b *TxBuilder) Multisig(p *MultisigPolicy) *TxBuilder {
+	b.tx.Multisig = p
+	return b
+}
+
+// SignWith makes Build sign the transaction with key, or cosign it if it
+// spends from a multisig address, or sign it as a party if it settles an
+// escrow.
+func (b *TxBuilder) SignWith(key *ecdsa.PrivateKey) *TxBuilder {
+	b.key = key
+	return b
+}
+
+// Build returns the assembled transaction with its hash computed and, if a
+// key was given, signed. It fails for anything a block would reject on the
+// transaction's own merits: a missing sender or recipient, negative
+// amounts, or, with NonceFrom, a sender who can't cover amount and fee or
+// whose spending limit won't allow them. It also catches a mistyped hex
+// address, by its length or its EIP-55 checksum, and writes valid ones in
+// lowercase.
+func (b *TxBuilder) Build() (Transaction, error) {
+	tx := b.tx
+	tx.Transfers = append([]Transfer(nil), b.tx.Transfers...)
+	tx.Tags = append([]string(nil), b.tx.Tags...)
+	tx.Conditions = append([]Condition(nil), b.tx.Conditions...)
+	if tx.Time.IsZero() {
+		tx.Time = time.Now()
+	}
+	var err error
+	if tx.From, err = canonicalAddress(tx.From); err != nil {
+		return Transaction{}, fmt.Errorf("sender: %w", err)
+	}
+	if tx.To, err = canonicalAddress(tx.To); err != nil {
+		return Transaction{}, fmt.Errorf("recipient: %w", err)
+	}
+	for i := range tx.Transfers {
+		if tx.Transfers[i].To, err = canonicalAddress(tx.Transfers[i].To); err != nil {
+			return Transaction{}, fmt.Errorf("leg %d: %w", i, err)
+		}
+	}
+	if b.ledger != nil {
+		from, ok := b.ledger.accounts[tx.From]
+		if !ok {
+			return Transaction{}, fmt.Errorf("%w: %s has no account", ErrInsufficientFunds, tx.From)
+		}
+		tx.Nonce = from.Nonce
+	}
+	if b.pool != nil {
+		tx.Fee = b.pool.EstimateCost(tx).Fee
+	}
+	if tx.From == "" {
+		return Transaction{}, errors.New("transaction has no sender")
+	}
+	if err := checkLegs(tx); err != nil {
+		return Transaction{}, err
+	}
+	if b.ledger != nil {
+		from := b.ledger.accounts[tx.From]
+		if cost := -tx.change(NativeAsset); !from.canAfford(cost) {
+			return Transaction{}, fmt.Errorf("%w: %s has %.2f, needs %.2f", ErrInsufficientFunds, tx.From, from.Balance, cost)
+		}
+		if total, _ := tx.Total(); tx.Asset != NativeAsset && from.BalanceOf(tx.Asset) < total {
+			return Transaction{}, fmt.Errorf("%w: %s has %.2f %s, needs %.2f", ErrInsufficientFunds, tx.From, from.BalanceOf(tx.Asset), tx.Asset, total)
+		}
+		if err := from.checkLimit(-tx.change(NativeAsset), b.ledger.now()); err != nil {
+			return Transaction{}, err
+		}
+	}
+	if err := tx.Asset.Validate(); err != nil {
+		return Transaction{}, err
+	}
+	if err := checkTxVersion(tx); err != nil {
+		return Transaction{}, err
+	}
+	for _, c := range tx.Conditions {
+		if err := c.Validate(); err != nil {
+			return Transaction{}, err
+		}
+	}
+	tx.Hash = computeTxHash(tx)
+	if b.key != nil {
+		sign := SignTx
+		switch {
+		case tx.Multisig != nil:
+			sign = CosignTx
+		case tx.Escrow != nil:
+			sign = SignEscrowTx
+		case tx.Reverses != "":
+			sign = SignReversalTx
+		}
+		if err := sign(&tx, b.key); err != nil {
+			return Transaction{}, err
+		}
+	}
+	return tx, nil
+}
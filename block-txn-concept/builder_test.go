@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTxBuilderFillsInAndSigns(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	l := NewLedger()
+	l.Mint(alice.Addr, 10*Coin)
+	l.Account(alice.Addr).Nonce = 3
+
+	a, err := ParseAddress(bob.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	tx, err := NewTxBuilder().From(alice.Addr).To(a.Checksum()).Amount(2 * Coin).Fee(Coin / 10).
+		Nonce(99).NonceFrom(l).Description("lunch").SignWith(alice.Key).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.To != bob.Addr {
+		t.Errorf("checksummed recipient written as %s, want %s", tx.To, bob.Addr)
+	}
+	if tx.Nonce != 3 {
+		t.Errorf("nonce %d, want the ledger's 3 over the explicit 99", tx.Nonce)
+	}
+	if tx.Version != CurrentTxVersion || tx.Type != Debit || tx.Time.Before(before) {
+		t.Errorf("version %d, type %s, time %v", tx.Version, tx.Type, tx.Time)
+	}
+	if tx.Hash != computeTxHash(tx) {
+		t.Error("hash not computed")
+	}
+	if err := VerifyTxSignature(tx); err != nil {
+		t.Errorf("signature: %v", err)
+	}
+
+	unsigned, err := NewTxBuilder().From(alice.Addr).To(bob.Addr).Amount(Coin).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsigned.Signature) != 0 {
+		t.Error("a builder without a key signed")
+	}
+}
+
+func TestTxBuilderRejects(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	l := NewLedger()
+	l.Mint(alice.Addr, 10*Coin)
+	a, err := ParseAddress(bob.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip the case of one letter in the checksummed form.
+	mistyped := []byte(a.Checksum())
+	for i := 2; i < len(mistyped); i++ {
+		if c := mistyped[i]; c >= 'a' && c <= 'f' {
+			mistyped[i] = c - 'a' + 'A'
+			break
+		} else if c >= 'A' && c <= 'F' {
+			mistyped[i] = c - 'A' + 'a'
+			break
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		b    *TxBuilder
+		want string
+	}{
+		"no sender":      {NewTxBuilder().To(bob.Addr).Amount(Coin), "no sender"},
+		"short address":  {NewTxBuilder().From(alice.Addr).To(bob.Addr[:20]).Amount(Coin), "recipient"},
+		"bad checksum":   {NewTxBuilder().From(alice.Addr).To(string(mistyped)).Amount(Coin), "recipient"},
+		"negative":       {NewTxBuilder().From(alice.Addr).To(bob.Addr).Amount(-Coin), ""},
+		"unknown sender": {NewTxBuilder().From(bob.Addr).To(alice.Addr).Amount(Coin).NonceFrom(l), "no account"},
+		"overspend":      {NewTxBuilder().From(alice.Addr).To(bob.Addr).Amount(10 * Coin).Fee(1).NonceFrom(l), "needs"},
+	} {
+		_, err := tc.b.Build()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want one mentioning %q", name, err, tc.want)
+		}
+		if strings.HasPrefix(name, "unknown") || name == "overspend" {
+			if !errors.Is(err, ErrInsufficientFunds) {
+				t.Errorf("%s: err = %v, want ErrInsufficientFunds", name, err)
+			}
+		}
+	}
+}
+
+func TestTxBuilderBuildsIndependentCopies(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	b := NewTxBuilder().From(alice.Addr).Transfer(bob.Addr, Coin).Tag("a").Time(testStart)
+	first, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := b.Transfer(carol.Addr, Coin).Tag("b").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Transfers) != 1 || len(first.Tags) != 1 {
+		t.Errorf("building again changed the first transaction: %d legs, tags %v", len(first.Transfers), first.Tags)
+	}
+	if len(second.Transfers) != 2 || first.Hash == second.Hash {
+		t.Errorf("second transaction has %d legs", len(second.Transfers))
+	}
+	if total, _ := second.Total(); total != 2*Coin {
+		t.Errorf("multi-transfer total %s, want 2", total)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// canonicalWriter writes the canonical encoding transactions are hashed
+// and signed in from TxVersion1 on. Each field is a one-character tag, the
+// length of its value in bytes in decimal, a colon, and the value, so the
+// encoding is unambiguous: a From of "ab" and a To of "c" can't run
+// together into what "a" and "bc" give. Values are strings in fixed
+// formats, integers in decimal, amounts as Amount.hashString, and times as
+// RFC 3339 in UTC, never floats formatted with %f. sign-transaction keeps
+// its own copy of this framing and writes the same TxVersion1 payload, so
+// a signature from either verifies in the other.
+type canonicalWriter struct{ w io.Writer }
+
+func (c canonicalWriter) field(tag, value string) {
+	io.WriteString(c.w, tag+strconv.Itoa(len(value))+":"+value)
+}
+
+// SigningPayload returns the bytes t's hash is the SHA-256 of, and so what
+// its signature covers: every field but Hash, PubKey, and Signature, in
+// the canonical encoding. A TxVersion0 transaction has none, since its
+// hash concatenates fields without lengths and predates the encoding.
+func SigningPayload(t Transaction) ([]byte, error) {
+	if t.Version == TxVersion0 {
+		return nil, fmt.Errorf("transaction version %d has no canonical payload", t.Version)
+	}
+	if err := checkTxVersion(t); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	hashTxV1(&buf, t)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSigningPayloadVector(t *testing.T) {
+	tx := Transaction{Version: TxVersion1, From: "ab", To: "c", Amount: 42 * Coin, Time: testStart, Type: Debit}
+	const want = "v1:1i1:0f2:abt1:cT20:2024-01-01T00:00:00Zd0:a9:42.000000F8:0.000000y5:debitC0:K0:n1:0L1:0c0:G1:0s0:A0:e0:"
+	payload, err := SigningPayload(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != want {
+		t.Errorf("payload %q, want %q", payload, want)
+	}
+	sum := sha256.Sum256(payload)
+	if got := computeTxHash(tx); got != "0x"+hex.EncodeToString(sum[:]) {
+		t.Errorf("hash %s is not the SHA-256 of the payload", got)
+	}
+	if got := computeTxHash(tx); got != "0x4a717b9fd58b7c0af8b9d25e1c7a86f915ee17e9aa717b6b328fe82ac372fdb2" {
+		t.Errorf("hash %s", got)
+	}
+}
+
+func TestSigningPayloadIsUnambiguous(t *testing.T) {
+	a := Transaction{Version: TxVersion1, From: "ab", To: "c", Amount: Coin, Type: Debit}
+	b := Transaction{Version: TxVersion1, From: "a", To: "bc", Amount: Coin, Type: Debit}
+	pa, err := SigningPayload(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := SigningPayload(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(pa, pb) {
+		t.Error(`From "ab", To "c" encodes the same as From "a", To "bc"`)
+	}
+	if _, err := SigningPayload(Transaction{Version: TxVersion0}); err == nil {
+		t.Error("version 0 transaction has a payload")
+	}
+}
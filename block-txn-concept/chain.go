@@ -0,0 +1,810 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Defaults used when a ChainConfig leaves a field at zero.
+const (
+	DefaultTargetBlockInterval = 10 * time.Second
+	DefaultMaxUncleDepth       = 6
+	uncleRewardDivisor         = 8 // uncle reward is (8 - depth) / 8 of a block reward
+	nephewRewardDivisor        = 32
+)
+
+// ForkChoiceRule selects how the chain picks its best tip among branches.
+type ForkChoiceRule string
+
+const (
+	// MostWork picks the tip of the branch with the greatest cumulative
+	// proof-of-work. Equal work is broken in favour of the lower block hash,
+	// so every node picks the same tip regardless of arrival order.
+	MostWork ForkChoiceRule = "work"
+	// LongestChain picks the tip of the branch with the most blocks, keeping
+	// whichever arrived first on a tie.
+	LongestChain ForkChoiceRule = "longest"
+	// GHOST (greedy heaviest-observed subtree) walks down from genesis,
+	// always stepping into the child whose subtree holds the most blocks,
+	// so work spent on orphaned siblings still counts for their parent.
+	GHOST ForkChoiceRule = "ghost"
+)
+
+// ChainConfig holds the consensus parameters every node on a chain agrees on.
+type ChainConfig struct {
+	// ChainID names the chain. Transactions carry the ID of the chain they
+	// are meant for and are rejected anywhere else, so several chains can
+	// run side by side without a transaction being replayed across them.
+	ChainID string
+
+	Engine      Engine
+	BlockReward Amount
+	ForkChoice  ForkChoiceRule // defaults to MostWork
+
+	// Alloc pre-funds accounts: each address starts with its amount as of
+	// the genesis block.
+	Alloc map[string]Amount
+
+	// GenesisKey, if set, is the operator key the chain must be attested
+	// by: NewChain refuses to start unless Attestation is signed with it and
+	// matches the genesis block, Alloc, and every consensus parameter.
+	GenesisKey  *ecdsa.PublicKey
+	Attestation *GenesisAttestation
+
+	// TargetBlockInterval is the desired time between blocks. Every
+	// RetargetInterval blocks the proof-of-work target is scaled by how far
+	// the actual interval strayed from it. RetargetInterval of zero keeps
+	// the genesis difficulty forever.
+	TargetBlockInterval time.Duration
+	RetargetInterval    int
+
+	// Uncles are recent stale blocks that a new block may reference to pay
+	// their miners a partial reward. MaxUncles of zero disables them.
+	MaxUncles      int
+	MaxUncleDepth  int  // how many generations back an uncle may be
+	CountUncleWork bool // count referenced uncles towards fork-choice weight
+
+	// AssumeValid, if set, is a trusted block. Once its headers are given to
+	// Chain.AssumeValidHeaders, its ancestors skip signature checks during
+	// sync; hash links, structure, and proof-of-work are still verified.
+	// Blocks on any other branch are checked in full, and a different block
+	// at its height is rejected outright.
+	AssumeValid *Checkpoint
+
+	// PruneDepth, if set, discards transaction bodies from best-chain blocks
+	// more than PruneDepth blocks below the tip. Headers and transaction
+	// hashes are kept, which is all that validating new blocks needs.
+	PruneDepth int
+
+	// Clock supplies timestamps for BuildBlock and the time AddBlock checks
+	// block timestamps against. It defaults to time.Now; see StepClock for
+	// reproducible tests.
+	Clock func() time.Time
+
+	// FinalityDepth is the number of confirmations after which a block is
+	// final and can no longer be reorganised away. Zero disables finality.
+	FinalityDepth int
+
+	// Upgrades schedules header versions. Until the first upgrade, legacy
+	// headers are accepted alongside newer ones; from each upgrade's height
+	// on, older versions are rejected. Without a schedule, every block must
+	// be a CurrentBlockVersion header.
+	Upgrades []Upgrade
+
+	// GCInterval, if set, runs CollectGarbage every GCInterval blocks of
+	// best-chain growth to drop side branches that can never be reorged to.
+	// It needs FinalityDepth to know what "never" is.
+	GCInterval int
+
+	// MinFee, if set, is the least fee a transaction may pay.
+	MinFee Amount
+
+	// StatusAuthority is the address whose transactions may change account
+	// statuses and who signs reversals; see NewStatusChange and
+	// NewReversal. Empty means neither can happen.
+	StatusAuthority string
+
+	// Overdraft sets how far below zero each listed sender's balance may
+	// go, from BlockVersion3 on; every other sender is strict.
+	Overdraft map[string]OverdraftPolicy
+
+	// SpendingLimits caps what each listed sender may send, fees included,
+	// in any window of block time; see SpendingLimit.
+	SpendingLimits map[string]SpendingLimit
+
+	// TxRules are extra rules every transaction must pass, after the
+	// built-in ones, both in blocks and in the mempool. They are code, so
+	// Spec can't describe them; every node must be given the same ones.
+	TxRules []TxValidator
+}
+
+// ErrDoubleSpend is returned for a block containing a transaction that
+// already appears earlier in the block or on the branch it extends.
+var ErrDoubleSpend = errors.New("transaction already included")
+
+// ErrWrongChain is returned for a block containing a transaction bound to a
+// different chain ID.
+var ErrWrongChain = errors.New("transaction is for a different chain")
+
+// ErrFinalizedReorg is returned when a block would fork the chain below its
+// finalized height.
+var ErrFinalizedReorg = errors.New("block forks below finalized height")
+
+// Checkpoint names a block by height and hash.
+type Checkpoint struct {
+	Height int
+	Hash   string
+}
+
+// Chain stores every valid block it has seen, including side branches, and
+// tracks the best tip.
+type Chain struct {
+	config   ChainConfig
+	blocks   map[string]*Block
+	weight   map[string]int      // block count of the branch ending at a block
+	work     map[string]*big.Int // cumulative work of the branch ending at a block
+	children map[string][]string // child hashes in the order they arrived
+	subtree  map[string]int      // number of blocks in the subtree rooted at a block
+	arrival  map[string]int      // order blocks were added in, for Reindex
+	arrived  int                 // blocks added so far, genesis included
+	genesis  *Block
+	tip      *Block
+
+	nonces    map[string]map[string]uint64        // next nonce of each sender in a block, after it
+	statuses  map[string]map[string]AccountStatus // account statuses a block changed, after it
+	txIndex   map[string]TxLocation               // best-chain transactions by hash
+	addrIndex map[string][]string                 // best-chain tx hashes by address, oldest first
+	receipts  map[string]Receipt                  // best-chain execution results by tx hash
+	reversals map[string]string                   // best-chain reversal hashes by the hash they reverse
+
+	snapshots map[string]*Snapshot // cached balances, keyed by block hash
+	restored  map[string]bool      // snapshots supplied via RestoreSnapshot
+
+	assumeValid  map[string]bool // the AssumeValid checkpoint and its ancestors
+	assumedValid int             // blocks whose signatures were skipped under AssumeValid
+	gcStats      GCStats
+
+	subs      map[int]subscription
+	nextSubID int
+
+	lastClock time.Time // latest reading of config.Clock
+}
+
+// NewChain creates a chain rooted at genesis. The genesis block is trusted
+// as-is; its seal is not checked.
+func NewChain(config ChainConfig, genesis Block) (*Chain, error) {
+	if config.Engine == nil {
+		return nil, errors.New("chain config has no consensus engine")
+	}
+	switch config.ForkChoice {
+	case "":
+		config.ForkChoice = MostWork
+	case MostWork, LongestChain, GHOST:
+	default:
+		return nil, fmt.Errorf("unknown fork choice rule %q", config.ForkChoice)
+	}
+	if config.Clock == nil {
+		config.Clock = time.Now
+	}
+	if config.TargetBlockInterval == 0 {
+		config.TargetBlockInterval = DefaultTargetBlockInterval
+	}
+	if config.MaxUncleDepth == 0 {
+		config.MaxUncleDepth = DefaultMaxUncleDepth
+	}
+	if config.MaxUncleDepth >= uncleRewardDivisor {
+		return nil, fmt.Errorf("max uncle depth must be below %d", uncleRewardDivisor)
+	}
+	if err := validateUpgrades(config.Upgrades); err != nil {
+		return nil, err
+	}
+	for addr, amount := range config.Alloc {
+		if amount < 0 {
+			return nil, fmt.Errorf("genesis allocation for %s is negative", addr)
+		}
+	}
+	for addr, p := range config.Overdraft {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", addr, err)
+		}
+	}
+	for addr, l := range config.SpendingLimits {
+		if err := l.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", addr, err)
+		}
+	}
+
+	g := genesis
+	c := &Chain{
+		config:    config,
+		blocks:    map[string]*Block{g.Hash: &g},
+		weight:    map[string]int{g.Hash: 1},
+		work:      map[string]*big.Int{g.Hash: Work(g.Bits)},
+		children:  make(map[string][]string),
+		subtree:   map[string]int{g.Hash: 1},
+		arrival:   map[string]int{g.Hash: 0},
+		arrived:   1,
+		nonces:    make(map[string]map[string]uint64),
+		statuses:  make(map[string]map[string]AccountStatus),
+		txIndex:   make(map[string]TxLocation),
+		addrIndex: make(map[string][]string),
+		receipts:  make(map[string]Receipt),
+		reversals: make(map[string]string),
+		snapshots: make(map[string]*Snapshot),
+		restored:  make(map[string]bool),
+		subs:      make(map[int]subscription),
+		genesis:   &g,
+		tip:       &g,
+	}
+	if s, ok := config.Engine.(*StakeMiner); ok && s.Chain == nil {
+		s.Chain = c
+	}
+	if config.GenesisKey != nil {
+		if err := c.checkAttestation(); err != nil {
+			return nil, err
+		}
+	}
+	c.updateTxIndex([]Block{g}, nil)
+	return c, nil
+}
+
+// Config returns the chain's consensus parameters.
+func (c *Chain) Config() ChainConfig {
+	return c.config
+}
+
+// Tip returns the head of the best chain.
+func (c *Chain) Tip() Block {
+	return *c.tip
+}
+
+// GetBlock returns a block by hash, whether or not it is on the best chain.
+func (c *Chain) GetBlock(hash string) (Block, bool) {
+	b, ok := c.blocks[hash]
+	if !ok {
+		return Block{}, false
+	}
+	return *b, true
+}
+
+// BestChain returns the best chain from genesis to tip, as selected by the
+// configured fork-choice rule.
+func (c *Chain) BestChain() []Block {
+	chain := make([]Block, c.tip.Index+1)
+	for b := c.tip; b != nil; b = c.blocks[b.PrevHash] {
+		chain[b.Index] = *b
+		if b == c.genesis {
+			break
+		}
+	}
+	return chain
+}
+
+// AddBlock validates b against its parent and the consensus engine, stores
+// it, and moves the tip if b's branch is now the heaviest.
+func (c *Chain) AddBlock(b Block) error {
+	if _, ok := c.blocks[b.Hash]; ok {
+		return fmt.Errorf("block %s already known", b.Hash)
+	}
+	parent, ok := c.blocks[b.PrevHash]
+	if !ok {
+		return fmt.Errorf("block %d: unknown parent %s", b.Index, b.PrevHash)
+	}
+	if b.Index != parent.Index+1 {
+		return fmt.Errorf("block %d: index does not follow parent %d", b.Index, parent.Index)
+	}
+	if want := c.NextBits(parent); b.Bits != want {
+		return fmt.Errorf("block %d: bits 0x%08x, expected 0x%08x", b.Index, b.Bits, want)
+	}
+	if fork := c.forkPoint(parent); c.Finalized(fork.Index + 1) {
+		return fmt.Errorf("block %d: %w (fork at %d)", b.Index, ErrFinalizedReorg, fork.Index)
+	}
+	if err := c.validateVersion(b); err != nil {
+		return err
+	}
+	if err := c.validateTimestamp(*parent, b); err != nil {
+		return err
+	}
+	if err := c.verifySeal(b); err != nil {
+		return err
+	}
+	if err := c.validateUncles(b); err != nil {
+		return err
+	}
+	ctx, err := c.validateTransactions(b)
+	if err != nil {
+		return err
+	}
+	if err := verifyBloom(b); err != nil {
+		return err
+	}
+	if err := c.validateCoinbase(b); err != nil {
+		return err
+	}
+	if err := c.validateStateRoot(b); err != nil {
+		return err
+	}
+
+	stored := b
+	oldTip := c.tip
+	c.blocks[b.Hash] = &stored
+	c.arrival[b.Hash] = c.arrived
+	c.arrived++
+	if ctx != nil && len(ctx.nonces) > 0 {
+		c.nonces[b.Hash] = ctx.nonces
+	}
+	if ctx != nil && len(ctx.statuses) > 0 {
+		c.statuses[b.Hash] = ctx.statuses
+	}
+	c.connect(&stored)
+	connected, disconnected := c.tipChange(oldTip)
+	c.updateTxIndex(connected, disconnected)
+	c.prune()
+	if n := c.config.GCInterval; n > 0 && c.tip != oldTip && c.tip.Index/n > oldTip.Index/n {
+		c.CollectGarbage()
+	}
+	c.notifyTipChange(connected, disconnected)
+	return nil
+}
+
+// connect updates the derived fork-choice indexes for a stored block and
+// moves the tip if needed.
+func (c *Chain) connect(b *Block) {
+	c.weight[b.Hash] = c.weight[b.PrevHash] + c.blockWeight(*b)
+	c.work[b.Hash] = new(big.Int).Add(c.work[b.PrevHash], c.blockWork(*b))
+	c.children[b.PrevHash] = append(c.children[b.PrevHash], b.Hash)
+	for p := b; p != nil; p = c.blocks[p.PrevHash] {
+		c.subtree[p.Hash]++
+		if p == c.genesis {
+			break
+		}
+	}
+
+	switch c.config.ForkChoice {
+	case GHOST:
+		c.tip = c.ghostTip()
+	case LongestChain:
+		if c.weight[b.Hash] > c.weight[c.tip.Hash] {
+			c.tip = b
+		}
+	default:
+		cmp := c.work[b.Hash].Cmp(c.work[c.tip.Hash])
+		if cmp > 0 || (cmp == 0 && b.Hash < c.tip.Hash) {
+			c.tip = b
+		}
+	}
+}
+
+// TotalWork returns the cumulative work of the branch ending at blockHash,
+// or nil if the block is unknown.
+func (c *Chain) TotalWork(blockHash string) *big.Int {
+	w, ok := c.work[blockHash]
+	if !ok {
+		return nil
+	}
+	return new(big.Int).Set(w)
+}
+
+// Reindex throws away every index derived from the stored blocks (fork
+// choice weights, the tip, per-block nonces and statuses, the transaction
+// and address indexes, cached balance snapshots, receipts) and rebuilds
+// them from the blocks themselves. Blocks are replayed in the order they
+// arrived, so fork-choice ties go the same way as before. Snapshots added
+// with RestoreSnapshot are kept, since they cannot be recomputed for
+// pruned blocks; so are the nonces and statuses of pruned blocks, and
+// address history for pruned blocks is lost. Use it to recover from
+// corrupted indexes or after adding a new kind of index.
+func (c *Chain) Reindex() error {
+	ordered := make([]*Block, 0, len(c.blocks))
+	for _, b := range c.blocks {
+		if b != c.genesis {
+			ordered = append(ordered, b)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return c.arrival[ordered[i].Hash] < c.arrival[ordered[j].Hash]
+	})
+
+	c.weight = map[string]int{c.genesis.Hash: 1}
+	c.work = map[string]*big.Int{c.genesis.Hash: Work(c.genesis.Bits)}
+	c.children = make(map[string][]string)
+	c.subtree = map[string]int{c.genesis.Hash: 1}
+	c.tip = c.genesis
+	nonces, statuses := c.nonces, c.statuses
+	c.nonces = make(map[string]map[string]uint64)
+	c.statuses = make(map[string]map[string]AccountStatus)
+	for _, b := range ordered {
+		if b.IsPruned() {
+			if n, ok := nonces[b.Hash]; ok {
+				c.nonces[b.Hash] = n
+			}
+			if s, ok := statuses[b.Hash]; ok {
+				c.statuses[b.Hash] = s
+			}
+		} else if len(b.Transactions) > 0 {
+			ctx := c.newTxContext(c.blocks[b.PrevHash], b.Version, b.Timestamp, false)
+			for _, tx := range b.Transactions {
+				ctx.apply(tx)
+			}
+			if len(ctx.nonces) > 0 {
+				c.nonces[b.Hash] = ctx.nonces
+			}
+			if len(ctx.statuses) > 0 {
+				c.statuses[b.Hash] = ctx.statuses
+			}
+		}
+		c.connect(b)
+	}
+
+	c.txIndex = make(map[string]TxLocation)
+	c.addrIndex = make(map[string][]string)
+	c.receipts = make(map[string]Receipt)
+	c.reversals = make(map[string]string)
+	c.updateTxIndex(c.BestChain(), nil)
+
+	snapshots := make(map[string]*Snapshot)
+	for hash := range c.restored {
+		if s, ok := c.snapshots[hash]; ok {
+			snapshots[hash] = s
+		}
+	}
+	c.snapshots = snapshots
+
+	_, err := c.Snapshot(c.tip.Index)
+	return err
+}
+
+// prune drops transaction bodies from best-chain blocks deeper than
+// PruneDepth. It stops at the first block that is already pruned.
+func (c *Chain) prune() {
+	if c.config.PruneDepth <= 0 {
+		return
+	}
+	b := c.ancestorAt(c.tip, c.tip.Index-c.config.PruneDepth-1)
+	for ; b != nil && !b.IsPruned(); b = c.blocks[b.PrevHash] {
+		b.PrunedTxHashes = b.TxHashes()
+		b.Transactions = nil
+		if b == c.genesis {
+			break
+		}
+	}
+}
+
+// ghostTip walks from genesis into the heaviest child subtree at each step.
+// Ties go to the child that arrived first.
+func (c *Chain) ghostTip() *Block {
+	b := c.genesis
+	for {
+		var best string
+		for _, child := range c.children[b.Hash] {
+			if best == "" || c.subtree[child] > c.subtree[best] {
+				best = child
+			}
+		}
+		if best == "" {
+			return b
+		}
+		b = c.blocks[best]
+	}
+}
+
+// Confirmations returns how many blocks on the best chain sit at or above the
+// given block, or 0 if it is unknown or on a side branch.
+func (c *Chain) Confirmations(blockHash string) int {
+	b, ok := c.blocks[blockHash]
+	if !ok || !c.onMainChain(b) {
+		return 0
+	}
+	return c.tip.Index - b.Index + 1
+}
+
+// Finalized reports whether the best-chain block at height has at least
+// FinalityDepth confirmations.
+func (c *Chain) Finalized(height int) bool {
+	if c.config.FinalityDepth <= 0 || height < 0 {
+		return false
+	}
+	return height <= c.tip.Index-c.config.FinalityDepth+1
+}
+
+// ancestorAt returns the ancestor of b (or b itself) at the given height.
+func (c *Chain) ancestorAt(b *Block, height int) *Block {
+	for b != nil && b.Index > height {
+		b = c.blocks[b.PrevHash]
+	}
+	return b
+}
+
+// onMainChain reports whether b is on the best chain.
+func (c *Chain) onMainChain(b *Block) bool {
+	a := c.ancestorAt(c.tip, b.Index)
+	return a != nil && a.Hash == b.Hash
+}
+
+// forkPoint returns the most recent ancestor of b (or b itself) that is on
+// the best chain.
+func (c *Chain) forkPoint(b *Block) *Block {
+	for !c.onMainChain(b) {
+		b = c.blocks[b.PrevHash]
+	}
+	return b
+}
+
+// AssumeValidHeaders records which blocks the AssumeValid checkpoint vouches
+// for. headers run from the genesis block's child up to the checkpoint,
+// oldest first; they need not carry transactions. Each must hash correctly
+// and link to the one before it, so only the checkpoint's true ancestors
+// are trusted, not any block at a height below it.
+func (c *Chain) AssumeValidHeaders(headers []Block) error {
+	av := c.config.AssumeValid
+	if av == nil {
+		return errors.New("chain has no assume-valid checkpoint")
+	}
+	if len(headers) != av.Height {
+		return fmt.Errorf("got %d headers, checkpoint is at height %d", len(headers), av.Height)
+	}
+	ancestors := make(map[string]bool, len(headers))
+	want := av.Hash
+	for i := len(headers) - 1; i >= 0; i-- {
+		h := headers[i]
+		if h.Hash != want {
+			return fmt.Errorf("header %d: hash %s, expected %s", h.Index, h.Hash, want)
+		}
+		if err := verifyBlockHash(h); err != nil {
+			return err
+		}
+		ancestors[h.Hash] = true
+		want = h.PrevHash
+	}
+	if want != c.genesis.Hash {
+		return fmt.Errorf("headers start from %s, not genesis %s", want, c.genesis.Hash)
+	}
+	c.assumeValid = ancestors
+	return nil
+}
+
+// verifySeal checks b's seal, skipping signature verification for the
+// assume-valid checkpoint and its ancestors.
+func (c *Chain) verifySeal(b Block) error {
+	av := c.config.AssumeValid
+	if av == nil || b.Index > av.Height {
+		return c.config.Engine.VerifySeal(b)
+	}
+	if b.Index == av.Height && b.Hash != av.Hash {
+		return fmt.Errorf("block %d: hash %s conflicts with assume-valid checkpoint %s", b.Index, b.Hash, av.Hash)
+	}
+
+	signed, ok := c.config.Engine.(SignedEngine)
+	if !ok || !c.assumeValid[b.Hash] {
+		return c.config.Engine.VerifySeal(b)
+	}
+	if err := signed.VerifyProposer(b); err != nil {
+		return err
+	}
+	c.assumedValid++
+	return nil
+}
+
+// AssumedValid returns how many blocks were accepted without checking their
+// signatures because they fell under the assume-valid checkpoint.
+func (c *Chain) AssumedValid() int {
+	return c.assumedValid
+}
+
+// validateTransactions checks that no transaction in b appears twice,
+// either within b or anywhere on the branch b extends, and runs each
+// through TxRules in order. It returns the context as of the end of b, which
+// holds every sender's next nonce and the statuses b changed, or nil if b
+// has no transactions.
+func (c *Chain) validateTransactions(b Block) (*TxContext, error) {
+	seen := make(map[string]bool, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		if seen[tx.Hash] {
+			return nil, fmt.Errorf("block %d: %w: %s appears twice", b.Index, ErrDoubleSpend, tx.Hash)
+		}
+		seen[tx.Hash] = true
+	}
+	if len(seen) == 0 {
+		return nil, nil
+	}
+
+	parent := c.blocks[b.PrevHash]
+	for p := parent; p != nil; p = c.blocks[p.PrevHash] {
+		for _, hash := range p.TxHashes() {
+			if seen[hash] {
+				return nil, fmt.Errorf("block %d: %w: %s is already in block %d", b.Index, ErrDoubleSpend, hash, p.Index)
+			}
+		}
+		if p == c.genesis {
+			break
+		}
+	}
+
+	ctx := c.newTxContext(parent, b.Version, b.Timestamp, false)
+	for _, tx := range b.Transactions {
+		if err := c.validateTx(ctx, tx); err != nil {
+			return nil, fmt.Errorf("block %d: %w", b.Index, err)
+		}
+		ctx.apply(tx)
+	}
+	return ctx, nil
+}
+
+// blockWork is how much work a single block adds to its branch: the work
+// implied by its own target, plus that of its uncles when CountUncleWork is
+// set.
+func (c *Chain) blockWork(b Block) *big.Int {
+	w := Work(b.Bits)
+	if c.config.CountUncleWork {
+		for _, hash := range b.Uncles {
+			if u, ok := c.blocks[hash]; ok {
+				w.Add(w, Work(u.Bits))
+			}
+		}
+	}
+	return w
+}
+
+// blockWeight is how much a single block adds to its branch's fork-choice
+// weight: one per block, plus one per uncle when CountUncleWork is set.
+func (c *Chain) blockWeight(b Block) int {
+	w := 1
+	if c.config.CountUncleWork {
+		w += len(b.Uncles)
+	}
+	return w
+}
+
+// validateUncles checks every uncle referenced by b: it must be a known
+// block off b's own branch, whose parent is an ancestor of b at most
+// MaxUncleDepth generations back, and not already referenced by an ancestor.
+func (c *Chain) validateUncles(b Block) error {
+	if len(b.Uncles) > c.config.MaxUncles {
+		return fmt.Errorf("block %d: %d uncles exceeds limit of %d", b.Index, len(b.Uncles), c.config.MaxUncles)
+	}
+	if len(b.Uncles) == 0 {
+		return nil
+	}
+
+	// Walk back over the eligible window, noting ancestors and the uncles
+	// they already claimed.
+	ancestors := make(map[string]bool)
+	claimed := make(map[string]bool)
+	p := c.blocks[b.PrevHash]
+	for depth := 0; p != nil && depth <= c.config.MaxUncleDepth; depth++ {
+		ancestors[p.Hash] = true
+		for _, u := range p.Uncles {
+			claimed[u] = true
+		}
+		p = c.blocks[p.PrevHash]
+	}
+
+	seen := make(map[string]bool)
+	for _, hash := range b.Uncles {
+		uncle, ok := c.blocks[hash]
+		switch {
+		case !ok:
+			return fmt.Errorf("block %d: unknown uncle %s", b.Index, hash)
+		case seen[hash] || claimed[hash]:
+			return fmt.Errorf("block %d: uncle %s already included", b.Index, hash)
+		case ancestors[hash]:
+			return fmt.Errorf("block %d: uncle %s is an ancestor", b.Index, hash)
+		case !ancestors[uncle.PrevHash]:
+			return fmt.Errorf("block %d: uncle %s is not a recent sibling branch", b.Index, hash)
+		}
+		depth := b.Index - uncle.Index
+		if depth < 1 || depth > c.config.MaxUncleDepth {
+			return fmt.Errorf("block %d: uncle %s is %d blocks deep", b.Index, hash, depth)
+		}
+		seen[hash] = true
+	}
+	return nil
+}
+
+// UncleCandidates returns side blocks that a new block built on the current
+// tip could reference as uncles, up to MaxUncles.
+func (c *Chain) UncleCandidates() []string {
+	if c.config.MaxUncles == 0 {
+		return nil
+	}
+	next := Block{Index: c.tip.Index + 1, PrevHash: c.tip.Hash}
+
+	hashes := make([]string, 0, len(c.blocks))
+	for hash := range c.blocks {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	var candidates []string
+	for _, hash := range hashes {
+		next.Uncles = append(append([]string(nil), candidates...), hash)
+		if c.validateUncles(next) == nil {
+			candidates = append(candidates, hash)
+			if len(candidates) == c.config.MaxUncles {
+				break
+			}
+		}
+	}
+	return candidates
+}
+
+// BuildBlock assembles a block on top of the current tip, referencing any
+// available uncles and claiming the full MaxCoinbase for coinbase, and seals
+// it with the chain's engine. The block still has to be passed to AddBlock.
+func (c *Chain) BuildBlock(coinbase string, txs []Transaction) (Block, error) {
+	return c.buildBlock(coinbase, txs, CurrentBlockVersion)
+}
+
+// buildBlock is BuildBlock with an older header version.
+func (c *Chain) buildBlock(coinbase string, txs []Transaction, version uint32) (Block, error) {
+	b := Block{
+		Version:      version,
+		Index:        c.tip.Index + 1,
+		Timestamp:    c.readClock(),
+		Bits:         c.NextBits(c.tip),
+		Coinbase:     coinbase,
+		Uncles:       c.UncleCandidates(),
+		Bloom:        blockBloom(txs),
+		PrevHash:     c.tip.Hash,
+		Transactions: txs,
+	}
+	if coinbase != "" {
+		amount, err := c.MaxCoinbase(b)
+		if err != nil {
+			return Block{}, err
+		}
+		b.CoinbaseAmount = amount
+	}
+	if version >= BlockVersion5 {
+		root, err := c.stateRoot(c.tip, b)
+		if err != nil {
+			return Block{}, err
+		}
+		b.StateRoot = root
+	}
+	if err := c.config.Engine.Seal(&b); err != nil {
+		return Block{}, err
+	}
+	return b, nil
+}
+
+// Rewards returns the payouts earned by including b: the coinbase amount
+// (the block reward, fees, and 1/32 of a block reward per uncle, as claimed
+// by the block; legacy blocks claim the full amount implicitly) for b's
+// coinbase, and (8-depth)/8 of a block reward for each uncle's coinbase.
+// It fails if a payout doesn't fit in an Amount.
+func (c *Chain) Rewards(b Block) (map[string]Amount, error) {
+	rewards := make(map[string]Amount)
+	if b.Coinbase != "" {
+		amount := b.CoinbaseAmount
+		if b.Version == LegacyBlockVersion {
+			var err error
+			if amount, err = c.MaxCoinbase(b); err != nil {
+				return nil, fmt.Errorf("block %d: coinbase: %w", b.Index, err)
+			}
+		}
+		rewards[b.Coinbase] = amount
+	}
+	for _, hash := range b.Uncles {
+		uncle, ok := c.blocks[hash]
+		if !ok {
+			continue
+		}
+		depth := b.Index - uncle.Index
+		if uncle.Coinbase != "" {
+			reward, err := c.config.BlockReward.MulDiv(int64(uncleRewardDivisor-depth), uncleRewardDivisor)
+			if err == nil {
+				reward, err = rewards[uncle.Coinbase].Add(reward)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("block %d: uncle %s reward: %w", b.Index, hash, err)
+			}
+			rewards[uncle.Coinbase] = reward
+		}
+	}
+	return rewards, nil
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// authorityConfig is testConfig on a proof-of-authority engine with a
+// single authority, whose clock the returned function sets.
+func authorityConfig(signer testAccount) (ChainConfig, func(time.Time)) {
+	var now time.Time
+	cfg := testConfig(nil)
+	cfg.Engine = &AuthorityEngine{
+		Authorities:  []Authority{{Address: signer.Addr, PubKey: &signer.Key.PublicKey}},
+		StepDuration: 10 * time.Second,
+		Address:      signer.Addr,
+		Key:          signer.Key,
+	}
+	cfg.Clock = func() time.Time { return now }
+	return cfg, func(t time.Time) { now = t }
+}
+
+func TestAssumeValidOnlyCoversCheckpointAncestors(t *testing.T) {
+	signer := newTestAccount(t)
+	cfg, setNow := authorityConfig(signer)
+	src := newTestChain(t, cfg)
+	var headers []Block
+	for i := 1; i <= 3; i++ {
+		setNow(testStart.Add(time.Duration(i) * 10 * time.Second))
+		b, err := src.BuildBlock("", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := src.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+		headers = append(headers, b)
+	}
+
+	// A side branch at height 1 with a signature nobody made.
+	setNow(testStart.Add(40 * time.Second))
+	src2 := newTestChain(t, cfg)
+	forged, err := src2.BuildBlock("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.Seal = []byte("forged")
+	forged.Hash = hashBlock(forged)
+
+	cfg.AssumeValid = &Checkpoint{Height: 3, Hash: headers[2].Hash}
+	c := newTestChain(t, cfg)
+	if err := c.AssumeValidHeaders(headers); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(forged); err == nil {
+		t.Error("forged side-branch block below the checkpoint was accepted")
+	}
+	for _, b := range headers {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := c.AssumedValid(); got != 3 {
+		t.Errorf("AssumedValid = %d, want 3", got)
+	}
+}
+
+func TestAssumeValidHeadersMustLinkToCheckpoint(t *testing.T) {
+	signer := newTestAccount(t)
+	cfg, setNow := authorityConfig(signer)
+	src := newTestChain(t, cfg)
+	setNow(testStart.Add(10 * time.Second))
+	b, err := src.BuildBlock("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.AssumeValid = &Checkpoint{Height: 1, Hash: b.Hash}
+	c := newTestChain(t, cfg)
+	tampered := b
+	tampered.Seal = []byte("forged")
+	if err := c.AssumeValidHeaders([]Block{tampered}); err == nil {
+		t.Error("header that doesn't hash to the checkpoint was accepted")
+	}
+	if err := c.AssumeValidHeaders([]Block{b}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReindexKeepsGhostTipAndIndexes(t *testing.T) {
+	auth, victim := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(nil)
+	cfg.ForkChoice = GHOST
+	cfg.StatusAuthority = auth.Addr
+	c := newTestChain(t, cfg)
+
+	// Two siblings with one block each: GHOST keeps the first to arrive.
+	// Add the one with the higher hash first, so sorting by hash would
+	// pick the other.
+	freeze := signedTx(t, c, auth, Transaction{To: victim.Addr, Status: StatusFrozen, Description: string(StatusFrozen)})
+	x, err := c.BuildBlock("", []Transaction{freeze})
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := c.BuildBlock("", []Transaction{freeze})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Hash < y.Hash {
+		x, y = y, x
+	}
+	for _, b := range []Block{x, y} {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Tip().Hash != x.Hash {
+		t.Fatal("GHOST didn't keep the first sibling to arrive")
+	}
+
+	// Lose the per-block indexes, as if they were corrupted.
+	c.nonces = make(map[string]map[string]uint64)
+	c.statuses = make(map[string]map[string]AccountStatus)
+	if err := c.Reindex(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Tip().Hash != x.Hash {
+		t.Errorf("tip moved to %s after reindexing", c.Tip().Hash)
+	}
+	if n := c.NextNonce(auth.Addr); n != 1 {
+		t.Errorf("next nonce %d, want 1", n)
+	}
+	tip := c.Tip()
+	if s := c.statusAt(&tip, victim.Addr); s != StatusFrozen {
+		t.Errorf("victim's status %q, want frozen", s)
+	}
+}
+
+// mineBlocks builds and adds n empty blocks on c's tip.
+func mineBlocks(t *testing.T, c *Chain, n int) []Block {
+	t.Helper()
+	var blocks []Block
+	for i := 0; i < n; i++ {
+		b, err := c.BuildBlock("", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+func TestFinalityRejectsDeepReorgs(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.FinalityDepth = 2
+	c := newTestChain(t, cfg)
+	best := mineBlocks(t, c, 3)
+
+	if got := c.Confirmations(best[0].Hash); got != 3 {
+		t.Errorf("block 1 has %d confirmations, want 3", got)
+	}
+	if !c.Finalized(2) || c.Finalized(3) {
+		t.Errorf("Finalized(2), Finalized(3) = %v, %v, want true, false", c.Finalized(2), c.Finalized(3))
+	}
+
+	// A longer rival from genesis forks below the finalized blocks.
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	rival := mineBlocks(t, newTestChain(t, rivalCfg), 4)
+	if err := c.AddBlock(rival[0]); !errors.Is(err, ErrFinalizedReorg) {
+		t.Fatalf("fork at genesis: err = %v, want ErrFinalizedReorg", err)
+	}
+
+	// One forking above them is still allowed, and can take over.
+	sideCfg := cfg
+	sideCfg.Clock = StepClock(testStart.Add(25*time.Second), 10*time.Second)
+	side := newTestChain(t, sideCfg)
+	for _, b := range best[:2] {
+		if err := side.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, b := range mineBlocks(t, side, 2) {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatalf("fork at block 2: %v", err)
+		}
+	}
+	if c.Tip().Hash != side.Tip().Hash {
+		t.Fatal("the heavier branch didn't become the tip")
+	}
+	if got := c.Confirmations(best[2].Hash); got != 0 {
+		t.Errorf("reorged-out block has %d confirmations, want 0", got)
+	}
+}
+
+func TestUncles(t *testing.T) {
+	miner, other, nephewMiner := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(nil)
+	cfg.MaxUncles = 1
+	c := newTestChain(t, cfg)
+
+	x, err := c.BuildBlock(miner.Addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := c.BuildBlock(other.Addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []Block{x, y} {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := y
+	if c.Tip().Hash == y.Hash {
+		stale = x
+	}
+
+	nephew, err := c.BuildBlock(nephewMiner.Addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nephew.Uncles) != 1 || nephew.Uncles[0] != stale.Hash {
+		t.Fatalf("uncles %v, want the stale sibling %s", nephew.Uncles, stale.Hash)
+	}
+	if want := cfg.BlockReward + cfg.BlockReward/nephewRewardDivisor; nephew.CoinbaseAmount != want {
+		t.Errorf("coinbase claims %v, want %v", nephew.CoinbaseAmount, want)
+	}
+	rewards, err := c.Rewards(nephew)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rewards[stale.Coinbase], cfg.BlockReward*7/8; got != want {
+		t.Errorf("uncle one block deep earns %v, want %v", got, want)
+	}
+	if err := c.AddBlock(nephew); err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := c.BuildBlock("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next.Uncles) != 0 {
+		t.Fatalf("offered %v again as uncles", next.Uncles)
+	}
+	for name, uncles := range map[string][]string{
+		"already included": {stale.Hash},
+		"ancestor":         {nephew.PrevHash},
+		"unknown":          {"0x" + strings.Repeat("ab", 32)},
+		"too many":         {stale.Hash, nephew.PrevHash},
+	} {
+		b := next
+		b.Uncles = uncles
+		if err := cfg.Engine.Seal(&b); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddBlock(b); err == nil {
+			t.Errorf("%s uncle was accepted", name)
+		}
+	}
+}
+
+func TestForkChoiceByWork(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.RetargetInterval = 2
+	genesis := Block{Version: CurrentBlockVersion, Timestamp: testStart, Bits: 0x1d00ffff, PrevHash: "0x" + strings.Repeat("0", 64)}
+	if err := cfg.Engine.Seal(&genesis); err != nil {
+		t.Fatal(err)
+	}
+	newChain := func(cfg ChainConfig) *Chain {
+		c, err := NewChain(cfg, genesis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// Both branches retarget at block 4: fast blocks make it four times
+	// harder, slow ones four times easier.
+	branch := func(step time.Duration, n int) []Block {
+		bcfg := cfg
+		bcfg.Clock = StepClock(testStart.Add(step), step)
+		return mineBlocks(t, newChain(bcfg), n)
+	}
+	heavy := branch(time.Second, 4)
+	long := branch(100*time.Second, 5)
+	if heavy[3].Bits == genesis.Bits || long[3].Bits == genesis.Bits {
+		t.Fatal("a branch didn't retarget")
+	}
+
+	for _, tc := range []struct {
+		rule ForkChoiceRule
+		want Block
+	}{
+		{MostWork, heavy[3]},
+		{LongestChain, long[4]},
+	} {
+		rcfg := cfg
+		rcfg.ForkChoice = tc.rule
+		c := newChain(rcfg)
+		for _, b := range append(append([]Block(nil), long...), heavy...) {
+			if err := c.AddBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if c.Tip().Hash != tc.want.Hash {
+			t.Errorf("%s: tip is block %d, want %d", tc.rule, c.Tip().Index, tc.want.Index)
+		}
+		want := Work(genesis.Bits)
+		for _, b := range heavy {
+			want.Add(want, Work(b.Bits))
+		}
+		if got := c.TotalWork(heavy[3].Hash); got.Cmp(want) != 0 {
+			t.Errorf("%s: heavy branch has work %v, want %v", tc.rule, got, want)
+		}
+	}
+}
+
+func TestForkChoiceBreaksTiesByHash(t *testing.T) {
+	c := newTestChain(t, testConfig(nil))
+	x, err := c.BuildBlock("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := c.BuildBlock("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Hash < y.Hash {
+		x, y = y, x
+	}
+	for _, order := range [][]Block{{x, y}, {y, x}} {
+		c := newTestChain(t, testConfig(nil))
+		for _, b := range order {
+			if err := c.AddBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if c.Tip().Hash != y.Hash {
+			t.Errorf("tip %s, want the lower hash %s", c.Tip().Hash, y.Hash)
+		}
+	}
+}
+
+func TestRepeatedTransactionsAreRejected(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	if err := mineTxs(t, c, tx, tx); !errors.Is(err, ErrDoubleSpend) {
+		t.Errorf("twice in one block: err = %v, want ErrDoubleSpend", err)
+	}
+
+	// Two siblings carrying the same transaction: each is fine on its own
+	// branch, but not on top of the other.
+	x, err := c.buildBlock("", []Transaction{tx}, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := c.buildBlock("", []Transaction{tx}, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(x); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(y); err != nil {
+		t.Errorf("same transaction on a sibling branch: %v", err)
+	}
+	// Whichever won, the tip's branch has it.
+	if err := mineTxs(t, c, tx); !errors.Is(err, ErrDoubleSpend) {
+		t.Errorf("repeated on the branch: err = %v, want ErrDoubleSpend", err)
+	}
+}
+
+// TestGHOSTFollowsHeaviestSubtree builds a short bushy branch and a longer
+// thin one. Longest chain and most work take the thin branch's tip; GHOST
+// counts the bushy branch's stale siblings and takes its first block.
+func TestGHOSTFollowsHeaviestSubtree(t *testing.T) {
+	cfg := testConfig(nil)
+	bushy := newTestChain(t, cfg)
+	a1 := mineBlocks(t, bushy, 1)[0]
+	var siblings []Block
+	for i := 0; i < 3; i++ {
+		b, err := bushy.BuildBlock("", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		siblings = append(siblings, b)
+	}
+	thinCfg := cfg
+	thinCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	thin := mineBlocks(t, newTestChain(t, thinCfg), 3)
+
+	for _, tc := range []struct {
+		rule ForkChoiceRule
+		want Block
+	}{
+		{LongestChain, thin[2]},
+		{MostWork, thin[2]},
+		{GHOST, siblings[0]}, // equal subtrees: the first to arrive
+	} {
+		rcfg := cfg
+		rcfg.ForkChoice = tc.rule
+		c := newTestChain(t, rcfg)
+		for _, b := range append(append(append([]Block(nil), thin...), a1), siblings...) {
+			if err := c.AddBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if c.Tip().Hash != tc.want.Hash {
+			t.Errorf("%s: tip is block %d %s, want %d %s", tc.rule, c.Tip().Index, c.Tip().Hash, tc.want.Index, tc.want.Hash)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// The DER of RFC 6979's P-256 signature of "sample": both r and s have
+// their top bit set, so each needs a leading zero byte.
+const (
+	compactVectorR   = "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716"
+	compactVectorS   = "F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"
+	compactVectorDER = "3046022100" + compactVectorR + "022100" + compactVectorS
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCompactVector(t *testing.T) {
+	der := mustHex(t, compactVectorDER)
+	compact, err := ASN1ToCompact(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(compact), strings.ToLower(compactVectorR+compactVectorS); got != want {
+		t.Errorf("ASN1ToCompact = %s, want %s", got, want)
+	}
+	for _, sig := range [][]byte{compact, append(compact, 1)} {
+		back, err := CompactToASN1(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(back) != strings.ToLower(compactVectorDER) {
+			t.Errorf("CompactToASN1(%d bytes) = %x", len(sig), back)
+		}
+	}
+}
+
+func TestCompactRejectsMalformed(t *testing.T) {
+	for name, der := range map[string]string{
+		"trailing byte":   compactVectorDER + "00",
+		"extra zero pad":  "304702220000" + compactVectorR + "022100" + compactVectorS,
+		"long-form len":   "308146022100" + compactVectorR + "022100" + compactVectorS,
+		"negative r":      "30450220" + compactVectorR + "022100" + compactVectorS,
+		"zero r":          "3026020100022100" + compactVectorS,
+		"not a signature": "0400",
+	} {
+		b, err := hex.DecodeString(der)
+		if err != nil {
+			// An odd-length string is a test typo.
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := ASN1ToCompact(b); !errors.Is(err, ErrSignatureEncoding) {
+			t.Errorf("%s: err = %v, want ErrSignatureEncoding", name, err)
+		}
+	}
+
+	n := elliptic.P256().Params().N.FillBytes(make([]byte, 32))
+	r := mustHex(t, compactVectorR)
+	for name, sig := range map[string][]byte{
+		"short":      r,
+		"s = N":      append(append([]byte(nil), r...), n...),
+		"r = 0":      append(make([]byte, 32), r...),
+		"recovery 4": append(append(append([]byte(nil), r...), r...), 4),
+	} {
+		if _, err := CompactToASN1(sig); !errors.Is(err, ErrSignatureEncoding) {
+			t.Errorf("%s: err = %v, want ErrSignatureEncoding", name, err)
+		}
+	}
+}
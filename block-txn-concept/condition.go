@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrConditionFailed is returned for a transaction offered for a block in
+// which one of its Conditions doesn't hold.
+var ErrConditionFailed = errors.New("transaction condition not met")
+
+// ConditionKind is the predicate a Condition tests.
+type ConditionKind string
+
+const (
+	// HeightAbove holds in blocks above Height.
+	HeightAbove ConditionKind = "height>"
+	// HeightBelow holds in blocks below Height.
+	HeightBelow ConditionKind = "height<"
+	// BalanceAbove holds while Address holds more than Amount.
+	BalanceAbove ConditionKind = "balance>"
+	// BalanceBelow holds while Address holds less than Amount.
+	BalanceBelow ConditionKind = "balance<"
+)
+
+// Condition is a predicate on the chain at the point a transaction is
+// included: the block's height, or an address's balance just before the
+// transaction, after those ahead of it in the block.
+type Condition struct {
+	Kind    ConditionKind `json:"kind"`
+	Address string        `json:"address,omitempty"` // for balance conditions
+	Height  int           `json:"height,omitempty"`  // for height conditions
+	Amount  Amount        `json:"amount,omitempty"`  // for balance conditions
+}
+
+// WhenHeightAbove returns a condition holding in blocks above height.
+func WhenHeightAbove(height int) Condition {
+	return Condition{Kind: HeightAbove, Height: height}
+}
+
+// WhenHeightBelow returns a condition holding in blocks below height.
+func WhenHeightBelow(height int) Condition {
+	return Condition{Kind: HeightBelow, Height: height}
+}
+
+// WhenBalanceAbove returns a condition holding while addr holds more than
+// amount.
+func WhenBalanceAbove(addr string, amount Amount) Condition {
+	return Condition{Kind: BalanceAbove, Address: addr, Amount: amount}
+}
+
+// WhenBalanceBelow returns a condition holding while addr holds less than
+// amount.
+func WhenBalanceBelow(addr string, amount Amount) Condition {
+	return Condition{Kind: BalanceBelow, Address: addr, Amount: amount}
+}
+
+// Validate checks that c is a known kind with only its own operands set.
+func (c Condition) Validate() error {
+	switch c.Kind {
+	case HeightAbove, HeightBelow:
+		if c.Address != "" || c.Amount != 0 {
+			return fmt.Errorf("%s condition with a balance operand", c.Kind)
+		}
+	case BalanceAbove, BalanceBelow:
+		if c.Address == "" || c.Height != 0 {
+			return fmt.Errorf("%s condition needs an address and no height", c.Kind)
+		}
+	default:
+		return fmt.Errorf("unknown condition %q", c.Kind)
+	}
+	return nil
+}
+
+// String describes c, e.g. "height > 10" or "balance of 0x… < 5".
+func (c Condition) String() string {
+	switch c.Kind {
+	case HeightAbove, HeightBelow:
+		return fmt.Sprintf("height %s %d", c.Kind[len(c.Kind)-1:], c.Height)
+	default:
+		return fmt.Sprintf("balance of %s %s %v", c.Address, c.Kind[len(c.Kind)-1:], c.Amount)
+	}
+}
+
+// holds evaluates c in ctx. A balance that pruning has made unknowable
+// counts as holding, as checkBalance does, so pruned nodes accept the
+// blocks full nodes accept.
+func (c Condition) holds(ctx *TxContext) bool {
+	switch c.Kind {
+	case HeightAbove:
+		return ctx.Height > c.Height
+	case HeightBelow:
+		return ctx.Height < c.Height
+	}
+	bal, ok := ctx.Balance(c.Address)
+	if !ok {
+		return true
+	}
+	if c.Kind == BalanceAbove {
+		return bal > c.Amount
+	}
+	return bal < c.Amount
+}
+
+// checkConditions rejects a transaction unless every one of its
+// Conditions holds at its place in the block. The mempool evaluates them
+// for the next block, so a transaction waiting for a height can only be
+// submitted once the chain is nearly there.
+func checkConditions(ctx *TxContext, tx Transaction) error {
+	for _, c := range tx.Conditions {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("tx %s: %w", tx.Hash, err)
+		}
+		if !c.holds(ctx) {
+			return fmt.Errorf("%w: tx %s needs %v at block %d", ErrConditionFailed, tx.Hash, c, ctx.Height)
+		}
+	}
+	return nil
+}
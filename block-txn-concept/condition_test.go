@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConditionValidate(t *testing.T) {
+	for _, bad := range []Condition{
+		{Kind: "height="},
+		{Kind: HeightAbove, Height: 1, Amount: Coin},
+		{Kind: BalanceBelow, Amount: Coin},
+		{Kind: BalanceAbove, Address: "0x01", Height: 1},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: accepted", bad)
+		}
+	}
+}
+
+func TestConditionsHoldAtTheirPlaceInTheBlock(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+
+	later := signedTx(t, c, alice, Transaction{Version: TxVersion2, To: bob.Addr, Amount: Coin, Conditions: []Condition{WhenHeightAbove(1)}})
+	if err := mineTxs(t, c, later); !errors.Is(err, ErrConditionFailed) {
+		t.Errorf("height above 1 in block 1: err = %v, want ErrConditionFailed", err)
+	}
+	if err := mineTxs(t, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := mineTxs(t, c, later); err != nil {
+		t.Fatalf("height above 1 in block 2: %v", err)
+	}
+
+	// Top bob up to one coin: the second payment sees the first.
+	topUp := Transaction{Version: TxVersion2, To: bob.Addr, Amount: Coin, Conditions: []Condition{WhenBalanceBelow(bob.Addr, 2*Coin)}}
+	first := signedTx(t, c, alice, topUp)
+	topUp.Description = "again"
+	second := signedTx(t, c, alice, topUp)
+	second.Nonce++
+	if err := mineTxs(t, c, first, resign(t, second, alice)); !errors.Is(err, ErrConditionFailed) {
+		t.Errorf("second top-up: err = %v, want ErrConditionFailed", err)
+	}
+	if err := mineTxs(t, c, first); err != nil {
+		t.Fatalf("first top-up: %v", err)
+	}
+	if got := tipBalance(t, c, bob.Addr); got != 2*Coin {
+		t.Errorf("bob holds %v, want 2", got)
+	}
+}
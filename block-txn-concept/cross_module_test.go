@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+// signedPayment is ../testdata/signed-payment.json, which sign-transaction's
+// tests read too: a payment, its payload, and its signature.
+type signedPayment struct {
+	Tx struct {
+		From        string    `json:"from"`
+		To          string    `json:"to"`
+		Time        time.Time `json:"time"`
+		Description string    `json:"description"`
+		Amount      int64     `json:"amount"` // minor units
+		Fee         int64     `json:"fee"`
+		Type        string    `json:"type"`
+		ChainID     string    `json:"chainID"`
+		Nonce       uint64    `json:"nonce"`
+	} `json:"tx"`
+	Payload   string `json:"payload"`
+	Hash      string `json:"hash"`
+	PublicKey string `json:"publicKey"`
+	Signature string `json:"signature"`
+}
+
+// TestSignaturesFromSignTransaction verifies the shared payment with the
+// signature sign-transaction makes for it, and checks SignTx makes the
+// same one.
+func TestSignaturesFromSignTransaction(t *testing.T) {
+	data, err := os.ReadFile("../testdata/signed-payment.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v signedPayment
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	tx := Transaction{
+		Version: TxVersion1, From: v.Tx.From, To: v.Tx.To, Time: v.Tx.Time, Description: v.Tx.Description,
+		Amount: Amount(v.Tx.Amount), Fee: Amount(v.Tx.Fee), Type: TransactionType(v.Tx.Type), ChainID: v.Tx.ChainID, Nonce: v.Tx.Nonce,
+	}
+	payload, err := SigningPayload(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != v.Payload {
+		t.Fatalf("payload %q, want %q", payload, v.Payload)
+	}
+	if tx.Hash = computeTxHash(tx); tx.Hash != "0x"+v.Hash {
+		t.Errorf("hash %s, want 0x%s", tx.Hash, v.Hash)
+	}
+	if tx.PubKey, err = hex.DecodeString(v.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Signature, err = hex.DecodeString(v.Signature); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTxSignature(tx); err != nil {
+		t.Errorf("sign-transaction's signature: %v", err)
+	}
+
+	signed := tx
+	if err := SignTx(&signed, rfc6979Key(t)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(signed.Signature, tx.Signature) {
+		t.Errorf("SignTx signature %x, want sign-transaction's %x", signed.Signature, tx.Signature)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// FakePoWEngine is a test-mining mode. Instead of grinding, it derives the
+// nonce from Seed and the block contents and accepts any target, so chain
+// logic can be exercised instantly and reproducibly. Never use it on a
+// real network: it provides no security at all.
+type FakePoWEngine struct {
+	Seed uint64
+}
+
+// Seal sets a deterministic nonce and the resulting hash.
+func (e *FakePoWEngine) Seal(b *Block) error {
+	b.Nonce = e.nonce(*b)
+	b.Hash = hashBlock(*b)
+	return nil
+}
+
+// VerifySeal checks the nonce and hash but not the target.
+func (e *FakePoWEngine) VerifySeal(b Block) error {
+	if want := e.nonce(b); b.Nonce != want {
+		return fmt.Errorf("block %d: nonce %d, expected %d for seed %d", b.Index, b.Nonce, want, e.Seed)
+	}
+	return verifyBlockHash(b)
+}
+
+func (e *FakePoWEngine) nonce(b Block) uint64 {
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], e.Seed)
+	h := sha256.Sum256(append(seed[:], sealHash(b)...))
+	return binary.BigEndian.Uint64(h[:8])
+}
+
+// StepClock returns a clock that starts at start and advances by step on
+// every call. Paired with FakePoWEngine via ChainConfig.Clock it makes block
+// timestamps, and therefore hashes, identical from run to run.
+func StepClock(start time.Time, step time.Duration) func() time.Time {
+	next := start
+	return func() time.Time {
+		t := next
+		next = next.Add(step)
+		return t
+	}
+}
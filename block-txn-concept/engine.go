@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+)
+
+// Engine is a consensus mode: it knows how to seal a freshly assembled block
+// and how to check the seal on a block produced by someone else.
+type Engine interface {
+	Seal(b *Block) error
+	VerifySeal(b Block) error
+}
+
+// SignedEngine is implemented by engines whose seal is a signature. The
+// chain uses VerifyProposer, which checks everything except the signature
+// itself, for blocks covered by an assume-valid checkpoint.
+type SignedEngine interface {
+	Engine
+	VerifyProposer(b Block) error
+}
+
+// PoWEngine seals blocks by grinding nonces until the hash meets b.Bits.
+type PoWEngine struct {
+	Miner Miner
+}
+
+// Seal mines b in place.
+func (e *PoWEngine) Seal(b *Block) error {
+	e.Miner.Mine(b)
+	return nil
+}
+
+// VerifySeal checks that the stored hash is correct and meets the target.
+func (e *PoWEngine) VerifySeal(b Block) error {
+	if err := verifyBlockHash(b); err != nil {
+		return err
+	}
+	if !meetsTarget(b.Hash, b.Bits) {
+		return fmt.Errorf("block %d: hash %s is above target 0x%08x", b.Index, b.Hash, b.Bits)
+	}
+	return nil
+}
+
+// verifyBlockHash checks that b.Hash matches the block's contents.
+func verifyBlockHash(b Block) error {
+	if got := hashBlock(b); got != b.Hash {
+		return fmt.Errorf("block %d: stored hash %s does not match computed %s", b.Index, b.Hash, got)
+	}
+	return nil
+}
+
+// sealHash hashes everything in the header except the fields an engine fills
+// in while sealing (nonce, seal data, and the final hash). Engines that sign
+// or otherwise commit to a block use it as their input. Before
+// BlockVersion7 it leaves out the state root, so a signed seal on an older
+// block doesn't vouch for its root.
+func sealHash(b Block) []byte {
+	h := sha256.New()
+	if b.Version != LegacyBlockVersion {
+		h.Write([]byte(fmt.Sprintf("v%d", b.Version)))
+	}
+	h.Write([]byte(fmt.Sprintf("%d", b.Index)))
+	h.Write([]byte(b.PrevHash))
+	h.Write([]byte(b.Timestamp.Format(time.RFC3339Nano)))
+	h.Write([]byte(fmt.Sprintf("%d", b.Bits)))
+	h.Write([]byte(b.Proposer))
+	h.Write([]byte(b.Coinbase))
+	if b.Version != LegacyBlockVersion {
+		h.Write([]byte(b.CoinbaseAmount.hashString()))
+	}
+	for _, u := range b.Uncles {
+		h.Write([]byte(u))
+	}
+	h.Write(b.Bloom)
+	if b.Version >= BlockVersion7 {
+		h.Write([]byte(b.StateRoot))
+	}
+	for _, txHash := range b.TxHashes() {
+		h.Write([]byte(txHash))
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSealHashCoversExactCoinbase(t *testing.T) {
+	b := Block{Version: CurrentBlockVersion, Index: 1, CoinbaseAmount: MustParseAmount("50.00000001")}
+	other := b
+	other.CoinbaseAmount = MustParseAmount("50.00000002")
+	if bytes.Equal(sealHash(b), sealHash(other)) {
+		t.Error("coinbase amounts differing in the eighth decimal seal the same")
+	}
+}
+
+func TestAuthoritySealCoversStateRoot(t *testing.T) {
+	signer := newTestAccount(t)
+	e := &AuthorityEngine{
+		Authorities: []Authority{{Address: signer.Addr, PubKey: &signer.Key.PublicKey}},
+		Address:     signer.Addr,
+		Key:         signer.Key,
+	}
+	for _, tc := range []struct {
+		version uint32
+		valid   bool
+	}{
+		{BlockVersion6, true},
+		{BlockVersion7, false},
+	} {
+		b := Block{Version: tc.version, Index: 1, Timestamp: testStart, StateRoot: "0xaa"}
+		if err := e.Seal(&b); err != nil {
+			t.Fatal(err)
+		}
+		b.StateRoot = "0xbb"
+		b.Hash = hashBlock(b)
+		err := e.VerifySeal(b)
+		if tc.valid && err != nil {
+			t.Errorf("v%d: %v", tc.version, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("v%d: swapped state root kept a valid seal", tc.version)
+		}
+	}
+}
+
+func TestAuthorityTakesTurns(t *testing.T) {
+	alice, bob, mallory := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	authorities := []Authority{
+		{Address: alice.Addr, PubKey: &alice.Key.PublicKey},
+		{Address: bob.Addr, PubKey: &bob.Key.PublicKey},
+	}
+	engine := func(signer testAccount) *AuthorityEngine {
+		return &AuthorityEngine{Authorities: authorities, StepDuration: 10 * time.Second, Address: signer.Addr, Key: signer.Key}
+	}
+	asAlice, asBob := engine(alice), engine(bob)
+
+	b := Block{Version: CurrentBlockVersion, Index: 1, Timestamp: testStart}
+	own, err := asAlice.Expected(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealer, other := asAlice, asBob
+	if own.Address == bob.Addr {
+		sealer, other = asBob, asAlice
+	}
+	if err := other.Seal(&b); !errors.Is(err, ErrNotProposer) {
+		t.Errorf("sealing out of turn: err = %v, want ErrNotProposer", err)
+	}
+	if err := sealer.Seal(&b); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.VerifySeal(b); err != nil {
+		t.Fatalf("the other authority rejects the block: %v", err)
+	}
+
+	// The right proposer named, but mallory's signature.
+	forged := b
+	if forged.Seal, err = SignDeterministic(mallory.Key, sealHash(forged)); err != nil {
+		t.Fatal(err)
+	}
+	forged.Hash = hashBlock(forged)
+	// The other authority named, in this one's step.
+	wrongTurn := b
+	wrongTurn.Proposer = other.Address
+	if wrongTurn.Seal, err = SignDeterministic(other.Key, sealHash(wrongTurn)); err != nil {
+		t.Fatal(err)
+	}
+	wrongTurn.Hash = hashBlock(wrongTurn)
+	for name, bad := range map[string]Block{"forged signature": forged, "wrong turn": wrongTurn} {
+		if err := sealer.VerifySeal(bad); err == nil {
+			t.Errorf("%s: seal verified", name)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// escrowDomain separates escrow address derivation from any other hash of
+// the same bytes.
+const escrowDomain = "go-principals escrow\x00"
+
+// ErrEscrow is returned for an escrow release or refund its signer isn't
+// entitled to make.
+var ErrEscrow = errors.New("escrow settlement not allowed")
+
+// EscrowTerms lock the funds sent to their address until Arbiter releases
+// them to Payee or refunds them to Payer; once the chain reaches Timeout,
+// Payer can take a refund without the arbiter. Like a multisig policy, the
+// terms need no record on chain: funding is an ordinary payment to their
+// address, and a settlement carries the terms, which validation checks
+// match it.
+type EscrowTerms struct {
+	Payer   Address `json:"payer"`
+	Payee   Address `json:"payee"`
+	Arbiter Address `json:"arbiter"`
+	Timeout int     `json:"timeout,omitempty"` // height from which Payer may refund alone; 0 for never
+}
+
+// Validate checks that the parties are set and the payer isn't the payee.
+func (e *EscrowTerms) Validate() error {
+	var zero Address
+	switch {
+	case e.Payer == zero || e.Payee == zero || e.Arbiter == zero:
+		return errors.New("escrow needs a payer, a payee, and an arbiter")
+	case e.Payer == e.Payee:
+		return errors.New("escrow payer and payee are the same")
+	case e.Timeout < 0:
+		return fmt.Errorf("negative escrow timeout %d", e.Timeout)
+	}
+	return nil
+}
+
+// Address derives the escrow's address: the last 20 bytes of a
+// domain-separated SHA-256 of the terms. No key's address can collide
+// with it, so only a settlement carrying the terms can spend from it.
+func (e *EscrowTerms) Address() (Address, error) {
+	if err := e.Validate(); err != nil {
+		return Address{}, err
+	}
+	h := sha256.New()
+	h.Write([]byte(escrowDomain))
+	h.Write(e.Payer[:])
+	h.Write(e.Payee[:])
+	h.Write(e.Arbiter[:])
+	h.Write([]byte(fmt.Sprintf("%d", e.Timeout)))
+	sum := h.Sum(nil)
+	var a Address
+	copy(a[:], sum[len(sum)-AddressLength:])
+	return a, nil
+}
+
+// NewEscrowFunding builds the payment from e's payer locking amount in its
+// address.
+func NewEscrowFunding(e *EscrowTerms, amount Amount) (*TxBuilder, error) {
+	addr, err := e.Address()
+	if err != nil {
+		return nil, err
+	}
+	return NewTxBuilder().From(e.Payer.String()).To(addr.String()).Amount(amount), nil
+}
+
+// NewEscrowRelease builds the settlement paying amount from e's address to
+// its payee. Only the arbiter can sign it.
+func NewEscrowRelease(e *EscrowTerms, amount Amount) (*TxBuilder, error) {
+	return newEscrowSettlement(e, e.Payee, amount)
+}
+
+// NewEscrowRefund builds the settlement returning amount from e's address
+// to its payer. The arbiter can sign it at any height, the payer from
+// e.Timeout on.
+func NewEscrowRefund(e *EscrowTerms, amount Amount) (*TxBuilder, error) {
+	return newEscrowSettlement(e, e.Payer, amount)
+}
+
+func newEscrowSettlement(e *EscrowTerms, to Address, amount Amount) (*TxBuilder, error) {
+	addr, err := e.Address()
+	if err != nil {
+		return nil, err
+	}
+	b := NewTxBuilder().From(addr.String()).To(to.String()).Amount(amount)
+	b.tx.Escrow = e
+	return b, nil
+}
+
+// SignEscrowTx signs tx, a settlement carrying the escrow terms its From
+// address is derived from, as one of the parties: key must be the
+// arbiter's or the payer's. The hash is recomputed first.
+func SignEscrowTx(tx *Transaction, key *ecdsa.PrivateKey) error {
+	e := tx.Escrow
+	if e == nil {
+		return errors.New("transaction has no escrow terms")
+	}
+	addr, err := e.Address()
+	if err != nil {
+		return err
+	}
+	if from, err := ParseAddress(tx.From); err != nil || from != addr {
+		return fmt.Errorf("escrow terms are for %s, not %s", addr, tx.From)
+	}
+	signer, err := AddressFromPubKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	if signer != e.Arbiter && signer != e.Payer {
+		return fmt.Errorf("key for %s is not a party to the escrow", signer)
+	}
+	digest, err := txDigest(*tx)
+	if err != nil {
+		return err
+	}
+	sig, err := SignDeterministic(key, digest)
+	if err != nil {
+		return err
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return err
+	}
+	tx.Hash = computeTxHash(*tx)
+	tx.PubKey, tx.Signature = pub, sig
+	return nil
+}
+
+// verifyEscrow checks that tx's From is its escrow terms' address and that
+// the arbiter or the payer signed it. Which of them may make which
+// settlement is checkEscrow's job, since it depends on the height.
+func verifyEscrow(tx Transaction) error {
+	e := tx.Escrow
+	addr, err := e.Address()
+	if err != nil {
+		return fmt.Errorf("%w: tx %s: %v", ErrBadSignature, tx.Hash, err)
+	}
+	if from, err := ParseAddress(tx.From); err != nil || from != addr {
+		return fmt.Errorf("%w: tx %s: escrow terms belong to %s, not %s", ErrBadSignature, tx.Hash, addr, tx.From)
+	}
+	signer, err := txSigner(tx)
+	if err != nil {
+		return err
+	}
+	if signer != e.Arbiter && signer != e.Payer {
+		return fmt.Errorf("%w: tx %s: signed by %s, not a party to the escrow", ErrBadSignature, tx.Hash, signer)
+	}
+	return nil
+}
+
+// checkEscrow enforces an escrow settlement's terms: every leg goes to the
+// payee, which only the arbiter can sign for, or every leg to the payer,
+// which the arbiter can sign for at any height and the payer from the
+// timeout on. Settlements need signatures, so blocks before BlockVersion4
+// can't carry them.
+func checkEscrow(ctx *TxContext, tx Transaction) error {
+	e := tx.Escrow
+	if e == nil {
+		return nil
+	}
+	if ctx.Version < BlockVersion4 {
+		return fmt.Errorf("%w: tx %s: escrow settlements need header version %d", ErrEscrow, tx.Hash, BlockVersion4)
+	}
+	signer, err := txSigner(tx)
+	if err != nil {
+		return err
+	}
+	var to Address
+	for i, l := range tx.Legs() {
+		a, err := ParseAddress(l.To)
+		if err != nil || (i > 0 && a != to) {
+			return fmt.Errorf("%w: tx %s pays someone other than one party", ErrEscrow, tx.Hash)
+		}
+		to = a
+	}
+	switch {
+	case to == e.Payee && signer != e.Arbiter:
+		return fmt.Errorf("%w: tx %s: only the arbiter can release", ErrEscrow, tx.Hash)
+	case to == e.Payer && signer != e.Arbiter && e.Timeout == 0:
+		return fmt.Errorf("%w: tx %s: only the arbiter can refund", ErrEscrow, tx.Hash)
+	case to == e.Payer && signer != e.Arbiter && ctx.Height < e.Timeout:
+		return fmt.Errorf("%w: tx %s: payer can't refund before height %d", ErrEscrow, tx.Hash, e.Timeout)
+	case to != e.Payee && to != e.Payer:
+		return fmt.Errorf("%w: tx %s pays %s, not the payee or the payer", ErrEscrow, tx.Hash, to)
+	}
+	return nil
+}
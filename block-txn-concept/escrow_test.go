@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEscrowSettlements(t *testing.T) {
+	payer, payee, arbiter, mallory := newTestAccount(t), newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	terms := &EscrowTerms{Timeout: 3}
+	for addr, acct := range map[*Address]testAccount{&terms.Payer: payer, &terms.Payee: payee, &terms.Arbiter: arbiter} {
+		a, err := ParseAddress(acct.Addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*addr = a
+	}
+	c := newTestChain(t, testConfig(map[string]Amount{payer.Addr: 10 * Coin}))
+
+	fund, err := NewEscrowFunding(terms, 5*Coin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := fund.Bind("test", "").Time(testStart).SignWith(payer.Key).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mineTxs(t, c, tx); err != nil {
+		t.Fatalf("funding: %v", err)
+	}
+
+	settle := func(build func(*EscrowTerms, Amount) (*TxBuilder, error), nonce uint64, signer testAccount) Transaction {
+		t.Helper()
+		b, err := build(terms, Coin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := b.Bind("test", "").Nonce(nonce).Time(testStart).SignWith(signer.Key).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	if b, err := NewEscrowRelease(terms, Coin); err != nil {
+		t.Fatal(err)
+	} else if _, err := b.Bind("test", "").SignWith(mallory.Key).Build(); err == nil {
+		t.Error("an outsider signed a settlement")
+	}
+	for name, bad := range map[string]Transaction{
+		"release by the payer":      settle(NewEscrowRelease, 0, payer),
+		"refund before the timeout": settle(NewEscrowRefund, 0, payer),
+	} {
+		if err := mineTxs(t, c, bad); !errors.Is(err, ErrEscrow) {
+			t.Errorf("%s: err = %v, want ErrEscrow", name, err)
+		}
+	}
+
+	if err := mineTxs(t, c, settle(NewEscrowRelease, 0, arbiter), settle(NewEscrowRefund, 1, arbiter)); err != nil {
+		t.Fatalf("arbiter's release and refund: %v", err)
+	}
+	if err := mineTxs(t, c, settle(NewEscrowRefund, 2, payer)); err != nil {
+		t.Fatalf("payer's refund at the timeout: %v", err)
+	}
+	if got := tipBalance(t, c, payer.Addr); got != 7*Coin {
+		t.Errorf("payer holds %v, want 7", got)
+	}
+	if got := tipBalance(t, c, payee.Addr); got != Coin {
+		t.Errorf("payee holds %v, want 1", got)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "math"
+
+// A transaction's signature isn't known until it is signed, so
+// EstimateCost sizes it with placeholders as large as a P-256 key makes
+// them.
+const (
+	maxPubKeySize    = 65 // uncompressed point
+	maxSignatureSize = 72 // ASN.1 sequence of r and s
+)
+
+// CostEstimate is what sending a transaction is expected to cost. Fees are
+// charged for space, not computation, so there is no separate gas figure:
+// Size is what the fee pays for.
+type CostEstimate struct {
+	Size  int    `json:"size"`  // TxSize once signed, at most
+	Fee   Amount `json:"fee"`   // least fee both the chain and the pool accept
+	Total Amount `json:"total"` // native coin leaving the sender: Fee plus any native amount sent
+}
+
+// EstimateCost works out, before tx is signed, the fee it needs to pass
+// the chain's MinFee and m's MinFeeRate, so a wallet can show the cost and
+// set Fee; tx's own Fee is ignored. Conditions, transfer legs, and
+// cosignatures all add to Size and so to the fee.
+func (m *Mempool) EstimateCost(tx Transaction) CostEstimate {
+	signed := tx
+	if tx.Multisig != nil {
+		signed.PubKey, signed.Signature = nil, nil
+		signed.Cosigs = make([]Cosig, tx.Multisig.Threshold)
+		for i := range signed.Cosigs {
+			signed.Cosigs[i] = Cosig{PubKey: make([]byte, maxPubKeySize), Signature: make([]byte, maxSignatureSize)}
+		}
+	} else {
+		signed.PubKey, signed.Signature = make([]byte, maxPubKeySize), make([]byte, maxSignatureSize)
+	}
+	signed.Hash = computeTxHash(signed)
+
+	// A larger fee can take more bytes to write, so raise it until the
+	// rate it gives at its own size is enough.
+	fee := m.chain.config.MinFee
+	for {
+		signed.Fee = fee
+		if FeeRate(signed) >= m.MinFeeRate {
+			break
+		}
+		need := Amount(math.Ceil(m.MinFeeRate * float64(TxSize(signed)) / 1000 * float64(Coin)))
+		fee = max(fee+1, need)
+	}
+	return CostEstimate{Size: TxSize(signed), Fee: fee, Total: -signed.change(NativeAsset)}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEstimateCostMeetsThePool(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	cfg.MinFee = Coin / 1000
+	c := newTestChain(t, cfg)
+	m := NewMempool(c)
+	defer m.Close()
+
+	// With no fee-rate floor the chain's MinFee is all that's needed.
+	tx := Transaction{From: alice.Addr, To: bob.Addr, Amount: Coin, Type: Debit, Time: testStart, Fee: 5 * Coin}
+	est := m.EstimateCost(tx)
+	if est.Fee != cfg.MinFee || est.Total != Coin+cfg.MinFee {
+		t.Errorf("without a rate floor: fee %s, total %s", est.Fee, est.Total)
+	}
+
+	m.MinFeeRate = 0.05
+	est = m.EstimateCost(tx)
+	if est.Fee <= cfg.MinFee {
+		t.Fatalf("fee %s doesn't reflect the pool's rate", est.Fee)
+	}
+	b := NewTxBuilder().From(alice.Addr).To(bob.Addr).Amount(Coin).Time(testStart).OnChain(c).FeeFrom(m).SignWith(alice.Key)
+	signed, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Fee != m.EstimateCost(signed).Fee {
+		t.Errorf("builder set fee %s, estimate is %s", signed.Fee, m.EstimateCost(signed).Fee)
+	}
+	if size := TxSize(signed); size > m.EstimateCost(signed).Size {
+		t.Errorf("signed size %d exceeds the estimate", size)
+	}
+
+	// One unit less than the estimate is underpriced.
+	cheap := signed
+	cheap.Fee--
+	if err := SignTx(&cheap, alice.Key); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(cheap); !errors.Is(err, ErrUnderpriced) {
+		t.Errorf("fee below the estimate: %v", err)
+	}
+	if err := m.Add(signed); err != nil {
+		t.Errorf("estimated fee refused: %v", err)
+	}
+
+	// A second leg makes the transaction bigger, so it costs more.
+	split := tx
+	split.Transfers = []Transfer{{To: carol.Addr, Amount: Coin}}
+	if more := m.EstimateCost(split); more.Size <= m.EstimateCost(tx).Size || more.Fee <= m.EstimateCost(tx).Fee {
+		t.Errorf("a second leg didn't raise the estimate: %+v", more)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+// EventKind identifies a kind of chain event.
+type EventKind string
+
+const (
+	// NewBlockEvent fires for every block that joins the best chain, in
+	// height order, including blocks connected by a reorg.
+	NewBlockEvent EventKind = "new-block"
+	// ReorgEvent fires when the best chain switches branches, before the
+	// NewBlockEvents for the newly connected blocks.
+	ReorgEvent EventKind = "reorg"
+)
+
+// Event describes a change to the best chain.
+type Event struct {
+	Kind  EventKind
+	Block Block // the connected block (NewBlockEvent) or new tip (ReorgEvent)
+
+	// Set for ReorgEvent only, both ordered from low to high height.
+	Disconnected []Block
+	Connected    []Block
+}
+
+type subscription struct {
+	kind    EventKind
+	handler func(Event)
+}
+
+// Subscribe registers handler for events of the given kind and returns a
+// function that removes it. Handlers run synchronously inside AddBlock, after
+// the chain has been updated, so they must not call AddBlock themselves.
+func (c *Chain) Subscribe(kind EventKind, handler func(Event)) (unsubscribe func()) {
+	c.nextSubID++
+	id := c.nextSubID
+	c.subs[id] = subscription{kind: kind, handler: handler}
+	return func() { delete(c.subs, id) }
+}
+
+func (c *Chain) publish(e Event) {
+	for _, sub := range c.subs {
+		if sub.kind == e.Kind {
+			sub.handler(e)
+		}
+	}
+}
+
+// tipChange returns the blocks that left and joined the best chain when the
+// tip moved from oldTip to the current tip, both ordered from low to high
+// height.
+func (c *Chain) tipChange(oldTip *Block) (connected, disconnected []Block) {
+	if c.tip == oldTip {
+		return nil, nil
+	}
+
+	// Walk both branches back to their common ancestor.
+	a, b := oldTip, c.tip
+	for a.Hash != b.Hash {
+		if b.Index >= a.Index {
+			connected = append(connected, *b)
+			b = c.blocks[b.PrevHash]
+		} else {
+			disconnected = append(disconnected, *a)
+			a = c.blocks[a.PrevHash]
+		}
+	}
+	reverseBlocks(connected)
+	reverseBlocks(disconnected)
+	return connected, disconnected
+}
+
+// notifyTipChange publishes the events for a tip change found by tipChange.
+func (c *Chain) notifyTipChange(connected, disconnected []Block) {
+	if len(disconnected) > 0 {
+		c.publish(Event{Kind: ReorgEvent, Block: *c.tip, Disconnected: disconnected, Connected: connected})
+	}
+	for _, blk := range connected {
+		c.publish(Event{Kind: NewBlockEvent, Block: blk})
+	}
+}
+
+func reverseBlocks(blocks []Block) {
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func blockHashes(blocks []Block) []string {
+	hashes := make([]string, len(blocks))
+	for i, b := range blocks {
+		hashes[i] = b.Hash
+	}
+	return hashes
+}
+
+func sameHashes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEventsFollowTheBestChain(t *testing.T) {
+	cfg := testConfig(nil)
+	c := newTestChain(t, cfg)
+	var events []Event
+	record := func(e Event) { events = append(events, e) }
+	c.Subscribe(NewBlockEvent, record)
+	c.Subscribe(ReorgEvent, record)
+
+	losing := mineBlocks(t, c, 2)
+	if len(events) != 2 || events[0].Block.Hash != losing[0].Hash || events[1].Block.Hash != losing[1].Hash {
+		t.Fatalf("mining two blocks published %d events, want a NewBlockEvent for each in order", len(events))
+	}
+
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	winning := mineBlocks(t, newTestChain(t, rivalCfg), 3)
+	events = nil
+	for _, b := range winning {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The switch happens at the tie at height 2 or at height 3, depending on
+	// the hashes; either way there is one reorg, before the winning blocks
+	// are announced in height order.
+	if len(events) != 4 || events[0].Kind != ReorgEvent {
+		t.Fatalf("got %d events, want a reorg and three new blocks", len(events))
+	}
+	reorg := events[0]
+	if !sameHashes(blockHashes(reorg.Disconnected), blockHashes(losing)) {
+		t.Errorf("disconnected %v, want %v", blockHashes(reorg.Disconnected), blockHashes(losing))
+	}
+	n := len(reorg.Connected)
+	if !sameHashes(blockHashes(reorg.Connected), blockHashes(winning[:n])) || reorg.Block.Hash != winning[n-1].Hash {
+		t.Errorf("connected %v, want a prefix of %v ending at the new tip", blockHashes(reorg.Connected), blockHashes(winning))
+	}
+	for i, e := range events[1:] {
+		if e.Kind != NewBlockEvent || e.Block.Hash != winning[i].Hash {
+			t.Errorf("event %d: %s for %s, want new-block for %s", i+1, e.Kind, e.Block.Hash, winning[i].Hash)
+		}
+	}
+}
+
+func TestUnsubscribeStopsEvents(t *testing.T) {
+	c := newTestChain(t, testConfig(nil))
+	var n int
+	unsubscribe := c.Subscribe(NewBlockEvent, func(Event) { n++ })
+	mineBlocks(t, c, 1)
+	unsubscribe()
+	mineBlocks(t, c, 1)
+	if n != 1 {
+		t.Errorf("handler ran %d times, want once before unsubscribing", n)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrExpired is returned for a transaction offered for a block past its
+// expiry.
+var ErrExpired = errors.New("transaction expired")
+
+// Expiry bounds when a transaction may be mined: in blocks up to and
+// including Height, and timestamped up to and including Time. A zero field
+// sets no bound.
+type Expiry struct {
+	Height int       `json:",omitempty"`
+	Time   time.Time `json:",omitempty"`
+}
+
+// expired reports whether a block at height, timestamped at, is too late
+// for e. A nil Expiry never expires.
+func (e *Expiry) expired(height int, at time.Time) bool {
+	if e == nil {
+		return false
+	}
+	return (e.Height > 0 && height > e.Height) || (!e.Time.IsZero() && at.After(e.Time))
+}
+
+// String describes e for error messages, e.g. "height 12, 2024-01-02T00:00:00Z".
+func (e *Expiry) String() string {
+	switch {
+	case e.Height > 0 && !e.Time.IsZero():
+		return fmt.Sprintf("height %d, %s", e.Height, e.Time.Format(time.RFC3339))
+	case e.Height > 0:
+		return fmt.Sprintf("height %d", e.Height)
+	default:
+		return e.Time.Format(time.RFC3339)
+	}
+}
+
+// checkExpiry rejects a transaction whose Expires has passed at the block's
+// height and time. The mempool checks against the tip's time, the latest
+// the chain knows of.
+func checkExpiry(ctx *TxContext, tx Transaction) error {
+	if tx.Expires.expired(ctx.Height, ctx.Time) {
+		return fmt.Errorf("%w: tx %s expired at %v (block %d, %s)", ErrExpired, tx.Hash, tx.Expires, ctx.Height, ctx.Time.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExpiredTransactionsAreRejected(t *testing.T) {
+	alice, carol, bob := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin, carol.Addr: 10 * Coin}))
+	byHeight := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin, Expires: &Expiry{Height: 1}})
+	byTime := signedTx(t, c, carol, Transaction{To: bob.Addr, Amount: Coin, Expires: &Expiry{Time: testStart.Add(25 * time.Second)}})
+
+	// Blocks come every ten seconds from testStart.
+	if err := mineTxs(t, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := mineTxs(t, c, byHeight); !errors.Is(err, ErrExpired) {
+		t.Errorf("block 2 past height 1: err = %v, want ErrExpired", err)
+	}
+	if err := mineTxs(t, c, byTime); !errors.Is(err, ErrExpired) {
+		t.Errorf("block at 30s past 25s: err = %v, want ErrExpired", err)
+	}
+	byTime.Expires.Time = testStart.Add(40 * time.Second)
+	if err := mineTxs(t, c, resign(t, byTime, carol)); err != nil {
+		t.Errorf("block at 40s, expiring at 40s: %v", err)
+	}
+}
+
+func TestMempoolEvictsExpiredTransactions(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	m := NewMempool(c)
+	defer m.Close()
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin, Expires: &Expiry{Height: 1}})
+	if err := m.Add(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := mineTxs(t, c); err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() != 0 {
+		t.Error("expired transaction left in the pool")
+	}
+	if err := m.Add(tx); !errors.Is(err, ErrExpired) {
+		t.Errorf("Add after expiry: err = %v, want ErrExpired", err)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Explorer answers queries about a stored chain.
+type Explorer struct {
+	blocks []Block
+	txs    map[string]TxLocation // keyed by lower-case hash
+	out    io.Writer
+}
+
+// NewExplorer creates an explorer over blocks, writing results to out.
+func NewExplorer(blocks []Block, out io.Writer) *Explorer {
+	txs := make(map[string]TxLocation)
+	for _, b := range blocks {
+		for i, tx := range b.Transactions {
+			txs[strings.ToLower(tx.Hash)] = TxLocation{BlockHash: b.Hash, Height: b.Index, Position: i}
+		}
+	}
+	return &Explorer{blocks: blocks, txs: txs, out: out}
+}
+
+// Query runs a single query: "block <height|hash>", "tx <hash>", or
+// "address <addr>".
+func (e *Explorer) Query(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: block <height|hash> | tx <hash> | address <addr>")
+	}
+	switch args[0] {
+	case "block":
+		return e.showBlock(args[1])
+	case "tx":
+		return e.showTx(args[1])
+	case "address":
+		return e.showAddress(args[1])
+	default:
+		return fmt.Errorf("unknown query %q", args[0])
+	}
+}
+
+func (e *Explorer) showBlock(ref string) error {
+	var found *Block
+	if height, err := strconv.Atoi(ref); err == nil {
+		if height >= 0 && height < len(e.blocks) {
+			found = &e.blocks[height]
+		}
+	} else {
+		for i := range e.blocks {
+			if strings.EqualFold(e.blocks[i].Hash, ref) {
+				found = &e.blocks[i]
+				break
+			}
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("block %s not found", ref)
+	}
+
+	b := found
+	fmt.Fprintf(e.out, "Block #%d (header v%d)\n", b.Index, b.Version)
+	fmt.Fprintf(e.out, "  Hash      : %s\n", b.Hash)
+	fmt.Fprintf(e.out, "  PrevHash  : %s\n", b.PrevHash)
+	fmt.Fprintf(e.out, "  Timestamp : %s\n", b.Timestamp.Format(time.RFC3339))
+	fmt.Fprintf(e.out, "  Nonce     : %d\n", b.Nonce)
+	fmt.Fprintf(e.out, "  Bits      : 0x%08x (difficulty %.0f)\n", b.Bits, Difficulty(b.Bits))
+	if b.Coinbase != "" {
+		fmt.Fprintf(e.out, "  Coinbase  : %s (%.2f)\n", b.Coinbase, b.CoinbaseAmount)
+	}
+	if b.Proposer != "" {
+		fmt.Fprintf(e.out, "  Proposer  : %s\n", b.Proposer)
+	}
+	for _, u := range b.Uncles {
+		fmt.Fprintf(e.out, "  Uncle     : %s\n", u)
+	}
+	fmt.Fprintf(e.out, "  Tx count  : %d\n", len(b.TxHashes()))
+	if b.IsPruned() {
+		fmt.Fprintln(e.out, "  (transaction bodies pruned)")
+	}
+	for _, tx := range b.Transactions {
+		total, _ := tx.Total()
+		fmt.Fprintf(e.out, "    - %s %s -> %s | %.2f (%s)\n", tx.Hash, tx.From, tx.payee(), total, tx.Type)
+	}
+	return nil
+}
+
+func (e *Explorer) showTx(hash string) error {
+	loc, ok := e.txs[strings.ToLower(hash)]
+	if !ok {
+		return fmt.Errorf("transaction %s not found", hash)
+	}
+	tx := e.blocks[loc.Height].Transactions[loc.Position]
+	fmt.Fprintf(e.out, "Tx %s\n", tx.Hash)
+	fmt.Fprintf(e.out, "  Block  : #%d (%s), position %d\n", loc.Height, loc.BlockHash, loc.Position)
+	fmt.Fprintf(e.out, "  Time   : %s\n", tx.Time.Format(time.RFC3339))
+	fmt.Fprintf(e.out, "  From   : %s\n", tx.From)
+	fmt.Fprintf(e.out, "  To     : %s\n", tx.payee())
+	for _, l := range tx.Transfers {
+		fmt.Fprintf(e.out, "           %s %.2f\n", l.To, l.Amount)
+	}
+	fmt.Fprintf(e.out, "  Type   : %s\n", tx.Type)
+	total, _ := tx.Total()
+	fmt.Fprintf(e.out, "  Amount : %.2f\n", total)
+	if tx.Fee > 0 {
+		fmt.Fprintf(e.out, "  Fee    : %.2f\n", tx.Fee)
+	}
+	fmt.Fprintf(e.out, "  Nonce  : %d\n", tx.Nonce)
+	fmt.Fprintf(e.out, "  Note   : %s\n", tx.Description)
+	return nil
+}
+
+func (e *Explorer) showAddress(addr string) error {
+	var received, sent Amount
+	count := 0
+	fmt.Fprintf(e.out, "Address %s\n", addr)
+	for _, b := range e.blocks {
+		for _, tx := range b.Transactions {
+			var dir string
+			var amount Amount
+			switch {
+			case strings.EqualFold(tx.From, addr):
+				dir = "out"
+				amount, _ = tx.Total()
+				sent += amount + tx.Fee
+			case tx.sendsTo(addr):
+				dir, amount = "in ", tx.receivedBy(addr)
+				received += amount
+			default:
+				continue
+			}
+			count++
+			fmt.Fprintf(e.out, "  #%-4d %s %s %10.2f  %s\n", b.Index, dir, tx.Hash[:18]+"...", amount, tx.Description)
+		}
+	}
+	fmt.Fprintf(e.out, "  %d transactions, received %.2f, sent %.2f\n", count, received, sent)
+	return nil
+}
+
+// Run reads queries from in, one per line, until EOF or "quit".
+func (e *Explorer) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(e.out, "explorer> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(e.out)
+			return scanner.Err()
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return nil
+		}
+		if err := e.Query(fields); err != nil {
+			fmt.Fprintln(e.out, "error:", err)
+		}
+	}
+}
+
+// runExplorer implements the "explorer" command. With a query on the command
+// line it answers that and exits; otherwise it starts an interactive prompt.
+func runExplorer(args []string) error {
+	fs := flag.NewFlagSet("explorer", flag.ContinueOnError)
+	path := fs.String("chain", "chain.json", "stored chain to inspect")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	blocks, err := LoadBlocks(*path)
+	if err != nil {
+		return err
+	}
+	e := NewExplorer(blocks, os.Stdout)
+	if fs.NArg() > 0 {
+		return e.Query(fs.Args())
+	}
+	return e.Run(os.Stdin)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExplorerQueries(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 2 * Coin, Fee: Coin / 10, Description: "lunch"})
+	if err := mineTxs(t, c, tx); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	e := NewExplorer(c.BestChain(), &out)
+
+	for _, tc := range []struct {
+		query []string
+		want  []string
+	}{
+		{[]string{"block", "1"}, []string{"Block #1", c.Tip().Hash, "Tx count  : 1", tx.Hash}},
+		{[]string{"block", strings.ToUpper(c.Tip().Hash)}, []string{"Block #1"}},
+		{[]string{"tx", tx.Hash}, []string{"Block  : #1", "position 0", "Amount : 2.00", "Fee    : 0.10", "Note   : lunch"}},
+		{[]string{"address", bob.Addr}, []string{"in ", "1 transactions, received 2.00, sent 0.00"}},
+		{[]string{"address", alice.Addr}, []string{"out", "1 transactions, received 0.00, sent 2.10"}},
+	} {
+		out.Reset()
+		if err := e.Query(tc.query); err != nil {
+			t.Fatalf("%v: %v", tc.query, err)
+		}
+		for _, w := range tc.want {
+			if !strings.Contains(out.String(), w) {
+				t.Errorf("%v: output lacks %q:\n%s", tc.query, w, out.String())
+			}
+		}
+	}
+
+	for _, q := range [][]string{{"block", "9"}, {"block", "0xdead"}, {"tx", "0xdead"}, {"balance", "x"}, {"block"}} {
+		if err := e.Query(q); err == nil {
+			t.Errorf("%v: no error", q)
+		}
+	}
+}
+
+func TestExplorerRunReportsErrorsAndStopsOnQuit(t *testing.T) {
+	var out bytes.Buffer
+	e := NewExplorer(newTestChain(t, testConfig(nil)).BestChain(), &out)
+	if err := e.Run(strings.NewReader("block 0\n\nblock 5\nquit\nblock 0\n")); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "Block #0"); n != 1 {
+		t.Errorf("block 0 shown %d times, want once before quit:\n%s", n, out.String())
+	}
+	if !strings.Contains(out.String(), "error: block 5 not found") {
+		t.Errorf("missing error line:\n%s", out.String())
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCoinbaseOverclaim is returned for a block whose coinbase claims more
+// than the block reward, fees, and uncle bonuses it is entitled to.
+var ErrCoinbaseOverclaim = errors.New("coinbase claims more than it earned")
+
+// TotalFees sums the fees of txs, or returns ErrAmountRange if the sum
+// doesn't fit.
+func TotalFees(txs []Transaction) (Amount, error) {
+	var total Amount
+	for _, tx := range txs {
+		sum, err := total.Add(tx.Fee)
+		if err != nil {
+			return 0, fmt.Errorf("fees: %w", err)
+		}
+		total = sum
+	}
+	return total, nil
+}
+
+// MaxCoinbase is the most b's coinbase may claim: the block reward, the fees
+// of every included transaction, and 1/32 of a block reward per uncle. It
+// fails if the total doesn't fit in an Amount.
+func (c *Chain) MaxCoinbase(b Block) (Amount, error) {
+	nephew, err := c.config.BlockReward.MulDiv(int64(len(b.Uncles)), nephewRewardDivisor)
+	if err != nil {
+		return 0, err
+	}
+	fees, err := TotalFees(b.Transactions)
+	if err != nil {
+		return 0, err
+	}
+	total, err := c.config.BlockReward.Add(fees)
+	if err != nil {
+		return 0, err
+	}
+	return total.Add(nephew)
+}
+
+// validateCoinbase checks that b doesn't claim more than MaxCoinbase. Claiming
+// less is allowed; the difference is simply never created.
+func (c *Chain) validateCoinbase(b Block) error {
+	if b.CoinbaseAmount < 0 {
+		return fmt.Errorf("block %d: negative coinbase amount", b.Index)
+	}
+	if b.Coinbase == "" && b.CoinbaseAmount != 0 {
+		return fmt.Errorf("block %d: coinbase amount without a coinbase address", b.Index)
+	}
+	if b.Version == LegacyBlockVersion {
+		// The amount isn't hashed in legacy headers, and they predate fees.
+		if b.CoinbaseAmount != 0 {
+			return fmt.Errorf("block %d: legacy header with a coinbase amount", b.Index)
+		}
+		if fees, err := TotalFees(b.Transactions); err != nil || fees != 0 {
+			return fmt.Errorf("block %d: legacy header with transaction fees", b.Index)
+		}
+		return nil
+	}
+	if b.IsPruned() {
+		return nil // fees unknown; the claim was checked when the block arrived
+	}
+	limit, err := c.MaxCoinbase(b)
+	if err != nil {
+		return fmt.Errorf("block %d: coinbase: %w", b.Index, err)
+	}
+	if b.CoinbaseAmount > limit {
+		return fmt.Errorf("block %d: %w: %v, at most %v", b.Index, ErrCoinbaseOverclaim, b.CoinbaseAmount, limit)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCoinbaseClaims(t *testing.T) {
+	alice, bob, miner := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin, Fee: Coin / 4})
+
+	b, err := c.buildBlock(miner.Addr, []Transaction{tx}, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := cfg.BlockReward + tx.Fee; b.CoinbaseAmount != want {
+		t.Fatalf("coinbase claims %v, want the reward plus fees, %v", b.CoinbaseAmount, want)
+	}
+
+	claim := func(coinbase string, amount Amount) Block {
+		bad := b
+		bad.Coinbase, bad.CoinbaseAmount = coinbase, amount
+		if err := cfg.Engine.Seal(&bad); err != nil {
+			t.Fatal(err)
+		}
+		return bad
+	}
+	if err := c.AddBlock(claim(miner.Addr, b.CoinbaseAmount+1)); !errors.Is(err, ErrCoinbaseOverclaim) {
+		t.Errorf("overclaim: err = %v, want ErrCoinbaseOverclaim", err)
+	}
+	for name, bad := range map[string]Block{
+		"negative":    claim(miner.Addr, -1),
+		"no coinbase": claim("", Coin),
+	} {
+		if err := c.AddBlock(bad); err == nil {
+			t.Errorf("%s claim was accepted", name)
+		}
+	}
+
+	// Claiming less is allowed; the rest is never created.
+	if err := c.AddBlock(claim(miner.Addr, Coin)); err != nil {
+		t.Fatalf("underclaim: %v", err)
+	}
+	if got := tipBalance(t, c, miner.Addr); got != Coin {
+		t.Errorf("miner holds %v, want 1", got)
+	}
+}
+
+func TestTotalFeesBounds(t *testing.T) {
+	half := Amount(math.MaxInt64 / 2)
+	if got, err := TotalFees([]Transaction{{Fee: half}, {Fee: half}, {Fee: 1}}); err != nil || got != math.MaxInt64 {
+		t.Errorf("fees summing to the maximum = %d, %v", got, err)
+	}
+	if _, err := TotalFees([]Transaction{{Fee: half}, {Fee: half}, {Fee: 2}}); !errors.Is(err, ErrAmountRange) {
+		t.Errorf("fees past the maximum: err = %v, want ErrAmountRange", err)
+	}
+	if got, err := TotalFees(nil); err != nil || got != 0 {
+		t.Errorf("no fees = %d, %v", got, err)
+	}
+}
+
+// TestCoinbaseRefusesToOverflow builds a block whose reward plus fees
+// can't be represented; the builder and the chain both refuse it rather
+// than wrap the coinbase around to a small or negative amount.
+func TestCoinbaseRefusesToOverflow(t *testing.T) {
+	alice, bob, miner := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	cfg.BlockReward = math.MaxInt64 - Coin/2
+	c := newTestChain(t, cfg)
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin, Fee: Coin})
+
+	if _, err := c.buildBlock(miner.Addr, []Transaction{tx}, BlockVersion4); !errors.Is(err, ErrAmountRange) {
+		t.Fatalf("build: err = %v, want ErrAmountRange", err)
+	}
+	b, err := c.buildBlock("", []Transaction{tx}, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Coinbase, b.CoinbaseAmount = miner.Addr, Coin
+	if err := cfg.Engine.Seal(&b); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); !errors.Is(err, ErrAmountRange) {
+		t.Errorf("add: err = %v, want ErrAmountRange", err)
+	}
+}
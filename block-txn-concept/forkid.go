@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrWrongFork is returned for a block containing a transaction bound to a
+// different fork of the chain.
+var ErrWrongFork = errors.New("transaction is for a different fork")
+
+// ForkID identifies the rule set in force at height: a short hash of the
+// genesis block, the genesis allocations, and every consensus parameter and
+// upgrade that has activated by then. Two networks started from the same
+// genesis but run under different rules get different fork IDs, even
+// though they share a chain ID. Upgrades scheduled above height don't
+// count, so nodes that agree so far share an ID until the split.
+func (c *Chain) ForkID(height int) string {
+	h := sha256.New()
+	h.Write([]byte(c.genesis.Hash))
+
+	spec, err := c.Spec()
+	if err != nil {
+		// An engine Spec doesn't know about: fall back to its type.
+		h.Write([]byte(fmt.Sprintf("%T", c.config.Engine)))
+	} else {
+		spec.Genesis, spec.Activations = nil, nil
+		data, _ := json.Marshal(spec)
+		h.Write(data)
+	}
+	upgrades := append([]Upgrade(nil), c.config.Upgrades...)
+	sort.Slice(upgrades, func(i, j int) bool { return upgrades[i].Height < upgrades[j].Height })
+	for _, u := range upgrades {
+		if u.Height <= height {
+			h.Write([]byte(fmt.Sprintf("v%d@%d", u.Version, u.Height)))
+		}
+	}
+	return "0x" + hex.EncodeToString(h.Sum(nil)[:4])
+}
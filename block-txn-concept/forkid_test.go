@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestForkID(t *testing.T) {
+	base := newTestChain(t, testConfig(nil))
+	if a, b := base.ForkID(0), newTestChain(t, testConfig(nil)).ForkID(0); a != b {
+		t.Errorf("same rules, different fork IDs %s and %s", a, b)
+	}
+
+	richer := testConfig(nil)
+	richer.BlockReward = 100 * Coin
+	if base.ForkID(0) == newTestChain(t, richer).ForkID(0) {
+		t.Error("a different block reward kept the fork ID")
+	}
+
+	upgraded := testConfig(nil)
+	upgraded.Upgrades = append(upgraded.Upgrades, Upgrade{BlockVersion5, 10})
+	c := newTestChain(t, upgraded)
+	if c.ForkID(9) != base.ForkID(9) {
+		t.Error("an upgrade changed the fork ID before it activated")
+	}
+	if c.ForkID(10) == base.ForkID(10) || c.ForkID(10) == c.ForkID(9) {
+		t.Error("an upgrade didn't change the fork ID once it activated")
+	}
+}
+
+func TestTransactionsBoundToFork(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	tx := signedTx(t, c, alice, Transaction{Version: TxVersion1, To: bob.Addr, Amount: Coin})
+
+	other := testConfig(nil)
+	other.BlockReward = 100 * Coin
+	tx.ForkID = newTestChain(t, other).ForkID(1)
+	if err := mineTxs(t, c, resign(t, tx, alice)); !errors.Is(err, ErrWrongFork) {
+		t.Errorf("other fork: err = %v, want ErrWrongFork", err)
+	}
+	tx.ForkID = c.ForkID(1)
+	if err := mineTxs(t, c, resign(t, tx, alice)); err != nil {
+		t.Errorf("this fork: %v", err)
+	}
+}
+
+// TestVersion0CantBindToFork shows why: without lengths, the version 0
+// hash lets a signature move between chain and fork IDs that concatenate
+// the same way.
+func TestVersion0CantBindToFork(t *testing.T) {
+	a := Transaction{From: "alice", To: "bob", Amount: Coin, Type: Debit, ChainID: "ab", ForkID: "c"}
+	b := a
+	b.ChainID, b.ForkID = "a", "bc"
+	if computeTxHash(a) != computeTxHash(b) {
+		t.Fatal("version 0 hashes tell the IDs apart; the rule below may be unneeded")
+	}
+	if err := checkTxVersion(a); err == nil {
+		t.Error("version 0 transaction with a fork ID passed")
+	}
+
+	a.Version, b.Version = TxVersion1, TxVersion1
+	if computeTxHash(a) == computeTxHash(b) {
+		t.Error("version 1 hashes collide across chain and fork IDs")
+	}
+	if err := checkTxVersion(a); err != nil {
+		t.Error(err)
+	}
+
+	// The builder refuses the combination too.
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	_, err := NewTxBuilder().Version(TxVersion0).Bind("test", "0x01020304").
+		From(alice.Addr).To(bob.Addr).Amount(Coin).SignWith(alice.Key).Build()
+	if err == nil || !strings.Contains(err.Error(), "fork IDs") {
+		t.Errorf("built a version 0 transaction with a fork ID: err = %v", err)
+	}
+}
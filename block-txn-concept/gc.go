@@ -0,0 +1,111 @@
+package main
+
+import "encoding/json"
+
+// GCStats reports what garbage collection reclaimed.
+type GCStats struct {
+	Runs         int
+	Blocks       int // side-branch blocks removed
+	Transactions int // transactions in those blocks
+	Bytes        int // their approximate size, as stored JSON
+}
+
+// CollectGarbage removes side branches that can no longer matter: ones that
+// fork below the finalized height, so AddBlock would reject anything built
+// on them, and that are too deep to be referenced as uncles. Blocks the best
+// chain already references as uncles are kept, since rewards depend on them.
+// Without a FinalityDepth nothing is ever beyond reorg, so nothing is
+// removed.
+//
+// It runs automatically every GCInterval blocks if that is set; call it
+// directly to trigger a collection by hand. The returned stats cover this
+// run only; GCStats has the running totals.
+func (c *Chain) CollectGarbage() GCStats {
+	run := GCStats{Runs: 1}
+	if c.config.FinalityDepth <= 0 {
+		c.gcStats.Runs++
+		return run
+	}
+
+	uncles := make(map[string]bool)
+	for b := c.tip; b != nil; b = c.blocks[b.PrevHash] {
+		for _, u := range b.Uncles {
+			uncles[u] = true
+		}
+		if b == c.genesis {
+			break
+		}
+	}
+
+	// Each side branch hangs off a best-chain block. Collect the roots of
+	// branches that are finalized away and out of uncle range.
+	var roots []*Block
+	for _, b := range c.blocks {
+		if c.onMainChain(b) || uncles[b.Hash] {
+			continue
+		}
+		parent := c.blocks[b.PrevHash]
+		if !c.onMainChain(parent) || !c.Finalized(parent.Index+1) {
+			continue
+		}
+		if c.config.MaxUncles > 0 && c.tip.Index+1-b.Index <= c.config.MaxUncleDepth {
+			continue
+		}
+		roots = append(roots, b)
+	}
+
+	for _, root := range roots {
+		removed := c.removeBranch(root, &run)
+		kept := c.children[root.PrevHash][:0]
+		for _, hash := range c.children[root.PrevHash] {
+			if hash != root.Hash {
+				kept = append(kept, hash)
+			}
+		}
+		c.children[root.PrevHash] = kept
+		for p := c.blocks[root.PrevHash]; p != nil; p = c.blocks[p.PrevHash] {
+			c.subtree[p.Hash] -= removed
+			if p == c.genesis {
+				break
+			}
+		}
+	}
+
+	c.gcStats.Runs++
+	c.gcStats.Blocks += run.Blocks
+	c.gcStats.Transactions += run.Transactions
+	c.gcStats.Bytes += run.Bytes
+	return run
+}
+
+// removeBranch deletes b and all its descendants, returning how many blocks
+// went.
+func (c *Chain) removeBranch(b *Block, stats *GCStats) int {
+	removed := 1
+	for _, child := range c.children[b.Hash] {
+		removed += c.removeBranch(c.blocks[child], stats)
+	}
+
+	if data, err := json.Marshal(b); err == nil {
+		stats.Bytes += len(data)
+	}
+	stats.Blocks++
+	stats.Transactions += len(b.TxHashes())
+
+	delete(c.blocks, b.Hash)
+	delete(c.nonces, b.Hash)
+	delete(c.statuses, b.Hash)
+	delete(c.weight, b.Hash)
+	delete(c.work, b.Hash)
+	delete(c.children, b.Hash)
+	delete(c.subtree, b.Hash)
+	delete(c.arrival, b.Hash)
+	delete(c.snapshots, b.Hash)
+	delete(c.restored, b.Hash)
+	return removed
+}
+
+// GCStats returns totals over every garbage collection run so far.
+func (c *Chain) GCStats() GCStats {
+	return c.gcStats
+}
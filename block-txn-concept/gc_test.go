@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// gcFixture returns a chain with a best chain of six blocks and a two-block
+// side branch forking at genesis, plus the side branch.
+func gcFixture(t *testing.T, cfg ChainConfig) (*Chain, []Block) {
+	t.Helper()
+	best := mineBlocks(t, newTestChain(t, cfg), 6)
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	side := mineBlocks(t, newTestChain(t, rivalCfg), 2)
+
+	c := newTestChain(t, cfg)
+	for _, b := range append(append(append([]Block(nil), best[:3]...), side...), best[3:]...) {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Tip().Hash != best[5].Hash {
+		t.Fatal("the side branch became the tip")
+	}
+	return c, side
+}
+
+func TestCollectGarbageRemovesFinalizedSideBranches(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.FinalityDepth = 4
+	c, side := gcFixture(t, cfg)
+
+	run := c.CollectGarbage()
+	if run.Blocks != 2 || run.Runs != 1 || run.Bytes == 0 {
+		t.Errorf("run = %+v, want the two side blocks", run)
+	}
+	for _, b := range side {
+		if _, ok := c.blocks[b.Hash]; ok {
+			t.Errorf("side block %d is still stored", b.Index)
+		}
+	}
+	if n := len(c.StaleBlocks()); n != 0 {
+		t.Errorf("%d stale blocks after collection", n)
+	}
+	if got := c.subtree[c.genesis.Hash]; got != 7 {
+		t.Errorf("genesis subtree counts %d blocks, want the 7 left", got)
+	}
+	if again := c.CollectGarbage(); again.Blocks != 0 {
+		t.Errorf("second run removed %d blocks", again.Blocks)
+	}
+	if total := c.GCStats(); total.Runs != 2 || total.Blocks != 2 || total.Bytes != run.Bytes {
+		t.Errorf("totals = %+v", total)
+	}
+}
+
+func TestCollectGarbageKeepsWhatCanStillMatter(t *testing.T) {
+	cfg := testConfig(nil)
+	c, _ := gcFixture(t, cfg)
+	if run := c.CollectGarbage(); run.Blocks != 0 {
+		t.Errorf("without finality, removed %d blocks", run.Blocks)
+	}
+
+	// Within uncle range, the side branch is kept.
+	cfg.FinalityDepth = 4
+	cfg.MaxUncles = 1
+	cfg.MaxUncleDepth = 7
+	c, _ = gcFixture(t, cfg)
+	if run := c.CollectGarbage(); run.Blocks != 0 {
+		t.Errorf("within uncle range, removed %d blocks", run.Blocks)
+	}
+}
+
+func TestGCIntervalCollectsAutomatically(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.FinalityDepth = 4
+	cfg.GCInterval = 3
+	// The tip reaching height 6 triggers a run, by when the side branch is
+	// finalized away.
+	c, side := gcFixture(t, cfg)
+	if _, ok := c.blocks[side[0].Hash]; ok {
+		t.Error("side branch survived the automatic collection")
+	}
+	if total := c.GCStats(); total.Runs != 2 || total.Blocks != 2 {
+		t.Errorf("totals = %+v, want runs at heights 3 and 6", total)
+	}
+}
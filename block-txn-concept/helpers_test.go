@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStart is when test chains begin.
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testAccount is an address and the key that signs for it.
+type testAccount struct {
+	Addr string
+	Key  *ecdsa.PrivateKey
+}
+
+func newTestAccount(t *testing.T) testAccount {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := AddressFromPubKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testAccount{addr.String(), key}
+}
+
+// testConfig is a fake proof-of-work chain config with the given
+// allocation, whose clock moves ten seconds a block.
+func testConfig(alloc map[string]Amount) ChainConfig {
+	return ChainConfig{
+		ChainID:     "test",
+		Engine:      &FakePoWEngine{Seed: 1},
+		BlockReward: 50 * Coin,
+		Alloc:       alloc,
+		Clock:       StepClock(testStart.Add(10*time.Second), 10*time.Second),
+		// Signatures from genesis on, but without requiring state roots,
+		// so tests can hand AddBlock blocks the builder would refuse.
+		Upgrades: []Upgrade{{BlockVersion4, 0}},
+	}
+}
+
+// newTestChain starts a chain on cfg from a genesis at testStart.
+func newTestChain(t *testing.T, cfg ChainConfig) *Chain {
+	t.Helper()
+	genesis := Block{Version: CurrentBlockVersion, Timestamp: testStart, Bits: BitsForLeadingZeros(0), PrevHash: "0x" + strings.Repeat("0", 64)}
+	if err := cfg.Engine.Seal(&genesis); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewChain(cfg, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// signedTx fills in tx's sender, chain, nonce, type, and time from c and
+// from, and signs it.
+func signedTx(t *testing.T, c *Chain, from testAccount, tx Transaction) Transaction {
+	t.Helper()
+	tx.From = from.Addr
+	tx.ChainID = c.Config().ChainID
+	tx.Nonce = c.NextNonce(from.Addr)
+	tx.Type = Debit
+	if tx.Time.IsZero() {
+		tx.Time = testStart
+	}
+	if err := SignTx(&tx, from.Key); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// tipBalance is addr's balance at c's tip.
+func tipBalance(t *testing.T, c *Chain, addr string) Amount {
+	t.Helper()
+	s, err := c.At(c.Tip().Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Balance(addr)
+}
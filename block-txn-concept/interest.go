@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// InterestPeriod is how often interest compounds.
+type InterestPeriod string
+
+const (
+	// CompoundPerBlock compounds once per block.
+	CompoundPerBlock InterestPeriod = "block"
+	// CompoundDaily compounds once per 24 hours of block time.
+	CompoundDaily InterestPeriod = "day"
+)
+
+// InterestSource is the From of every interest credit. It is no key's
+// address: interest is new money, like a block reward, and nobody sends it.
+const InterestSource = "interest"
+
+// InterestPolicy pays Rate millionths of a balance per Period, added to the
+// balance before the next period's interest is worked out.
+type InterestPolicy struct {
+	Rate   int64          `json:"rate"` // parts per million per period
+	Period InterestPeriod `json:"period"`
+}
+
+// Validate checks that p has a known period and no negative rate.
+func (p InterestPolicy) Validate() error {
+	if p.Period != CompoundPerBlock && p.Period != CompoundDaily {
+		return fmt.Errorf("unknown compounding period %q (want block or day)", p.Period)
+	}
+	if p.Rate < 0 {
+		return fmt.Errorf("negative interest rate %d", p.Rate)
+	}
+	return nil
+}
+
+// String describes p, e.g. "500ppm per block".
+func (p InterestPolicy) String() string {
+	return fmt.Sprintf("%dppm per %s", p.Rate, p.Period)
+}
+
+// compound returns the interest bal earns over periods periods, each
+// period's interest truncated to the smallest unit before it compounds. It
+// fails if the balance with interest no longer fits in an Amount.
+func (p InterestPolicy) compound(bal Amount, periods int) (Amount, error) {
+	var interest Amount
+	for i := 0; i < periods; i++ {
+		total, err := bal.Add(interest)
+		if err != nil {
+			return 0, err
+		}
+		earned, err := total.MulDiv(p.Rate, 1_000_000)
+		if err != nil {
+			return 0, err
+		}
+		if interest, err = interest.Add(earned); err != nil {
+			return 0, err
+		}
+	}
+	return interest, nil
+}
+
+// InterestAccrual pays interest on a ledger's positive native balances as
+// blocks are applied to it. It is a simulation on top of the ledger, not a
+// consensus rule: the credits it posts are in no block, so a chain replayed
+// into another ledger won't have them. Since they are the newest entries
+// in their statements, the blocks before them can't be unapplied.
+type InterestAccrual struct {
+	Policy InterestPolicy
+	ledger *Ledger
+
+	started    bool
+	lastHeight int       // height interest was last paid to
+	lastTime   time.Time // block time last paid to; for CompoundDaily, the start of the current day
+}
+
+// NewInterestAccrual returns an accrual paying p on l's balances, starting
+// from the first Accrue.
+func NewInterestAccrual(l *Ledger, p InterestPolicy) (*InterestAccrual, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &InterestAccrual{Policy: p, ledger: l}, nil
+}
+
+// Accrue pays the interest due since the last call, now that the block at
+// height, timestamped at, has been applied: one period per block since,
+// or per whole day, with the rest of a day carried over. Each account with
+// a positive native balance gets one Credit from InterestSource for all
+// the periods, filed under the "interest" category; closed accounts get
+// nothing. The first call only starts the clock. It returns the credits.
+func (ia *InterestAccrual) Accrue(height int, at time.Time) ([]Transaction, error) {
+	if !ia.started {
+		ia.started, ia.lastHeight, ia.lastTime = true, height, at
+		return nil, nil
+	}
+	if height < ia.lastHeight || at.Before(ia.lastTime) {
+		return nil, fmt.Errorf("interest already paid to block %d at %s", ia.lastHeight, ia.lastTime.Format(time.RFC3339))
+	}
+	var periods int
+	var desc string
+	switch ia.Policy.Period {
+	case CompoundPerBlock:
+		periods = height - ia.lastHeight
+		desc = fmt.Sprintf("interest, blocks %d to %d", ia.lastHeight+1, height)
+		if periods == 1 {
+			desc = fmt.Sprintf("interest, block %d", height)
+		}
+		ia.lastTime = at
+	case CompoundDaily:
+		periods = int(at.Sub(ia.lastTime) / (24 * time.Hour))
+		desc = fmt.Sprintf("interest, %d days from %s", periods, ia.lastTime.Format(time.RFC3339))
+		ia.lastTime = ia.lastTime.Add(time.Duration(periods) * 24 * time.Hour)
+	}
+	ia.lastHeight = height
+	if periods == 0 || ia.Policy.Rate == 0 {
+		return nil, nil
+	}
+
+	var credits []Transaction
+	for _, addr := range ia.ledger.Addresses() {
+		a := ia.ledger.accounts[addr]
+		if addr == InterestSource || a.Balance <= 0 || a.Status == StatusClosed {
+			continue
+		}
+		interest, err := ia.Policy.compound(a.Balance, periods)
+		if err != nil {
+			return nil, fmt.Errorf("interest for %s: %w", addr, err)
+		}
+		if interest == 0 {
+			continue
+		}
+		credit := Transaction{
+			From:        InterestSource,
+			To:          addr,
+			Time:        at,
+			Description: desc,
+			Amount:      interest,
+			Type:        Credit,
+			Category:    "interest",
+		}
+		credit.Hash = computeTxHash(credit)
+		if err := a.apply(credit, at); err != nil {
+			return credits, err
+		}
+		ia.ledger.recordMint(height, addr, interest)
+		credits = append(credits, credit)
+	}
+	return credits, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestInterestCompounds(t *testing.T) {
+	p := InterestPolicy{Rate: 100_000, Period: CompoundPerBlock} // 10%
+	for _, tc := range []struct {
+		bal     Amount
+		periods int
+		want    Amount
+	}{
+		{100 * Coin, 0, 0},
+		{100 * Coin, 1, 10 * Coin},
+		{100 * Coin, 2, 21 * Coin}, // the second period earns on the first's interest
+		{9, 1, 0},                  // truncated to the smallest unit
+		{10, 2, 2},
+	} {
+		got, err := p.compound(tc.bal, tc.periods)
+		if err != nil || got != tc.want {
+			t.Errorf("compound(%d, %d) = %d, %v; want %d", tc.bal, tc.periods, got, err, tc.want)
+		}
+	}
+	if _, err := (InterestPolicy{Rate: 1_000_000, Period: CompoundPerBlock}).compound(math.MaxInt64/2+1, 2); err == nil {
+		t.Error("interest overflowed without an error")
+	}
+}
+
+func TestInterestPolicyValidate(t *testing.T) {
+	for _, p := range []InterestPolicy{{Rate: 1, Period: "week"}, {Rate: -1, Period: CompoundDaily}} {
+		if _, err := NewInterestAccrual(NewLedger(), p); err == nil {
+			t.Errorf("accepted %v", p)
+		}
+	}
+	if p := (InterestPolicy{Rate: 500, Period: CompoundPerBlock}); p.String() != "500ppm per block" {
+		t.Errorf("String() = %q", p.String())
+	}
+}
+
+func TestAccruePerBlock(t *testing.T) {
+	l := NewLedger()
+	l.Mint("saver", 100*Coin)
+	l.Mint("closed", 100*Coin)
+	l.Account("closed").Status = StatusClosed
+	l.Account("broke")
+	ia, err := NewInterestAccrual(l, InterestPolicy{Rate: 100_000, Period: CompoundPerBlock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if credits, err := ia.Accrue(1, testStart); err != nil || len(credits) != 0 {
+		t.Fatalf("first accrual paid %d credits, err %v; want it only to start the clock", len(credits), err)
+	}
+	credits, err := ia.Accrue(3, testStart.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credits) != 1 {
+		t.Fatalf("%d credits, want one for the only open funded account", len(credits))
+	}
+	c := credits[0]
+	if c.From != InterestSource || c.To != "saver" || c.Amount != 21*Coin || c.Type != Credit || c.Category != "interest" ||
+		c.Description != "interest, blocks 2 to 3" || c.Hash != computeTxHash(c) {
+		t.Errorf("credit %+v", c)
+	}
+	if bal := l.Account("saver").Balance; bal != 121*Coin {
+		t.Errorf("saver holds %s, want 121", bal)
+	}
+	if bal := l.Account("closed").Balance; bal != 100*Coin {
+		t.Errorf("a closed account earned interest: %s", bal)
+	}
+
+	if credits, _ := ia.Accrue(3, testStart.Add(time.Minute)); len(credits) != 0 {
+		t.Error("paid interest twice for the same block")
+	}
+	if _, err := ia.Accrue(2, testStart.Add(2*time.Minute)); err == nil {
+		t.Error("paid interest for an earlier block")
+	}
+}
+
+func TestAccrueDailyCarriesPartDays(t *testing.T) {
+	l := NewLedger()
+	l.Mint("saver", 100*Coin)
+	ia, err := NewInterestAccrual(l, InterestPolicy{Rate: 100_000, Period: CompoundDaily})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ia.Accrue(0, testStart)
+	if credits, _ := ia.Accrue(1, testStart.Add(20*time.Hour)); len(credits) != 0 {
+		t.Error("paid interest before a day had passed")
+	}
+	// 20 hours carried over plus 8 more make the first whole day.
+	credits, err := ia.Accrue(2, testStart.Add(28*time.Hour))
+	if err != nil || len(credits) != 1 || credits[0].Amount != 10*Coin {
+		t.Fatalf("after one day: %+v, %v", credits, err)
+	}
+	if _, err := ia.Accrue(3, testStart.Add(23*time.Hour)); err == nil {
+		t.Error("paid interest for a time already covered")
+	}
+	credits, _ = ia.Accrue(4, testStart.Add(72*time.Hour))
+	if len(credits) != 1 || credits[0].Amount != 23*Coin+Coin/10 {
+		t.Errorf("after two more days: %+v", credits)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BookKind is the class of a bookkeeping account, which decides which side
+// increases it.
+type BookKind string
+
+const (
+	Assets      BookKind = "assets"
+	Liabilities BookKind = "liabilities"
+	Equity      BookKind = "equity"
+	Income      BookKind = "income"
+	Expenses    BookKind = "expenses"
+)
+
+// debitNormal reports whether debits increase accounts of kind k.
+func (k BookKind) debitNormal() bool {
+	return k == Assets || k == Expenses
+}
+
+// Names of the accounts JournalAccount books into. Categories become
+// sub-accounts, e.g. "expenses:food".
+const (
+	WalletAccount  = "assets:wallet"
+	OpeningAccount = "equity:opening"
+	FeesAccount    = "expenses:fees"
+)
+
+// ErrUnbalanced is returned for a journal entry whose debits and credits
+// differ.
+var ErrUnbalanced = errors.New("entry does not balance")
+
+// Posting is one line of a journal entry: an amount debited or credited to
+// a named account.
+type Posting struct {
+	Account string
+	Debit   Amount
+	Credit  Amount
+}
+
+// JournalEntry is a set of postings whose debits equal its credits.
+type JournalEntry struct {
+	Time        time.Time
+	TxHash      string // the transaction it records, if any
+	Description string
+	Postings    []Posting
+}
+
+// Journal is a double-entry book: every entry debits and credits named
+// accounts by the same total, so the debits across the book always equal
+// the credits.
+type Journal struct {
+	kinds   map[string]BookKind
+	entries []JournalEntry
+}
+
+// NewJournal creates an empty journal.
+func NewJournal() *Journal {
+	return &Journal{kinds: make(map[string]BookKind)}
+}
+
+// Open adds a named account of the given kind. Names are "kind:name", e.g.
+// "expenses:food"; opening an existing account again is a no-op.
+func (j *Journal) Open(name string) error {
+	kind := BookKind(strings.SplitN(name, ":", 2)[0])
+	switch kind {
+	case Assets, Liabilities, Equity, Income, Expenses:
+	default:
+		return fmt.Errorf("account %q: unknown kind %q", name, kind)
+	}
+	j.kinds[name] = kind
+	return nil
+}
+
+// Post records e, opening any accounts it names. It fails, recording
+// nothing, if e doesn't balance or has a negative amount.
+func (j *Journal) Post(e JournalEntry) error {
+	var debits, credits Amount
+	for _, p := range e.Postings {
+		if p.Debit < 0 || p.Credit < 0 {
+			return fmt.Errorf("entry %q: negative amount for %s", e.Description, p.Account)
+		}
+		debits += p.Debit
+		credits += p.Credit
+	}
+	if debits != credits {
+		return fmt.Errorf("%w: %q debits %v, credits %v", ErrUnbalanced, e.Description, debits, credits)
+	}
+	for _, p := range e.Postings {
+		if _, ok := j.kinds[p.Account]; !ok {
+			if err := j.Open(p.Account); err != nil {
+				return err
+			}
+		}
+	}
+	j.entries = append(j.entries, e)
+	return nil
+}
+
+// Entries returns the posted entries in order.
+func (j *Journal) Entries() []JournalEntry {
+	return append([]JournalEntry(nil), j.entries...)
+}
+
+// TrialBalanceRow is one account's totals in a trial balance. Balance is
+// on the account's normal side: positive for an asset holding funds or an
+// income account that earned them.
+type TrialBalanceRow struct {
+	Account string
+	Kind    BookKind
+	Debits  Amount
+	Credits Amount
+	Balance Amount
+}
+
+// TrialBalance totals every account's postings, in name order, and checks
+// that debits equal credits across the book.
+func (j *Journal) TrialBalance() ([]TrialBalanceRow, error) {
+	rows := make(map[string]*TrialBalanceRow, len(j.kinds))
+	for name, kind := range j.kinds {
+		rows[name] = &TrialBalanceRow{Account: name, Kind: kind}
+	}
+	var debits, credits Amount
+	for _, e := range j.entries {
+		for _, p := range e.Postings {
+			r := rows[p.Account]
+			r.Debits += p.Debit
+			r.Credits += p.Credit
+			debits += p.Debit
+			credits += p.Credit
+		}
+	}
+	list := make([]TrialBalanceRow, 0, len(rows))
+	for _, r := range rows {
+		r.Balance = r.Credits - r.Debits
+		if r.Kind.debitNormal() {
+			r.Balance = -r.Balance
+		}
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Account < list[b].Account })
+	if debits != credits {
+		return list, fmt.Errorf("%w: book debits %v, credits %v", ErrUnbalanced, debits, credits)
+	}
+	return list, nil
+}
+
+// JournalAccount books a's history as double entries from its owner's
+// point of view: funds it held before its first transaction are opening
+// equity, payments it made are expenses under their category (fees under
+// expenses:fees), and payments it received are income under theirs, all
+// against assets:wallet. The wallet's balance then equals a's. The book is
+// in the native coin: token amounts aren't booked, only the fees paid on
+// them.
+func JournalAccount(a *Account) (*Journal, error) {
+	j := NewJournal()
+	for _, name := range []string{WalletAccount, OpeningAccount} {
+		j.Open(name)
+	}
+	s := a.Statement()
+	if s.Opening != 0 {
+		e := JournalEntry{Description: "opening balance"}
+		if len(a.Transactions) > 0 {
+			e.Time = a.Transactions[0].Time
+		}
+		e.Postings = balanced(WalletAccount, OpeningAccount, s.Opening)
+		if err := j.Post(e); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range a.Transactions {
+		cat := t.Category
+		if cat == "" {
+			cat = Uncategorized
+		}
+		e := JournalEntry{Time: t.Time, TxHash: t.Hash, Description: t.Description}
+		switch t.Type {
+		case Debit:
+			sent := -t.change(NativeAsset) - t.Fee
+			e.Postings = []Posting{{Account: "expenses:" + cat, Debit: sent}}
+			if t.Fee != 0 {
+				e.Postings = append(e.Postings, Posting{Account: FeesAccount, Debit: t.Fee})
+			}
+			e.Postings = append(e.Postings, Posting{Account: WalletAccount, Credit: sent + t.Fee})
+		case Credit:
+			e.Postings = balanced(WalletAccount, "income:"+cat, t.change(NativeAsset))
+		default:
+			return nil, fmt.Errorf("tx %s: unknown type %q", t.Hash, t.Type)
+		}
+		if err := j.Post(e); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// balanced debits debit and credits credit by amount, swapping the sides
+// of a negative amount.
+func balanced(debit, credit string, amount Amount) []Posting {
+	if amount < 0 {
+		debit, credit, amount = credit, debit, -amount
+	}
+	return []Posting{{Account: debit, Debit: amount}, {Account: credit, Credit: amount}}
+}
+
+// PrintTrialBalance prints the trial balance, or why it fails.
+func (j *Journal) PrintTrialBalance() {
+	rows, err := j.TrialBalance()
+	fmt.Printf("\n=== Trial Balance =========================================\n")
+	fmt.Printf("%-24s %11s %11s %11s\n", "Account", "Debits", "Credits", "Balance")
+	var debits, credits Amount
+	for _, r := range rows {
+		fmt.Printf("%-24s %11.2f %11.2f %11.2f\n", r.Account, r.Debits, r.Credits, r.Balance)
+		debits += r.Debits
+		credits += r.Credits
+	}
+	fmt.Printf("%-24s %11.2f %11.2f\n", "Total", debits, credits)
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Print("===========================================================\n\n")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJournalAccountBalancesTheBook(t *testing.T) {
+	l, alice, _ := statementLedger(t)
+	j, err := JournalAccount(l.Account(alice.Addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(j.Entries()); n != 3 {
+		t.Errorf("%d entries, want opening, payment and refund", n)
+	}
+	rows, err := j.TrialBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Amount{
+		WalletAccount:             7*Coin + Coin/2,
+		OpeningAccount:            10 * Coin,
+		"expenses:housing":        3 * Coin,
+		FeesAccount:               Coin / 2,
+		"income:" + Uncategorized: Coin,
+	}
+	if len(rows) != len(want) {
+		t.Errorf("%d accounts, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, r := range rows {
+		if i > 0 && rows[i-1].Account >= r.Account {
+			t.Errorf("%s listed after %s", r.Account, rows[i-1].Account)
+		}
+		if r.Balance != want[r.Account] {
+			t.Errorf("%s: balance %s, want %s", r.Account, r.Balance, want[r.Account])
+		}
+	}
+	if wallet := l.Account(alice.Addr).Balance; wallet != want[WalletAccount] {
+		t.Errorf("wallet account disagrees with the ledger's %s", wallet)
+	}
+}
+
+func TestJournalPostRejects(t *testing.T) {
+	j := NewJournal()
+	err := j.Post(JournalEntry{Description: "lopsided", Postings: []Posting{
+		{Account: WalletAccount, Debit: 2 * Coin},
+		{Account: OpeningAccount, Credit: Coin},
+	}})
+	if !errors.Is(err, ErrUnbalanced) {
+		t.Errorf("unbalanced entry: %v", err)
+	}
+	if err := j.Post(JournalEntry{Postings: []Posting{
+		{Account: WalletAccount, Debit: -Coin},
+		{Account: OpeningAccount, Credit: -Coin},
+	}}); err == nil {
+		t.Error("posted a negative amount")
+	}
+	if err := j.Post(JournalEntry{Postings: balanced("savings:jar", OpeningAccount, Coin)}); err == nil {
+		t.Error("posted to an account of unknown kind")
+	}
+	if len(j.Entries()) != 0 {
+		t.Errorf("rejected entries were recorded: %+v", j.Entries())
+	}
+	if rows, err := j.TrialBalance(); err != nil || len(rows) != 0 {
+		t.Errorf("empty book: %d rows, err %v", len(rows), err)
+	}
+}
+
+func TestBalancedSwapsNegativeAmounts(t *testing.T) {
+	p := balanced(WalletAccount, OpeningAccount, -Coin)
+	if p[0].Account != OpeningAccount || p[0].Debit != Coin || p[1].Account != WalletAccount || p[1].Credit != Coin {
+		t.Errorf("balanced(-1) = %+v", p)
+	}
+}
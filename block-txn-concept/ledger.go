@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrInsufficientFunds is returned for a transaction whose sender can't
+// cover its amount and fee.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrDuplicateTx is returned for a transaction that has already been
+// applied, such as one in a block processed a second time.
+var ErrDuplicateTx = errors.New("transaction already applied")
+
+// Ledger keeps an Account for every address transactions touch, so both
+// sides of each transfer are tracked and the total across all accounts
+// only changes when coins are minted.
+type Ledger struct {
+	accounts map[string]*Account
+	seen     map[string]bool // hashes of the transactions and blocks applied
+
+	// fees holds what transactions paid in fees until a block's rewards
+	// pay it out, so Total stays constant across Apply.
+	fees Amount
+
+	// blocks records each applied block's effect on balances, oldest
+	// first, for BalanceAt.
+	blocks []blockDelta
+
+	// Clock is the time Apply applies transactions at, which spending
+	// limits are measured against. It defaults to time.Now; ApplyBlock
+	// uses the block's timestamp instead.
+	Clock func() time.Time
+}
+
+// blockDelta is how one block changed each balance it touched.
+type blockDelta struct {
+	height int
+	hash   string
+	deltas map[string]Amount
+}
+
+// NewLedger creates an empty ledger.
+func NewLedger() *Ledger {
+	return &Ledger{accounts: make(map[string]*Account), seen: make(map[string]bool)}
+}
+
+// Account returns addr's account, creating an empty one if the ledger
+// hasn't seen addr yet.
+func (l *Ledger) Account(addr string) *Account {
+	a, ok := l.accounts[addr]
+	if !ok {
+		a = &Account{Address: addr}
+		l.accounts[addr] = a
+	}
+	return a
+}
+
+// Open returns addr's account with its owner set.
+func (l *Ledger) Open(addr, owner string) *Account {
+	a := l.Account(addr)
+	a.Owner = owner
+	return a
+}
+
+// OpenMultisig returns the account for p's address with its owner and
+// policy set.
+func (l *Ledger) OpenMultisig(p *MultisigPolicy, owner string) (*Account, error) {
+	a, err := NewMultisigAccount(p, owner)
+	if err != nil {
+		return nil, err
+	}
+	if existing, ok := l.accounts[a.Address]; ok {
+		existing.Owner, existing.Multisig = owner, p
+		return existing, nil
+	}
+	l.accounts[a.Address] = a
+	return a, nil
+}
+
+// Addresses returns every address in the ledger, sorted.
+func (l *Ledger) Addresses() []string {
+	addrs := make([]string, 0, len(l.accounts))
+	for addr := range l.accounts {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Mint credits newly created coins, such as genesis allocations and block
+// rewards, to addr.
+func (l *Ledger) Mint(addr string, amount Amount) {
+	l.Account(addr).Balance += amount
+}
+
+// recordMint counts amount minted to addr after the block at height was
+// applied as part of that block's changes, so BalanceAt sees it.
+func (l *Ledger) recordMint(height int, addr string, amount Amount) {
+	if n := len(l.blocks); n > 0 && l.blocks[n-1].height == height {
+		l.blocks[n-1].deltas[addr] += amount
+	}
+}
+
+// Total is the sum of every balance plus fees not yet paid out.
+func (l *Ledger) Total() Amount {
+	total := l.fees
+	for _, a := range l.accounts {
+		total += a.Balance
+	}
+	return total
+}
+
+// Apply moves each of tx's legs from its sender to the leg's recipient and
+// holds its fee for the next block's rewards. tx must be a Debit, as it
+// appears on chain, signed by its sender; each recipient's statement
+// records the matching Credit. Either every side changes or, on error,
+// none does. A transaction can only be applied once; applying its hash
+// again fails with ErrDuplicateTx. Reversals and status changes are
+// refused, since only a chain knows its StatusAuthority; ApplyBlock
+// applies those.
+func (l *Ledger) Apply(tx Transaction) error {
+	if tx.Reverses != "" {
+		return fmt.Errorf("tx %s: reversals are applied with their block", tx.Hash)
+	}
+	if tx.Status != "" {
+		return fmt.Errorf("%w: tx %s: status changes are applied with their block", ErrNotAuthority, tx.Hash)
+	}
+	return l.apply(tx, true, l.now())
+}
+
+// now is the ledger's clock.
+func (l *Ledger) now() time.Time {
+	if l.Clock != nil {
+		return l.Clock()
+	}
+	return time.Now()
+}
+
+// apply is Apply at a given time, checking tx's signature only if verify
+// is set.
+func (l *Ledger) apply(tx Transaction, verify bool, at time.Time) error {
+	if tx.Type != Debit {
+		return fmt.Errorf("tx %s: ledger applies debits, not %s", tx.Hash, tx.Type)
+	}
+	if l.seen[tx.Hash] {
+		return fmt.Errorf("tx %s: %w", tx.Hash, ErrDuplicateTx)
+	}
+	if err := checkLegs(tx); err != nil {
+		return fmt.Errorf("tx %s: %w", tx.Hash, err)
+	}
+	if cost, _ := tx.Cost(); cost > 0 && l.accounts[tx.From] == nil {
+		return fmt.Errorf("tx %s: %w: %s has no account", tx.Hash, ErrInsufficientFunds, tx.From)
+	}
+	from := l.Account(tx.From) // a free transaction, such as a status change, needs no funds
+	apply := from.applyTransaction
+	if !verify {
+		apply = from.apply
+	}
+	if err := apply(tx, at); err != nil {
+		return err
+	}
+	// Each recipient's side is a credit of its leg alone; the fee is the
+	// sender's. Credits can't fail.
+	for _, leg := range tx.Legs() {
+		credit := tx
+		credit.Type, credit.Fee = Credit, 0
+		credit.To, credit.Amount, credit.Transfers = leg.To, leg.Amount, nil
+		l.Account(leg.To).apply(credit, at)
+	}
+	l.fees += tx.Fee
+	l.seen[tx.Hash] = true
+	return nil
+}
+
+// ApplyBlock applies b's transactions in order and then pays out its
+// rewards from c, which include the fees the transactions paid; the
+// genesis block also mints c's allocations. b's header version must be one
+// c's upgrade schedule allows at its height; signatures are checked for
+// blocks from BlockVersion4 on, which older ones predate. It is all or
+// nothing: if any transaction fails, the ledger is left as it was. A block
+// already applied fails with ErrDuplicateTx, since its rewards would be
+// paid twice.
+func (l *Ledger) ApplyBlock(c *Chain, b Block) error {
+	if b.IsPruned() {
+		return fmt.Errorf("block %d is pruned", b.Index)
+	}
+	if l.seen[b.Hash] {
+		return fmt.Errorf("block %d: %w", b.Index, ErrDuplicateTx)
+	}
+	if b.Hash != c.genesis.Hash {
+		if err := c.validateVersion(b); err != nil {
+			return err
+		}
+	}
+	rewards, err := c.Rewards(b)
+	if err != nil {
+		return err
+	}
+	fees, err := TotalFees(b.Transactions)
+	if err != nil {
+		return fmt.Errorf("block %d: %w", b.Index, err)
+	}
+	restore := l.save()
+
+	if b.Hash == c.genesis.Hash {
+		for addr, amount := range c.config.Alloc {
+			l.Mint(addr, amount)
+		}
+	}
+	for i, tx := range b.Transactions {
+		// Take on the chain's overdraft policy and spending limit, so the
+		// ledger accepts exactly what the chain does.
+		if p, ok := c.config.Overdraft[tx.From]; ok {
+			l.Account(tx.From).Overdraft = &p
+		}
+		if lim, ok := c.config.SpendingLimits[tx.From]; ok {
+			l.Account(tx.From).Limit = &lim
+		}
+		if err := l.apply(tx, b.Version >= BlockVersion4, b.Timestamp); err != nil {
+			for _, done := range b.Transactions[:i] {
+				delete(l.seen, done.Hash)
+			}
+			restore()
+			return fmt.Errorf("block %d: %w", b.Index, err)
+		}
+	}
+	for addr, reward := range rewards {
+		l.Mint(addr, reward)
+	}
+	// The coinbase's reward includes these fees; a coinbase that claimed
+	// less than it could simply never receives the rest.
+	l.fees -= fees
+	l.seen[b.Hash] = true
+
+	// Replaying b onto empty balances gives exactly its changes.
+	d := &Snapshot{Balances: make(map[string]Amount)}
+	if err := c.applyBlock(d, b); err == nil {
+		l.blocks = append(l.blocks, blockDelta{height: b.Index, hash: b.Hash, deltas: d.Balances})
+	}
+	return nil
+}
+
+// Unapply reverses Apply for tx, which must be the last transaction
+// applied to its sender and to each of its recipients.
+func (l *Ledger) Unapply(tx Transaction) error {
+	from, ok := l.accounts[tx.From]
+	if !ok {
+		return fmt.Errorf("tx %s: %s has no account", tx.Hash, tx.From)
+	}
+	// The recipients' credits were recorded after the sender's debit, in
+	// leg order; check every side is where it should be before undoing any.
+	legs := tx.Legs()
+	undone := make(map[string]int)
+	for i := len(legs) - 1; i >= 0; i-- {
+		a, ok := l.accounts[legs[i].To]
+		k := undone[legs[i].To]
+		if !ok || !a.appliedLast(tx.Hash, Credit, k) {
+			return fmt.Errorf("tx %s: credit to %s is not the last applied", tx.Hash, legs[i].To)
+		}
+		undone[legs[i].To]++
+	}
+	if !from.appliedLast(tx.Hash, Debit, undone[tx.From]) {
+		return fmt.Errorf("tx %s: debit from %s is not the last applied", tx.Hash, tx.From)
+	}
+
+	for i := len(legs) - 1; i >= 0; i-- {
+		a := l.accounts[legs[i].To]
+		a.UnapplyTransaction(a.Transactions[len(a.Transactions)-1])
+	}
+	from.UnapplyTransaction(from.Transactions[len(from.Transactions)-1])
+	l.fees -= tx.Fee
+	delete(l.seen, tx.Hash)
+	return nil
+}
+
+// appliedLast reports whether the transaction skip entries from the end of
+// a's history is the given side of hash.
+func (a *Account) appliedLast(hash string, typ TransactionType, skip int) bool {
+	i := len(a.Transactions) - 1 - skip
+	return i >= 0 && a.Transactions[i].Hash == hash && a.Transactions[i].Type == typ
+}
+
+// UnapplyBlock reverses ApplyBlock for b, which must be the last block
+// applied: its rewards are taken back, its transactions undone newest
+// first, and, for the genesis block, the allocations removed. Like
+// ApplyBlock it is all or nothing.
+func (l *Ledger) UnapplyBlock(c *Chain, b Block) error {
+	if b.IsPruned() {
+		return fmt.Errorf("block %d is pruned", b.Index)
+	}
+	rewards, err := c.Rewards(b)
+	if err != nil {
+		return err
+	}
+	fees, err := TotalFees(b.Transactions)
+	if err != nil {
+		return fmt.Errorf("block %d: %w", b.Index, err)
+	}
+	restore := l.save()
+
+	l.fees += fees
+	for addr, reward := range rewards {
+		l.Mint(addr, -reward)
+	}
+	for i := len(b.Transactions) - 1; i >= 0; i-- {
+		if err := l.Unapply(b.Transactions[i]); err != nil {
+			for _, undone := range b.Transactions[i+1:] {
+				l.seen[undone.Hash] = true
+			}
+			restore()
+			return fmt.Errorf("block %d: %w", b.Index, err)
+		}
+	}
+	if b.Hash == c.genesis.Hash {
+		for addr, amount := range c.config.Alloc {
+			l.Mint(addr, -amount)
+		}
+	}
+	if n := len(l.blocks); n > 0 && l.blocks[n-1].hash == b.Hash {
+		l.blocks = l.blocks[:n-1]
+	}
+	delete(l.seen, b.Hash)
+	return nil
+}
+
+// BalanceAt returns addr's balance as of the applied block at height, by
+// taking back the changes of every block applied after it. Heights run
+// from the first block the ledger applied to the last; transactions
+// applied with Apply since then count as part of the last.
+func (l *Ledger) BalanceAt(addr string, height int) (Amount, error) {
+	n := len(l.blocks)
+	if n == 0 {
+		return 0, errors.New("ledger has applied no blocks")
+	}
+	if first, last := l.blocks[0].height, l.blocks[n-1].height; height < first || height > last {
+		return 0, fmt.Errorf("height %d is outside the ledger's blocks %d to %d", height, first, last)
+	}
+	var bal Amount
+	if a, ok := l.accounts[addr]; ok {
+		bal = a.Balance
+	}
+	for i := n - 1; i >= 0 && l.blocks[i].height > height; i-- {
+		bal -= l.blocks[i].deltas[addr]
+	}
+	return bal, nil
+}
+
+// Follow keeps l in step with c's best chain from now on: blocks a reorg
+// disconnects are unapplied newest first, and every newly connected block
+// is applied. l must already reflect c's current best chain. Errors, which
+// mean l and c have diverged, go to onErr. It returns a function that stops
+// following.
+func (l *Ledger) Follow(c *Chain, onErr func(error)) (unfollow func()) {
+	stopReorg := c.Subscribe(ReorgEvent, func(e Event) {
+		for i := len(e.Disconnected) - 1; i >= 0; i-- {
+			if err := l.UnapplyBlock(c, e.Disconnected[i]); err != nil {
+				onErr(err)
+			}
+		}
+	})
+	stopBlocks := c.Subscribe(NewBlockEvent, func(e Event) {
+		if err := l.ApplyBlock(c, e.Block); err != nil {
+			onErr(err)
+		}
+	})
+	return func() {
+		stopReorg()
+		stopBlocks()
+	}
+}
+
+// save copies every account and the held fees, returning a function that
+// restores them in place, so *Accounts handed out earlier stay live.
+func (l *Ledger) save() (restore func()) {
+	saved, savedFees := make(map[string]*Account, len(l.accounts)), l.fees
+	for addr, a := range l.accounts {
+		copied := *a
+		copied.Assets = make(map[Asset]Amount, len(a.Assets))
+		for asset, bal := range a.Assets {
+			copied.Assets[asset] = bal
+		}
+		saved[addr] = &copied
+	}
+	return func() {
+		for addr, a := range l.accounts {
+			if old, ok := saved[addr]; ok {
+				*a = *old
+			} else {
+				delete(l.accounts, addr)
+			}
+		}
+		l.fees = savedFees
+	}
+}
+
+// PrintBalances prints every non-empty account and the total.
+func (l *Ledger) PrintBalances() {
+	fmt.Printf("\n=== Ledger ================================================\n")
+	for _, addr := range l.Addresses() {
+		a := l.accounts[addr]
+		if a.Balance == 0 && len(a.Transactions) == 0 {
+			continue
+		}
+		fmt.Printf("%-44s %12.2f\n", addr, a.Balance)
+	}
+	fmt.Printf("%-44s %12.2f\n", "Total", l.Total())
+	fmt.Print("===========================================================\n\n")
+}
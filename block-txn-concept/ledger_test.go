@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestLedgerApplyRefusesStatusChange is the exploit where anyone froze any
+// account by applying a status change to a ledger directly, with no one
+// to check it came from the status authority.
+func TestLedgerApplyRefusesStatusChange(t *testing.T) {
+	mallory, victim := newTestAccount(t), newTestAccount(t)
+	l := NewLedger()
+	l.Mint(victim.Addr, 10*Coin)
+	tx, err := NewStatusChange(mallory.Addr, victim.Addr, StatusFrozen, 0).Time(testStart).SignWith(mallory.Key).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Apply(tx); !errors.Is(err, ErrNotAuthority) {
+		t.Fatalf("Apply = %v, want ErrNotAuthority", err)
+	}
+	if s := l.Account(victim.Addr).Status; s != "" {
+		t.Errorf("victim's status is %q", s)
+	}
+}
+
+func TestDuplicatesAreRejected(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	l := NewLedger()
+	if err := l.ApplyBlock(c, c.Tip()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyBlock(c, c.Tip()); !errors.Is(err, ErrDuplicateTx) {
+		t.Errorf("genesis applied twice: err = %v, want ErrDuplicateTx", err)
+	}
+
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	if err := l.Apply(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Apply(tx); !errors.Is(err, ErrDuplicateTx) {
+		t.Errorf("tx applied twice: err = %v, want ErrDuplicateTx", err)
+	}
+	if got := l.Account(bob.Addr).Balance; got != Coin {
+		t.Errorf("bob holds %v, want 1", got)
+	}
+
+	m := NewMempool(c)
+	defer m.Close()
+	if err := m.Add(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(tx); !errors.Is(err, ErrDuplicateTx) {
+		t.Errorf("tx pooled twice: err = %v, want ErrDuplicateTx", err)
+	}
+}
+
+func TestUnapplyReversesApply(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	l := NewLedger()
+	if err := l.ApplyBlock(c, c.Tip()); err != nil {
+		t.Fatal(err)
+	}
+	first := signedTx(t, c, alice, Transaction{Transfers: []Transfer{{To: bob.Addr, Amount: Coin}, {To: carol.Addr, Amount: 2 * Coin}, {To: bob.Addr, Amount: Coin}}, Fee: Coin / 4})
+	if err := l.Apply(first); err != nil {
+		t.Fatal(err)
+	}
+	second := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	second.Nonce = 1
+	if err := SignTx(&second, alice.Key); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Apply(second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Unapply(first); err == nil {
+		t.Error("unapplied a transaction that isn't the last")
+	}
+	for _, tx := range []Transaction{second, first} {
+		if err := l.Unapply(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := l.Account(alice.Addr)
+	if a.Balance != 10*Coin || a.Nonce != 0 || len(a.Transactions) != 0 {
+		t.Errorf("alice after undoing both: balance %s, nonce %d, %d transactions", a.Balance, a.Nonce, len(a.Transactions))
+	}
+	if b := l.Account(bob.Addr); b.Balance != 0 || len(b.Transactions) != 0 {
+		t.Errorf("bob after undoing both: balance %s, %d transactions", b.Balance, len(b.Transactions))
+	}
+	// Undone transactions can be applied again.
+	if err := l.Apply(first); err != nil {
+		t.Errorf("reapplying: %v", err)
+	}
+}
+
+func TestLedgerFollowsReorgs(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	l := NewLedger()
+	if err := l.ApplyBlock(c, c.Tip()); err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	unfollow := l.Follow(c, func(err error) { errs = append(errs, err) })
+
+	if err := mineTxs(t, c, signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 3 * Coin})); err != nil {
+		t.Fatal(err)
+	}
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	rival := newTestChain(t, rivalCfg)
+	if err := mineTxs(t, rival, signedTx(t, rival, alice, Transaction{To: carol.Addr, Amount: 4 * Coin})); err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, rival, 1)
+	for _, b := range rival.BestChain()[1:] {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Tip().Hash != rival.Tip().Hash {
+		t.Fatal("the rival branch didn't become the tip")
+	}
+	if len(errs) != 0 {
+		t.Fatalf("ledger diverged: %v", errs)
+	}
+	for _, acct := range []testAccount{alice, bob, carol} {
+		if got, want := l.Account(acct.Addr).Balance, tipBalance(t, c, acct.Addr); got != want {
+			t.Errorf("%s: ledger has %s, chain %s", acct.Addr, got, want)
+		}
+	}
+
+	unfollow()
+	mineBlocks(t, c, 1)
+	if n := len(l.blocks); n != 3 {
+		t.Errorf("ledger holds %d blocks after unfollowing, want 3", n)
+	}
+}
+
+func TestBalanceAt(t *testing.T) {
+	alice, bob, miner := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	l := NewLedger()
+	if _, err := l.BalanceAt(alice.Addr, 0); err == nil {
+		t.Error("BalanceAt on an empty ledger succeeded")
+	}
+	if err := l.ApplyBlock(c, c.Tip()); err != nil {
+		t.Fatal(err)
+	}
+	l.Follow(c, func(err error) { t.Error(err) })
+
+	for i := 1; i <= 3; i++ {
+		b, err := c.BuildBlock(miner.Addr, []Transaction{signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Amount(i) * Coin})})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for h := 0; h <= 3; h++ {
+		v, err := c.At(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, addr := range []string{alice.Addr, bob.Addr, miner.Addr} {
+			got, err := l.BalanceAt(addr, h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := v.Balance(addr); got != want {
+				t.Errorf("%s at %d: ledger says %s, chain %s", addr, h, got, want)
+			}
+		}
+	}
+	if _, err := l.BalanceAt(alice.Addr, 4); err == nil {
+		t.Error("BalanceAt above the last applied block succeeded")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrSpendingLimit is returned for a debit that would take its sender past
+// its spending limit.
+var ErrSpendingLimit = errors.New("spending limit exceeded")
+
+// SpendingLimit caps what an account may send, fees included, in any
+// rolling window of time.
+type SpendingLimit struct {
+	Amount Amount        `json:"amount"`
+	Window time.Duration `json:"window"`
+}
+
+// DailyLimit returns a limit of amount in any 24 hours.
+func DailyLimit(amount Amount) SpendingLimit {
+	return SpendingLimit{Amount: amount, Window: 24 * time.Hour}
+}
+
+// Validate checks that l has a positive window and no negative amount.
+func (l SpendingLimit) Validate() error {
+	if l.Window <= 0 {
+		return fmt.Errorf("spending limit window %s is not positive", l.Window)
+	}
+	if l.Amount < 0 {
+		return fmt.Errorf("negative spending limit %v", l.Amount)
+	}
+	return nil
+}
+
+// String describes l for error messages, e.g. "limit 100 per 24h0m0s".
+func (l SpendingLimit) String() string {
+	return fmt.Sprintf("limit %v per %s", l.Amount, l.Window)
+}
+
+// Spend is one debit counted towards a spending limit: what it sent, fee
+// included, and when it was applied. That is the block's timestamp or the
+// ledger's clock, never the transaction's own Time, which its sender
+// chose.
+type Spend struct {
+	Hash   string
+	At     time.Time
+	Amount Amount
+}
+
+// SetSpendingLimit caps what a may send in any window of l.Window. The
+// debits a has already made count towards it. Set a.Limit to nil to lift
+// it.
+func (a *Account) SetSpendingLimit(l SpendingLimit) error {
+	if err := l.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", a.Address, err)
+	}
+	a.Limit = &l
+	return nil
+}
+
+// Spent returns the native coin a sent, fees included, in the window
+// ending at now: its debits applied after now-window and not after now.
+// Tokens don't count towards a limit.
+func (a *Account) Spent(window time.Duration, now time.Time) Amount {
+	since := now.Add(-window)
+	var spent Amount
+	for _, s := range a.Spends {
+		if s.At.After(since) && !s.At.After(now) {
+			spent += s.Amount
+		}
+	}
+	return spent
+}
+
+// Allowance returns how much more a may send at now without passing its
+// spending limit. ok is false if a has no limit.
+func (a *Account) Allowance(now time.Time) (left Amount, ok bool) {
+	if a.Limit == nil {
+		return 0, false
+	}
+	left = a.Limit.Amount - a.Spent(a.Limit.Window, now)
+	if left < 0 {
+		left = 0
+	}
+	return left, true
+}
+
+// checkLimit returns ErrSpendingLimit if sending cost at now would take a
+// past its spending limit.
+func (a *Account) checkLimit(cost Amount, now time.Time) error {
+	left, ok := a.Allowance(now)
+	if ok && cost > left {
+		return fmt.Errorf("%w: %s spends %v with %v left (%v)", ErrSpendingLimit, a.Address, cost, left, *a.Limit)
+	}
+	return nil
+}
+
+// limitSpec lists spending limits in address order.
+func limitSpec(limits map[string]SpendingLimit) []LimitSpec {
+	var list []LimitSpec
+	for addr, l := range limits {
+		list = append(list, LimitSpec{Address: addr, Amount: l.Amount, Window: l.Window.String()})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Address < list[j].Address })
+	return list
+}
+
+// Spent returns what addr sent, fees included, in the window ending at the
+// block being validated: in blocks on its branch dated within the window,
+// and earlier in the block itself. ok is false if pruning has discarded a
+// block in the window.
+func (ctx *TxContext) Spent(addr string, window time.Duration) (spent Amount, ok bool) {
+	since := ctx.Time.Add(-window)
+	spent = ctx.spent[addr]
+	for b := ctx.parent; b != nil && b.Timestamp.After(since); b = ctx.Chain.blocks[b.PrevHash] {
+		if b.IsPruned() {
+			return 0, false
+		}
+		for _, tx := range b.Transactions {
+			if tx.From == addr {
+				spent -= tx.change(NativeAsset)
+			}
+		}
+		if b == ctx.Chain.genesis {
+			break
+		}
+	}
+	return spent, true
+}
+
+// checkSpendingLimit checks that tx keeps its sender within its
+// ChainConfig.SpendingLimits entry, measured by block timestamps. Blocks
+// whose bodies are pruned are taken on trust, as checkBalance does.
+func checkSpendingLimit(ctx *TxContext, tx Transaction) error {
+	l, ok := ctx.Chain.config.SpendingLimits[tx.From]
+	if !ok {
+		return nil
+	}
+	spent, ok := ctx.Spent(tx.From, l.Window)
+	if !ok {
+		return nil
+	}
+	if cost, left := -tx.change(NativeAsset), l.Amount-spent; cost > left {
+		return fmt.Errorf("%w: tx %s: %s spends %v with %v left (%v)", ErrSpendingLimit, tx.Hash, tx.From, cost, max(left, 0), l)
+	}
+	return nil
+}
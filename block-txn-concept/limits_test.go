@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSpendingLimitIgnoresTxTime is the exploit where a sender backdated
+// each debit out of the window and sent far past a daily limit.
+func TestSpendingLimitIgnoresTxTime(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	now := testStart
+	l := NewLedger()
+	l.Clock = func() time.Time { return now }
+	l.Mint(alice.Addr, 100*Coin)
+	if err := l.Account(alice.Addr).SetSpendingLimit(DailyLimit(10 * Coin)); err != nil {
+		t.Fatal(err)
+	}
+	send := func(nonce uint64, backdate time.Duration) error {
+		tx, err := NewTxBuilder().From(alice.Addr).To(bob.Addr).Amount(9 * Coin).
+			Nonce(nonce).Time(now.Add(-backdate)).SignWith(alice.Key).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l.Apply(tx)
+	}
+
+	if err := send(0, 48*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(1, 96*time.Hour); !errors.Is(err, ErrSpendingLimit) {
+		t.Fatalf("backdated second debit: err = %v, want ErrSpendingLimit", err)
+	}
+	now = now.Add(25 * time.Hour)
+	if err := send(1, 0); err != nil {
+		t.Errorf("a day later: %v", err)
+	}
+}
+
+func TestChainEnforcesSpendingLimit(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 100 * Coin})
+	cfg.SpendingLimits = map[string]SpendingLimit{alice.Addr: DailyLimit(10 * Coin)}
+	c := newTestChain(t, cfg)
+
+	first := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 9 * Coin, Time: testStart.Add(-48 * time.Hour)})
+	b, err := c.BuildBlock("", []Transaction{first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	second := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 9 * Coin, Time: testStart.Add(-96 * time.Hour)})
+	m := NewMempool(c)
+	defer m.Close()
+	if err := m.Add(second); !errors.Is(err, ErrSpendingLimit) {
+		t.Errorf("mempool: err = %v, want ErrSpendingLimit", err)
+	}
+	b, err = c.buildBlock("", []Transaction{second}, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); !errors.Is(err, ErrSpendingLimit) {
+		t.Errorf("block: err = %v, want ErrSpendingLimit", err)
+	}
+
+	// A ledger following the chain applies the same limit.
+	l := NewLedger()
+	for _, b := range c.BestChain() {
+		if err := l.ApplyBlock(c, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := l.Account(alice.Addr).Limit; got == nil || *got != DailyLimit(10*Coin) {
+		t.Errorf("ledger limit = %v", got)
+	}
+}
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 )
@@ -15,33 +22,163 @@ const (
 	Debit  TransactionType = "debit"
 )
 
+// Transaction is identified by its Hash, which mempools, indexes, receipts,
+// and statements all key on.
 type Transaction struct {
-	ID          int
+	Version     uint32 `json:",omitempty"` // format of the hash; see TxVersion1
+	ID          int    `json:",omitempty"` // optional user label, e.g. a statement line; not unique
 	Hash        string
 	From        string
 	To          string
 	Time        time.Time
 	Description string
-	Amount      float64
+	Amount      Amount
+	Fee         Amount `json:",omitempty"` // paid by From on top of Amount, collected by the miner
 	Type        TransactionType
+	ChainID     string `json:",omitempty"` // chain the tx is valid on; see ChainConfig.ChainID
+	ForkID      string `json:",omitempty"` // rule set the tx is valid under; see Chain.ForkID
+	Nonce       uint64 `json:",omitempty"` // number of txs From sent before this one
+
+	// PubKey and Signature prove From sent the transaction; see SignTx.
+	// Neither is part of Hash, which is what the signature covers.
+	PubKey    []byte `json:",omitempty"`
+	Signature []byte `json:",omitempty"`
+
+	// Multisig, for a transaction from a multisig address, is the policy
+	// the address is derived from, and Cosigs are its participants'
+	// signatures; see CosignTx. PubKey and Signature are then unused.
+	Multisig *MultisigPolicy `json:",omitempty"`
+	Cosigs   []Cosig         `json:",omitempty"`
+
+	// Escrow, for a release or refund from an escrow address, is the terms
+	// the address is derived from; PubKey and Signature are then a party's.
+	// See NewEscrowRelease.
+	Escrow *EscrowTerms `json:",omitempty"`
+
+	// Transfers, if set, makes this a multi-transfer paying every leg
+	// atomically, and To and Amount are left empty; see Legs.
+	Transfers []Transfer `json:",omitempty"`
+
+	// Category and Tags label the transaction for reporting; see
+	// Account.Report.
+	Category string   `json:",omitempty"`
+	Tags     []string `json:",omitempty"`
+
+	// Status, if set, makes this an admin transaction from the chain's
+	// status authority setting To's account status; it moves no funds. See
+	// NewStatusChange.
+	Status AccountStatus `json:",omitempty"`
+
+	// Asset, if set, is the token the transaction moves instead of the
+	// native coin. Fee is still in the native coin.
+	Asset Asset `json:",omitempty"`
+
+	// Expires, if set, is the last height and time the transaction may be
+	// mined at; the mempool evicts it after that.
+	Expires *Expiry `json:",omitempty"`
+
+	// Conditions, if set, must all hold in the block that includes the
+	// transaction, or the block is invalid. They need TxVersion2.
+	Conditions []Condition `json:",omitempty"`
+
+	// Reverses, if set, makes this a reversal of the transaction with that
+	// hash, signed by the chain's status authority rather than From. It
+	// needs TxVersion3; see NewReversal.
+	Reverses string `json:",omitempty"`
 }
 
 type Account struct {
 	Address      string
 	Owner        string
-	Balance      float64
+	Balance      Amount // in the native coin
+	Nonce        uint64 // nonce the account's next debit must carry
 	Transactions []Transaction
+
+	// Multisig, if set, is the policy the address is derived from; see
+	// NewMultisigAccount.
+	Multisig *MultisigPolicy `json:",omitempty"`
+
+	// Overdraft, if set, lets debits take the balance below zero; nil is
+	// strict.
+	Overdraft *OverdraftPolicy `json:",omitempty"`
+
+	// Assets holds the balances of tokens other than the native coin; see
+	// BalanceOf.
+	Assets map[Asset]Amount `json:",omitempty"`
+
+	// Limit, if set, caps what debits may send in any rolling window; see
+	// SetSpendingLimit.
+	Limit *SpendingLimit `json:",omitempty"`
+
+	// Spends records every debit applied, oldest first, for Limit.
+	Spends []Spend `json:",omitempty"`
+
+	// Status is set by status changes credited to the account; empty is
+	// active.
+	Status AccountStatus `json:",omitempty"`
 }
 
+// ApplyTransaction applies t to a, checking a debit's signature. A debit
+// counts towards a's spending limit as of now, by the local clock.
 func (a *Account) ApplyTransaction(t Transaction) error {
+	return a.applyTransaction(t, time.Now())
+}
+
+// applyTransaction is ApplyTransaction with the time it is applied at, such
+// as the timestamp of the block t is in.
+func (a *Account) applyTransaction(t Transaction, at time.Time) error {
+	if t.Type == Debit {
+		if err := VerifyTxSignature(t); err != nil {
+			return err
+		}
+	}
+	return a.apply(t, at)
+}
+
+// apply is applyTransaction without the signature check, for debits from
+// blocks that predate signatures.
+func (a *Account) apply(t Transaction, at time.Time) error {
+	if t.Hash == "" {
+		t.Hash = computeTxHash(t) // statements are keyed by hash too
+	}
 	switch t.Type {
 	case Credit:
-		a.Balance += t.Amount
+		if a.Status == StatusClosed {
+			return fmt.Errorf("%w: tx %s pays %s", ErrAccountClosed, t.Hash, a.Address)
+		}
+		a.adjust(t, false)
+		if t.Status != "" {
+			a.Status = t.Status
+		}
 	case Debit:
-		if t.Amount > a.Balance {
-			return fmt.Errorf("insufficient funds for tx %d", t.ID)
+		if err := checkLegs(t); err != nil {
+			return fmt.Errorf("tx %s: %w", t.Hash, err)
 		}
-		a.Balance -= t.Amount
+		switch a.Status {
+		case StatusFrozen:
+			return fmt.Errorf("%w: tx %s: %s", ErrAccountFrozen, t.Hash, a.Address)
+		case StatusClosed:
+			return fmt.Errorf("%w: tx %s: %s", ErrAccountClosed, t.Hash, a.Address)
+		}
+		if t.Nonce != a.Nonce {
+			return fmt.Errorf("tx %s: %w: nonce %d, account is at %d", t.Hash, ErrBadNonce, t.Nonce, a.Nonce)
+		}
+		if err := t.Asset.Validate(); err != nil {
+			return fmt.Errorf("tx %s: %w", t.Hash, err)
+		}
+		if !a.canAfford(-t.change(NativeAsset)) {
+			return fmt.Errorf("%w for tx %s", ErrInsufficientFunds, t.Hash)
+		}
+		if total, _ := t.Total(); t.Asset != NativeAsset && a.BalanceOf(t.Asset) < total {
+			return fmt.Errorf("%w for tx %s: %s holds %v %s", ErrInsufficientFunds, t.Hash, a.Address, a.BalanceOf(t.Asset), t.Asset)
+		}
+		cost := -t.change(NativeAsset)
+		if err := a.checkLimit(cost, at); err != nil {
+			return fmt.Errorf("tx %s: %w", t.Hash, err)
+		}
+		a.adjust(t, false)
+		a.Nonce++
+		a.Spends = append(a.Spends, Spend{Hash: t.Hash, At: at, Amount: cost})
 	default:
 		return fmt.Errorf("unknown transaction type: %s", t.Type)
 	}
@@ -50,201 +187,500 @@ func (a *Account) ApplyTransaction(t Transaction) error {
 	return nil
 }
 
-func (a *Account) PrintStatement() {
-	fmt.Printf("\n=== Account Statement =====================================\n")
-	fmt.Printf("Owner   : %s\n", a.Owner)
-	fmt.Printf("Address : %s\n\n", a.Address)
-
+// FindTransaction returns the transaction in a's history with the given
+// hash. For a transfer to itself it returns the debit.
+func (a *Account) FindTransaction(hash string) (Transaction, bool) {
 	for _, t := range a.Transactions {
-		sign := "+"
-		if t.Type == Debit {
-			sign = "-"
+		if strings.EqualFold(t.Hash, hash) {
+			return t, true
+		}
+	}
+	return Transaction{}, false
+}
+
+// canAfford reports whether a's overdraft policy lets it spend cost.
+func (a *Account) canAfford(cost Amount) bool {
+	var p OverdraftPolicy
+	if a.Overdraft != nil {
+		p = *a.Overdraft
+	}
+	return p.Allows(a.Balance, cost)
+}
+
+// UnapplyTransaction reverses the effect of the most recently applied
+// transaction, which t must be, restoring the balance and nonce from before
+// it. Transactions are undone newest first, as when a reorg disconnects
+// blocks.
+func (a *Account) UnapplyTransaction(t Transaction) error {
+	n := len(a.Transactions)
+	if n == 0 || a.Transactions[n-1].Hash != t.Hash || a.Transactions[n-1].Type != t.Type {
+		return fmt.Errorf("tx %s is not the last transaction applied to %s", t.Hash, a.Address)
+	}
+	t = a.Transactions[n-1]
+	a.Transactions = a.Transactions[:n-1]
+	switch t.Type {
+	case Credit:
+		a.adjust(t, true)
+		if t.Status != "" {
+			a.Status = a.statusFromHistory()
+		}
+	case Debit:
+		a.adjust(t, true)
+		a.Nonce--
+		if n := len(a.Spends); n > 0 && a.Spends[n-1].Hash == t.Hash {
+			a.Spends = a.Spends[:n-1]
 		}
-		fmt.Printf("Tx %d (%s)\n", t.ID, t.Hash[:16]+"...")
-		fmt.Printf("  Time   : %s\n", t.Time.Format(time.RFC3339))
-		fmt.Printf("  From   : %s\n", t.From)
-		fmt.Printf("  To     : %s\n", t.To)
-		fmt.Printf("  Type   : %s\n", t.Type)
-		fmt.Printf("  Amount : %s%.2f\n", sign, t.Amount)
-		fmt.Printf("  Note   : %s\n\n", t.Description)
 	}
+	return nil
+}
 
-	fmt.Printf("Final balance: %.2f\n", a.Balance)
-	fmt.Println("===========================================================\n")
+// statusFromHistory returns the status set by the latest status change in
+// a's history, or "" if there is none.
+func (a *Account) statusFromHistory() AccountStatus {
+	for i := len(a.Transactions) - 1; i >= 0; i-- {
+		if t := a.Transactions[i]; t.Type == Credit && t.Status != "" {
+			return t.Status
+		}
+	}
+	return ""
+}
+
+// PrintStatement writes a's statement to stdout in the plain format.
+func (a *Account) PrintStatement() {
+	a.WriteStatement(os.Stdout, RenderPlain)
 }
 
 // Block represents a simple block in the chain.
 type Block struct {
-	Index        int
-	Timestamp    time.Time
-	Nonce        uint64
-	PrevHash     string
-	Hash         string
-	Transactions []Transaction
+	Version   uint32 `json:",omitempty"` // header version; see CurrentBlockVersion
+	Index     int
+	Timestamp time.Time
+	Nonce     uint64
+	Bits      uint32 // compact encoding of the proof-of-work target
+	Seal      []byte // engine-specific seal data (e.g. a VDF output and proof)
+	Proposer  string // address of the signing validator, for signed engines
+	Coinbase  string // address credited with the block reward
+	// CoinbaseAmount is what the coinbase claims: at most the block reward
+	// plus fees and uncle bonuses (see MaxCoinbase).
+	CoinbaseAmount Amount   `json:",omitempty"`
+	Uncles         []string // hashes of recent stale blocks referenced for partial rewards
+	Bloom          []byte   `json:",omitempty"` // bloom filter of tx hashes and addresses; see MayContain
+	StateRoot      string   `json:",omitempty"` // root of the balances after the block, from BlockVersion5
+	PrevHash       string
+	Hash           string
+	Transactions   []Transaction
+
+	// PrunedTxHashes replaces Transactions once a block's bodies have been
+	// pruned, so the block hash can still be recomputed from the header.
+	PrunedTxHashes []string `json:",omitempty"`
+}
+
+// ErrPruned is returned when something needs the transactions of a block
+// whose bodies have been pruned.
+var ErrPruned = errors.New("transaction bodies pruned")
+
+// IsPruned reports whether the block's transaction bodies were discarded.
+func (b Block) IsPruned() bool {
+	return b.PrunedTxHashes != nil
+}
+
+// TxHashes returns the hashes of the block's transactions, pruned or not.
+func (b Block) TxHashes() []string {
+	if b.IsPruned() {
+		return b.PrunedTxHashes
+	}
+	hashes := make([]string, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		hashes[i] = tx.Hash
+	}
+	return hashes
 }
 
-// computeTxHash returns a hash for a transaction (for display only).
+// computeTxHash returns the hash that identifies a transaction, computed
+// the way its Version says; see txFormats. Chains check it when a block is
+// added, so it must cover every field. An unknown version hashes to "",
+// which matches no transaction.
 func computeTxHash(t Transaction) string {
+	f, ok := txFormats[t.Version]
+	if !ok {
+		return ""
+	}
 	h := sha256.New()
+	f.hash(h, t)
+	return "0x" + hex.EncodeToString(h.Sum(nil))
+}
+
+// hashTxV0 writes t in the TxVersion0 format.
+func hashTxV0(h io.Writer, t Transaction) {
 	h.Write([]byte(fmt.Sprintf("%d", t.ID)))
 	h.Write([]byte(t.From))
 	h.Write([]byte(t.To))
 	h.Write([]byte(t.Time.Format(time.RFC3339Nano)))
 	h.Write([]byte(t.Description))
-	h.Write([]byte(fmt.Sprintf("%f", t.Amount)))
+	h.Write([]byte(t.Amount.hashString()))
+	if t.Fee != 0 {
+		h.Write([]byte(t.Fee.hashString())) // fee-less txs hash as they did before fees
+	}
 	h.Write([]byte(t.Type))
-	return "0x" + hex.EncodeToString(h.Sum(nil))
+	h.Write([]byte(t.ChainID))
+	h.Write([]byte(t.ForkID)) // always empty in a valid transaction; see checkTxV0
+	if t.Nonce != 0 {
+		h.Write([]byte(fmt.Sprintf("n%d", t.Nonce))) // the first tx hashes as it did before nonces
+	}
+	for _, l := range t.Transfers {
+		h.Write([]byte(fmt.Sprintf("l%d:%s%s", len(l.To), l.To, l.Amount.hashString())))
+	}
+	if t.Category != "" {
+		h.Write([]byte(fmt.Sprintf("c%d:%s", len(t.Category), t.Category)))
+	}
+	for _, tag := range t.Tags {
+		h.Write([]byte(fmt.Sprintf("t%d:%s", len(tag), tag)))
+	}
+	if t.Status != "" {
+		h.Write([]byte("s" + string(t.Status)))
+	}
+	if t.Asset != NativeAsset {
+		h.Write([]byte(fmt.Sprintf("a%d:%s", len(t.Asset), t.Asset)))
+	}
+	if t.Expires != nil {
+		h.Write([]byte(fmt.Sprintf("e%d:%s", t.Expires.Height, t.Expires.Time.Format(time.RFC3339Nano))))
+	}
 }
 
 // hashBlock computes the hash of the block based on:
-// index, nonce, previous hash, timestamp, and tx hashes.
+// version, index, nonce, previous hash, timestamp, target bits, seal,
+// proposer, coinbase and its amount, uncle hashes, bloom filter, state
+// root, and tx hashes. Legacy headers leave out the version and coinbase
+// amount, and headers before BlockVersion5 the state root, so they keep the
+// hashes they had before those existed.
 func hashBlock(b Block) string {
 	h := sha256.New()
 
-	// Order: Index -> Nonce -> PrevHash -> Timestamp -> Tx hashes
+	// Order: Version -> Index -> Nonce -> PrevHash -> Timestamp -> Bits -> Seal
+	// -> Proposer -> Coinbase -> CoinbaseAmount -> Uncles -> Bloom -> StateRoot
+	// -> Tx hashes
+	if b.Version != LegacyBlockVersion {
+		h.Write([]byte(fmt.Sprintf("v%d", b.Version)))
+	}
 	h.Write([]byte(fmt.Sprintf("%d", b.Index)))
 	h.Write([]byte(fmt.Sprintf("%d", b.Nonce)))
 	h.Write([]byte(b.PrevHash))
 	h.Write([]byte(b.Timestamp.Format(time.RFC3339Nano)))
+	h.Write([]byte(fmt.Sprintf("%d", b.Bits)))
+	h.Write(b.Seal)
+	h.Write([]byte(b.Proposer))
+	h.Write([]byte(b.Coinbase))
+	if b.Version != LegacyBlockVersion {
+		h.Write([]byte(b.CoinbaseAmount.hashString()))
+	}
+	for _, u := range b.Uncles {
+		h.Write([]byte(u))
+	}
+	h.Write(b.Bloom)
+	if b.Version >= BlockVersion5 {
+		h.Write([]byte(b.StateRoot))
+	}
 
-	for _, tx := range b.Transactions {
-		h.Write([]byte(tx.Hash))
+	for _, txHash := range b.TxHashes() {
+		h.Write([]byte(txHash))
 	}
 
 	return "0x" + hex.EncodeToString(h.Sum(nil))
 }
 
-// MineBlock finds a nonce such that the hash has `difficulty` leading zeros.
-func MineBlock(b *Block, difficulty int) {
-	target := "0x" + strings.Repeat("0", difficulty)
-
-	for {
-		hash := hashBlock(*b)
-		if strings.HasPrefix(hash, target) {
-			b.Hash = hash
-			return
-		}
-		b.Nonce++
-	}
+// MineBlock finds a nonce such that the hash, read as a 256-bit number,
+// is at or below the target encoded in b.Bits. Use a Miner directly to
+// observe progress while it runs.
+func MineBlock(b *Block) {
+	(&Miner{}).Mine(b)
 }
 
-func NewGenesisBlock(difficulty int) Block {
+func NewGenesisBlock(bits uint32) Block {
 	b := Block{
+		Version:      CurrentBlockVersion,
 		Index:        0,
 		Timestamp:    time.Now(),
 		Nonce:        0,
+		Bits:         bits,
 		PrevHash:     "0x0000000000000000000000000000000000000000000000000000000000000000",
 		Transactions: nil,
 	}
-	MineBlock(&b, difficulty)
+	MineBlock(&b)
 	return b
 }
 
-func NewBlock(prev Block, txs []Transaction, difficulty int) Block {
+// NewBlock mines a block on prev without a chain. With no chain to compute
+// balances from, it can't commit to a state root, so it builds a
+// BlockVersion4 header, which only a chain with an upgrade schedule accepts;
+// use Chain.BuildBlock for current ones.
+func NewBlock(prev Block, txs []Transaction, bits uint32) Block {
 	b := Block{
+		Version:      BlockVersion4,
 		Index:        prev.Index + 1,
 		Timestamp:    time.Now(),
 		Nonce:        0,
+		Bits:         bits,
+		Bloom:        blockBloom(txs),
 		PrevHash:     prev.Hash,
 		Transactions: txs,
 	}
-	MineBlock(&b, difficulty)
+	MineBlock(&b)
 	return b
 }
 
-func printChain(chain []Block) {
-	fmt.Println("=== Blockchain ============================================")
-	for _, b := range chain {
-		fmt.Printf("Block #%d\n", b.Index)
-		fmt.Printf("  Timestamp : %s\n", b.Timestamp.Format(time.RFC3339))
-		fmt.Printf("  Nonce     : %d\n", b.Nonce)
-		fmt.Printf("  PrevHash  : %s\n", b.PrevHash[:20]+"...")
-		fmt.Printf("  Hash      : %s\n", b.Hash[:20]+"...")
-		fmt.Printf("  Tx count  : %d\n", len(b.Transactions))
+func printStats(s ChainStats) {
+	fmt.Println("=== Chain Stats ===========================================")
+	fmt.Printf("Blocks         : %d\n", s.Blocks)
+	fmt.Printf("Transactions   : %d\n", s.Transactions)
+	fmt.Printf("Stale blocks   : %d (%.1f%%)\n", s.StaleBlocks, 100*s.StaleRate)
+	fmt.Printf("Avg interval   : %s (target %s)\n", s.AvgBlockInterval, s.TargetInterval)
+	fmt.Printf("Avg attempts   : %.0f\n", s.AvgNonceAttempts)
+	fmt.Printf("Difficulty     : %.0f (bits 0x%08x)\n", s.CurrentDifficulty, s.CurrentBits)
+	fmt.Printf("Total work     : %s\n", s.TotalWork)
+	fmt.Println("===========================================================")
+}
 
-		for _, tx := range b.Transactions {
-			fmt.Printf("    - Tx %d: %s -> %s | %.2f (%s)\n",
-				tx.ID,
-				tx.From[:10]+"...",
-				tx.To[:10]+"...",
-				tx.Amount,
-				tx.Type,
-			)
+func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
 		}
-		fmt.Println()
+		return
+	}
+	if err := runDemo(nil); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
-	fmt.Println("===========================================================")
 }
 
-func main() {
+// runCommand dispatches a subcommand.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "demo":
+		return runDemo(args)
+	case "explorer":
+		return runExplorer(args)
+	case "tx":
+		return runTx(args)
+	case "simulate":
+		return runSimulate(args)
+	case "watch":
+		return runWatch(args)
+	case "state":
+		return runState(args)
+	case "spec":
+		return runSpec(args)
+	case "telemetry":
+		return runTelemetry(args)
+	case "seed":
+		return runSeed(args)
+	case "verify":
+		return runVerify(args)
+	case "genesis":
+		return runGenesis(args)
+	case "utxo":
+		return runUTXO(args)
+	case "address":
+		return runAddress(args)
+	case "alias":
+		return runAlias(args)
+	case "migrate":
+		return runMigrate(args)
+	case "walkthrough":
+		return runWalkthrough(args)
+	default:
+		return fmt.Errorf("unknown command %q (want demo, utxo, explorer, verify, watch, state, spec, genesis, address, alias, migrate, walkthrough, telemetry, seed, tx, or simulate)", name)
+	}
+}
+
+// runDemo mines a small chain, applies its transactions to an account, and
+// prints both. With -out it also stores the chain for the explorer.
+func runDemo(args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ContinueOnError)
+	out := fs.String("out", "", "write the mined chain to this file")
+	telemetry := fs.String("telemetry", "", "send anonymous stats to this collector URL when done")
+	statement := fs.String("statement", "", "also export Devon's statement to this file")
+	format := fs.String("format", "csv", "statement export format: csv or json")
+	journal := fs.Bool("journal", false, "also book Devon's account as double entries and print the trial balance")
+	renderFlag := fs.String("render", "plain", "how to print the chain and statement: plain, table, or jsonl")
+	interestRate := fs.Int64("interest", 0, "pay interest on balances at this many parts per million per period")
+	compound := fs.String("compound", "block", "interest compounding period: block or day")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if f := StatementFormat(*format); f != StatementCSV && f != StatementJSON {
+		return fmt.Errorf("unknown statement format %q (want csv or json)", f)
+	}
+	render, err := ParseRenderFormat(*renderFlag)
+	if err != nil {
+		return err
+	}
+	interest := InterestPolicy{Rate: *interestRate, Period: InterestPeriod(*compound)}
+	if err := interest.Validate(); err != nil {
+		return err
+	}
+
+	// Devon's address is derived from a fresh key, which signs the txs
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	addr, err := AddressFromPubKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
 	account := &Account{
-		Address: "0xAbC1234567890defABC1234567890defABC12345",
+		Address: addr.String(),
 		Owner:   "Devon",
 	}
 
 	now := time.Now()
 
-	// Example "addresses"
-	alice := "0xA1cE000000000000000000000000000000000001"
-	coffeeShop := "0xC0Ffee000000000000000000000000000000003"
-	bookStore := "0xB00k000000000000000000000000000000000004"
+	// The shops' addresses come from keys of their own too, though only
+	// Devon signs anything here
+	var payees [2]string
+	for i := range payees {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return err
+		}
+		a, err := AddressFromPubKey(&k.PublicKey)
+		if err != nil {
+			return err
+		}
+		payees[i] = a.String()
+	}
+	coffeeShop, bookStore := payees[0], payees[1]
 
 	// Create raw txs
 	rawTx1 := Transaction{
 		ID:          1,
-		From:        alice,
-		To:          account.Address,
-		Time:        now,
-		Description: "Initial deposit",
-		Amount:      1000.0,
-		Type:        Credit,
-	}
-	rawTx2 := Transaction{
-		ID:          2,
 		From:        account.Address,
 		To:          coffeeShop,
 		Time:        now.Add(1 * time.Hour),
 		Description: "Coffee",
-		Amount:      4.50,
+		Category:    "food",
+		Amount:      MustParseAmount("4.50"),
 		Type:        Debit,
 	}
-	rawTx3 := Transaction{
-		ID:          3,
+	rawTx2 := Transaction{
+		ID:          2,
 		From:        account.Address,
 		To:          bookStore,
 		Time:        now.Add(2 * time.Hour),
 		Description: "Book",
-		Amount:      25.00,
+		Category:    "books",
+		Amount:      MustParseAmount("25.00"),
 		Type:        Debit,
+		Nonce:       1,
 	}
 
-	// Compute tx hashes
-	tx1 := rawTx1
-	tx1.Hash = computeTxHash(rawTx1)
-
-	tx2 := rawTx2
-	tx2.Hash = computeTxHash(rawTx2)
+	// Compute tx hashes and sign them
+	tx1, tx2 := rawTx1, rawTx2
+	for _, tx := range []*Transaction{&tx1, &tx2} {
+		if err := SignTx(tx, key); err != nil {
+			return err
+		}
+	}
 
-	tx3 := rawTx3
-	tx3.Hash = computeTxHash(rawTx3)
+	bits := BitsForLeadingZeros(3) // compact target, roughly 3 leading hex zeros
+
+	genesis := NewGenesisBlock(bits)
+	chain, err := NewChain(ChainConfig{
+		Engine:      &PoWEngine{},
+		BlockReward: 50 * Coin,
+		MaxUncles:   2,
+		Alloc:       map[string]Amount{account.Address: 1000 * Coin},
+	}, genesis)
+	if err != nil {
+		return err
+	}
 
-	difficulty := 3 // number of leading zeros required in hash
+	mineStart := time.Now()
+	var nonces uint64
+	for _, tx := range []Transaction{tx1, tx2} {
+		b, err := chain.BuildBlock("", []Transaction{tx})
+		if err != nil {
+			return err
+		}
+		nonces += b.Nonce + 1 // nonces start at 0
+		if err := chain.AddBlock(b); err != nil {
+			fmt.Println("error adding block:", err)
+		}
+	}
+	mined := newMiningProgress(nonces, time.Since(mineStart))
+
+	// Replay the chain into a ledger of every account it touches, starting
+	// from the genesis allocations
+	ledger := NewLedger()
+	ledger.Open(account.Address, account.Owner)
+	accrual, err := NewInterestAccrual(ledger, interest)
+	if err != nil {
+		return err
+	}
+	for _, b := range chain.BestChain() {
+		if err := ledger.ApplyBlock(chain, b); err != nil {
+			fmt.Println("error applying block:", err)
+		}
+		if interest.Rate > 0 {
+			if _, err := accrual.Accrue(b.Index, b.Timestamp); err != nil {
+				return err
+			}
+		}
+	}
+	account = ledger.Account(account.Address)
 
-	genesis := NewGenesisBlock(difficulty)
-	block1 := NewBlock(genesis, []Transaction{tx1, tx2}, difficulty)
-	block2 := NewBlock(block1, []Transaction{tx3}, difficulty)
+	if err := WriteChain(os.Stdout, chain.BestChain(), render); err != nil {
+		return err
+	}
+	printStats(chain.ChainStats())
+	if err := account.WriteStatement(os.Stdout, render); err != nil {
+		return err
+	}
+	account.PrintReport()
+	if *journal {
+		j, err := JournalAccount(account)
+		if err != nil {
+			return err
+		}
+		j.PrintTrialBalance()
+	}
+	ledger.PrintBalances()
 
-	chain := []Block{genesis, block1, block2}
+	if *out != "" {
+		if err := SaveChain(*out, chain); err != nil {
+			return err
+		}
+		fmt.Printf("Chain written to %s\n", *out)
+	}
 
-	// Apply txs from blocks to account
-	for _, b := range chain {
-		for _, tx := range b.Transactions {
-			if err := account.ApplyTransaction(tx); err != nil {
-				fmt.Println("error applying tx:", err)
-			}
+	if *statement != "" {
+		f, err := os.Create(*statement)
+		if err != nil {
+			return err
+		}
+		if err := account.ExportStatement(f, StatementFormat(*format)); err != nil {
+			f.Close()
+			return err
 		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("Statement written to %s\n", *statement)
 	}
 
-	printChain(chain)
-	account.PrintStatement()
+	if *telemetry != "" {
+		client, err := NewTelemetryClient(*telemetry)
+		if err != nil {
+			return err
+		}
+		client.Height = func() int { return chain.Tip().Index }
+		client.ObserveMining(mined)
+		if err := client.Report(); err != nil {
+			return fmt.Errorf("telemetry: %w", err)
+		}
+		fmt.Printf("Telemetry sent to %s\n", *telemetry)
+	}
+	return nil
 }
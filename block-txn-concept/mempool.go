@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrUnderpriced is returned for a transaction that pays less than the
+// chain's MinFee, or by Mempool.Add for one whose fee rate is below the
+// pool's minimum.
+var ErrUnderpriced = errors.New("transaction fee too low")
+
+// TxSize is the size of tx in bytes, as its JSON encoding. Fee rates are
+// measured against it, so a transaction pays for the space it takes up.
+func TxSize(tx Transaction) int {
+	data, _ := json.Marshal(tx)
+	return len(data)
+}
+
+// FeeRate is tx's fee per 1000 bytes of TxSize.
+func FeeRate(tx Transaction) float64 {
+	return tx.Fee.Coins() * 1000 / float64(TxSize(tx))
+}
+
+// Mempool holds transactions waiting for a block and hands them to the
+// block builder in fee-rate order. It follows its chain: transactions that
+// make it into the best chain leave the pool, and those disconnected by a
+// reorg come back if they are still valid on the new tip.
+type Mempool struct {
+	chain *Chain
+	txs   map[string]Transaction
+
+	// MinFeeRate, if set, is the lowest FeeRate Add accepts.
+	MinFeeRate float64
+
+	unsubscribe []func()
+}
+
+// NewMempool creates an empty pool for c.
+func NewMempool(c *Chain) *Mempool {
+	m := &Mempool{chain: c, txs: make(map[string]Transaction)}
+	m.unsubscribe = []func(){
+		c.Subscribe(NewBlockEvent, func(e Event) { m.drop(e.Block) }),
+		c.Subscribe(ReorgEvent, func(e Event) { m.readd(e.Disconnected) }),
+	}
+	return m
+}
+
+// Close stops the pool from following its chain.
+func (m *Mempool) Close() {
+	for _, unsubscribe := range m.unsubscribe {
+		unsubscribe()
+	}
+	m.unsubscribe = nil
+}
+
+// Add validates tx with the chain's TxRules, as if for the next block on
+// the tip, and adds it to the pool. A transaction may carry a nonce beyond
+// its sender's next one; it waits in the pool until the gap is filled.
+func (m *Mempool) Add(tx Transaction) error {
+	if _, ok := m.txs[tx.Hash]; ok {
+		return fmt.Errorf("tx %s: %w: already in the pool", tx.Hash, ErrDuplicateTx)
+	}
+	if err := m.validate(tx); err != nil {
+		return err
+	}
+	m.txs[tx.Hash] = tx
+	return nil
+}
+
+// validate checks tx against the tip as Add does, apart from looking for
+// it in the pool.
+func (m *Mempool) validate(tx Transaction) error {
+	if _, ok := m.chain.txIndex[tx.Hash]; ok {
+		return fmt.Errorf("tx %s: %w", tx.Hash, ErrDoubleSpend)
+	}
+	ctx := m.chain.newTxContext(m.chain.tip, CurrentBlockVersion, m.chain.tip.Timestamp, true)
+	if err := m.chain.validateTx(ctx, tx); err != nil {
+		return err
+	}
+	if rate := FeeRate(tx); rate < m.MinFeeRate {
+		return fmt.Errorf("tx %s: %w: %.4f, at least %.4f", tx.Hash, ErrUnderpriced, rate, m.MinFeeRate)
+	}
+	return nil
+}
+
+// readd returns the transactions of blocks a reorg disconnected to the
+// pool. The branch that replaced them may have spent the same funds, used
+// the same nonces, or included the transactions itself, so each is checked
+// again against the new tip and dropped if it no longer passes.
+func (m *Mempool) readd(disconnected []Block) {
+	for _, b := range disconnected {
+		for _, tx := range b.Transactions {
+			if m.validate(tx) == nil {
+				m.txs[tx.Hash] = tx
+			}
+		}
+	}
+}
+
+// PendingNonce returns the nonce addr's next transaction should carry,
+// counting those already waiting in the pool.
+func (m *Mempool) PendingNonce(addr string) uint64 {
+	pooled := make(map[uint64]bool)
+	for _, tx := range m.txs {
+		if tx.From == addr {
+			pooled[tx.Nonce] = true
+		}
+	}
+	next := m.chain.NextNonce(addr)
+	for pooled[next] {
+		next++
+	}
+	return next
+}
+
+// Len returns the number of pooled transactions.
+func (m *Mempool) Len() int {
+	return len(m.txs)
+}
+
+// drop removes b's transactions, any that its nonces made stale, and any
+// that have expired as of the new tip.
+func (m *Mempool) drop(b Block) {
+	for _, hash := range b.TxHashes() {
+		delete(m.txs, hash)
+	}
+	tip := m.chain.tip
+	for hash, tx := range m.txs {
+		if tx.Nonce < m.chain.NextNonce(tx.From) || tx.Expires.expired(tip.Index+1, tip.Timestamp) {
+			delete(m.txs, hash)
+		}
+	}
+}
+
+// Select returns up to limit transactions for the next block, highest fee
+// rate first. A sender's transactions always come out in nonce order, so a
+// cheap transaction can hold back its sender's dearer ones, and none come
+// out past a nonce gap. Ties go to the lower hash. limit <= 0 means no
+// limit.
+func (m *Mempool) Select(limit int) []Transaction {
+	// Queue each sender's transactions by nonce, starting from its next one.
+	queues := make(map[string][]Transaction)
+	for _, tx := range m.txs {
+		queues[tx.From] = append(queues[tx.From], tx)
+	}
+	for from, q := range queues {
+		sort.Slice(q, func(i, j int) bool {
+			if q[i].Nonce != q[j].Nonce {
+				return q[i].Nonce < q[j].Nonce
+			}
+			return FeeRate(q[i]) > FeeRate(q[j])
+		})
+		next := m.chain.NextNonce(from)
+		var ready []Transaction
+		for _, tx := range q {
+			if tx.Nonce == next {
+				ready = append(ready, tx)
+				next++
+			}
+		}
+		queues[from] = ready
+	}
+
+	var selected []Transaction
+	for limit <= 0 || len(selected) < limit {
+		best := ""
+		for from, q := range queues {
+			if len(q) == 0 {
+				continue
+			}
+			if best == "" || better(q[0], queues[best][0]) {
+				best = from
+			}
+		}
+		if best == "" {
+			break
+		}
+		selected = append(selected, queues[best][0])
+		queues[best] = queues[best][1:]
+	}
+	return selected
+}
+
+// better reports whether a should be included before b.
+func better(a, b Transaction) bool {
+	ra, rb := FeeRate(a), FeeRate(b)
+	if ra != rb {
+		return ra > rb
+	}
+	return a.Hash < b.Hash
+}
+
+// BuildBlock builds a block on the pool's chain from up to limit of the
+// best-paying transactions; see Select and Chain.BuildBlock.
+func (m *Mempool) BuildBlock(coinbase string, limit int) (Block, error) {
+	return m.chain.BuildBlock(coinbase, m.Select(limit))
+}
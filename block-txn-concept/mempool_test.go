@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReorgReaddsOnlyStillValidTransactions(t *testing.T) {
+	alice, carol, bob, dave := newTestAccount(t), newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin, carol.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	m := NewMempool(c)
+	defer m.Close()
+
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	rival := newTestChain(t, rivalCfg)
+
+	// On the branch that loses, alice pays bob twice and carol pays once.
+	small := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	both := signedTx(t, c, carol, Transaction{To: bob.Addr, Amount: Coin})
+	if err := mineTxs(t, c, small, both); err != nil {
+		t.Fatal(err)
+	}
+	overspent := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 6 * Coin})
+	stillValid := signedTx(t, c, carol, Transaction{To: bob.Addr, Amount: Coin})
+	if err := mineTxs(t, c, overspent, stillValid); err != nil {
+		t.Fatal(err)
+	}
+
+	// The winning branch spends most of alice's coins elsewhere and
+	// includes carol's first payment itself.
+	if err := mineTxs(t, rival, signedTx(t, rival, alice, Transaction{To: dave.Addr, Amount: 8 * Coin}), both); err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, rival, 2)
+	for _, b := range rival.BestChain()[1:] {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Tip().Hash != rival.Tip().Hash {
+		t.Fatal("the rival branch didn't become the tip")
+	}
+
+	if m.Len() != 1 {
+		t.Errorf("pool holds %d transactions, want 1", m.Len())
+	}
+	if _, ok := m.txs[stillValid.Hash]; !ok {
+		t.Error("carol's second payment, valid on both branches, wasn't re-added")
+	}
+	if _, ok := m.txs[overspent.Hash]; ok {
+		t.Error("alice's payment was re-added though she can no longer afford it")
+	}
+	if _, ok := m.txs[both.Hash]; ok {
+		t.Error("a transaction the new branch included was re-added")
+	}
+}
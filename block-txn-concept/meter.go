@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrBandwidthCap is returned once a metered source has used up its budget.
+var ErrBandwidthCap = errors.New("peer bandwidth cap exceeded")
+
+// TrafficStats counts messages and bytes exchanged with one peer.
+type TrafficStats struct {
+	MessagesSent     int
+	MessagesReceived int
+	BytesSent        int
+	BytesReceived    int
+}
+
+// MeteredSource wraps a BlockSource and accounts for the traffic it causes.
+// There is no wire protocol yet, so message sizes are measured as their JSON
+// encoding, and the only messages are the block requests a Syncer makes and
+// the blocks sent back; Syncer.Stats reports the counters per peer. If
+// MaxBytes is set, requests that would push the total past it fail with
+// ErrBandwidthCap, which makes a Syncer drop the peer.
+type MeteredSource struct {
+	Source   BlockSource
+	MaxBytes int // combined sent+received cap; zero means unlimited
+
+	mu    sync.Mutex
+	stats TrafficStats
+}
+
+// ID returns the wrapped source's ID.
+func (m *MeteredSource) ID() string {
+	return m.Source.ID()
+}
+
+// BlockByHeight fetches a block through the wrapped source, counting the
+// request and the response.
+func (m *MeteredSource) BlockByHeight(ctx context.Context, height int) (Block, error) {
+	req, err := json.Marshal(struct {
+		Height int `json:"height"`
+	}{height})
+	if err != nil {
+		return Block{}, err
+	}
+	if err := m.record(len(req), 0); err != nil {
+		return Block{}, err
+	}
+
+	b, err := m.Source.BlockByHeight(ctx, height)
+	if err != nil {
+		return Block{}, err
+	}
+
+	resp, err := json.Marshal(b)
+	if err != nil {
+		return Block{}, err
+	}
+	if err := m.record(0, len(resp)); err != nil {
+		return Block{}, err
+	}
+	return b, nil
+}
+
+// Traffic returns a snapshot of the counters.
+func (m *MeteredSource) Traffic() TrafficStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+func (m *MeteredSource) record(sent, received int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := m.stats.BytesSent + m.stats.BytesReceived + sent + received
+	if m.MaxBytes > 0 && total > m.MaxBytes {
+		return ErrBandwidthCap
+	}
+	if sent > 0 {
+		m.stats.MessagesSent++
+		m.stats.BytesSent += sent
+	}
+	if received > 0 {
+		m.stats.MessagesReceived++
+		m.stats.BytesReceived += received
+	}
+	return nil
+}
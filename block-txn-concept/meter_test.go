@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMeteredSourceCountsTraffic(t *testing.T) {
+	blocks, _ := syncFixture(t, 3)
+	m := &MeteredSource{Source: &testSource{id: "a", blocks: blocks}}
+	var want TrafficStats
+	for h := 1; h <= 3; h++ {
+		if _, err := m.BlockByHeight(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		req, _ := json.Marshal(struct {
+			Height int `json:"height"`
+		}{h})
+		resp, _ := json.Marshal(blocks[h])
+		want.MessagesSent++
+		want.MessagesReceived++
+		want.BytesSent += len(req)
+		want.BytesReceived += len(resp)
+	}
+	if got := m.Traffic(); got != want {
+		t.Errorf("traffic = %+v, want %+v", got, want)
+	}
+	if m.ID() != "a" {
+		t.Errorf("ID = %q, want the wrapped source's", m.ID())
+	}
+}
+
+func TestMeteredSourceEnforcesCap(t *testing.T) {
+	blocks, _ := syncFixture(t, 3)
+	one, _ := json.Marshal(blocks[1])
+	m := &MeteredSource{Source: &testSource{id: "a", blocks: blocks}}
+	if _, err := m.BlockByHeight(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	// Room for exactly one more request, but not its response.
+	m.MaxBytes = m.Traffic().BytesSent + m.Traffic().BytesReceived + len(`{"height":2}`)
+	if _, err := m.BlockByHeight(context.Background(), 2); !errors.Is(err, ErrBandwidthCap) {
+		t.Fatalf("err = %v, want ErrBandwidthCap", err)
+	}
+	got := m.Traffic()
+	if got.MessagesSent != 2 || got.MessagesReceived != 1 || got.BytesReceived != len(one) {
+		t.Errorf("traffic after the refused response = %+v", got)
+	}
+	// Nothing more gets through once the budget is spent.
+	if _, err := m.BlockByHeight(context.Background(), 3); !errors.Is(err, ErrBandwidthCap) {
+		t.Fatalf("err = %v, want ErrBandwidthCap", err)
+	}
+	if m.Traffic() != got {
+		t.Errorf("refused request was counted: %+v", m.Traffic())
+	}
+}
+
+func TestSyncerReportsTrafficPerPeer(t *testing.T) {
+	blocks, c := syncFixture(t, 6)
+	one, _ := json.Marshal(blocks[1])
+	capped := &MeteredSource{Source: &testSource{id: "capped", blocks: blocks}, MaxBytes: len(one)}
+	open := &MeteredSource{Source: &testSource{id: "open", blocks: blocks}}
+	s := &Syncer{Chain: c, Sources: []BlockSource{capped, open}}
+	if err := s.Sync(context.Background(), 6); err != nil {
+		t.Fatal(err)
+	}
+	checkSynced(t, c, blocks)
+
+	stats := s.Stats()
+	if !errors.Is(stats["capped"].Err, ErrBandwidthCap) {
+		t.Errorf("capped peer's error = %v, want ErrBandwidthCap", stats["capped"].Err)
+	}
+	for _, m := range []*MeteredSource{capped, open} {
+		st := stats[m.ID()]
+		if st.Traffic != m.Traffic() {
+			t.Errorf("%s: Stats traffic %+v, source says %+v", m.ID(), st.Traffic, m.Traffic())
+		}
+		if st.Traffic.MessagesReceived != st.Blocks {
+			t.Errorf("%s: %d responses counted for %d blocks", m.ID(), st.Traffic.MessagesReceived, st.Blocks)
+		}
+	}
+	if got := stats["capped"].Traffic.BytesSent + stats["capped"].Traffic.BytesReceived; got > capped.MaxBytes {
+		t.Errorf("capped peer used %d bytes, over its cap of %d", got, capped.MaxBytes)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// MigrationIssue is a record Migrate couldn't convert, and why.
+type MigrationIssue struct {
+	Where  string // e.g. "block 3 tx 0xab12..."
+	Reason string
+}
+
+// MigrationReport summarises a migration.
+type MigrationReport struct {
+	Blocks       int
+	Transactions int
+	Issues       []MigrationIssue
+}
+
+func (r *MigrationReport) issue(where, format string, args ...any) {
+	r.Issues = append(r.Issues, MigrationIssue{Where: where, Reason: fmt.Sprintf(format, args...)})
+}
+
+// legacyBits is the target Migrate gives a genesis block stored before
+// headers carried one: the demo's, roughly 3 leading hex zeros.
+var legacyBits = BitsForLeadingZeros(3)
+
+// checkAmount rejects amounts no transaction could carry. Rounding to
+// minor units already happened when the old file was read; see
+// Amount.UnmarshalJSON.
+func checkAmount(a Amount) error {
+	if a < 0 {
+		return fmt.Errorf("amount %v is negative", a)
+	}
+	return nil
+}
+
+// migrateTx converts one legacy transaction: typed addresses, bound to
+// chainID if it wasn't bound to a chain, the given nonce,
+// and a recomputed hash. Its fork ID is dropped, since the migrated chain
+// is a different fork.
+func migrateTx(tx Transaction, book *AliasBook, chainID string, nonce uint64) (Transaction, error) {
+	if tx.Type != Debit && tx.Type != Credit {
+		return Transaction{}, fmt.Errorf("unknown type %q", tx.Type)
+	}
+	if tx.ChainID != "" && tx.ChainID != chainID {
+		return Transaction{}, fmt.Errorf("bound to chain %q, not %q", tx.ChainID, chainID)
+	}
+	if err := checkAmount(tx.Amount); err != nil {
+		return Transaction{}, err
+	}
+	if err := checkAmount(tx.Fee); err != nil {
+		return Transaction{}, fmt.Errorf("fee: %w", err)
+	}
+	tx, err := book.RewriteTx(tx)
+	if err != nil {
+		return Transaction{}, err
+	}
+	tx.ChainID, tx.ForkID = chainID, ""
+	tx.Nonce = nonce
+	tx.Hash = computeTxHash(tx)
+	return tx, nil
+}
+
+// Migrate rebuilds a chain stored by older versions of this code as a
+// chain of BlockVersion3 blocks under cfg, the newest version that doesn't
+// require signatures; a cfg without an upgrade schedule gets
+// LegacyUpgrades, which is saved with the chain. Addresses are replaced by their
+// typed form (legacy identifiers via book), each sender's transactions get nonces in order, and every
+// transaction and block hash is recomputed; blocks are resealed with
+// cfg.Engine at their original timestamps. A genesis block without a
+// proof-of-work target, as the first versions stored it, gets legacyBits.
+//
+// Records that can't be converted are dropped and listed in the report
+// rather than failing the whole migration: transactions with bad amounts,
+// types, or addresses, and the transactions of pruned blocks, whose bodies
+// are gone. Uncle references are dropped too, since a stored chain doesn't
+// hold the uncles. An error means the rebuilt chain itself was invalid.
+func Migrate(blocks []Block, alloc map[string]Amount, cfg ChainConfig, book *AliasBook) (*Chain, MigrationReport, error) {
+	var r MigrationReport
+	if len(blocks) == 0 {
+		return nil, r, errors.New("no blocks to migrate")
+	}
+
+	cfg.Alloc = make(map[string]Amount, len(alloc))
+	for id, amount := range alloc {
+		where := "alloc " + id
+		a, err := book.Resolve(id)
+		if err != nil {
+			r.issue(where, "%v", err)
+			continue
+		}
+		if err := checkAmount(amount); err != nil {
+			r.issue(where, "%v", err)
+			continue
+		}
+		cfg.Alloc[a.String()] += amount
+	}
+
+	if len(cfg.Upgrades) == 0 {
+		cfg.Upgrades = LegacyUpgrades
+	}
+	var timestamp time.Time
+	cfg.Clock = func() time.Time { return timestamp }
+
+	old := blocks[0]
+	genesis := Block{
+		Version:   CurrentBlockVersion,
+		Timestamp: old.Timestamp,
+		Bits:      old.Bits,
+		PrevHash:  old.PrevHash,
+	}
+	if genesis.Bits == 0 {
+		genesis.Bits = legacyBits
+	}
+	if err := cfg.Engine.Seal(&genesis); err != nil {
+		return nil, r, fmt.Errorf("genesis: %w", err)
+	}
+	chain, err := NewChain(cfg, genesis)
+	if err != nil {
+		return nil, r, err
+	}
+	r.Blocks = 1
+
+	nonces := make(map[string]uint64)
+	for _, old := range blocks[1:] {
+		where := fmt.Sprintf("block %d", old.Index)
+		if old.IsPruned() {
+			r.issue(where, "bodies were pruned; %d transactions lost", len(old.PrunedTxHashes))
+		}
+		if len(old.Uncles) > 0 {
+			r.issue(where, "%d uncle references dropped", len(old.Uncles))
+		}
+		var txs []Transaction
+		for _, tx := range old.Transactions {
+			where := fmt.Sprintf("block %d tx %s", old.Index, tx.Hash)
+			from, err := book.Resolve(tx.From)
+			if err != nil {
+				r.issue(where, "sender: %v", err)
+				continue
+			}
+			migrated, err := migrateTx(tx, book, cfg.ChainID, nonces[from.String()])
+			if err != nil {
+				r.issue(where, "%v", err)
+				continue
+			}
+			nonces[from.String()]++
+			txs = append(txs, migrated)
+		}
+
+		coinbase := ""
+		if old.Coinbase != "" {
+			a, err := book.Resolve(old.Coinbase)
+			if err != nil {
+				r.issue(where, "coinbase: %v", err)
+			} else {
+				coinbase = a.String()
+			}
+		}
+		timestamp = old.Timestamp
+		// Legacy identifiers have no keys, so their transactions can't be
+		// signed; build at the last version that doesn't require it.
+		b, err := chain.buildBlock(coinbase, txs, BlockVersion3)
+		if err != nil {
+			return nil, r, fmt.Errorf("%s: %w", where, err)
+		}
+		if err := chain.AddBlock(b); err != nil {
+			return nil, r, fmt.Errorf("%s: %w", where, err)
+		}
+		r.Blocks++
+		r.Transactions += len(txs)
+	}
+	return chain, r, nil
+}
+
+// MigrateAccount converts an account dumped from the old Account struct:
+// its address and transactions get the same treatment as in Migrate, its
+// and its nonce is the number of debits it made.
+func MigrateAccount(a Account, chainID string, book *AliasBook) (Account, MigrationReport, error) {
+	var r MigrationReport
+	addr, err := book.Resolve(a.Address)
+	if err != nil {
+		return Account{}, r, fmt.Errorf("account address: %w", err)
+	}
+	if err := checkAmount(a.Balance); err != nil {
+		return Account{}, r, fmt.Errorf("account balance: %w", err)
+	}
+	out := Account{Address: addr.String(), Owner: a.Owner, Balance: a.Balance}
+	for _, tx := range a.Transactions {
+		where := "tx " + tx.Hash
+		nonce := tx.Nonce // a credit carries its sender's nonce, which we can't know
+		if tx.Type == Debit {
+			nonce = out.Nonce
+		}
+		migrated, err := migrateTx(tx, book, chainID, nonce)
+		if err != nil {
+			r.issue(where, "%v", err)
+			continue
+		}
+		if migrated.Type == Debit {
+			out.Nonce++
+		}
+		out.Transactions = append(out.Transactions, migrated)
+		r.Transactions++
+	}
+	return out, r, nil
+}
+
+// Print writes the report to stdout.
+func (r MigrationReport) Print() {
+	if r.Blocks > 0 {
+		fmt.Printf("Blocks       : %d\n", r.Blocks)
+	}
+	fmt.Printf("Transactions : %d\n", r.Transactions)
+	if len(r.Issues) == 0 {
+		fmt.Println("Unconverted  : none")
+		return
+	}
+	fmt.Printf("Unconverted  : %d\n", len(r.Issues))
+	for _, is := range r.Issues {
+		fmt.Printf("  %s: %s\n", is.Where, is.Reason)
+	}
+}
+
+// runMigrate implements the "migrate" command:
+//
+//	migrate -in old.json -out new.json [-book aliases.json] [-strict] [config flags]
+//
+// The input is a chain file, a bare JSON array of blocks, or a dumped
+// Account; the output is the same kind of thing in the current format. The
+// config flags describe the chain to rebuild into and are ignored for
+// accounts, apart from -chain-id.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	in := fs.String("in", "", "chain or account written by an older version")
+	out := fs.String("out", "", "file to write the migrated chain or account to")
+	bookPath := fs.String("book", "aliases.json", "alias book to record legacy identifiers in")
+	strict := fs.Bool("strict", false, "fail, writing nothing, if any record can't be converted")
+	flags := addConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *out == "" {
+		return errors.New("usage: migrate -in old.json -out new.json")
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+	book, err := LoadAliasBook(*bookPath)
+	if err != nil {
+		return err
+	}
+
+	var (
+		report MigrationReport
+		write  func() error
+	)
+	trimmed := bytes.TrimSpace(data)
+	var probe struct {
+		Blocks json.RawMessage `json:"blocks"`
+	}
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		var blocks []Block
+		if err := json.Unmarshal(data, &blocks); err != nil {
+			return fmt.Errorf("%s: %w", *in, err)
+		}
+		chain, r, err := Migrate(blocks, nil, cfg, book)
+		if err != nil {
+			return err
+		}
+		report, write = r, func() error { return SaveChain(*out, chain) }
+
+	case json.Unmarshal(data, &probe) == nil && probe.Blocks != nil:
+		f, err := readChainFile(*in)
+		if err != nil {
+			return err
+		}
+		chain, r, err := Migrate(f.Blocks, f.Alloc, cfg, book)
+		if err != nil {
+			return err
+		}
+		if f.Attestation != nil {
+			r.issue("attestation", "dropped; the migrated genesis needs a new one (genesis sign)")
+		}
+		report, write = r, func() error { return SaveChain(*out, chain) }
+
+	default:
+		var a Account
+		if err := json.Unmarshal(data, &a); err != nil {
+			return fmt.Errorf("%s: %w", *in, err)
+		}
+		if a.Address == "" {
+			return fmt.Errorf("%s: neither a chain nor an account", *in)
+		}
+		migrated, r, err := MigrateAccount(a, cfg.ChainID, book)
+		if err != nil {
+			return err
+		}
+		report, write = r, func() error {
+			data, err := json.MarshalIndent(migrated, "", "  ")
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(*out, data, 0o644)
+		}
+	}
+
+	report.Print()
+	if *strict && len(report.Issues) > 0 {
+		return fmt.Errorf("%d records could not be converted", len(report.Issues))
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	fmt.Printf("Migrated %s to %s\n", *in, *out)
+	return book.Save(*bookPath)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// legacyBlocks is a chain as older versions stored it: legacy identifiers,
+// no targets, nonces or signatures, and one block whose bodies were pruned.
+func legacyBlocks() []Block {
+	at := testStart
+	tx := func(from, to string, amount Amount, typ TransactionType) Transaction {
+		at = at.Add(time.Second)
+		return Transaction{From: from, To: to, Amount: amount, Type: typ, Time: at, Hash: "0xold" + from + to}
+	}
+	return []Block{
+		{Index: 0, Timestamp: testStart, Hash: "0xg"},
+		{Index: 1, Timestamp: testStart.Add(time.Minute), PrevHash: "0xg", Hash: "0x1", Coinbase: "miner", Transactions: []Transaction{
+			tx("alice", "bob", 5*Coin, Debit),
+			tx("alice", "carol", -Coin, Debit),
+			tx("bob", "carol", Coin, "swap"),
+			tx("alice", "carol", 2*Coin, Debit),
+		}},
+		{Index: 2, Timestamp: testStart.Add(2 * time.Minute), PrevHash: "0x1", Hash: "0x2", PrunedTxHashes: []string{"0xa", "0xb"}, Uncles: []string{"0xu"}},
+	}
+}
+
+func TestMigrateRebuildsLegacyChain(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.Upgrades = nil
+	book := NewAliasBook()
+	c, r, err := Migrate(legacyBlocks(), map[string]Amount{"alice": 10 * Coin, "mallory": -Coin}, cfg, book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Tip().Index != 2 {
+		t.Fatalf("migrated chain's tip is at %d, want 2", c.Tip().Index)
+	}
+	if bits := c.BestChain()[0].Bits; bits != legacyBits {
+		t.Errorf("genesis without a target got bits %08x, want legacyBits", bits)
+	}
+	if r.Blocks != 3 || r.Transactions != 2 {
+		t.Errorf("migrated %d blocks and %d transactions, want 3 and 2", r.Blocks, r.Transactions)
+	}
+	var reasons []string
+	for _, is := range r.Issues {
+		reasons = append(reasons, is.Where+": "+is.Reason)
+	}
+	for _, want := range []string{"alloc mallory", "is negative", `unknown type "swap"`, "2 transactions lost", "1 uncle references dropped"} {
+		if !strings.Contains(strings.Join(reasons, "\n"), want) {
+			t.Errorf("no issue mentions %q:\n%s", want, strings.Join(reasons, "\n"))
+		}
+	}
+
+	alice, bob, carol := LegacyAlias("alice").String(), LegacyAlias("bob").String(), LegacyAlias("carol").String()
+	if got, _ := book.Legacy(LegacyAlias("bob")); got != "bob" {
+		t.Errorf("book maps bob's alias back to %q", got)
+	}
+	txs := c.BestChain()[1].Transactions
+	for i, tx := range txs {
+		if tx.From != alice || tx.Nonce != uint64(i) || tx.ChainID != cfg.ChainID || tx.Hash != computeTxHash(tx) {
+			t.Errorf("tx %d: from %s nonce %d chain %q hash %s", i, tx.From, tx.Nonce, tx.ChainID, tx.Hash)
+		}
+	}
+	for addr, want := range map[string]Amount{alice: 3 * Coin, bob: 5 * Coin, carol: 2 * Coin} {
+		if got := tipBalance(t, c, addr); got != want {
+			t.Errorf("%s has %s, want %s", addr, got, want)
+		}
+	}
+	if len(c.Config().Upgrades) != 1 || c.Config().Upgrades[0] != LegacyUpgrades[0] {
+		t.Errorf("upgrades %v, want LegacyUpgrades", c.Config().Upgrades)
+	}
+	if got := c.BestChain()[2].Timestamp; !got.Equal(testStart.Add(2 * time.Minute)) {
+		t.Errorf("block 2 resealed at %v, want its original time", got)
+	}
+}
+
+func TestMigrateTxRefusesAnotherChain(t *testing.T) {
+	tx := Transaction{From: "alice", To: "bob", Amount: Coin, Type: Debit, ChainID: "other"}
+	if _, err := migrateTx(tx, NewAliasBook(), "test", 0); err == nil {
+		t.Error("a transaction bound to another chain was migrated")
+	}
+	tx.ChainID, tx.ForkID = "", "0xfork"
+	got, err := migrateTx(tx, NewAliasBook(), "test", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ChainID != "test" || got.ForkID != "" || got.Nonce != 3 {
+		t.Errorf("migrated to chain %q fork %q nonce %d", got.ChainID, got.ForkID, got.Nonce)
+	}
+}
+
+func TestMigrateAccount(t *testing.T) {
+	a := Account{Address: "alice", Owner: "Alice", Balance: 4 * Coin, Transactions: []Transaction{
+		{From: "alice", To: "bob", Amount: Coin, Type: Debit},
+		{From: "carol", To: "alice", Amount: 2 * Coin, Type: Credit, Nonce: 7},
+		{From: "alice", To: "bob", Amount: Coin, Type: Debit},
+		{From: "alice", To: "bob", Amount: -Coin, Type: Debit},
+	}}
+	got, r, err := MigrateAccount(a, "test", NewAliasBook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Address != LegacyAlias("alice").String() || got.Nonce != 2 || len(got.Transactions) != 3 || len(r.Issues) != 1 {
+		t.Errorf("account %s nonce %d with %d transactions, %d issues", got.Address, got.Nonce, len(got.Transactions), len(r.Issues))
+	}
+	if n := got.Transactions[2].Nonce; n != 1 {
+		t.Errorf("second debit has nonce %d, want 1", n)
+	}
+	if n := got.Transactions[1].Nonce; n != 7 {
+		t.Errorf("credit has nonce %d, want its sender's 7", n)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "time"
+
+// progressCheckEvery is how many nonces are tried between clock reads, so
+// progress reporting doesn't slow the hashing loop down.
+const progressCheckEvery = 1 << 12
+
+// MiningProgress is a snapshot of an in-flight (or finished) mining attempt.
+type MiningProgress struct {
+	Attempts uint64        // nonces tried so far
+	Elapsed  time.Duration // time since mining started
+	HashRate float64       // estimated hashes per second
+	Done     bool          // true on the final report, once a nonce is found
+}
+
+// Miner searches for a nonce that satisfies a block's target. If OnProgress
+// is set it is called roughly every Interval while mining, and once more
+// when a valid nonce is found.
+type Miner struct {
+	OnProgress func(MiningProgress)
+	Interval   time.Duration // defaults to one second
+}
+
+// Mine grinds b.Nonce until the block hash meets b.Bits, sets b.Hash, and
+// returns the final progress report.
+func (m *Miner) Mine(b *Block) MiningProgress {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	start := time.Now()
+	lastReport := start
+	var attempts uint64
+
+	for {
+		hash := hashBlock(*b)
+		attempts++
+		if meetsTarget(hash, b.Bits) {
+			b.Hash = hash
+			p := newMiningProgress(attempts, time.Since(start))
+			p.Done = true
+			if m.OnProgress != nil {
+				m.OnProgress(p)
+			}
+			return p
+		}
+		b.Nonce++
+
+		if m.OnProgress != nil && attempts%progressCheckEvery == 0 {
+			if now := time.Now(); now.Sub(lastReport) >= interval {
+				lastReport = now
+				m.OnProgress(newMiningProgress(attempts, now.Sub(start)))
+			}
+		}
+	}
+}
+
+func newMiningProgress(attempts uint64, elapsed time.Duration) MiningProgress {
+	p := MiningProgress{Attempts: attempts, Elapsed: elapsed}
+	if elapsed > 0 {
+		p.HashRate = float64(attempts) / elapsed.Seconds()
+	}
+	return p
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// multisigDomain separates multisig address derivation from any other hash
+// of the same bytes.
+const multisigDomain = "go-principals multisig\x00"
+
+// MultisigPolicy is an M-of-N spending rule: Threshold of the participants'
+// Keys must sign. Its address is derived from the policy itself, so the
+// chain needs no record of it; a transaction spending from the address
+// carries the policy, and validation checks it matches.
+type MultisigPolicy struct {
+	Threshold int      `json:"threshold"`
+	Keys      []string `json:"keys"` // hex, uncompressed, sorted
+}
+
+// Cosig is one participant's signature on a multisig transaction.
+type Cosig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// NewMultisigPolicy creates a threshold-of-len(keys) policy.
+func NewMultisigPolicy(threshold int, keys ...*ecdsa.PublicKey) (*MultisigPolicy, error) {
+	p := &MultisigPolicy{Threshold: threshold}
+	for _, key := range keys {
+		raw, err := key.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		p.Keys = append(p.Keys, hex.EncodeToString(raw))
+	}
+	sort.Strings(p.Keys)
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks that the threshold is reachable and the keys are valid,
+// distinct, and sorted, so each set of participants has one address.
+func (p *MultisigPolicy) Validate() error {
+	if p.Threshold < 1 || p.Threshold > len(p.Keys) {
+		return fmt.Errorf("multisig threshold %d of %d keys", p.Threshold, len(p.Keys))
+	}
+	for i, k := range p.Keys {
+		if _, err := ParsePublicKey(k); err != nil {
+			return fmt.Errorf("multisig key %d: %w", i, err)
+		}
+		if i > 0 && k <= p.Keys[i-1] {
+			return errors.New("multisig keys must be distinct and sorted")
+		}
+	}
+	return nil
+}
+
+// Address derives the policy's address: the last 20 bytes of a
+// domain-separated SHA-256 of the threshold and keys. No single key's
+// address can collide with it.
+func (p *MultisigPolicy) Address() (Address, error) {
+	if err := p.Validate(); err != nil {
+		return Address{}, err
+	}
+	h := sha256.New()
+	h.Write([]byte(multisigDomain))
+	h.Write([]byte(fmt.Sprintf("%d", p.Threshold)))
+	for _, k := range p.Keys {
+		h.Write([]byte(":" + k))
+	}
+	sum := h.Sum(nil)
+	var a Address
+	copy(a[:], sum[len(sum)-AddressLength:])
+	return a, nil
+}
+
+// has reports whether raw is one of the participants' keys.
+func (p *MultisigPolicy) has(raw []byte) bool {
+	k := hex.EncodeToString(raw)
+	i := sort.SearchStrings(p.Keys, k)
+	return i < len(p.Keys) && p.Keys[i] == k
+}
+
+// CosignTx adds key's signature to tx, which must carry the multisig
+// policy its From address is derived from. Signing again with the same key
+// replaces the earlier signature. The hash is recomputed first.
+func CosignTx(tx *Transaction, key *ecdsa.PrivateKey) error {
+	p := tx.Multisig
+	if p == nil {
+		return errors.New("transaction has no multisig policy")
+	}
+	addr, err := p.Address()
+	if err != nil {
+		return err
+	}
+	if from, err := ParseAddress(tx.From); err != nil || from != addr {
+		return fmt.Errorf("multisig policy is for %s, not %s", addr, tx.From)
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return err
+	}
+	if !p.has(pub) {
+		return errors.New("key is not a participant in the multisig policy")
+	}
+	digest, err := txDigest(*tx)
+	if err != nil {
+		return err
+	}
+	sig, err := SignDeterministic(key, digest)
+	if err != nil {
+		return err
+	}
+	tx.Hash = computeTxHash(*tx)
+	for i, c := range tx.Cosigs {
+		if bytes.Equal(c.PubKey, pub) {
+			tx.Cosigs[i].Signature = sig
+			return nil
+		}
+	}
+	tx.Cosigs = append(tx.Cosigs, Cosig{PubKey: pub, Signature: sig})
+	return nil
+}
+
+// verifyMultisig checks that tx's From is its policy's address and that at
+// least Threshold distinct participants signed its hash. A signature from
+// a non-participant or a bad signature fails the whole transaction rather
+// than being skipped.
+func verifyMultisig(tx Transaction) error {
+	p := tx.Multisig
+	addr, err := p.Address()
+	if err != nil {
+		return fmt.Errorf("%w: tx %s: %v", ErrBadSignature, tx.Hash, err)
+	}
+	if from, err := ParseAddress(tx.From); err != nil || from != addr {
+		return fmt.Errorf("%w: tx %s: policy belongs to %s, not %s", ErrBadSignature, tx.Hash, addr, tx.From)
+	}
+	digest, err := txDigest(tx)
+	if err != nil {
+		return err
+	}
+	signed := make(map[string]bool)
+	for _, c := range tx.Cosigs {
+		if !p.has(c.PubKey) {
+			return fmt.Errorf("%w: tx %s: signed by a non-participant", ErrBadSignature, tx.Hash)
+		}
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), c.PubKey)
+		if err != nil {
+			return fmt.Errorf("%w: tx %s: public key: %v", ErrBadSignature, tx.Hash, err)
+		}
+		if !ecdsa.VerifyASN1(pub, digest, c.Signature) {
+			return fmt.Errorf("%w: tx %s: bad cosignature", ErrBadSignature, tx.Hash)
+		}
+		signed[string(c.PubKey)] = true
+	}
+	if len(signed) < p.Threshold {
+		return fmt.Errorf("%w: tx %s has %d of %d required signatures", ErrBadSignature, tx.Hash, len(signed), p.Threshold)
+	}
+	return nil
+}
+
+// NewMultisigAccount creates the account for p's address, recording the
+// policy its debits are checked against.
+func NewMultisigAccount(p *MultisigPolicy, owner string) (*Account, error) {
+	addr, err := p.Address()
+	if err != nil {
+		return nil, err
+	}
+	return &Account{Address: addr.String(), Owner: owner, Multisig: p}, nil
+}
+
+// LoadMultisigPolicy reads a policy written by "tx multisig".
+func LoadMultisigPolicy(path string) (*MultisigPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p MultisigPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	for i, k := range p.Keys {
+		p.Keys[i] = strings.ToLower(k)
+	}
+	sort.Strings(p.Keys)
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMultisigPolicyValidate(t *testing.T) {
+	a, b := newTestAccount(t), newTestAccount(t)
+	p, err := NewMultisigPolicy(2, &a.Key.PublicKey, &b.Key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, bad := range map[string]MultisigPolicy{
+		"zero threshold":     {Threshold: 0, Keys: p.Keys},
+		"threshold too high": {Threshold: 3, Keys: p.Keys},
+		"unsorted":           {Threshold: 1, Keys: []string{p.Keys[1], p.Keys[0]}},
+		"repeated key":       {Threshold: 1, Keys: []string{p.Keys[0], p.Keys[0]}},
+		"bad key":            {Threshold: 1, Keys: []string{"04abcd"}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%s: policy accepted", name)
+		}
+	}
+
+	// The threshold is part of the address.
+	one, err := NewMultisigPolicy(1, &b.Key.PublicKey, &a.Key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, y := mustMultisigAddress(t, p), mustMultisigAddress(t, one); x == y {
+		t.Error("1-of-2 and 2-of-2 share an address")
+	}
+}
+
+func mustMultisigAddress(t *testing.T, p *MultisigPolicy) string {
+	t.Helper()
+	a, err := p.Address()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.String()
+}
+
+func TestMultisigNeedsThresholdSignatures(t *testing.T) {
+	a, b, c, mallory, bob := newTestAccount(t), newTestAccount(t), newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	p, err := NewMultisigPolicy(2, &a.Key.PublicKey, &b.Key.PublicKey, &c.Key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := mustMultisigAddress(t, p)
+	chain := newTestChain(t, testConfig(map[string]Amount{wallet: 10 * Coin}))
+	tx := Transaction{From: wallet, To: bob.Addr, Amount: Coin, ChainID: "test", Type: Debit, Time: testStart, Multisig: p}
+
+	cosign := func(tx Transaction, keys ...testAccount) Transaction {
+		tx.Cosigs = append([]Cosig(nil), tx.Cosigs...)
+		for _, k := range keys {
+			if err := CosignTx(&tx, k.Key); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return tx
+	}
+	if err := CosignTx(&tx, mallory.Key); err == nil {
+		t.Error("CosignTx accepted a non-participant's key")
+	}
+
+	// Mallory's signature slipped in alongside a real one.
+	outsider := cosign(tx, a)
+	digest, err := txDigest(outsider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignDeterministic(mallory.Key, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := mallory.Key.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsider.Cosigs = append(outsider.Cosigs, Cosig{PubKey: pub, Signature: sig})
+
+	// A policy of mallory's own, claiming the wallet's address.
+	own, err := NewMultisigPolicy(1, &mallory.Key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongPolicy := tx
+	wrongPolicy.Multisig = own
+	wrongPolicy.Hash = computeTxHash(wrongPolicy)
+	if digest, err = txDigest(wrongPolicy); err != nil {
+		t.Fatal(err)
+	}
+	if sig, err = SignDeterministic(mallory.Key, digest); err != nil {
+		t.Fatal(err)
+	}
+	wrongPolicy.Cosigs = []Cosig{{PubKey: pub, Signature: sig}}
+
+	for name, bad := range map[string]Transaction{
+		"one signature":    cosign(tx, a),
+		"same key twice":   cosign(tx, a, a),
+		"non-participant":  outsider,
+		"someone's policy": wrongPolicy,
+	} {
+		if err := VerifyTxSignature(bad); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s: err = %v, want ErrBadSignature", name, err)
+		}
+		if err := mineTxs(t, chain, bad); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s block: err = %v, want ErrBadSignature", name, err)
+		}
+	}
+	if err := mineTxs(t, chain, cosign(tx, c, a)); err != nil {
+		t.Fatalf("two of three: %v", err)
+	}
+	if got := tipBalance(t, chain, bob.Addr); got != Coin {
+		t.Errorf("bob holds %v, want 1", got)
+	}
+}
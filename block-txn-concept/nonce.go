@@ -0,0 +1,30 @@
+package main
+
+import "errors"
+
+// ErrBadNonce is returned for a block containing a transaction whose nonce
+// isn't its sender's next one: a replay of an earlier transaction, or one
+// submitted out of order.
+var ErrBadNonce = errors.New("transaction nonce out of order")
+
+// nextNonce returns the nonce addr's next transaction must carry on the
+// branch ending at b: the number of transactions it has sent so far. It
+// walks back to the most recent block addr sent from, using the nonces
+// recorded when each block was added, so pruned bodies don't matter.
+func (c *Chain) nextNonce(b *Block, addr string) uint64 {
+	for ; b != nil; b = c.blocks[b.PrevHash] {
+		if n, ok := c.nonces[b.Hash][addr]; ok {
+			return n
+		}
+		if b == c.genesis {
+			break
+		}
+	}
+	return 0
+}
+
+// NextNonce returns the nonce addr's next transaction must carry to be
+// included on top of the current tip.
+func (c *Chain) NextNonce(addr string) uint64 {
+	return c.nextNonce(c.tip, addr)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// OverdraftKind names how far below zero an account may go.
+type OverdraftKind string
+
+const (
+	// OverdraftStrict never lets a balance go negative. It is the default.
+	OverdraftStrict OverdraftKind = "strict"
+	// OverdraftLimited lets a balance go as low as -Limit.
+	OverdraftLimited OverdraftKind = "limit"
+	// OverdraftUnrestricted lets a balance go arbitrarily low, for issuer
+	// or clearing accounts whose negative balance is the credit they
+	// extended.
+	OverdraftUnrestricted OverdraftKind = "unrestricted"
+)
+
+// OverdraftPolicy decides whether a sender may spend more than it holds.
+// The zero value is strict.
+type OverdraftPolicy struct {
+	Kind  OverdraftKind `json:"kind"`
+	Limit Amount        `json:"limit,omitempty"` // for OverdraftLimited
+}
+
+// OverdraftUpTo returns a policy allowing balances down to -limit.
+func OverdraftUpTo(limit Amount) OverdraftPolicy {
+	return OverdraftPolicy{Kind: OverdraftLimited, Limit: limit}
+}
+
+// Validate checks that p is a known kind with a sensible limit.
+func (p OverdraftPolicy) Validate() error {
+	switch p.Kind {
+	case "", OverdraftStrict, OverdraftUnrestricted:
+		if p.Limit != 0 {
+			return fmt.Errorf("%s overdraft policy with a limit", p.kind())
+		}
+	case OverdraftLimited:
+		if p.Limit < 0 {
+			return fmt.Errorf("negative overdraft limit %v", p.Limit)
+		}
+	default:
+		return fmt.Errorf("unknown overdraft policy %q", p.Kind)
+	}
+	return nil
+}
+
+// Allows reports whether an account holding balance may spend cost.
+func (p OverdraftPolicy) Allows(balance, cost Amount) bool {
+	switch p.Kind {
+	case OverdraftUnrestricted:
+		return true
+	case OverdraftLimited:
+		return balance-cost >= -p.Limit
+	default:
+		return cost <= balance
+	}
+}
+
+// String describes p for error messages, e.g. "overdraft limit 50".
+func (p OverdraftPolicy) String() string {
+	if p.Kind == OverdraftLimited {
+		return fmt.Sprintf("overdraft limit %v", p.Limit)
+	}
+	return p.kind() + " overdraft policy"
+}
+
+func (p OverdraftPolicy) kind() string {
+	if p.Kind == "" {
+		return string(OverdraftStrict)
+	}
+	return string(p.Kind)
+}
+
+// overdraft returns the policy ChainConfig.Overdraft sets for addr.
+func (cfg ChainConfig) overdraft(addr string) OverdraftPolicy {
+	return cfg.Overdraft[addr]
+}
+
+// overdraftSpec lists the non-strict policies in address order.
+func overdraftSpec(policies map[string]OverdraftPolicy) []OverdraftSpec {
+	var list []OverdraftSpec
+	for addr, p := range policies {
+		if p.kind() == string(OverdraftStrict) {
+			continue
+		}
+		list = append(list, OverdraftSpec{Address: addr, Kind: p.Kind, Limit: p.Limit})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Address < list[j].Address })
+	return list
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOverdraftPolicyAllows(t *testing.T) {
+	for _, tc := range []struct {
+		p             OverdraftPolicy
+		balance, cost Amount
+		want          bool
+	}{
+		{OverdraftPolicy{}, 5, 5, true},
+		{OverdraftPolicy{}, 5, 6, false},
+		{OverdraftPolicy{Kind: OverdraftStrict}, 0, 1, false},
+		{OverdraftUpTo(3), 5, 8, true},
+		{OverdraftUpTo(3), 5, 9, false},
+		{OverdraftUpTo(3), -3, 1, false},
+		{OverdraftPolicy{Kind: OverdraftUnrestricted}, -100, 100, true},
+	} {
+		if got := tc.p.Allows(tc.balance, tc.cost); got != tc.want {
+			t.Errorf("%v: Allows(%d, %d) = %v, want %v", tc.p, tc.balance, tc.cost, got, tc.want)
+		}
+	}
+	for _, bad := range []OverdraftPolicy{
+		{Kind: "lenient"},
+		{Kind: OverdraftStrict, Limit: 1},
+		{Kind: OverdraftUnrestricted, Limit: 1},
+		OverdraftUpTo(-1),
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: accepted", bad)
+		}
+	}
+}
+
+func TestOverdraftOnChain(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: Coin, carol.Addr: Coin})
+	cfg.Overdraft = map[string]OverdraftPolicy{alice.Addr: OverdraftUpTo(2 * Coin)}
+	c := newTestChain(t, cfg)
+
+	if err := mineTxs(t, c, signedTx(t, c, carol, Transaction{To: bob.Addr, Amount: 2 * Coin})); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("strict sender overdrawing: err = %v, want ErrInsufficientFunds", err)
+	}
+	if err := mineTxs(t, c, signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 3 * Coin})); err != nil {
+		t.Fatalf("to the overdraft limit: %v", err)
+	}
+	if got := tipBalance(t, c, alice.Addr); got != -2*Coin {
+		t.Errorf("alice holds %v, want -2", got)
+	}
+	if err := mineTxs(t, c, signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 1})); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("past the limit: err = %v, want ErrInsufficientFunds", err)
+	}
+}
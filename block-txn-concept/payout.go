@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PayoutRow is one line of a payout file.
+type PayoutRow struct {
+	Line    int
+	Address string
+	Amount  Amount
+	Note    string
+}
+
+// ReadPayouts parses CSV rows of address,amount[,note]. A first line whose
+// amount column is text without digits, such as "amount", is treated as a
+// header and skipped; a malformed number there fails like on any other line.
+func ReadPayouts(r io.Reader) ([]PayoutRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var rows []PayoutRow
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("line %d: want address,amount[,note]", line)
+		}
+		field := strings.TrimSpace(rec[1])
+		if line == 1 && field != "" && !strings.ContainsAny(field, "0123456789") {
+			continue
+		}
+		amount, err := ParseAmount(field)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad amount %q", line, rec[1])
+		}
+		if amount <= 0 {
+			return nil, fmt.Errorf("line %d: amount must be positive", line)
+		}
+		addr := strings.TrimSpace(rec[0])
+		if addr == "" {
+			return nil, fmt.Errorf("line %d: missing address", line)
+		}
+		row := PayoutRow{Line: line, Address: addr, Amount: amount}
+		if len(rec) > 2 {
+			row.Note = strings.TrimSpace(rec[2])
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("payout file has no rows")
+	}
+	return rows, nil
+}
+
+// storedBalance is addr's balance in a stored chain: its genesis
+// allocation plus net transfers, less fees paid. Block rewards depend on
+// the chain config, which isn't stored, so they aren't counted. Every sum
+// is checked, since the file may hold anything.
+func storedBalance(blocks []Block, alloc map[string]Amount, addr string) (Amount, error) {
+	var bal Amount
+	var err error
+	for a, amount := range alloc {
+		if strings.EqualFold(a, addr) {
+			if bal, err = bal.Add(amount); err != nil {
+				return 0, err
+			}
+		}
+	}
+	for _, b := range blocks {
+		for _, tx := range b.Transactions {
+			if strings.EqualFold(tx.From, addr) {
+				cost, err := tx.Cost()
+				if err != nil {
+					return 0, fmt.Errorf("tx %s: %w", tx.Hash, err)
+				}
+				if bal, err = bal.Sub(cost); err != nil {
+					return 0, fmt.Errorf("tx %s: %w", tx.Hash, err)
+				}
+			}
+			for _, l := range tx.Legs() {
+				if strings.EqualFold(l.To, addr) {
+					if bal, err = bal.Add(l.Amount); err != nil {
+						return 0, fmt.Errorf("tx %s: %w", tx.Hash, err)
+					}
+				}
+			}
+		}
+	}
+	return bal, nil
+}
+
+// storedNonce is the nonce addr's next transaction must carry after a
+// stored chain: the number of transactions it has sent in it.
+func storedNonce(blocks []Block, addr string) uint64 {
+	var n uint64
+	for _, b := range blocks {
+		for _, tx := range b.Transactions {
+			if strings.EqualFold(tx.From, addr) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// runPayout implements "tx payout": it builds one transaction per row of a
+// payout CSV after checking the total against the sender's balance, and
+// writes the hashes to a results CSV. With -atomic it builds a single
+// multi-transfer instead, so either every payee is paid or none is.
+//
+// The transactions are written out as JSON rather than submitted, since
+// there is no running node whose Mempool they could go to.
+func runPayout(args []string) error {
+	fs := flag.NewFlagSet("tx payout", flag.ContinueOnError)
+	from := fs.String("from", "", "sender address")
+	chainPath := fs.String("chain", "chain.json", "stored chain used to check the sender's balance")
+	store := fs.String("store", "txbook.json", "file holding saved payees")
+	results := fs.String("out", "payout-results.csv", "results CSV to write")
+	txsPath := fs.String("txs", "payout-txs.json", "file to write the built transactions to")
+	chainID := fs.String("chain-id", "", "chain the transactions are for")
+	forkID := fs.String("fork-id", "", "fork the transactions are for (see spec dump)")
+	keyPath := fs.String("key", "", "sender's private key to sign with (see genesis keygen)")
+	atomic := fs.Bool("atomic", false, "pay every row in one multi-transfer transaction")
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *from == "" {
+		return errors.New("usage: tx payout -from ADDR [flags] payees.csv")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	rows, err := ReadPayouts(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	book, err := LoadTxBook(*store)
+	if err != nil {
+		return err
+	}
+	var total Amount
+	for i := range rows {
+		addr, err := book.ResolvePayee(rows[i].Address)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", rows[i].Line, err)
+		}
+		rows[i].Address = addr
+		if total, err = total.Add(rows[i].Amount); err != nil {
+			return fmt.Errorf("line %d: payout total: %w", rows[i].Line, err)
+		}
+	}
+
+	blocks, err := LoadBlocks(*chainPath)
+	if err != nil {
+		return err
+	}
+	alloc, err := LoadAlloc(*chainPath)
+	if err != nil {
+		return err
+	}
+	available, err := storedBalance(blocks, alloc, *from)
+	if err != nil {
+		return err
+	}
+	if total > available {
+		return fmt.Errorf("payout total %.2f exceeds available balance %.2f", total, available)
+	}
+
+	var key *ecdsa.PrivateKey
+	if *keyPath != "" {
+		if key, err = readKeyFile(*keyPath); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	nonce := storedNonce(blocks, *from)
+	var txs []Transaction
+	if *atomic {
+		tx := Transaction{
+			Version:     CurrentTxVersion,
+			ID:          1,
+			From:        *from,
+			Time:        now,
+			Description: "payout",
+			Type:        Debit,
+			ChainID:     *chainID,
+			ForkID:      *forkID,
+			Nonce:       nonce,
+		}
+		for _, row := range rows {
+			tx.Transfers = append(tx.Transfers, Transfer{To: row.Address, Amount: row.Amount})
+		}
+		txs = append(txs, tx)
+	} else {
+		for i, row := range rows {
+			txs = append(txs, Transaction{
+				Version:     CurrentTxVersion,
+				ID:          i + 1,
+				From:        *from,
+				To:          row.Address,
+				Time:        now,
+				Description: row.Note,
+				Amount:      row.Amount,
+				Type:        Debit,
+				ChainID:     *chainID,
+				ForkID:      *forkID,
+				Nonce:       nonce + uint64(i),
+			})
+		}
+	}
+	for i := range txs {
+		txs[i].Hash = computeTxHash(txs[i])
+		if key != nil {
+			if err := SignTx(&txs[i], key); err != nil {
+				return err
+			}
+		}
+	}
+	hashes := make([]string, len(rows)) // the transaction paying each row
+	for i := range rows {
+		if *atomic {
+			hashes[i] = txs[0].Hash
+		} else {
+			hashes[i] = txs[i].Hash
+		}
+	}
+
+	fmt.Printf("Payout from %s: %d transactions, total %.2f (balance %.2f)\n", *from, len(txs), total, available)
+	for _, row := range rows {
+		fmt.Printf("  %-20s %10.2f  %s\n", row.Address, row.Amount, row.Note)
+	}
+	if !*yes {
+		fmt.Print("Proceed? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("cancelled")
+		}
+	}
+
+	data, err := json.MarshalIndent(txs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*txsPath, data, 0o644); err != nil {
+		return err
+	}
+	if err := writePayoutResults(*results, rows, hashes); err != nil {
+		return err
+	}
+	fmt.Printf("Transactions written to %s, results to %s\n", *txsPath, *results)
+	return nil
+}
+
+func writePayoutResults(path string, rows []PayoutRow, hashes []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"line", "address", "amount", "tx_hash"})
+	for i, row := range rows {
+		w.Write([]string{
+			strconv.Itoa(row.Line),
+			row.Address,
+			row.Amount.String(),
+			hashes[i],
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadPayoutsHeader(t *testing.T) {
+	rows, err := ReadPayouts(strings.NewReader("address,amount,note\n0xa11ce,1.50,rent\n0xb0b,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Line != 2 || rows[0].Address != "0xa11ce" || rows[0].Note != "rent" {
+		t.Fatalf("rows = %+v", rows)
+	}
+	if want, _ := ParseAmount("1.50"); rows[0].Amount != want {
+		t.Errorf("amount = %d, want %d", rows[0].Amount, want)
+	}
+
+	rows, err = ReadPayouts(strings.NewReader("0xa11ce,1.50\n"))
+	if err != nil || len(rows) != 1 || rows[0].Line != 1 {
+		t.Fatalf("headerless file: rows = %+v, err = %v", rows, err)
+	}
+}
+
+func TestReadPayoutsRejectsBadFirstAmount(t *testing.T) {
+	for _, in := range []string{
+		"0xa11ce,1.5.0\n0xb0b,2\n",
+		"0xa11ce,1O0\n0xb0b,2\n",
+		"0xa11ce,\n0xb0b,2\n",
+		"address,amount\n0xa11ce,abc\n",
+	} {
+		if rows, err := ReadPayouts(strings.NewReader(in)); err == nil {
+			t.Errorf("%q: rows = %+v, want error", in, rows)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+)
+
+// maxTarget is the easiest possible target: every 256-bit hash satisfies it.
+var maxTarget = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// CompactToBig expands a compact difficulty value (Bitcoin's nBits) into the
+// full 256-bit target it represents.
+//
+// The compact form packs a base-256 exponent into the top byte and a 23-bit
+// mantissa into the low three bytes, with bit 23 acting as a sign bit:
+//
+//	target = mantissa * 256^(exponent-3)
+func CompactToBig(bits uint32) *big.Int {
+	mantissa := bits & 0x007fffff
+	negative := bits&0x00800000 != 0
+	exponent := uint(bits >> 24)
+
+	var n *big.Int
+	if exponent <= 3 {
+		mantissa >>= 8 * (3 - exponent)
+		n = big.NewInt(int64(mantissa))
+	} else {
+		n = big.NewInt(int64(mantissa))
+		n.Lsh(n, 8*(exponent-3))
+	}
+
+	if negative {
+		n.Neg(n)
+	}
+	return n
+}
+
+// BigToCompact is the inverse of CompactToBig. Precision below the top three
+// bytes of the target is lost, exactly as in Bitcoin.
+func BigToCompact(n *big.Int) uint32 {
+	if n.Sign() == 0 {
+		return 0
+	}
+
+	var mantissa uint32
+	exponent := uint(len(n.Bytes()))
+	if exponent <= 3 {
+		mantissa = uint32(new(big.Int).Abs(n).Uint64())
+		mantissa <<= 8 * (3 - exponent)
+	} else {
+		tmp := new(big.Int).Abs(n)
+		mantissa = uint32(tmp.Rsh(tmp, 8*(exponent-3)).Uint64())
+	}
+
+	// The mantissa's high bit is the sign bit, so shift it out of the way.
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	compact := uint32(exponent<<24) | mantissa
+	if n.Sign() < 0 {
+		compact |= 0x00800000
+	}
+	return compact
+}
+
+// BitsForLeadingZeros returns the compact target that roughly corresponds to
+// requiring `zeros` leading hex zeros in a block hash, the coarse difficulty
+// scale the demo originally used.
+func BitsForLeadingZeros(zeros int) uint32 {
+	target := new(big.Int).Rsh(maxTarget, uint(4*zeros))
+	return BigToCompact(target)
+}
+
+// Difficulty returns how many times harder the target encoded in bits is
+// than the easiest possible target.
+func Difficulty(bits uint32) float64 {
+	target := CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return 0
+	}
+	d, _ := new(big.Rat).SetFrac(maxTarget, target).Float64()
+	return d
+}
+
+// Work returns the expected number of hashes needed to find a block at the
+// target encoded in bits: 2^256 / (target + 1).
+func Work(bits uint32) *big.Int {
+	target := CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return new(big.Int)
+	}
+	denom := new(big.Int).Add(target, big.NewInt(1))
+	return new(big.Int).Div(new(big.Int).Lsh(big.NewInt(1), 256), denom)
+}
+
+// hashToBig interprets a "0x"-prefixed hex hash as a 256-bit unsigned integer.
+func hashToBig(hash string) *big.Int {
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(hash, "0x"), 16)
+	if !ok {
+		// Unparseable hashes must never satisfy a target.
+		return new(big.Int).Lsh(big.NewInt(1), 256)
+	}
+	return n
+}
+
+// meetsTarget reports whether hash, read as a number, is at or below the
+// target encoded in bits.
+func meetsTarget(hash string, bits uint32) bool {
+	target := CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return false
+	}
+	return hashToBig(hash).Cmp(target) <= 0
+}
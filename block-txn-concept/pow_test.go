@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// Bitcoin's SetCompact/GetCompact vectors from arith_uint256_tests.cpp:
+// each compact value, the target it expands to, and what that target
+// compacts back to.
+func TestCompactToBigVectors(t *testing.T) {
+	for _, tc := range []struct {
+		bits   uint32
+		target string
+		back   uint32
+	}{
+		{0x00123456, "0", 0},
+		{0x01003456, "0", 0},
+		{0x02000056, "0", 0},
+		{0x03000000, "0", 0},
+		{0x04000000, "0", 0},
+		{0x00923456, "0", 0},
+		{0x01803456, "0", 0},
+		{0x02800056, "0", 0},
+		{0x03800000, "0", 0},
+		{0x04800000, "0", 0},
+		{0x01123456, "12", 0x01120000},
+		{0x01fedcba, "-7e", 0x01fe0000},
+		{0x02123456, "1234", 0x02123400},
+		{0x03123456, "123456", 0x03123456},
+		{0x04123456, "12345600", 0x04123456},
+		{0x04923456, "-12345600", 0x04923456},
+		{0x05009234, "92340000", 0x05009234},
+		{0x20123456, "1234560000000000000000000000000000000000000000000000000000000000", 0x20123456},
+		{0x1d00ffff, "ffff0000000000000000000000000000000000000000000000000000", 0x1d00ffff},
+	} {
+		want, _ := new(big.Int).SetString(tc.target, 16)
+		got := CompactToBig(tc.bits)
+		if got.Cmp(want) != 0 {
+			t.Errorf("CompactToBig(0x%08x) = %x, want %x", tc.bits, got, want)
+		}
+		if back := BigToCompact(got); back != tc.back {
+			t.Errorf("BigToCompact(%x) = 0x%08x, want 0x%08x", got, back, tc.back)
+		}
+	}
+
+	// 0x80 would set the sign bit as a one-byte mantissa, so it takes an
+	// extra byte of exponent.
+	if got := BigToCompact(big.NewInt(0x80)); got != 0x02008000 {
+		t.Errorf("BigToCompact(0x80) = 0x%08x, want 0x02008000", got)
+	}
+}
+
+func TestWork(t *testing.T) {
+	// Bitcoin's genesis target: 2^256 / (0xffff * 2^208 + 1).
+	if got := Work(0x1d00ffff); got.Cmp(big.NewInt(4295032833)) != 0 {
+		t.Errorf("Work(0x1d00ffff) = %v, want 4295032833", got)
+	}
+	for _, bits := range []uint32{0, 0x04923456} {
+		if got := Work(bits); got.Sign() != 0 {
+			t.Errorf("Work(0x%08x) = %v, want 0", bits, got)
+		}
+	}
+	if Work(BitsForLeadingZeros(2)).Cmp(Work(BitsForLeadingZeros(1))) <= 0 {
+		t.Error("a harder target should need more work")
+	}
+}
+
+func TestMeetsTargetBoundary(t *testing.T) {
+	const bits = 0x1d00ffff
+	target := CompactToBig(bits)
+	hashOf := func(n *big.Int) string { return fmt.Sprintf("0x%064x", n) }
+	if !meetsTarget(hashOf(target), bits) {
+		t.Error("a hash equal to the target should meet it")
+	}
+	if meetsTarget(hashOf(new(big.Int).Add(target, big.NewInt(1))), bits) {
+		t.Error("a hash one above the target should not meet it")
+	}
+	if meetsTarget("0xnothex", bits) {
+		t.Error("an unparseable hash met the target")
+	}
+	for _, bits := range []uint32{0, 0x04923456} {
+		if meetsTarget(hashOf(big.NewInt(0)), bits) {
+			t.Errorf("zero hash met target 0x%08x", bits)
+		}
+	}
+}
+
+func TestPoWEngineVerifySeal(t *testing.T) {
+	c := newTestChain(t, testConfig(nil))
+	b, err := c.buildBlock("", nil, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := &PoWEngine{}
+	b.Bits = BitsForLeadingZeros(1)
+	if err := engine.Seal(&b); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.VerifySeal(b); err != nil {
+		t.Fatalf("mined block: %v", err)
+	}
+
+	wrongHash := b
+	wrongHash.Nonce++
+	if err := engine.VerifySeal(wrongHash); err == nil {
+		t.Error("accepted a hash that doesn't match the header")
+	}
+
+	// A correct hash for a target it was never mined against.
+	tooHard := b
+	tooHard.Bits = 0x03000001
+	tooHard.Hash = hashBlock(tooHard)
+	if err := engine.VerifySeal(tooHard); err == nil {
+		t.Error("accepted a hash above the target")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPruneDropsBodiesBelowDepth(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	cfg.PruneDepth = 2
+	c := newTestChain(t, cfg)
+	hashes := make(map[int][]string)
+	for i := 0; i < 5; i++ {
+		if err := mineTxs(t, c, signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})); err != nil {
+			t.Fatal(err)
+		}
+		hashes[c.Tip().Index] = c.Tip().TxHashes()
+	}
+
+	for _, b := range c.BestChain() {
+		if want := b.Index < c.Tip().Index-cfg.PruneDepth; b.IsPruned() != want {
+			t.Errorf("block %d: pruned = %v, want %v", b.Index, b.IsPruned(), want)
+		}
+		if b.Index == 0 {
+			continue
+		}
+		if got := b.TxHashes(); len(got) != len(hashes[b.Index]) || got[0] != hashes[b.Index][0] {
+			t.Errorf("block %d: tx hashes %v, want %v", b.Index, got, hashes[b.Index])
+		}
+	}
+
+	// New blocks still validate against the pruned history.
+	if err := mineTxs(t, c, signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})); err != nil {
+		t.Fatal(err)
+	}
+	if got := tipBalance(t, c, bob.Addr); got != 6*Coin {
+		t.Errorf("bob has %s, want 6", got)
+	}
+
+	// Without a cached snapshot to start from, a pruned height can't be
+	// replayed.
+	c.snapshots = make(map[string]*Snapshot)
+	if _, err := c.Snapshot(2); !errors.Is(err, ErrPruned) {
+		t.Errorf("snapshot below the prune depth: err = %v, want ErrPruned", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// TxFilter selects transactions from a statement or ledger. Zero fields
+// match everything, so TxFilter{} matches every transaction.
+type TxFilter struct {
+	Since, Until time.Time       // Until is exclusive
+	Counterparty string          // sender or any recipient
+	MinAmount    Amount          // compared with Total
+	MaxAmount    Amount          // 0 is no maximum
+	Asset        Asset           // with either bound set, only txs in this asset match
+	Type         TransactionType // Debit or Credit
+	Description  string          // case-insensitive substring
+	Category     string          // exact; Uncategorized matches none set
+	Tag          string          // one of the transaction's tags
+
+	// Offset and Limit select a page of the matches; Limit 0 returns
+	// every match from Offset on.
+	Offset, Limit int
+}
+
+// TxPage is one page of the transactions a TxFilter matched.
+type TxPage struct {
+	Transactions []Transaction
+	Total        int // matches across all pages
+	Next         int // Offset of the next page, or 0 if this is the last
+}
+
+// Match reports whether tx passes every filter except the page bounds.
+func (f TxFilter) Match(tx Transaction) bool {
+	total, _ := tx.Total()
+	switch {
+	case !f.Since.IsZero() && tx.Time.Before(f.Since),
+		!f.Until.IsZero() && !tx.Time.Before(f.Until),
+		f.Type != "" && tx.Type != f.Type,
+		(f.MinAmount != 0 || f.MaxAmount != 0) && tx.Asset != f.Asset,
+		total < f.MinAmount,
+		f.MaxAmount != 0 && total > f.MaxAmount:
+		return false
+	}
+	if f.Counterparty != "" && !strings.EqualFold(tx.From, f.Counterparty) && !tx.sendsTo(f.Counterparty) {
+		return false
+	}
+	if f.Category != "" && f.Category != tx.Category && !(f.Category == Uncategorized && tx.Category == "") {
+		return false
+	}
+	if f.Tag != "" && !tx.hasTag(f.Tag) {
+		return false
+	}
+	if f.Description != "" && !strings.Contains(strings.ToLower(tx.Description), strings.ToLower(f.Description)) {
+		return false
+	}
+	return true
+}
+
+// page returns the page of txs that f matches.
+func (f TxFilter) page(txs []Transaction) TxPage {
+	var p TxPage
+	for _, tx := range txs {
+		if !f.Match(tx) {
+			continue
+		}
+		p.Total++
+		if p.Total <= f.Offset || (f.Limit > 0 && len(p.Transactions) == f.Limit) {
+			continue
+		}
+		p.Transactions = append(p.Transactions, tx)
+	}
+	if end := f.Offset + len(p.Transactions); f.Limit > 0 && end < p.Total {
+		p.Next = end
+	}
+	return p
+}
+
+// Filter returns the page of a's transactions that f matches, in the order
+// they were applied.
+func (a *Account) Filter(f TxFilter) TxPage {
+	return f.page(a.Transactions)
+}
+
+// Query returns the page of transactions across the ledger that f matches,
+// oldest first. Each transaction appears once, as applied: a Debit from its
+// sender, so a Type of Credit matches nothing. Use Account.Filter for one
+// account's side of its transfers.
+func (l *Ledger) Query(f TxFilter) TxPage {
+	var txs []Transaction
+	for _, addr := range l.Addresses() {
+		for _, tx := range l.accounts[addr].Transactions {
+			if tx.Type == Debit {
+				txs = append(txs, tx)
+			}
+		}
+	}
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].Time.Before(txs[j].Time) })
+	return f.page(txs)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// queryLedger has alice make five payments, one an hour apart starting at
+// testStart: 1, 2, 3, 4 and 5 coins, to bob for odd amounts and carol for
+// even ones.
+func queryLedger(t *testing.T) (*Ledger, testAccount, testAccount, testAccount) {
+	t.Helper()
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	l := NewLedger()
+	l.Mint(alice.Addr, 100*Coin)
+	for i := 1; i <= 5; i++ {
+		to, note, cat := bob, "Coffee", "food"
+		if i%2 == 0 {
+			to, note, cat = carol, "train", ""
+		}
+		b := NewTxBuilder().From(alice.Addr).To(to.Addr).Amount(Amount(i) * Coin).NonceFrom(l).
+			Time(testStart.Add(time.Duration(i-1) * time.Hour)).Description(note).Category(cat).SignWith(alice.Key)
+		if i == 5 {
+			b.Tag("Weekend")
+		}
+		tx, err := b.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Apply(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l, alice, bob, carol
+}
+
+func amounts(p TxPage) []Amount {
+	var out []Amount
+	for _, tx := range p.Transactions {
+		out = append(out, tx.Amount/Coin)
+	}
+	return out
+}
+
+func sameAmounts(a, b []Amount) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTxFilterMatches(t *testing.T) {
+	l, alice, bob, _ := queryLedger(t)
+	for name, tc := range map[string]struct {
+		f    TxFilter
+		want []Amount
+	}{
+		"everything":    {TxFilter{}, []Amount{1, 2, 3, 4, 5}},
+		"since":         {TxFilter{Since: testStart.Add(3 * time.Hour)}, []Amount{4, 5}},
+		"until":         {TxFilter{Until: testStart.Add(time.Hour)}, []Amount{1}},
+		"counterparty":  {TxFilter{Counterparty: bob.Addr}, []Amount{1, 3, 5}},
+		"amounts":       {TxFilter{MinAmount: 2 * Coin, MaxAmount: 4 * Coin}, []Amount{2, 3, 4}},
+		"other asset":   {TxFilter{MinAmount: Coin, Asset: "USD"}, nil},
+		"description":   {TxFilter{Description: "coff"}, []Amount{1, 3, 5}},
+		"category":      {TxFilter{Category: "food"}, []Amount{1, 3, 5}},
+		"uncategorized": {TxFilter{Category: Uncategorized}, []Amount{2, 4}},
+		"tag":           {TxFilter{Tag: "weekend"}, []Amount{5}},
+		"credits":       {TxFilter{Type: Credit}, nil},
+		"combined":      {TxFilter{Counterparty: bob.Addr, Since: testStart.Add(time.Hour)}, []Amount{3, 5}},
+	} {
+		if got := amounts(l.Account(alice.Addr).Filter(tc.f)); !sameAmounts(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+		}
+	}
+
+	// Bob's side of the same payments are credits.
+	if got := amounts(l.Account(bob.Addr).Filter(TxFilter{Type: Credit})); !sameAmounts(got, []Amount{1, 3, 5}) {
+		t.Errorf("bob's credits: %v", got)
+	}
+}
+
+func TestTxFilterPages(t *testing.T) {
+	l, _, _, _ := queryLedger(t)
+	var got []Amount
+	f := TxFilter{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatal("more than three pages of two for five transactions")
+		}
+		p := l.Query(f)
+		if p.Total != 5 {
+			t.Errorf("page at %d: total %d, want 5", f.Offset, p.Total)
+		}
+		got = append(got, amounts(p)...)
+		if p.Next == 0 {
+			break
+		}
+		f.Offset = p.Next
+	}
+	if !sameAmounts(got, []Amount{1, 2, 3, 4, 5}) {
+		t.Errorf("pages gave %v, want every transaction once, oldest first", got)
+	}
+
+	if p := l.Query(TxFilter{Offset: 4, Limit: 2}); !sameAmounts(amounts(p), []Amount{5}) || p.Next != 0 {
+		t.Errorf("last page %v, next %d", amounts(p), p.Next)
+	}
+	if p := l.Query(TxFilter{Offset: 9}); len(p.Transactions) != 0 || p.Total != 5 {
+		t.Errorf("past the end: %v of %d", amounts(p), p.Total)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+// ReceiptStatus is the outcome of executing an included transaction.
+type ReceiptStatus string
+
+const (
+	ReceiptSuccess ReceiptStatus = "success"
+	// ReceiptOverdrawn marks a transaction that left its sender with a
+	// negative balance: one whose overdraft policy allows it, or any sender
+	// in blocks from before BlockVersion3, which didn't check balances.
+	ReceiptOverdrawn ReceiptStatus = "overdrawn"
+	// ReceiptReversed marks a transaction a best-chain reversal has undone;
+	// see NewReversal.
+	ReceiptReversed ReceiptStatus = "reversed"
+)
+
+// Receipt records what executing a best-chain transaction did, not just
+// where it was included.
+type Receipt struct {
+	TxHash     string
+	Status     ReceiptStatus
+	BlockHash  string
+	Height     int
+	Position   int               // index within the block's transactions
+	Fee        Amount            // paid by the sender
+	Balances   map[string]Amount // sender's and recipients' balances right after the tx
+	ReversedBy string            // hash of the reversal, if Status is ReceiptReversed
+}
+
+// indexReceipts records receipts for the transactions of blocks that
+// joined the best chain, oldest first, by replaying them from their
+// parent's balances. Blocks whose parent state can't be rebuilt, because
+// it lies under pruned bodies, get no receipts.
+func (c *Chain) indexReceipts(connected []Block) {
+	var s *Snapshot
+	for _, b := range connected {
+		if b.IsPruned() {
+			s = nil
+			continue
+		}
+		if s == nil || s.BlockHash != b.PrevHash {
+			if b.Index == 0 {
+				s = &Snapshot{Height: -1, Balances: make(map[string]Amount)}
+			} else if parent, err := c.Snapshot(b.Index - 1); err == nil && parent.BlockHash == b.PrevHash {
+				s = parent
+			} else {
+				s = nil
+				continue
+			}
+		}
+		c.applyBlockFunc(s, b, func(i int, tx Transaction) {
+			r := Receipt{
+				TxHash:    tx.Hash,
+				Status:    ReceiptSuccess,
+				BlockHash: b.Hash,
+				Height:    b.Index,
+				Position:  i,
+				Fee:       tx.Fee,
+				Balances:  make(map[string]Amount),
+			}
+			for _, addr := range txAddresses(tx) {
+				r.Balances[addr] = s.Balances[addr]
+			}
+			if s.Balances[tx.From] < 0 {
+				r.Status = ReceiptOverdrawn
+			}
+			c.receipts[tx.Hash] = r
+		})
+	}
+}
+
+// GetReceipt returns the receipt of a best-chain transaction, with status
+// ReceiptReversed once a best-chain reversal has undone it. It reports
+// false for transactions that aren't on the best chain and for those whose
+// receipts couldn't be computed; see indexReceipts.
+func (c *Chain) GetReceipt(hash string) (Receipt, bool) {
+	r, ok := c.receipts[hash]
+	if rev, reversed := c.reversals[hash]; ok && reversed {
+		r.Status, r.ReversedBy = ReceiptReversed, rev
+	}
+	return r, ok
+}
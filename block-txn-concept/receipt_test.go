@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReceiptsRecordOutcomes(t *testing.T) {
+	auth, alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: Coin, carol.Addr: 10 * Coin})
+	cfg.Overdraft = map[string]OverdraftPolicy{alice.Addr: OverdraftUpTo(2 * Coin)}
+	cfg.StatusAuthority = auth.Addr
+	c := newTestChain(t, cfg)
+
+	paid := signedTx(t, c, carol, Transaction{To: bob.Addr, Amount: 4 * Coin, Fee: Coin / 2})
+	overdrawn := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 2 * Coin})
+	if err := mineTxs(t, c, paid, overdrawn); err != nil {
+		t.Fatal(err)
+	}
+
+	r, ok := c.GetReceipt(paid.Hash)
+	if !ok {
+		t.Fatal("no receipt for an included transaction")
+	}
+	want := Receipt{TxHash: paid.Hash, Status: ReceiptSuccess, BlockHash: c.Tip().Hash, Height: 1, Position: 0, Fee: Coin / 2}
+	if r.TxHash != want.TxHash || r.Status != want.Status || r.BlockHash != want.BlockHash || r.Height != want.Height || r.Position != want.Position || r.Fee != want.Fee {
+		t.Errorf("receipt %+v, want %+v", r, want)
+	}
+	if r.Balances[carol.Addr] != 5*Coin+Coin/2 || r.Balances[bob.Addr] != 4*Coin || len(r.Balances) != 2 {
+		t.Errorf("balances after the payment %v", r.Balances)
+	}
+
+	r, _ = c.GetReceipt(overdrawn.Hash)
+	if r.Status != ReceiptOverdrawn || r.Position != 1 || r.Balances[alice.Addr] != -Coin || r.Balances[bob.Addr] != 6*Coin {
+		t.Errorf("overdraft receipt %+v", r)
+	}
+
+	b, err := NewReversal(paid, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rev, err := b.Bind("test", "").Time(testStart).SignWith(auth.Key).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mineTxs(t, c, rev); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := c.GetReceipt(paid.Hash); r.Status != ReceiptReversed || r.ReversedBy != rev.Hash {
+		t.Errorf("after the reversal: status %s, reversed by %s", r.Status, r.ReversedBy)
+	}
+	if r, ok := c.GetReceipt(rev.Hash); !ok || r.Status != ReceiptSuccess {
+		t.Errorf("the reversal's own receipt: %+v, %v", r, ok)
+	}
+	if _, ok := c.GetReceipt("0xdead"); ok {
+		t.Error("receipt for an unknown hash")
+	}
+}
+
+func TestReceiptsFollowReorgs(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	rival := newTestChain(t, rivalCfg)
+
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	if err := mineTxs(t, c, tx); err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, rival, 1)
+	if err := mineTxs(t, rival, tx); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range rival.BestChain()[1:] {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, ok := c.GetReceipt(tx.Hash)
+	if !ok || r.Height != 2 || r.BlockHash != rival.Tip().Hash {
+		t.Errorf("after the reorg the receipt is at %d %s (%v), want the rival's block 2", r.Height, r.BlockHash, ok)
+	}
+}
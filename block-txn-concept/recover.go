@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// RecoverableSigLength is the size of a signature with a recovery id: r and
+// s, 32 bytes each and big-endian, then the id.
+const RecoverableSigLength = 65
+
+// ErrRecovery is returned for a recoverable signature no public key can be
+// recovered from.
+var ErrRecovery = errors.New("cannot recover public key")
+
+// SignRecoverable signs digest with key and returns r || s || v. The
+// recovery id v, 0 to 3, picks which of the up to four keys that verify
+// (r, s) made it: bit 0 is the parity of the y coordinate of the point r
+// was taken from, and bit 1 is set if its x coordinate was r + N rather
+// than r, which for P-256 almost never happens.
+func SignRecoverable(key *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, errors.New("recoverable signatures need a P-256 key")
+	}
+	der, err := SignDeterministic(key, digest)
+	if err != nil {
+		return nil, err
+	}
+	compact, err := ASN1ToCompact(der)
+	if err != nil {
+		return nil, err
+	}
+	sig := append(compact, 0)
+	for v := byte(0); v < 4; v++ {
+		sig[64] = v
+		if pub, err := RecoverPubKey(digest, sig); err == nil && pub.Equal(&key.PublicKey) {
+			return sig, nil
+		}
+	}
+	return nil, fmt.Errorf("%w from a signature just made", ErrRecovery)
+}
+
+// RecoverPubKey returns the public key that made sig, a signature from
+// SignRecoverable, over hash. Any valid (r, s) recovers some key, so the
+// caller still has to check the key is the one it expects, usually by
+// comparing its address with the sender's.
+func RecoverPubKey(hash, sig []byte) (*ecdsa.PublicKey, error) {
+	if len(sig) != RecoverableSigLength {
+		return nil, fmt.Errorf("%w: signature is %d bytes, want %d", ErrRecovery, len(sig), RecoverableSigLength)
+	}
+	curve := elliptic.P256()
+	params := curve.Params()
+	r, s, err := parseCompact(sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRecovery, err)
+	}
+	v := sig[64]
+
+	// R is the point whose x coordinate r was taken from.
+	x := new(big.Int).Set(r)
+	if v&2 != 0 {
+		x.Add(x, params.N)
+	}
+	if x.Cmp(params.P) >= 0 {
+		return nil, fmt.Errorf("%w: x coordinate past the field", ErrRecovery)
+	}
+	y := p256Y(x, v&1 == 1)
+	if y == nil {
+		return nil, fmt.Errorf("%w: r is not on the curve", ErrRecovery)
+	}
+
+	// Q = r⁻¹(sR − eG) = (s/r)R + (−e/r)G
+	e := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - params.N.BitLen(); excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+	rInv := new(big.Int).ModInverse(r, params.N)
+	u1 := new(big.Int).Mul(s, rInv)
+	u1.Mod(u1, params.N)
+	u2 := new(big.Int).Mul(e, rInv)
+	u2.Neg(u2).Mod(u2, params.N)
+	x1, y1 := curve.ScalarMult(x, y, u1.Bytes())
+	x2, y2 := curve.ScalarBaseMult(u2.Bytes())
+	qx, qy := curve.Add(x1, y1, x2, y2)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, fmt.Errorf("%w: key is the point at infinity", ErrRecovery)
+	}
+
+	raw := make([]byte, 65)
+	raw[0] = 4
+	qx.FillBytes(raw[1:33])
+	qy.FillBytes(raw[33:])
+	pub, err := ecdsa.ParseUncompressedPublicKey(curve, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRecovery, err)
+	}
+	if !ecdsa.Verify(pub, hash, r, s) {
+		return nil, fmt.Errorf("%w: recovered key does not verify", ErrRecovery)
+	}
+	return pub, nil
+}
+
+// p256Y returns the y coordinate with the given parity of the P-256 point
+// with x coordinate x, or nil if there is none: y² = x³ − 3x + b.
+func p256Y(x *big.Int, odd bool) *big.Int {
+	params := elliptic.P256().Params()
+	y2 := new(big.Int).Exp(x, big.NewInt(3), params.P)
+	threeX := new(big.Int).Lsh(x, 1)
+	threeX.Add(threeX, x)
+	y2.Sub(y2, threeX).Add(y2, params.B).Mod(y2, params.P)
+	y := new(big.Int).ModSqrt(y2, params.P)
+	if y == nil {
+		return nil
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(params.P, y)
+	}
+	return y
+}
+
+// SignTxRecoverable is SignTx with a recoverable signature: it fills in
+// Signature with r || s || v and leaves PubKey empty, since whoever checks
+// the transaction can recover the key from the signature and derive From
+// from it, as Ethereum does. That saves the 65 bytes of the key. Blocks
+// carry such transactions from BlockVersion6 on.
+func SignTxRecoverable(tx *Transaction, key *ecdsa.PrivateKey) error {
+	signer, err := AddressFromPubKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	if from, err := ParseAddress(tx.From); err != nil || from != signer {
+		return fmt.Errorf("key for %s can't sign for %s", signer, tx.From)
+	}
+	digest, err := txDigest(*tx)
+	if err != nil {
+		return err
+	}
+	sig, err := SignRecoverable(key, digest)
+	if err != nil {
+		return err
+	}
+	tx.Hash = computeTxHash(*tx)
+	tx.PubKey, tx.Signature = nil, sig
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestRecoverPubKeyVector recovers the public key of RFC 6979's P-256 key
+// from its signature of "sample": exactly one of the two recovery ids
+// gives the key the RFC lists, and the other gives some other key.
+func TestRecoverPubKeyVector(t *testing.T) {
+	const (
+		ux = "60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6"
+		uy = "7903FE1008B8BC99A41AE9E95628BC64F2F1B20C2D7E9F5177A3C294D4462299"
+	)
+	digest := sha256.Sum256([]byte("sample"))
+	sig, err := hex.DecodeString(rfc6979SampleR + rfc6979SampleS + "00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := hex.DecodeString("04" + ux + uy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := 0
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		pub, err := RecoverPubKey(digest[:], sig)
+		if err != nil {
+			t.Fatalf("v=%d: %v", v, err)
+		}
+		if raw, _ := pub.Bytes(); bytes.Equal(raw, want) {
+			matches++
+		}
+	}
+	if matches != 1 {
+		t.Errorf("%d recovery ids gave the RFC's key, want 1", matches)
+	}
+
+	key := rfc6979Key(t)
+	got, err := SignRecoverable(key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(got[:64]) != strings.ToLower(rfc6979SampleR+rfc6979SampleS) {
+		t.Errorf("SignRecoverable r || s = %x", got[:64])
+	}
+	if pub, err := RecoverPubKey(digest[:], got); err != nil || !pub.Equal(&key.PublicKey) {
+		t.Errorf("RecoverPubKey(SignRecoverable) = %v, %v", pub, err)
+	}
+}
+
+func TestRecoverPubKeyRejectsMalformed(t *testing.T) {
+	key := newTestAccount(t).Key
+	digest := sha256.Sum256([]byte("payload"))
+	sig, err := SignRecoverable(key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	zeroR := append([]byte(nil), sig...)
+	copy(zeroR[:32], make([]byte, 32))
+	for name, bad := range map[string][]byte{
+		"short":    sig[:64],
+		"zero r":   zeroR,
+		"bad v":    append(append([]byte(nil), sig[:64]...), 4),
+		"overflow": append(append([]byte(nil), sig[:64]...), 2),
+	} {
+		if _, err := RecoverPubKey(digest[:], bad); !errors.Is(err, ErrRecovery) {
+			t.Errorf("%s: err = %v, want ErrRecovery", name, err)
+		}
+	}
+	other := sha256.Sum256([]byte("other payload"))
+	if pub, err := RecoverPubKey(other[:], sig); err == nil && pub.Equal(&key.PublicKey) {
+		t.Error("signature recovered the signer's key for another digest")
+	}
+}
+
+func TestRecoverableTxNeedsVersion6(t *testing.T) {
+	alice, bob, mallory := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	if err := SignTxRecoverable(&tx, alice.Key); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTxSignature(tx); err != nil {
+		t.Fatalf("VerifyTxSignature: %v", err)
+	}
+
+	// A recoverable signature always recovers some key, so one moved onto
+	// a transaction paying mallory must still fail.
+	moved := tx
+	moved.To = mallory.Addr
+	moved.Hash = computeTxHash(moved)
+	if err := VerifyTxSignature(moved); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("moved signature: err = %v, want ErrBadSignature", err)
+	}
+
+	old, err := c.buildBlock("", []Transaction{tx}, BlockVersion5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(old); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("version 5 block: err = %v, want ErrBadSignature", err)
+	}
+	b, err := c.buildBlock("", []Transaction{tx}, BlockVersion6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Errorf("version 6 block: %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// RenderFormat selects how WriteStatement and WriteChain lay out their
+// output.
+type RenderFormat string
+
+const (
+	// RenderPlain is the labelled, human-readable layout the demo prints.
+	RenderPlain RenderFormat = "plain"
+	// RenderTable lays out one row per transaction or block in aligned
+	// columns.
+	RenderTable RenderFormat = "table"
+	// RenderJSONLines writes one JSON object per line: a StatementEntry per
+	// transaction, or a Block per block, for tools that read a stream.
+	RenderJSONLines RenderFormat = "jsonl"
+)
+
+// ParseRenderFormat returns the format named s.
+func ParseRenderFormat(s string) (RenderFormat, error) {
+	switch f := RenderFormat(s); f {
+	case RenderPlain, RenderTable, RenderJSONLines:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown render format %q (want plain, table, or jsonl)", s)
+}
+
+// printer writes formatted output and keeps the first write error, so the
+// renderers needn't check every line.
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+func (p *printer) printf(format string, args ...any) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+// WriteStatement renders a's statement to w in format f.
+func (a *Account) WriteStatement(w io.Writer, f RenderFormat) error {
+	switch f {
+	case RenderPlain:
+		return a.writeStatementPlain(w)
+	case RenderTable:
+		return a.writeStatementTable(w)
+	case RenderJSONLines:
+		enc := json.NewEncoder(w)
+		for _, e := range a.Statement().Entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown render format %q", f)
+}
+
+func (a *Account) writeStatementPlain(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("\n=== Account Statement =====================================\n")
+	p.printf("Owner   : %s\n", a.Owner)
+	if a.Status != "" && a.Status != StatusActive {
+		p.printf("Status  : %s\n", a.Status)
+	}
+	p.printf("Address : %s\n\n", a.Address)
+
+	for _, t := range a.Transactions {
+		sign := "+"
+		if t.Type == Debit {
+			sign = "-"
+		}
+		p.printf("Tx %s\n", t.Hash[:16]+"...")
+		if t.ID != 0 {
+			p.printf("  ID     : %d\n", t.ID)
+		}
+		p.printf("  Time   : %s\n", t.Time.Format(time.RFC3339))
+		p.printf("  From   : %s\n", t.From)
+		p.printf("  To     : %s\n", t.payee())
+		for _, l := range t.Transfers {
+			p.printf("           %s %.2f\n", l.To, l.Amount)
+		}
+		p.printf("  Type   : %s\n", t.Type)
+		total, _ := t.Total()
+		if t.Asset != NativeAsset {
+			p.printf("  Amount : %s%.2f %s\n", sign, total, t.Asset)
+		} else {
+			p.printf("  Amount : %s%.2f\n", sign, total)
+		}
+		if t.Category != "" || len(t.Tags) > 0 {
+			p.printf("  Labels : %s\n", t.labels())
+		}
+		p.printf("  Note   : %s\n\n", t.Description)
+	}
+
+	p.printf("Final balance: %.2f\n", a.Balance)
+	for _, asset := range a.assetSymbols() {
+		p.printf("Final %s: %.2f\n", asset, a.Assets[asset])
+	}
+	p.printf("===========================================================\n\n")
+	return p.err
+}
+
+// writeStatementTable writes one row per transaction with the running
+// native balance after it, between a title line and the final balances.
+func (a *Account) writeStatementTable(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("%s (%s)\n", a.Owner, a.Address)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	tp := &printer{w: tw}
+	tp.printf("TIME\tTX\tTYPE\tCOUNTERPARTY\tAMOUNT\tBALANCE\tNOTE\n")
+	for i, e := range a.Statement().Entries {
+		t := a.Transactions[i]
+		party := e.From
+		if t.Type == Debit {
+			party = t.payee()
+		}
+		amount := fmt.Sprintf("%+.2f", e.Change)
+		if e.Asset != NativeAsset {
+			amount = fmt.Sprintf("%.2f %s", e.Amount, e.Asset)
+			if t.Type == Debit {
+				amount = "-" + amount
+			}
+		}
+		tp.printf("%s\t%s\t%s\t%s\t%s\t%.2f\t%s\n",
+			e.Time.Format(time.RFC3339), e.Hash[:10]+"...", e.Type, party, amount, e.Balance, e.Description)
+	}
+	if tp.err == nil {
+		tp.err = tw.Flush()
+	}
+	p.err = tp.err
+	p.printf("Final balance: %.2f\n", a.Balance)
+	for _, asset := range a.assetSymbols() {
+		p.printf("Final %s: %.2f\n", asset, a.Assets[asset])
+	}
+	return p.err
+}
+
+// WriteChain renders blocks to w in format f.
+func WriteChain(w io.Writer, blocks []Block, f RenderFormat) error {
+	switch f {
+	case RenderPlain:
+		return writeChainPlain(w, blocks)
+	case RenderTable:
+		return writeChainTable(w, blocks)
+	case RenderJSONLines:
+		enc := json.NewEncoder(w)
+		for _, b := range blocks {
+			if err := enc.Encode(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown render format %q", f)
+}
+
+func writeChainPlain(w io.Writer, blocks []Block) error {
+	p := &printer{w: w}
+	p.printf("=== Blockchain ============================================\n")
+	for _, b := range blocks {
+		p.printf("Block #%d\n", b.Index)
+		p.printf("  Timestamp : %s\n", b.Timestamp.Format(time.RFC3339))
+		p.printf("  Nonce     : %d\n", b.Nonce)
+		p.printf("  Bits      : 0x%08x (difficulty %.0f)\n", b.Bits, Difficulty(b.Bits))
+		p.printf("  PrevHash  : %s\n", b.PrevHash[:20]+"...")
+		p.printf("  Hash      : %s\n", b.Hash[:20]+"...")
+		if b.StateRoot != "" {
+			p.printf("  StateRoot : %s\n", b.StateRoot[:20]+"...")
+		}
+		p.printf("  Tx count  : %d\n", len(b.TxHashes()))
+
+		for _, tx := range b.Transactions {
+			to := tx.payee()
+			if len(tx.Transfers) == 0 {
+				to = to[:10] + "..."
+			}
+			total, _ := tx.Total()
+			p.printf("    - Tx %s: %s -> %s | %.2f (%s)\n",
+				tx.Hash[:10]+"...",
+				tx.From[:10]+"...",
+				to,
+				total,
+				tx.Type,
+			)
+		}
+		p.printf("\n")
+	}
+	p.printf("===========================================================\n")
+	return p.err
+}
+
+// writeChainTable writes one row per block header.
+func writeChainTable(w io.Writer, blocks []Block) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	p := &printer{w: tw}
+	p.printf("HEIGHT\tTIME\tHASH\tPREV\tDIFFICULTY\tTXS\n")
+	for _, b := range blocks {
+		p.printf("%d\t%s\t%s\t%s\t%.0f\t%d\n",
+			b.Index, b.Timestamp.Format(time.RFC3339), b.Hash[:20]+"...", b.PrevHash[:20]+"...",
+			Difficulty(b.Bits), len(b.TxHashes()))
+	}
+	if p.err != nil {
+		return p.err
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// failingWriter accepts n writes, then fails every one after.
+type failingWriter struct{ n int }
+
+var errWriteFailed = errors.New("write failed")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errWriteFailed
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestParseRenderFormat(t *testing.T) {
+	for _, s := range []string{"plain", "table", "jsonl"} {
+		if f, err := ParseRenderFormat(s); err != nil || string(f) != s {
+			t.Errorf("ParseRenderFormat(%q) = %q, %v", s, f, err)
+		}
+	}
+	if _, err := ParseRenderFormat("csv"); err == nil {
+		t.Error("parsed an unknown format")
+	}
+}
+
+func TestWriteStatement(t *testing.T) {
+	l, alice, bob := statementLedger(t)
+	a := l.Account(alice.Addr)
+
+	var buf bytes.Buffer
+	if err := a.WriteStatement(&buf, RenderPlain); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"=== Account Statement", "Address : " + alice.Addr, "rent, march", "Final balance: 7.50"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("plain statement lacks %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := a.WriteStatement(&buf, RenderTable); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[1], "TIME") || lines[4] != "Final balance: 7.50" {
+		t.Fatalf("table statement:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[2], bob.Addr) || !strings.Contains(lines[2], "-3.50") || !strings.Contains(lines[3], "+1.00") {
+		t.Errorf("table rows:\n%s\n%s", lines[2], lines[3])
+	}
+	if strings.Index(lines[1], "BALANCE") != strings.Index(lines[2], "6.50") {
+		t.Errorf("table columns don't line up:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := a.WriteStatement(&buf, RenderJSONLines); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&buf)
+	var got []StatementEntry
+	for dec.More() {
+		var e StatementEntry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+	if want := a.Statement().Entries; len(got) != len(want) || got[1].Balance != want[1].Balance {
+		t.Errorf("jsonl entries %+v, want %+v", got, want)
+	}
+
+	if err := a.WriteStatement(&buf, "csv"); err == nil {
+		t.Error("wrote an unknown format")
+	}
+	for _, f := range []RenderFormat{RenderPlain, RenderTable, RenderJSONLines} {
+		if err := a.WriteStatement(&failingWriter{n: 1}, f); !errors.Is(err, errWriteFailed) {
+			t.Errorf("%s: write error lost: %v", f, err)
+		}
+	}
+}
+
+func TestWriteChain(t *testing.T) {
+	c := newTestChain(t, testConfig(nil))
+	mineBlocks(t, c, 2)
+	blocks := c.BestChain()
+
+	var buf bytes.Buffer
+	if err := WriteChain(&buf, blocks, RenderPlain); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "Block #"); n != len(blocks) {
+		t.Errorf("plain chain shows %d blocks, want %d", n, len(blocks))
+	}
+
+	buf.Reset()
+	if err := WriteChain(&buf, blocks, RenderTable); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(blocks)+1 || !strings.HasPrefix(lines[0], "HEIGHT") || !strings.HasPrefix(lines[2], "1 ") {
+		t.Errorf("table chain:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteChain(&buf, blocks, RenderJSONLines); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&buf)
+	for i := 0; dec.More(); i++ {
+		var b Block
+		if err := dec.Decode(&b); err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(blocks) || b.Hash != blocks[i].Hash {
+			t.Errorf("jsonl block %d is %s", i, b.Hash)
+		}
+	}
+
+	if err := WriteChain(&buf, blocks, "csv"); err == nil {
+		t.Error("wrote an unknown format")
+	}
+	for _, f := range []RenderFormat{RenderPlain, RenderTable, RenderJSONLines} {
+		if err := WriteChain(&failingWriter{}, blocks, f); !errors.Is(err, errWriteFailed) {
+			t.Errorf("%s: write error lost: %v", f, err)
+		}
+	}
+}
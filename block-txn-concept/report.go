@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Uncategorized is the category reported for transactions without one.
+const Uncategorized = "uncategorized"
+
+// ReportRow totals one category's transactions in one calendar month.
+type ReportRow struct {
+	Month    string // "2006-01", in UTC
+	Category string
+	Count    int
+	Spent    Amount // native coin sent, fees included
+	Received Amount // native coin received
+}
+
+// Net is what the category added to the balance that month.
+func (r ReportRow) Net() Amount {
+	return r.Received - r.Spent
+}
+
+// Report totals a's transactions per month and category, oldest month
+// first and categories in name order within it.
+func (a *Account) Report() []ReportRow {
+	rows := make(map[[2]string]*ReportRow)
+	for _, t := range a.Transactions {
+		cat := t.Category
+		if cat == "" {
+			cat = Uncategorized
+		}
+		month := t.Time.UTC().Format("2006-01")
+		r, ok := rows[[2]string{month, cat}]
+		if !ok {
+			r = &ReportRow{Month: month, Category: cat}
+			rows[[2]string{month, cat}] = r
+		}
+		r.Count++
+		switch t.Type {
+		case Debit:
+			r.Spent -= t.change(NativeAsset)
+		case Credit:
+			r.Received += t.change(NativeAsset)
+		}
+	}
+	list := make([]ReportRow, 0, len(rows))
+	for _, r := range rows {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Month != list[j].Month {
+			return list[i].Month < list[j].Month
+		}
+		return list[i].Category < list[j].Category
+	})
+	return list
+}
+
+// SpendingByCategory totals a's debits, fees included, per category
+// across all time.
+func (a *Account) SpendingByCategory() map[string]Amount {
+	totals := make(map[string]Amount)
+	for _, r := range a.Report() {
+		if r.Spent != 0 {
+			totals[r.Category] += r.Spent
+		}
+	}
+	return totals
+}
+
+// PrintReport prints Report as a table.
+func (a *Account) PrintReport() {
+	fmt.Printf("\n=== Spending by Category ==================================\n")
+	fmt.Printf("%-8s %-16s %5s %12s %12s\n", "Month", "Category", "Txs", "Spent", "Received")
+	for _, r := range a.Report() {
+		fmt.Printf("%-8s %-16s %5d %12.2f %12.2f\n", r.Month, r.Category, r.Count, r.Spent, r.Received)
+	}
+	fmt.Print("===========================================================\n\n")
+}
+
+// labels describes tx's category and tags for display, e.g.
+// "food #coffee #work".
+func (t Transaction) labels() string {
+	parts := make([]string, 0, 1+len(t.Tags))
+	if t.Category != "" {
+		parts = append(parts, t.Category)
+	}
+	for _, tag := range t.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	return strings.Join(parts, " ")
+}
+
+// hasTag reports whether tx carries tag, compared case-insensitively.
+func (t Transaction) hasTag(tag string) bool {
+	for _, have := range t.Tags {
+		if strings.EqualFold(have, tag) {
+			return true
+		}
+	}
+	return false
+}
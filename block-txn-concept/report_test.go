@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportTotalsByMonthAndCategory(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	l := NewLedger()
+	l.Mint(alice.Addr, 100*Coin)
+	l.Mint(bob.Addr, 100*Coin)
+	pay := func(from, to testAccount, amount, fee Amount, at time.Time, cat string) {
+		t.Helper()
+		tx, err := NewTxBuilder().From(from.Addr).To(to.Addr).Amount(amount).Fee(fee).NonceFrom(l).
+			Time(at).Category(cat).SignWith(from.Key).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Apply(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jan, feb := testStart, testStart.AddDate(0, 1, 0)
+	pay(alice, bob, 3*Coin, Coin/10, jan, "food")
+	pay(alice, bob, 2*Coin, 0, jan.Add(time.Hour), "food")
+	pay(alice, bob, 10*Coin, 0, jan.Add(2*time.Hour), "rent")
+	pay(bob, alice, 4*Coin, 0, feb, "")
+	pay(alice, bob, Coin, 0, feb.Add(time.Hour), "food")
+
+	want := []ReportRow{
+		{Month: "2024-01", Category: "food", Count: 2, Spent: 5*Coin + Coin/10},
+		{Month: "2024-01", Category: "rent", Count: 1, Spent: 10 * Coin},
+		{Month: "2024-02", Category: "food", Count: 1, Spent: Coin},
+		{Month: "2024-02", Category: Uncategorized, Count: 1, Received: 4 * Coin},
+	}
+	got := l.Account(alice.Addr).Report()
+	if len(got) != len(want) {
+		t.Fatalf("report has %d rows, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if net := got[3].Net(); net != 4*Coin {
+		t.Errorf("net for the refund = %s, want 4", net)
+	}
+
+	spending := l.Account(alice.Addr).SpendingByCategory()
+	if len(spending) != 2 || spending["food"] != 6*Coin+Coin/10 || spending["rent"] != 10*Coin {
+		t.Errorf("spending by category = %v", spending)
+	}
+}
+
+func TestLabelsAreHashedAndShown(t *testing.T) {
+	base := Transaction{From: "a", To: "b", Amount: Coin, Type: Debit, Time: testStart}
+	labelled := func(cat string, tags ...string) Transaction {
+		tx := base
+		tx.Category, tx.Tags = cat, tags
+		return tx
+	}
+	hashes := make(map[string]string)
+	for name, tx := range map[string]Transaction{
+		"none":     base,
+		"category": labelled("food"),
+		"tag":      labelled("", "food"),
+		"one tag":  labelled("", "ab"),
+		"two tags": labelled("", "a", "b"),
+	} {
+		h := computeTxHash(tx)
+		if other, ok := hashes[h]; ok {
+			t.Errorf("%s and %s hash the same", name, other)
+		}
+		hashes[h] = name
+	}
+
+	if got := labelled("food", "coffee", "work").labels(); got != "food #coffee #work" {
+		t.Errorf("labels = %q", got)
+	}
+	if !labelled("", "Work").hasTag("work") || labelled("", "work").hasTag("play") {
+		t.Error("hasTag should compare tags case-insensitively and only match carried ones")
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math/big"
+	"time"
+)
+
+// maxRetargetFactor limits how far a single retarget can move difficulty.
+const maxRetargetFactor = 4
+
+// NextBits returns the target bits required for a block built on parent.
+// Outside retarget boundaries this is the parent's bits. At a boundary the
+// parent's target is multiplied by actual/expected time for the last
+// RetargetInterval blocks, clamped to a factor of four either way.
+func (c *Chain) NextBits(parent *Block) uint32 {
+	interval := c.config.RetargetInterval
+	height := parent.Index + 1
+	if interval <= 0 || height%interval != 0 || parent.Index < interval {
+		return parent.Bits
+	}
+
+	first := c.ancestorAt(parent, parent.Index-interval)
+	actual := parent.Timestamp.Sub(first.Timestamp)
+	expected := time.Duration(interval) * c.config.TargetBlockInterval
+
+	if actual < expected/maxRetargetFactor {
+		actual = expected / maxRetargetFactor
+	}
+	if actual > expected*maxRetargetFactor {
+		actual = expected * maxRetargetFactor
+	}
+
+	target := CompactToBig(parent.Bits)
+	target.Mul(target, big.NewInt(int64(actual)))
+	target.Div(target, big.NewInt(int64(expected)))
+	if target.Cmp(maxTarget) > 0 {
+		target.Set(maxTarget)
+	}
+	return BigToCompact(target)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// retargetChain mines seven blocks, step apart, on a chain that retargets
+// every four blocks of ten seconds from a genesis at bits, so the next
+// block is the first boundary with a full window behind it.
+func retargetChain(t *testing.T, bits uint32, step time.Duration) *Chain {
+	t.Helper()
+	cfg := testConfig(nil)
+	cfg.TargetBlockInterval = 10 * time.Second
+	cfg.RetargetInterval = 4
+	cfg.Clock = StepClock(testStart.Add(step), step)
+	genesis := Block{Version: CurrentBlockVersion, Timestamp: testStart, Bits: bits, PrevHash: "0x" + strings.Repeat("0", 64)}
+	if err := cfg.Engine.Seal(&genesis); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewChain(cfg, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < 8; i++ {
+		b, err := c.BuildBlock("", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Bits != bits {
+			t.Fatalf("block %d: bits 0x%08x before the first boundary, want 0x%08x", i, b.Bits, bits)
+		}
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestNextBitsRetargets(t *testing.T) {
+	const bits = 0x1d00ffff
+	scaled := func(num, den int64) uint32 {
+		target := CompactToBig(bits)
+		target.Mul(target, big.NewInt(num))
+		return BigToCompact(target.Div(target, big.NewInt(den)))
+	}
+	for _, tc := range []struct {
+		name string
+		step time.Duration
+		want uint32
+	}{
+		{"on target", 10 * time.Second, bits},
+		{"twice as fast", 5 * time.Second, scaled(1, 2)},
+		{"twice as slow", 20 * time.Second, scaled(2, 1)},
+		{"ten times as fast", time.Second, scaled(1, maxRetargetFactor)},
+		{"ten times as slow", 100 * time.Second, scaled(maxRetargetFactor, 1)},
+	} {
+		c := retargetChain(t, bits, tc.step)
+		if got := c.NextBits(c.tip); got != tc.want {
+			t.Errorf("%s: NextBits = 0x%08x, want 0x%08x", tc.name, got, tc.want)
+		}
+	}
+
+	// The easiest target can't get any easier.
+	easiest := BitsForLeadingZeros(0)
+	c := retargetChain(t, easiest, 100*time.Second)
+	if got := c.NextBits(c.tip); got != easiest {
+		t.Errorf("slow chain at the easiest target: NextBits = 0x%08x, want 0x%08x", got, easiest)
+	}
+}
+
+func TestAddBlockRejectsWrongBits(t *testing.T) {
+	c := retargetChain(t, 0x1d00ffff, 5*time.Second)
+	b, err := c.BuildBlock("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := b
+	stale.Bits = c.Tip().Bits
+	if err := c.AddBlock(stale); err == nil {
+		t.Fatal("accepted a block that skipped the retarget")
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Fatalf("retargeted block: %v", err)
+	}
+}
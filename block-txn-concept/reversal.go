@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+)
+
+// ErrReversal is returned for a reversal the chain won't accept: one not
+// signed by the status authority, or not matching the transaction it
+// names.
+var ErrReversal = errors.New("transaction reversal not allowed")
+
+// NewReversal builds the chargeback of orig: a payment of orig's amount
+// back from its recipient to its sender, carrying nonce, the recipient's
+// next. Only the chain's status authority can sign it, with
+// SignReversalTx; orig's fee stays with the miner that collected it.
+// Multi-transfers and reversals can't be reversed.
+func NewReversal(orig Transaction, nonce uint64) (*TxBuilder, error) {
+	if err := reversible(orig); err != nil {
+		return nil, err
+	}
+	b := NewTxBuilder().From(orig.To).To(orig.From).Amount(orig.Amount).Nonce(nonce).
+		Description("reversal of " + orig.Hash)
+	b.tx.Reverses = orig.Hash
+	return b, nil
+}
+
+// reversible checks that orig is a plain payment a reversal can undo.
+func reversible(orig Transaction) error {
+	switch {
+	case orig.Hash == "":
+		return errors.New("transaction to reverse has no hash")
+	case len(orig.Transfers) > 0:
+		return fmt.Errorf("tx %s is a multi-transfer", orig.Hash)
+	case orig.Reverses != "":
+		return fmt.Errorf("tx %s is itself a reversal", orig.Hash)
+	case orig.Status != "":
+		return fmt.Errorf("tx %s is a status change", orig.Hash)
+	}
+	return nil
+}
+
+// SignReversalTx signs tx, a reversal, with key, which should be the
+// chain's status authority's; checkReversal rejects any other. The hash
+// is recomputed first.
+func SignReversalTx(tx *Transaction, key *ecdsa.PrivateKey) error {
+	if tx.Reverses == "" {
+		return errors.New("transaction is not a reversal")
+	}
+	digest, err := txDigest(*tx)
+	if err != nil {
+		return err
+	}
+	sig, err := SignDeterministic(key, digest)
+	if err != nil {
+		return err
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return err
+	}
+	tx.Hash = computeTxHash(*tx)
+	tx.PubKey, tx.Signature = pub, sig
+	return nil
+}
+
+// findReversible looks for the transaction with hash on the branch ending
+// at b. It returns the transaction if found, whether a reversal of it
+// follows it there, and whether pruning hid part of the branch, in which
+// case not finding it proves nothing.
+func (c *Chain) findReversible(b *Block, hash string) (orig *Transaction, reversed, pruned bool) {
+	for ; b != nil; b = c.blocks[b.PrevHash] {
+		if b.IsPruned() {
+			pruned = true
+		}
+		for i := range b.Transactions {
+			switch tx := &b.Transactions[i]; {
+			case tx.Hash == hash:
+				return tx, reversed, pruned
+			case tx.Reverses == hash:
+				reversed = true
+			}
+		}
+		if b == c.genesis {
+			break
+		}
+	}
+	return nil, reversed, pruned
+}
+
+// checkReversal enforces reversals: signed by ChainConfig.StatusAuthority,
+// from BlockVersion4 on, of a transaction earlier on the branch that no
+// reversal has undone yet, paying its amount from its recipient back to
+// its sender. A reversal of a transaction lost to pruning is taken on
+// trust, so pruned nodes accept the blocks full nodes do.
+func checkReversal(ctx *TxContext, tx Transaction) error {
+	if tx.Reverses == "" {
+		return nil
+	}
+	if ctx.Version < BlockVersion4 {
+		return fmt.Errorf("%w: tx %s: reversals need header version %d", ErrReversal, tx.Hash, BlockVersion4)
+	}
+	signer, err := txSigner(tx)
+	if err != nil {
+		return err
+	}
+	authority, err := ParseAddress(ctx.Chain.config.StatusAuthority)
+	if err != nil || signer != authority {
+		return fmt.Errorf("%w: tx %s is signed by %s, not the status authority", ErrReversal, tx.Hash, signer)
+	}
+	if tx.Multisig != nil || tx.Escrow != nil || tx.Status != "" || len(tx.Transfers) > 0 {
+		return fmt.Errorf("%w: tx %s is not a plain payment", ErrReversal, tx.Hash)
+	}
+	if ctx.reversed[tx.Reverses] {
+		return fmt.Errorf("%w: tx %s is already reversed in this block", ErrReversal, tx.Reverses)
+	}
+	orig, reversed, pruned := ctx.Chain.findReversible(ctx.parent, tx.Reverses)
+	switch {
+	case reversed:
+		return fmt.Errorf("%w: tx %s is already reversed", ErrReversal, tx.Reverses)
+	case orig == nil && pruned:
+		return nil
+	case orig == nil:
+		return fmt.Errorf("%w: tx %s is not on this chain", ErrReversal, tx.Reverses)
+	}
+	if err := reversible(*orig); err != nil {
+		return fmt.Errorf("%w: %v", ErrReversal, err)
+	}
+	if tx.From != orig.To || tx.To != orig.From || tx.Amount != orig.Amount || tx.Asset != orig.Asset {
+		return fmt.Errorf("%w: tx %s doesn't pay back tx %s", ErrReversal, tx.Hash, orig.Hash)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReversals(t *testing.T) {
+	auth, alice, bob, mallory := newTestAccount(t), newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	cfg.StatusAuthority = auth.Addr
+	c := newTestChain(t, cfg)
+	orig := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 3 * Coin})
+	if err := mineTxs(t, c, orig); err != nil {
+		t.Fatal(err)
+	}
+
+	reversal := func(orig Transaction, signer testAccount) Transaction {
+		t.Helper()
+		b, err := NewReversal(orig, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := b.Bind("test", "").Time(testStart).SignWith(signer.Key).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	short := reversal(orig, auth)
+	short.Amount = Coin
+	if err := SignReversalTx(&short, auth.Key); err != nil {
+		t.Fatal(err)
+	}
+	unknown := orig
+	unknown.Description = "never mined"
+	unknown.Hash = computeTxHash(unknown)
+
+	for name, bad := range map[string]Transaction{
+		"signed by mallory": reversal(orig, mallory),
+		"partial amount":    short,
+		"not on the chain":  reversal(unknown, auth),
+	} {
+		if err := mineTxs(t, c, bad); !errors.Is(err, ErrReversal) {
+			t.Errorf("%s: err = %v, want ErrReversal", name, err)
+		}
+	}
+
+	back := reversal(orig, auth)
+	if err := mineTxs(t, c, back); err != nil {
+		t.Fatalf("authority's reversal: %v", err)
+	}
+	if got := tipBalance(t, c, alice.Addr); got != 10*Coin {
+		t.Errorf("alice holds %v after the reversal, want 10", got)
+	}
+	again := back
+	again.Nonce = 1
+	again.Description = "second reversal"
+	if err := SignReversalTx(&again, auth.Key); err != nil {
+		t.Fatal(err)
+	}
+	if err := mineTxs(t, c, again); !errors.Is(err, ErrReversal) {
+		t.Errorf("second reversal: err = %v, want ErrReversal", err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ScheduledTx is a payment the Scheduler makes when it falls due, once or
+// every Every.
+type ScheduledTx struct {
+	ID          int
+	From, To    string
+	Amount, Fee Amount
+	Description string
+	Category    string
+
+	Next      time.Time     // when the next payment is due
+	Every     time.Duration // 0 for a one-off payment
+	Remaining int           // payments left on a recurring schedule; 0 for no end
+
+	key *ecdsa.PrivateKey
+}
+
+// Scheduler holds scheduled payments and submits each to a mempool, built
+// and signed, when its time arrives. It has no clock of its own: Run
+// submits whatever is due at the time it is given.
+type Scheduler struct {
+	pool      *Mempool
+	schedules map[int]*ScheduledTx
+	nextID    int
+}
+
+// NewScheduler creates a scheduler submitting to pool.
+func NewScheduler(pool *Mempool) *Scheduler {
+	return &Scheduler{pool: pool, schedules: make(map[int]*ScheduledTx)}
+}
+
+// Schedule adds tx, to be signed with key when it falls due, and returns
+// its ID. key may be nil on chains that don't yet require signatures.
+func (s *Scheduler) Schedule(tx ScheduledTx, key *ecdsa.PrivateKey) (int, error) {
+	switch {
+	case tx.From == "" || tx.To == "":
+		return 0, errors.New("scheduled payment needs a sender and a recipient")
+	case tx.Amount <= 0:
+		return 0, errors.New("scheduled payment needs a positive amount")
+	case tx.Fee < 0:
+		return 0, errors.New("negative fee")
+	case tx.Next.IsZero():
+		return 0, errors.New("scheduled payment has no due time")
+	case tx.Every < 0 || tx.Remaining < 0:
+		return 0, errors.New("negative interval or payment count")
+	}
+	s.nextID++
+	tx.ID, tx.key = s.nextID, key
+	s.schedules[tx.ID] = &tx
+	return tx.ID, nil
+}
+
+// Cancel removes a scheduled payment before any more of it is made.
+func (s *Scheduler) Cancel(id int) error {
+	if _, ok := s.schedules[id]; !ok {
+		return fmt.Errorf("no scheduled payment %d", id)
+	}
+	delete(s.schedules, id)
+	return nil
+}
+
+// List returns the scheduled payments, soonest first.
+func (s *Scheduler) List() []ScheduledTx {
+	list := make([]ScheduledTx, 0, len(s.schedules))
+	for _, tx := range s.schedules {
+		list = append(list, *tx)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Next.Equal(list[j].Next) {
+			return list[i].Next.Before(list[j].Next)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Run submits every payment due at or before now, oldest due time first,
+// and returns the transactions it added to the pool. A recurring payment
+// that fell due several times since the last Run is made once for each.
+// Each transaction is dated when it fell due and carries its sender's next
+// nonce after whatever is already pooled. If the pool rejects one, Run
+// stops and returns the error; that payment stays due and is retried on
+// the next Run.
+func (s *Scheduler) Run(now time.Time) ([]Transaction, error) {
+	var added []Transaction
+	for {
+		due := s.List()
+		if len(due) == 0 || due[0].Next.After(now) {
+			return added, nil
+		}
+		st := s.schedules[due[0].ID]
+		tx, err := NewTxBuilder().
+			From(st.From).To(st.To).Amount(st.Amount).Fee(st.Fee).
+			OnChain(s.pool.chain).Nonce(s.pool.PendingNonce(st.From)).
+			Time(st.Next).Description(st.Description).Category(st.Category).
+			SignWith(st.key).
+			Build()
+		if err == nil {
+			err = s.pool.Add(tx)
+		}
+		if err != nil {
+			return added, fmt.Errorf("scheduled payment %d: %w", st.ID, err)
+		}
+		added = append(added, tx)
+
+		switch {
+		case st.Every == 0 || st.Remaining == 1:
+			delete(s.schedules, st.ID)
+		case st.Remaining > 1:
+			st.Remaining--
+			fallthrough
+		default:
+			st.Next = st.Next.Add(st.Every)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedulerMakesDuePayments(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	m := NewMempool(c)
+	defer m.Close()
+	s := NewScheduler(m)
+
+	rent, err := s.Schedule(ScheduledTx{From: alice.Addr, To: bob.Addr, Amount: Coin, Description: "rent", Next: testStart, Every: time.Hour, Remaining: 3}, alice.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Schedule(ScheduledTx{From: alice.Addr, To: carol.Addr, Amount: 2 * Coin, Next: testStart.Add(30 * time.Minute)}, alice.Key); err != nil {
+		t.Fatal(err)
+	}
+
+	if added, err := s.Run(testStart.Add(-time.Second)); err != nil || len(added) != 0 {
+		t.Fatalf("before anything is due: %d added, err %v", len(added), err)
+	}
+	added, err := s.Run(testStart.Add(90 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		to string
+		at time.Duration
+	}{{bob.Addr, 0}, {carol.Addr, 30 * time.Minute}, {bob.Addr, time.Hour}}
+	if len(added) != len(want) {
+		t.Fatalf("%d payments made, want %d", len(added), len(want))
+	}
+	for i, w := range want {
+		tx := added[i]
+		if tx.To != w.to || !tx.Time.Equal(testStart.Add(w.at)) || tx.Nonce != uint64(i) {
+			t.Errorf("payment %d: to %s at %v nonce %d; want to %s at +%v nonce %d", i, tx.To, tx.Time, tx.Nonce, w.to, w.at, i)
+		}
+		if err := VerifyTxSignature(tx); err != nil {
+			t.Errorf("payment %d: %v", i, err)
+		}
+	}
+	if m.Len() != 3 {
+		t.Errorf("pool holds %d transactions, want 3", m.Len())
+	}
+
+	list := s.List()
+	if len(list) != 1 || list[0].ID != rent || list[0].Remaining != 1 || !list[0].Next.Equal(testStart.Add(2*time.Hour)) {
+		t.Fatalf("left scheduled: %+v", list)
+	}
+	if added, _ := s.Run(testStart.Add(10 * time.Hour)); len(added) != 1 {
+		t.Errorf("last rent payment: %d made, want 1", len(added))
+	}
+	if len(s.List()) != 0 {
+		t.Error("a finished schedule is still listed")
+	}
+}
+
+func TestSchedulerRetriesRejectedPayments(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: Coin}))
+	m := NewMempool(c)
+	defer m.Close()
+	s := NewScheduler(m)
+	id, err := s.Schedule(ScheduledTx{From: alice.Addr, To: bob.Addr, Amount: 5 * Coin, Next: testStart}, alice.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Run(testStart); err == nil {
+		t.Fatal("an unaffordable payment was submitted")
+	}
+	if list := s.List(); len(list) != 1 || list[0].ID != id {
+		t.Errorf("rejected payment no longer due: %+v", list)
+	}
+	if err := s.Cancel(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Cancel(id); err == nil {
+		t.Error("cancelled the same payment twice")
+	}
+	if added, err := s.Run(testStart); err != nil || len(added) != 0 {
+		t.Errorf("after cancelling: %d added, err %v", len(added), err)
+	}
+}
+
+func TestScheduleValidates(t *testing.T) {
+	s := NewScheduler(nil)
+	ok := ScheduledTx{From: "a", To: "b", Amount: Coin, Next: testStart}
+	for name, mod := range map[string]func(*ScheduledTx){
+		"no sender":      func(tx *ScheduledTx) { tx.From = "" },
+		"no recipient":   func(tx *ScheduledTx) { tx.To = "" },
+		"zero amount":    func(tx *ScheduledTx) { tx.Amount = 0 },
+		"negative fee":   func(tx *ScheduledTx) { tx.Fee = -1 },
+		"no due time":    func(tx *ScheduledTx) { tx.Next = time.Time{} },
+		"negative every": func(tx *ScheduledTx) { tx.Every = -time.Hour },
+		"negative count": func(tx *ScheduledTx) { tx.Remaining = -1 },
+	} {
+		tx := ok
+		mod(&tx)
+		if _, err := s.Schedule(tx, nil); err == nil {
+			t.Errorf("%s: scheduled", name)
+		}
+	}
+	if len(s.List()) != 0 {
+		t.Error("a rejected payment was listed")
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPeerPort is the port assumed for seed addresses that come from A
+// and AAAA records, which carry no port.
+const DefaultPeerPort = 9333
+
+// maxTXTString is the longest character-string a TXT record can hold.
+const maxTXTString = 255
+
+// SeedResolver looks up a DNS seed. *net.Resolver implements it; tests use
+// a fake.
+type SeedResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// LookupSeed resolves a DNS seed to peer addresses. TXT records list
+// host:port entries separated by spaces; A and AAAA records give hosts on
+// port. Either kind is enough: it fails only if neither lookup returns an
+// address.
+func LookupSeed(ctx context.Context, r SeedResolver, name string, port int) ([]string, error) {
+	seen := make(map[string]bool)
+	var addrs []string
+	add := func(addr string) {
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+
+	txts, txtErr := r.LookupTXT(ctx, name)
+	for _, txt := range txts {
+		for _, field := range strings.Fields(txt) {
+			if err := checkPeerAddr(field); err != nil {
+				return nil, fmt.Errorf("seed %s: TXT record: %w", name, err)
+			}
+			add(field)
+		}
+	}
+	hosts, hostErr := r.LookupHost(ctx, name)
+	for _, host := range hosts {
+		add(net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+
+	if len(addrs) == 0 {
+		err := hostErr
+		if err == nil {
+			err = txtErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("seed %s: %w", name, err)
+		}
+		return nil, fmt.Errorf("seed %s: no addresses", name)
+	}
+	return addrs, nil
+}
+
+// checkPeerAddr checks that addr is a host and a port from 1 to 65535.
+func checkPeerAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("%q has no host", addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q has an invalid port", addr)
+	}
+	return nil
+}
+
+// AddSeeds resolves each seed and adds what it returns to m, with the seed
+// as the source, so one seed's addresses only compete with each other for
+// buckets. It returns how many addresses the seeds gave and fails only if
+// every seed did.
+func (m *AddrManager) AddSeeds(ctx context.Context, r SeedResolver, seeds []string, port int) (int, error) {
+	var n int
+	var errs []error
+	for _, seed := range seeds {
+		addrs, err := LookupSeed(ctx, r, seed, port)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.Add(addrs, "dns:"+seed)
+		n += len(addrs)
+	}
+	if n == 0 && len(errs) > 0 {
+		return 0, errors.Join(errs...)
+	}
+	return n, nil
+}
+
+// SelectN returns up to n distinct addresses drawn with Select, for a
+// seeder to hand out.
+func (m *AddrManager) SelectN(n int) []string {
+	if known := m.Len(); n > known {
+		n = known
+	}
+	seen := make(map[string]bool, n)
+	var addrs []string
+	for tries := 0; len(addrs) < n && tries < 8*n; tries++ {
+		p, ok := m.Select()
+		if !ok {
+			break
+		}
+		if !seen[p.Addr] {
+			seen[p.Addr] = true
+			addrs = append(addrs, p.Addr)
+		}
+	}
+	return addrs
+}
+
+// Seeder serves addresses from an address manager over HTTP, n at a time:
+// GET /seeds returns them one per line.
+type Seeder struct {
+	Addrs *AddrManager
+	N     int
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Seeder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || r.URL.Path != "/seeds" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, addr := range s.Addrs.SelectN(s.N) {
+		fmt.Fprintln(w, addr)
+	}
+}
+
+// WriteSeedZone writes DNS records for name listing addrs, in zone file
+// syntax for an authoritative server to load: an A or AAAA record for each
+// IP address on DefaultPeerPort, and TXT records holding the rest as
+// host:port, which is the format LookupSeed reads.
+func WriteSeedZone(w io.Writer, name string, addrs []string, ttl time.Duration) error {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	secs := int(ttl / time.Second)
+	var txt []string
+	for _, addr := range addrs {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || port != strconv.Itoa(DefaultPeerPort) {
+			txt = append(txt, addr)
+			continue
+		}
+		kind := "AAAA"
+		if ip.To4() != nil {
+			kind = "A"
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%d\tIN\t%s\t%s\n", name, secs, kind, ip); err != nil {
+			return err
+		}
+	}
+	for len(txt) > 0 {
+		line := txt[0]
+		txt = txt[1:]
+		for len(txt) > 0 && len(line)+1+len(txt[0]) <= maxTXTString {
+			line += " " + txt[0]
+			txt = txt[1:]
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%d\tIN\tTXT\t%q\n", name, secs, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runSeed implements the "seed" command:
+//
+//	seed lookup -name seed.example.org [-port 9333] [-addrs peers.json]
+//	seed serve -addrs peers.json [-addr :9334] [-n 25]
+//	seed zone -addrs peers.json -name seed.example.org [-n 25] [-ttl 1m]
+//
+// lookup resolves a DNS seed and prints its addresses, adding them to the
+// address file if one is given. serve and zone hand out addresses from an
+// address file: over HTTP, or as records for a DNS server.
+func runSeed(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: seed lookup|serve|zone [flags]")
+	}
+	fs := flag.NewFlagSet("seed "+args[0], flag.ContinueOnError)
+	addrsPath := fs.String("addrs", "", "address manager file")
+	switch args[0] {
+	case "lookup":
+		name := fs.String("name", "", "DNS seed to resolve")
+		port := fs.Int("port", DefaultPeerPort, "port for addresses from A and AAAA records")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New("seed lookup needs -name")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		addrs, err := LookupSeed(ctx, net.DefaultResolver, *name, *port)
+		if err != nil {
+			return err
+		}
+		for _, addr := range addrs {
+			fmt.Println(addr)
+		}
+		if *addrsPath == "" {
+			return nil
+		}
+		m, err := LoadAddrManager(*addrsPath)
+		if errors.Is(err, os.ErrNotExist) {
+			m, err = NewAddrManager()
+		}
+		if err != nil {
+			return err
+		}
+		m.Add(addrs, "dns:"+*name)
+		return m.Save(*addrsPath)
+
+	case "serve":
+		addr := fs.String("addr", ":9334", "address to listen on")
+		n := fs.Int("n", 25, "addresses per response")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		m, err := LoadAddrManager(*addrsPath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Serving %d known addresses on %s (GET /seeds)\n", m.Len(), *addr)
+		return http.ListenAndServe(*addr, &Seeder{Addrs: m, N: *n})
+
+	case "zone":
+		name := fs.String("name", "", "DNS name the records are for")
+		n := fs.Int("n", 25, "addresses to list")
+		ttl := fs.Duration("ttl", time.Minute, "record TTL")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New("seed zone needs -name")
+		}
+		m, err := LoadAddrManager(*addrsPath)
+		if err != nil {
+			return err
+		}
+		return WriteSeedZone(os.Stdout, *name, m.SelectN(*n), *ttl)
+
+	default:
+		return fmt.Errorf("unknown seed command %q (want lookup, serve, or zone)", args[0])
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeResolver answers from fixed records, or fails for names it lacks.
+type fakeResolver struct {
+	txt   map[string][]string
+	hosts map[string][]string
+}
+
+var errNoSuchHost = errors.New("no such host")
+
+func (r fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if txt, ok := r.txt[name]; ok {
+		return txt, nil
+	}
+	return nil, errNoSuchHost
+}
+
+func (r fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if hosts, ok := r.hosts[host]; ok {
+		return hosts, nil
+	}
+	return nil, errNoSuchHost
+}
+
+func TestLookupSeed(t *testing.T) {
+	r := fakeResolver{
+		txt:   map[string][]string{"seed.test": {"10.0.0.1:9444 node.example.org:9333", "[2001:db8::1]:9555"}},
+		hosts: map[string][]string{"seed.test": {"10.0.0.2", "10.0.0.1"}, "hosts.test": {"10.0.0.3"}},
+	}
+	got, err := LookupSeed(context.Background(), r, "seed.test", 9333)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1:9444", "node.example.org:9333", "[2001:db8::1]:9555", "10.0.0.2:9333", "10.0.0.1:9333"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// A records alone are enough.
+	if got, err := LookupSeed(context.Background(), r, "hosts.test", 9333); err != nil || len(got) != 1 {
+		t.Errorf("A records only: %v, %v", got, err)
+	}
+	if _, err := LookupSeed(context.Background(), r, "missing.test", 9333); !errors.Is(err, errNoSuchHost) {
+		t.Errorf("missing seed: err = %v", err)
+	}
+	bad := fakeResolver{txt: map[string][]string{"bad.test": {"10.0.0.1"}}}
+	if _, err := LookupSeed(context.Background(), bad, "bad.test", 9333); err == nil {
+		t.Error("TXT entry without a port was accepted")
+	}
+}
+
+func TestAddSeeds(t *testing.T) {
+	r := fakeResolver{hosts: map[string][]string{"a.test": {"10.0.0.1", "10.1.0.1"}}}
+	m := newAddrManager([32]byte{1})
+	n, err := m.AddSeeds(context.Background(), r, []string{"missing.test", "a.test"}, 9333)
+	if err != nil || n != 2 || m.Len() != 2 {
+		t.Errorf("AddSeeds = %d, %v; %d known", n, err, m.Len())
+	}
+	if _, err := m.AddSeeds(context.Background(), r, []string{"missing.test"}, 9333); err == nil {
+		t.Error("no seed resolved, but no error")
+	}
+}
+
+// TestSeedZoneRoundTrip checks that the records a seeder writes resolve to
+// the addresses it was given.
+func TestSeedZoneRoundTrip(t *testing.T) {
+	addrs := []string{"10.0.0.1:9333", "[2001:db8::1]:9333", "10.0.0.2:9444", "node.example.org:9333"}
+	for i := 0; i < 30; i++ { // enough to need two TXT strings
+		addrs = append(addrs, fmt.Sprintf("peer%d.example.org:%d", i, 9400+i))
+	}
+	var zone strings.Builder
+	if err := WriteSeedZone(&zone, "seed.test", addrs, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	r := fakeResolver{txt: map[string][]string{}, hosts: map[string][]string{}}
+	sc := bufio.NewScanner(strings.NewReader(zone.String()))
+	for sc.Scan() {
+		f := strings.SplitN(sc.Text(), "\t", 5)
+		if f[0] != "seed.test." || f[1] != "60" {
+			t.Fatalf("record %q", sc.Text())
+		}
+		switch f[3] {
+		case "A", "AAAA":
+			r.hosts["seed.test"] = append(r.hosts["seed.test"], f[4])
+		case "TXT":
+			txt := strings.Trim(f[4], `"`)
+			if len(txt) > maxTXTString {
+				t.Errorf("TXT string of %d bytes", len(txt))
+			}
+			r.txt["seed.test"] = append(r.txt["seed.test"], txt)
+		}
+	}
+	got, err := LookupSeed(context.Background(), r, "seed.test", DefaultPeerPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.txt["seed.test"]) < 2 {
+		t.Error("expected the TXT entries to be split across strings")
+	}
+	seen := make(map[string]bool)
+	for _, addr := range got {
+		seen[addr] = true
+	}
+	for _, addr := range addrs {
+		if !seen[addr] {
+			t.Errorf("%s missing after the round trip", addr)
+		}
+	}
+}
+
+func TestSeederServesKnownAddresses(t *testing.T) {
+	m := newAddrManager([32]byte{1})
+	known := []string{"10.0.0.1:9333", "10.1.0.1:9333", "10.2.0.1:9333"}
+	m.Add(known, "test")
+
+	rec := httptest.NewRecorder()
+	(&Seeder{Addrs: m, N: 2}).ServeHTTP(rec, httptest.NewRequest("GET", "/seeds", nil))
+	lines := strings.Fields(rec.Body.String())
+	if len(lines) != 2 || lines[0] == lines[1] {
+		t.Fatalf("served %q, want two distinct addresses", lines)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "10.") {
+			t.Errorf("served unknown address %q", line)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	(&Seeder{Addrs: m, N: 2}).ServeHTTP(rec, httptest.NewRequest("GET", "/other", nil))
+	if rec.Code != 404 {
+		t.Errorf("GET /other: %d", rec.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBadSignature is returned for a transaction that isn't signed by the
+// key its From address is derived from.
+var ErrBadSignature = errors.New("invalid transaction signature")
+
+// txDigest is what a transaction's signature covers: the bytes of its
+// computed hash, which commits to every field except PubKey and Signature.
+func txDigest(tx Transaction) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(computeTxHash(tx), "0x"))
+}
+
+// SignDeterministic signs digest, a SHA-256 hash, with key and returns the
+// ASN.1 signature. The nonce is derived from the key and digest as RFC 6979
+// describes instead of drawn at random, so the same key and digest always
+// give the same signature, and a weak random source at signing time can't
+// leak the key. Every signature this chain makes goes through it.
+func SignDeterministic(key *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	return key.Sign(nil, digest, crypto.SHA256)
+}
+
+// SignTx recomputes tx's hash and signs it with key, filling in PubKey and
+// Signature. key must be the one tx.From is derived from; see
+// AddressFromPubKey.
+func SignTx(tx *Transaction, key *ecdsa.PrivateKey) error {
+	signer, err := AddressFromPubKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	if from, err := ParseAddress(tx.From); err != nil || from != signer {
+		return fmt.Errorf("key for %s can't sign for %s", signer, tx.From)
+	}
+	digest, err := txDigest(*tx)
+	if err != nil {
+		return err
+	}
+	sig, err := SignDeterministic(key, digest)
+	if err != nil {
+		return err
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return err
+	}
+	tx.Hash = computeTxHash(*tx)
+	tx.PubKey, tx.Signature = pub, sig
+	return nil
+}
+
+// VerifyTxSignature checks that tx carries a valid signature over its hash
+// by the key in PubKey, and that From is that key's address. For a multisig
+// transaction it checks Cosigs against the policy instead, for an escrow
+// settlement that the key is one of the escrow's parties, and for a
+// reversal only the signature, since whose key it must be is a chain rule.
+func VerifyTxSignature(tx Transaction) error {
+	if got := computeTxHash(tx); got != tx.Hash {
+		return fmt.Errorf("tx %s: hash does not match computed %s", tx.Hash, got)
+	}
+	switch {
+	case tx.Multisig != nil && tx.Escrow != nil:
+		return fmt.Errorf("%w: tx %s has both a multisig policy and escrow terms", ErrBadSignature, tx.Hash)
+	case tx.Multisig != nil:
+		return verifyMultisig(tx)
+	case tx.Escrow != nil:
+		return verifyEscrow(tx)
+	case tx.Reverses != "":
+		_, err := txSigner(tx)
+		return err
+	}
+	signer, err := txSigner(tx)
+	if err != nil {
+		return err
+	}
+	if from, err := ParseAddress(tx.From); err != nil || from != signer {
+		return fmt.Errorf("%w: tx %s: key belongs to %s, not %s", ErrBadSignature, tx.Hash, signer, tx.From)
+	}
+	return nil
+}
+
+// txSigner checks that tx's Signature is valid for its PubKey and returns
+// the key's address. A transaction with no PubKey carries a recoverable
+// signature instead, and the key is recovered from it.
+func txSigner(tx Transaction) (Address, error) {
+	if len(tx.Signature) == 0 {
+		return Address{}, fmt.Errorf("%w: tx %s is unsigned", ErrBadSignature, tx.Hash)
+	}
+	digest, err := txDigest(tx)
+	if err != nil {
+		return Address{}, err
+	}
+	if len(tx.PubKey) == 0 {
+		pub, err := RecoverPubKey(digest, tx.Signature)
+		if err != nil {
+			return Address{}, fmt.Errorf("%w: tx %s: %v", ErrBadSignature, tx.Hash, err)
+		}
+		return AddressFromPubKey(pub)
+	}
+	pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), tx.PubKey)
+	if err != nil {
+		return Address{}, fmt.Errorf("%w: tx %s: public key: %v", ErrBadSignature, tx.Hash, err)
+	}
+	if !ecdsa.VerifyASN1(pub, digest, tx.Signature) {
+		return Address{}, fmt.Errorf("%w: tx %s", ErrBadSignature, tx.Hash)
+	}
+	return AddressFromPubKey(pub)
+}
+
+// checkSignature requires every transaction to be signed by its sender,
+// from BlockVersion4 on, and allows recoverable signatures without a
+// public key from BlockVersion6 on.
+func checkSignature(ctx *TxContext, tx Transaction) error {
+	if ctx.Version < BlockVersion4 {
+		return nil
+	}
+	if len(tx.PubKey) == 0 && len(tx.Signature) > 0 && ctx.Version < BlockVersion6 {
+		return fmt.Errorf("%w: tx %s: signatures without a public key need header version %d", ErrBadSignature, tx.Hash, BlockVersion6)
+	}
+	return VerifyTxSignature(tx)
+}
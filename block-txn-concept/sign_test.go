@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestSignatureRules covers the ways to spend someone else's coins with a
+// transaction that isn't theirs: leaving it unsigned, signing with another
+// key, and changing a signed transaction.
+func TestSignatureRules(t *testing.T) {
+	alice, bob, mallory := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+
+	unsigned := tx
+	unsigned.PubKey, unsigned.Signature = nil, nil
+	if err := VerifyTxSignature(unsigned); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("unsigned: err = %v, want ErrBadSignature", err)
+	}
+
+	// Mallory's own key and a valid signature by it, over a transaction
+	// from alice.
+	forged := tx
+	forged.To = mallory.Addr
+	forged.Hash = computeTxHash(forged)
+	digest, err := txDigest(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forged.Signature, err = SignDeterministic(mallory.Key, digest); err != nil {
+		t.Fatal(err)
+	}
+	if forged.PubKey, err = mallory.Key.PublicKey.Bytes(); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTxSignature(forged); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("mallory's key: err = %v, want ErrBadSignature", err)
+	}
+	if err := SignTx(&forged, mallory.Key); err == nil {
+		t.Error("SignTx signed for another address")
+	}
+
+	// Alice's signature, moved onto a transaction paying mallory.
+	tampered := tx
+	tampered.To = mallory.Addr
+	tampered.Hash = computeTxHash(tampered)
+	if err := VerifyTxSignature(tampered); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered: err = %v, want ErrBadSignature", err)
+	}
+
+	for name, bad := range map[string]Transaction{"unsigned": unsigned, "forged": forged, "tampered": tampered} {
+		if err := mineTxs(t, c, bad); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s block: err = %v, want ErrBadSignature", name, err)
+		}
+	}
+	if err := mineTxs(t, c, tx); err != nil {
+		t.Fatalf("signed by alice: %v", err)
+	}
+}
+
+// TestLowVersionBlocksCantSkipSignatures builds blocks at each version
+// from before signatures on chains that never scheduled them, carrying an
+// unsigned overspend from alice's account.
+func TestLowVersionBlocksCantSkipSignatures(t *testing.T) {
+	alice, mallory := newTestAccount(t), newTestAccount(t)
+	for name, upgrades := range map[string][]Upgrade{
+		"no schedule":      nil,
+		"after an upgrade": {{BlockVersion4, 0}},
+	} {
+		cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+		cfg.Upgrades = upgrades
+		c := newTestChain(t, cfg)
+		overspend := signedTx(t, c, alice, Transaction{To: mallory.Addr, Amount: 1000 * Coin})
+		overspend.PubKey, overspend.Signature = nil, nil
+		// The ledger checks balances itself; it has to refuse even an
+		// affordable unsigned transaction.
+		affordable := signedTx(t, c, alice, Transaction{To: mallory.Addr, Amount: Coin})
+		affordable.PubKey, affordable.Signature = nil, nil
+		for _, version := range []uint32{LegacyBlockVersion, BlockVersion1, BlockVersion2, BlockVersion3} {
+			b, err := c.buildBlock("", []Transaction{overspend}, version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.AddBlock(b); err == nil {
+				t.Errorf("%s: version %d block with an unsigned overspend accepted", name, version)
+			}
+			if b, err = c.buildBlock("", []Transaction{affordable}, version); err != nil {
+				t.Fatal(err)
+			}
+			l := NewLedger()
+			if err := l.ApplyBlock(c, c.Tip()); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.ApplyBlock(c, b); err == nil {
+				t.Errorf("%s: ledger applied a version %d block with an unsigned transaction", name, version)
+			}
+		}
+		if got := tipBalance(t, c, mallory.Addr); got != 0 {
+			t.Errorf("%s: mallory has %v", name, got)
+		}
+	}
+
+	// Only a chain that asks for the legacy rules gets them.
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	cfg.Upgrades = LegacyUpgrades
+	c := newTestChain(t, cfg)
+	tx := signedTx(t, c, alice, Transaction{To: mallory.Addr, Amount: Coin})
+	tx.PubKey, tx.Signature = nil, nil
+	b, err := c.buildBlock("", []Transaction{tx}, BlockVersion3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Errorf("version 3 block on a legacy chain: %v", err)
+	}
+}
+
+// RFC 6979's P-256 key, A.2.5, and its SHA-256 signature of "sample".
+const (
+	rfc6979KeyHex  = "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721"
+	rfc6979SampleR = "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716"
+	rfc6979SampleS = "F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"
+)
+
+func rfc6979Key(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	d, err := hex.DecodeString(rfc6979KeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSignDeterministicVectors(t *testing.T) {
+	key := rfc6979Key(t)
+	for _, tc := range []struct{ msg, r, s string }{
+		{"sample", rfc6979SampleR, rfc6979SampleS},
+		{"test", "F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367", "019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083"},
+	} {
+		digest := sha256.Sum256([]byte(tc.msg))
+		der, err := SignDeterministic(key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := ASN1ToCompact(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := hex.EncodeToString(sig), strings.ToLower(tc.r+tc.s); got != want {
+			t.Errorf("%q: r || s = %s, want %s", tc.msg, got, want)
+		}
+	}
+}
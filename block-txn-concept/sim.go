@@ -0,0 +1,371 @@
+package main
+
+import (
+	"container/heap"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SimConfig describes a simulated network. All times are simulated, so
+// hours of network activity take seconds to run.
+type SimConfig struct {
+	Nodes         int
+	BlockInterval time.Duration // mean time between blocks across the whole network
+	Latency       time.Duration // one-way delay for every block message
+	Jitter        time.Duration // extra random delay, uniform in [0, Jitter)
+	Duration      time.Duration // how long nodes keep mining
+	Partitions    []Partition
+	ForkChoice    ForkChoiceRule
+	MaxUncles     int
+	Seed          uint64
+}
+
+// Partition cuts the nodes in Isolated off from everyone else between Start
+// and End. Messages that would cross the cut are held and delivered once it
+// heals, the way reconnecting peers catch up on what they missed.
+type Partition struct {
+	Start, End time.Duration
+	Isolated   []int
+}
+
+// SimReport summarises a simulation run.
+type SimReport struct {
+	Mined       int     // blocks mined by all nodes
+	Height      int     // best-chain height once every message is delivered
+	StaleBlocks int     // mined blocks that ended up off the best chain
+	ForkRate    float64 // StaleBlocks / Mined
+	Reorgs      int     // reorgs summed over all nodes
+	MaxReorg    int     // deepest reorg any node saw, in blocks
+
+	// Disagreement is the total simulated time during which nodes had
+	// different tips; LongestSplit is the longest single such stretch.
+	Disagreement time.Duration
+	LongestSplit time.Duration
+
+	// HealTimes holds, for each partition, how long after it healed all
+	// nodes agreed on a tip again, or -1 if they never did.
+	HealTimes []time.Duration
+
+	Converged bool // all nodes finished on the same tip
+}
+
+// simEpoch is the wall-clock time that simulated time zero maps to, so block
+// timestamps and hashes are the same on every run with the same seed.
+var simEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type simNode struct {
+	id      int
+	chain   *Chain
+	pending []Block // received blocks whose parent or uncles haven't arrived
+}
+
+type simEvent struct {
+	at    time.Duration
+	seq   int  // tie-breaker so equal times run in scheduling order
+	mine  bool // a mining event; otherwise a delivery
+	node  int
+	block Block
+}
+
+type simQueue []simEvent
+
+func (q simQueue) Len() int { return len(q) }
+func (q simQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+func (q simQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *simQueue) Push(x any)   { *q = append(*q, x.(simEvent)) }
+func (q *simQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// Simulate runs cfg.Nodes in-process nodes, each with its own Chain, as a
+// discrete-event simulation. Every node has equal hash power, so blocks
+// arrive as a Poisson process at the network-wide rate and each is mined by
+// a random node on its current tip, then broadcast to the others with the
+// configured latency. Blocks are sealed with FakePoWEngine, so only the
+// timing is simulated, not the grinding.
+func Simulate(cfg SimConfig) (SimReport, error) {
+	if cfg.Nodes < 1 {
+		return SimReport{}, errors.New("simulation needs at least one node")
+	}
+	if cfg.BlockInterval <= 0 || cfg.Duration <= 0 {
+		return SimReport{}, errors.New("block interval and duration must be positive")
+	}
+	for _, p := range cfg.Partitions {
+		if p.End <= p.Start {
+			return SimReport{}, fmt.Errorf("partition %v-%v ends before it starts", p.Start, p.End)
+		}
+		for _, id := range p.Isolated {
+			if id < 0 || id >= cfg.Nodes {
+				return SimReport{}, fmt.Errorf("partition names node %d, but there are only %d", id, cfg.Nodes)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x5eed))
+	var now time.Duration
+	clock := func() time.Time { return simEpoch.Add(now) }
+
+	engine := &FakePoWEngine{Seed: cfg.Seed}
+	genesis := NewGenesisBlock(BitsForLeadingZeros(1))
+	genesis.Timestamp = simEpoch
+	if err := engine.Seal(&genesis); err != nil {
+		return SimReport{}, err
+	}
+
+	var report SimReport
+	nodes := make([]*simNode, cfg.Nodes)
+	for i := range nodes {
+		chain, err := NewChain(ChainConfig{
+			Engine:     engine,
+			ForkChoice: cfg.ForkChoice,
+			MaxUncles:  cfg.MaxUncles,
+			Clock:      clock,
+		}, genesis)
+		if err != nil {
+			return SimReport{}, err
+		}
+		chain.Subscribe(ReorgEvent, func(e Event) {
+			report.Reorgs++
+			report.MaxReorg = max(report.MaxReorg, len(e.Disconnected))
+		})
+		nodes[i] = &simNode{id: i, chain: chain}
+	}
+
+	var queue simQueue
+	seq := 0
+	schedule := func(e simEvent) {
+		seq++
+		e.seq = seq
+		heap.Push(&queue, e)
+	}
+	nextMine := func() {
+		wait := time.Duration(rng.ExpFloat64() * float64(cfg.BlockInterval))
+		if now+wait < cfg.Duration {
+			schedule(simEvent{at: now + wait, mine: true, node: rng.IntN(cfg.Nodes)})
+		}
+	}
+	broadcast := func(from int, b Block) {
+		for to := range nodes {
+			if to == from {
+				continue
+			}
+			at := now + cfg.Latency
+			if cfg.Jitter > 0 {
+				at += time.Duration(rng.Int64N(int64(cfg.Jitter)))
+			}
+			for _, p := range cfg.Partitions {
+				if now < p.End && at >= p.Start && p.separates(from, to) {
+					at = max(at, p.End+cfg.Latency)
+				}
+			}
+			schedule(simEvent{at: at, node: to, block: b})
+		}
+	}
+
+	// Track how long the nodes' tips disagree.
+	agreed := true
+	var splitStart time.Duration
+	healed := make([]bool, len(cfg.Partitions))
+	report.HealTimes = make([]time.Duration, len(cfg.Partitions))
+	for i := range report.HealTimes {
+		report.HealTimes[i] = -1
+	}
+	observe := func() {
+		same := true
+		for _, n := range nodes[1:] {
+			if n.chain.tip.Hash != nodes[0].chain.tip.Hash {
+				same = false
+				break
+			}
+		}
+		switch {
+		case agreed && !same:
+			splitStart = now
+		case !agreed && same:
+			split := now - splitStart
+			report.Disagreement += split
+			report.LongestSplit = max(report.LongestSplit, split)
+		}
+		agreed = same
+		if same {
+			for i, p := range cfg.Partitions {
+				if !healed[i] && now >= p.End {
+					healed[i] = true
+					report.HealTimes[i] = now - p.End
+				}
+			}
+		}
+	}
+
+	nextMine()
+	for queue.Len() > 0 {
+		e := heap.Pop(&queue).(simEvent)
+		now = e.at
+		n := nodes[e.node]
+		if e.mine {
+			b, err := n.chain.BuildBlock(fmt.Sprintf("node-%d", n.id), nil)
+			if err != nil {
+				return SimReport{}, err
+			}
+			if err := n.chain.AddBlock(b); err != nil {
+				return SimReport{}, fmt.Errorf("node %d rejected its own block: %w", n.id, err)
+			}
+			report.Mined++
+			broadcast(n.id, b)
+			nextMine()
+		} else {
+			n.receive(e.block)
+		}
+		observe()
+	}
+
+	if !agreed {
+		split := now - splitStart
+		report.Disagreement += split
+		report.LongestSplit = max(report.LongestSplit, split)
+	}
+	report.Converged = agreed
+	report.Height = nodes[0].chain.tip.Index
+	report.StaleBlocks = report.Mined - report.Height
+	if report.Mined > 0 {
+		report.ForkRate = float64(report.StaleBlocks) / float64(report.Mined)
+	}
+	return report, nil
+}
+
+// separates reports whether a and b are on opposite sides of the partition.
+func (p Partition) separates(a, b int) bool {
+	inA, inB := false, false
+	for _, id := range p.Isolated {
+		inA = inA || id == a
+		inB = inB || id == b
+	}
+	return inA != inB
+}
+
+// receive adds b to the node's chain, parking it until its parent and uncles
+// arrive if they haven't yet. Each successful add retries the parked blocks.
+func (n *simNode) receive(b Block) {
+	if _, ok := n.chain.blocks[b.Hash]; ok {
+		return
+	}
+	n.pending = append(n.pending, b)
+	for progress := true; progress; {
+		progress = false
+		remaining := n.pending[:0]
+		for _, p := range n.pending {
+			if n.chain.AddBlock(p) == nil {
+				progress = true
+			} else if _, ok := n.chain.blocks[p.Hash]; !ok {
+				remaining = append(remaining, p)
+			}
+		}
+		n.pending = remaining
+	}
+}
+
+// runSimulate implements the "simulate" command.
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	nodes := fs.Int("nodes", 8, "number of nodes")
+	interval := fs.Duration("interval", 15*time.Second, "mean block interval across the network")
+	latency := fs.Duration("latency", 2*time.Second, "one-way message latency")
+	jitter := fs.Duration("jitter", time.Second, "extra random latency")
+	duration := fs.Duration("duration", 2*time.Hour, "simulated mining time")
+	fork := fs.String("fork", "", "fork choice rule: work, longest, or ghost (default: compare all three)")
+	uncles := fs.Int("uncles", 2, "max uncles per block")
+	seed := fs.Uint64("seed", 1, "random seed")
+	var partitions partitionFlag
+	fs.Var(&partitions, "partition", "start-end:node,node,... cuts those nodes off, e.g. 1h-2h:0,1,2 (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := SimConfig{
+		Nodes:         *nodes,
+		BlockInterval: *interval,
+		Latency:       *latency,
+		Jitter:        *jitter,
+		Duration:      *duration,
+		Partitions:    partitions,
+		MaxUncles:     *uncles,
+		Seed:          *seed,
+	}
+	rules := []ForkChoiceRule{MostWork, LongestChain, GHOST}
+	if *fork != "" {
+		rules = []ForkChoiceRule{ForkChoiceRule(*fork)}
+	}
+
+	fmt.Printf("%d nodes, %v blocks, %v latency (+%v jitter), %v simulated, seed %d\n\n",
+		cfg.Nodes, cfg.BlockInterval, cfg.Latency, cfg.Jitter, cfg.Duration, cfg.Seed)
+	fmt.Printf("%-8s %6s %6s %6s %9s %7s %9s %13s %13s\n",
+		"rule", "mined", "height", "stale", "fork rate", "reorgs", "max reorg", "disagreement", "longest split")
+	for _, rule := range rules {
+		cfg.ForkChoice = rule
+		r, err := Simulate(cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-8s %6d %6d %6d %8.1f%% %7d %9d %13v %13v\n",
+			rule, r.Mined, r.Height, r.StaleBlocks, r.ForkRate*100, r.Reorgs, r.MaxReorg,
+			r.Disagreement.Round(time.Second), r.LongestSplit.Round(time.Second))
+		for i, heal := range r.HealTimes {
+			if heal < 0 {
+				fmt.Printf("         partition %d: never converged\n", i+1)
+			} else {
+				fmt.Printf("         partition %d: converged %v after healing\n", i+1, heal.Round(time.Millisecond))
+			}
+		}
+		if !r.Converged {
+			fmt.Println("         nodes finished on different tips")
+		}
+	}
+	return nil
+}
+
+// partitionFlag parses repeated -partition flags.
+type partitionFlag []Partition
+
+func (f *partitionFlag) String() string { return fmt.Sprint(*f) }
+
+func (f *partitionFlag) Set(s string) error {
+	window, list, ok := strings.Cut(s, ":")
+	if !ok {
+		return errors.New("want start-end:node,node,...")
+	}
+	startStr, endStr, ok := strings.Cut(window, "-")
+	if !ok {
+		return errors.New("want start-end:node,node,...")
+	}
+	start, err := time.ParseDuration(startStr)
+	if err != nil {
+		return err
+	}
+	end, err := time.ParseDuration(endStr)
+	if err != nil {
+		return err
+	}
+	p := Partition{Start: start, End: end}
+	for _, field := range strings.Split(list, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return fmt.Errorf("bad node id %q", field)
+		}
+		p.Isolated = append(p.Isolated, id)
+	}
+	*f = append(*f, p)
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSimulateIsReproducible(t *testing.T) {
+	cfg := SimConfig{Nodes: 4, BlockInterval: 10 * time.Second, Latency: 2 * time.Second, Jitter: time.Second, Duration: 10 * time.Minute, Seed: 7}
+	a, err := Simulate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Simulate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed, different reports:\n%+v\n%+v", a, b)
+	}
+	if a.Mined == 0 || !a.Converged || a.Height+a.StaleBlocks != a.Mined {
+		t.Errorf("report doesn't add up: %+v", a)
+	}
+}
+
+func TestSimulateWithoutLatencyNeverForks(t *testing.T) {
+	r, err := Simulate(SimConfig{Nodes: 3, BlockInterval: 10 * time.Second, Duration: 5 * time.Minute, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.StaleBlocks != 0 || r.Reorgs != 0 || r.Disagreement != 0 || r.Height != r.Mined {
+		t.Errorf("instant delivery still forked: %+v", r)
+	}
+}
+
+func TestSimulatePartitionSplitsThenHeals(t *testing.T) {
+	r, err := Simulate(SimConfig{
+		Nodes: 4, BlockInterval: 10 * time.Second, Latency: 100 * time.Millisecond,
+		Duration:   10 * time.Minute,
+		Partitions: []Partition{{Start: time.Minute, End: 6 * time.Minute, Isolated: []int{0, 1}}},
+		Seed:       3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Converged || len(r.HealTimes) == 0 {
+		t.Errorf("network didn't heal: %+v", r)
+	}
+	if r.LongestSplit < 4*time.Minute || r.MaxReorg == 0 {
+		t.Errorf("a five-minute partition left a %v split and a %d-block reorg", r.LongestSplit, r.MaxReorg)
+	}
+}
+
+func TestSimulateRejectsBadConfig(t *testing.T) {
+	ok := SimConfig{Nodes: 2, BlockInterval: time.Second, Duration: time.Minute}
+	for name, mod := range map[string]func(*SimConfig){
+		"no nodes":      func(c *SimConfig) { c.Nodes = 0 },
+		"no interval":   func(c *SimConfig) { c.BlockInterval = 0 },
+		"no duration":   func(c *SimConfig) { c.Duration = 0 },
+		"backwards":     func(c *SimConfig) { c.Partitions = []Partition{{Start: 2 * time.Second, End: time.Second}} },
+		"unknown node":  func(c *SimConfig) { c.Partitions = []Partition{{End: time.Second, Isolated: []int{2}}} },
+		"negative node": func(c *SimConfig) { c.Partitions = []Partition{{End: time.Second, Isolated: []int{-1}}} },
+	} {
+		cfg := ok
+		mod(&cfg)
+		if _, err := Simulate(cfg); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestPartitionFlag(t *testing.T) {
+	var f partitionFlag
+	if err := f.Set("30s-2m:0, 2"); err != nil {
+		t.Fatal(err)
+	}
+	want := Partition{Start: 30 * time.Second, End: 2 * time.Minute, Isolated: []int{0, 2}}
+	if len(f) != 1 || !reflect.DeepEqual(f[0], want) {
+		t.Errorf("parsed %+v, want %+v", f, want)
+	}
+	for _, bad := range []string{"30s-2m", "30s:0", "x-2m:0", "30s-y:0", "30s-2m:a"} {
+		if err := f.Set(bad); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+	if !want.separates(0, 1) || want.separates(0, 2) || want.separates(1, 3) {
+		t.Error("separates disagrees with Isolated")
+	}
+}
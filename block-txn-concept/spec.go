@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// SpecVersion is bumped whenever the layout of ChainSpec changes.
+const SpecVersion = 1
+
+// ChainSpec is a machine-readable description of a chain's consensus rules,
+// derived from its config and engine. Two nodes with the same spec agree on
+// which blocks are valid and which tip is best; diffing two specs shows
+// where they don't. Node-local settings (pruning, garbage collection, the
+// clock, assume-valid) are left out.
+type ChainSpec struct {
+	SpecVersion     int              `json:"specVersion"`
+	ChainID         string           `json:"chainId"`
+	Genesis         *GenesisSpec     `json:"genesis,omitempty"`
+	ForkID          string           `json:"forkId,omitempty"` // at the tip; see Chain.ForkID
+	Consensus       ConsensusSpec    `json:"consensus"`
+	Target          TargetSpec       `json:"target"`
+	Rewards         RewardSpec       `json:"rewards"`
+	ForkChoice      ForkChoiceSpec   `json:"forkChoice"`
+	Alloc           []AllocSpec      `json:"alloc"`
+	Overdraft       []OverdraftSpec  `json:"overdraft,omitempty"`
+	SpendingLimits  []LimitSpec      `json:"spendingLimits,omitempty"`
+	StatusAuthority string           `json:"statusAuthority,omitempty"` // may freeze, close, and reactivate accounts
+	Activations     []ActivationSpec `json:"activations"`
+}
+
+// GenesisSpec identifies the genesis block.
+type GenesisSpec struct {
+	Hash      string `json:"hash"`
+	Bits      string `json:"bits"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ConsensusSpec describes how blocks are hashed and sealed.
+type ConsensusSpec struct {
+	Engine        string       `json:"engine"`
+	HashAlgorithm string       `json:"hashAlgorithm"`
+	Seal          string       `json:"seal"`
+	Iterations    uint64       `json:"iterations,omitempty"`   // vdf
+	Modulus       string       `json:"modulus,omitempty"`      // vdf, hex
+	StepDuration  string       `json:"stepDuration,omitempty"` // poa
+	Signers       []SignerSpec `json:"signers,omitempty"`      // poa authorities or stake validators
+	Seed          *uint64      `json:"seed,omitempty"`         // fake-pow
+}
+
+// SignerSpec is one key allowed to seal blocks.
+type SignerSpec struct {
+	Address string `json:"address"`
+	PubKey  string `json:"pubKey"` // hex, uncompressed
+}
+
+// TargetSpec describes the proof-of-work target rules.
+type TargetSpec struct {
+	Rule                string `json:"rule"`
+	TargetBlockInterval string `json:"targetBlockInterval"`
+	RetargetInterval    int    `json:"retargetInterval"`
+	MaxRetargetFactor   int    `json:"maxRetargetFactor,omitempty"`
+}
+
+// RewardSpec describes what a block pays and to whom.
+type RewardSpec struct {
+	BlockReward         Amount `json:"blockReward"`
+	Schedule            string `json:"schedule"`
+	Fees                string `json:"fees"`
+	UncleRewardDivisor  int    `json:"uncleRewardDivisor"`
+	NephewRewardDivisor int    `json:"nephewRewardDivisor"`
+	MinFee              Amount `json:"minFee,omitempty"`
+}
+
+// ForkChoiceSpec describes how the best chain is chosen.
+type ForkChoiceSpec struct {
+	Rule           ForkChoiceRule `json:"rule"`
+	MaxUncles      int            `json:"maxUncles"`
+	MaxUncleDepth  int            `json:"maxUncleDepth"`
+	CountUncleWork bool           `json:"countUncleWork"`
+	FinalityDepth  int            `json:"finalityDepth"`
+}
+
+// AllocSpec is one genesis allocation.
+type AllocSpec struct {
+	Address string `json:"address"`
+	Amount  Amount `json:"amount"`
+}
+
+// OverdraftSpec is one sender allowed to spend below zero; see
+// ChainConfig.Overdraft.
+type OverdraftSpec struct {
+	Address string        `json:"address"`
+	Kind    OverdraftKind `json:"kind"`
+	Limit   Amount        `json:"limit,omitempty"`
+}
+
+// LimitSpec is one sender's spending limit; see ChainConfig.SpendingLimits.
+type LimitSpec struct {
+	Address string `json:"address"`
+	Amount  Amount `json:"amount"`
+	Window  string `json:"window"`
+}
+
+// ActivationSpec is the first height at which a rule applies: either a
+// header version from the chain's upgrade schedule, or a rule that is always
+// on, listed at the height it first takes effect under the current config.
+type ActivationSpec struct {
+	Rule   string `json:"rule"`
+	Height int    `json:"height"`
+}
+
+// Spec describes the chain's consensus rules.
+func (c *Chain) Spec() (ChainSpec, error) {
+	cfg := c.config
+	s := ChainSpec{
+		SpecVersion: SpecVersion,
+		ChainID:     cfg.ChainID,
+		Genesis: &GenesisSpec{
+			Hash:      c.genesis.Hash,
+			Bits:      fmt.Sprintf("0x%08x", c.genesis.Bits),
+			Timestamp: c.genesis.Timestamp.UTC().Format(time.RFC3339Nano),
+		},
+		Target: TargetSpec{
+			Rule:                "none",
+			TargetBlockInterval: cfg.TargetBlockInterval.String(),
+			RetargetInterval:    cfg.RetargetInterval,
+		},
+		Rewards: RewardSpec{
+			BlockReward:         cfg.BlockReward,
+			Schedule:            "constant",
+			Fees:                "coinbase",
+			UncleRewardDivisor:  uncleRewardDivisor,
+			NephewRewardDivisor: nephewRewardDivisor,
+			MinFee:              cfg.MinFee,
+		},
+		ForkChoice: ForkChoiceSpec{
+			Rule:           cfg.ForkChoice,
+			MaxUncles:      cfg.MaxUncles,
+			MaxUncleDepth:  cfg.MaxUncleDepth,
+			CountUncleWork: cfg.CountUncleWork,
+			FinalityDepth:  cfg.FinalityDepth,
+		},
+		Alloc:           allocSpec(cfg.Alloc),
+		Overdraft:       overdraftSpec(cfg.Overdraft),
+		SpendingLimits:  limitSpec(cfg.SpendingLimits),
+		StatusAuthority: cfg.StatusAuthority,
+	}
+
+	cons, pow, err := engineSpec(cfg.Engine)
+	if err != nil {
+		return ChainSpec{}, err
+	}
+	s.Consensus = cons
+	if pow {
+		s.Target.Rule = "compact-bits"
+		if cfg.RetargetInterval > 0 {
+			s.Target.Rule = "compact-bits-retarget"
+			s.Target.MaxRetargetFactor = maxRetargetFactor
+		}
+	}
+
+	s.Activations = []ActivationSpec{
+		{Rule: "chain-id", Height: 1},
+		{Rule: "bloom", Height: 1},
+		{Rule: "fees", Height: 1}, // in headers of version 1 and up
+	}
+	if cfg.MaxUncles > 0 {
+		s.Activations = append(s.Activations, ActivationSpec{Rule: "uncles", Height: 2})
+	}
+	if pow && cfg.RetargetInterval > 0 {
+		// NextBits needs a full window of history before the first retarget.
+		s.Activations = append(s.Activations, ActivationSpec{Rule: "retarget", Height: 2 * cfg.RetargetInterval})
+	}
+	if cfg.MinFee > 0 {
+		s.Activations = append(s.Activations, ActivationSpec{Rule: "min-fee", Height: 1})
+	}
+	if cfg.FinalityDepth > 0 {
+		s.Activations = append(s.Activations, ActivationSpec{Rule: "finality", Height: cfg.FinalityDepth})
+	}
+	if len(cfg.Upgrades) == 0 {
+		s.Activations = append(s.Activations, ActivationSpec{Rule: fmt.Sprintf("header-v%d", CurrentBlockVersion), Height: 0})
+	}
+	for _, u := range cfg.Upgrades {
+		s.Activations = append(s.Activations, ActivationSpec{Rule: fmt.Sprintf("header-v%d", u.Version), Height: u.Height})
+	}
+	sort.SliceStable(s.Activations, func(i, j int) bool { return s.Activations[i].Height < s.Activations[j].Height })
+	return s, nil
+}
+
+// allocSpec lists genesis allocations in address order.
+func allocSpec(alloc map[string]Amount) []AllocSpec {
+	list := []AllocSpec{}
+	for addr, amount := range alloc {
+		list = append(list, AllocSpec{Address: addr, Amount: amount})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Address < list[j].Address })
+	return list
+}
+
+// engineSpec describes a consensus engine and reports whether it enforces a
+// proof-of-work target.
+func engineSpec(e Engine) (ConsensusSpec, bool, error) {
+	s := ConsensusSpec{HashAlgorithm: "sha256"}
+	switch e := e.(type) {
+	case *PoWEngine:
+		s.Engine = "pow"
+		s.Seal = "block hash at or below the compact target"
+		return s, true, nil
+	case *FakePoWEngine:
+		s.Engine = "fake-pow"
+		s.Seal = "nonce derived from seed and seal hash; target not enforced"
+		seed := e.Seed
+		s.Seed = &seed
+		return s, false, nil
+	case *VDFEngine:
+		s.Engine = "vdf"
+		s.Seal = "wesolowski proof of x^(2^T) mod N over the seal hash"
+		s.Iterations = e.Iterations
+		s.Modulus = hex.EncodeToString(e.Modulus.Bytes())
+		return s, false, nil
+	case *AuthorityEngine:
+		s.Engine = "poa"
+		s.Seal = "ecdsa signature over the seal hash by the round-robin authority for the step"
+		step := e.StepDuration
+		if step == 0 {
+			step = DefaultStepDuration
+		}
+		s.StepDuration = step.String()
+		for _, a := range e.Authorities {
+			key, err := a.PubKey.Bytes()
+			if err != nil {
+				return ConsensusSpec{}, false, fmt.Errorf("authority %s: %w", a.Address, err)
+			}
+			s.Signers = append(s.Signers, SignerSpec{Address: a.Address, PubKey: hex.EncodeToString(key)})
+		}
+		return s, false, nil
+	case *StakeMiner:
+		s.Engine = "stake"
+		s.Seal = "ecdsa signature over the seal hash by the proposer drawn by balance at the parent block"
+		for _, v := range e.Validators {
+			key, err := v.PubKey.Bytes()
+			if err != nil {
+				return ConsensusSpec{}, false, fmt.Errorf("validator %s: %w", v.Address, err)
+			}
+			s.Signers = append(s.Signers, SignerSpec{Address: v.Address, PubKey: hex.EncodeToString(key)})
+		}
+		return s, false, nil
+	default:
+		return ConsensusSpec{}, false, fmt.Errorf("no spec for engine %T", e)
+	}
+}
+
+// WriteSpec writes spec as indented JSON.
+func WriteSpec(w io.Writer, spec ChainSpec) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(spec)
+}
+
+// configFlags are the consensus flags shared by commands that build a chain
+// config from the command line. The defaults match the demo.
+type configFlags struct {
+	chainID  *string
+	engine   *string
+	seed     *uint64
+	reward   *Amount
+	minFee   *Amount
+	fork     *string
+	uncles   *int
+	interval *time.Duration
+	retarget *int
+	finality *int
+}
+
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	return &configFlags{
+		chainID:  fs.String("chain-id", "", "chain ID"),
+		engine:   fs.String("engine", "pow", "consensus engine: pow or fake-pow"),
+		seed:     fs.Uint64("seed", 0, "fake-pow seed"),
+		reward:   amountFlag(fs, "reward", 50*Coin, "block reward"),
+		minFee:   amountFlag(fs, "min-fee", 0, "least fee a transaction may pay"),
+		fork:     fs.String("fork", string(MostWork), "fork choice rule: work, longest, or ghost"),
+		uncles:   fs.Int("uncles", 2, "max uncles per block"),
+		interval: fs.Duration("interval", DefaultTargetBlockInterval, "target block interval"),
+		retarget: fs.Int("retarget", 0, "retarget interval in blocks, 0 for none"),
+		finality: fs.Int("finality", 0, "finality depth, 0 for none"),
+	}
+}
+
+// config builds the chain config the flags describe.
+func (f *configFlags) config() (ChainConfig, error) {
+	cfg := ChainConfig{
+		ChainID:             *f.chainID,
+		BlockReward:         *f.reward,
+		MinFee:              *f.minFee,
+		ForkChoice:          ForkChoiceRule(*f.fork),
+		MaxUncles:           *f.uncles,
+		TargetBlockInterval: *f.interval,
+		RetargetInterval:    *f.retarget,
+		FinalityDepth:       *f.finality,
+	}
+	switch *f.engine {
+	case "pow":
+		cfg.Engine = &PoWEngine{}
+	case "fake-pow":
+		cfg.Engine = &FakePoWEngine{Seed: *f.seed}
+	default:
+		return ChainConfig{}, fmt.Errorf("unknown engine %q (want pow or fake-pow)", *f.engine)
+	}
+	return cfg, nil
+}
+
+// runSpec implements "spec dump". The chain config comes from flags, which
+// default to the demo's; -chain takes the genesis block and allocations
+// from a stored chain. Without -chain the genesis section is left out, since
+// a fresh genesis is mined with the current time and wouldn't diff cleanly.
+func runSpec(args []string) error {
+	if len(args) == 0 || args[0] != "dump" {
+		return errors.New("usage: spec dump [flags]")
+	}
+	fs := flag.NewFlagSet("spec dump", flag.ContinueOnError)
+	chainPath := fs.String("chain", "", "stored chain to take the genesis block and allocations from")
+	flags := addConfigFlags(fs)
+	out := fs.String("out", "", "write the spec to this file instead of stdout")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+
+	genesis := NewGenesisBlock(BitsForLeadingZeros(3))
+	tipHeight := 0
+	if *chainPath != "" {
+		blocks, err := LoadBlocks(*chainPath)
+		if err != nil {
+			return err
+		}
+		if len(blocks) == 0 {
+			return fmt.Errorf("%s holds no blocks", *chainPath)
+		}
+		genesis = blocks[0]
+		tipHeight = blocks[len(blocks)-1].Index
+		if cfg.Alloc, err = LoadAlloc(*chainPath); err != nil {
+			return err
+		}
+		if cfg.Upgrades, err = LoadUpgrades(*chainPath); err != nil {
+			return err
+		}
+	}
+
+	chain, err := NewChain(cfg, genesis)
+	if err != nil {
+		return err
+	}
+	spec, err := chain.Spec()
+	if err != nil {
+		return err
+	}
+	if *chainPath == "" {
+		spec.Genesis = nil
+	} else {
+		spec.ForkID = chain.ForkID(tipHeight)
+	}
+	if *out == "" {
+		return WriteSpec(os.Stdout, spec)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := WriteSpec(f, spec); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
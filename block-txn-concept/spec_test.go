@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func specJSON(t *testing.T, cfg ChainConfig) string {
+	t.Helper()
+	s, err := newTestChain(t, cfg).Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSpec(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestSpecCoversConsensusRulesOnly(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 5 * Coin, bob.Addr: 7 * Coin})
+	base := specJSON(t, cfg)
+
+	local := cfg
+	local.PruneDepth = 10
+	local.GCInterval = 5
+	local.Clock = StepClock(testStart.Add(time.Hour), time.Minute)
+	if got := specJSON(t, local); got != base {
+		t.Errorf("node-local settings changed the spec:\n%s\nvs\n%s", got, base)
+	}
+
+	for name, mod := range map[string]func(*ChainConfig){
+		"reward":      func(c *ChainConfig) { c.BlockReward++ },
+		"uncles":      func(c *ChainConfig) { c.MaxUncles = 2 },
+		"fork choice": func(c *ChainConfig) { c.ForkChoice = GHOST },
+		"finality":    func(c *ChainConfig) { c.FinalityDepth = 6 },
+		"min fee":     func(c *ChainConfig) { c.MinFee = Coin / 100 },
+		"engine seed": func(c *ChainConfig) { c.Engine = &FakePoWEngine{Seed: 2} },
+	} {
+		changed := cfg
+		mod(&changed)
+		if specJSON(t, changed) == base {
+			t.Errorf("%s: changing it left the spec as it was", name)
+		}
+	}
+}
+
+func TestSpecContents(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 5 * Coin, bob.Addr: 7 * Coin})
+	cfg.MaxUncles = 2
+	cfg.FinalityDepth = 6
+	c := newTestChain(t, cfg)
+	s, err := c.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.SpecVersion != SpecVersion || s.Genesis.Hash != c.BestChain()[0].Hash {
+		t.Errorf("version %d, genesis %s", s.SpecVersion, s.Genesis.Hash)
+	}
+	if s.Consensus.Engine != "fake-pow" || s.Consensus.Seed == nil || *s.Consensus.Seed != 1 || s.Target.Rule != "none" {
+		t.Errorf("consensus %+v, target rule %q", s.Consensus, s.Target.Rule)
+	}
+	if len(s.Alloc) != 2 || s.Alloc[0].Address > s.Alloc[1].Address {
+		t.Errorf("alloc %+v, want both accounts in address order", s.Alloc)
+	}
+	if !sort.SliceIsSorted(s.Activations, func(i, j int) bool { return s.Activations[i].Height < s.Activations[j].Height }) {
+		t.Errorf("activations out of height order: %+v", s.Activations)
+	}
+	want := map[string]int{"uncles": 2, "finality": 6, "header-v4": 0}
+	for _, a := range s.Activations {
+		if h, ok := want[a.Rule]; ok {
+			if a.Height != h {
+				t.Errorf("%s activates at %d, want %d", a.Rule, a.Height, h)
+			}
+			delete(want, a.Rule)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("missing activations %v", want)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSpec(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	var back ChainSpec
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, s) {
+		t.Errorf("spec didn't survive a JSON round trip:\n%+v\n%+v", back, s)
+	}
+}
+
+func TestSpecForProofOfWork(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.Engine = &PoWEngine{}
+	cfg.RetargetInterval = 5
+	s, err := newTestChain(t, cfg).Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Target.Rule != "compact-bits-retarget" || s.Target.MaxRetargetFactor != maxRetargetFactor {
+		t.Errorf("target %+v", s.Target)
+	}
+	var found bool
+	for _, a := range s.Activations {
+		found = found || a.Rule == "retarget" && a.Height == 10
+	}
+	if !found {
+		t.Errorf("no retarget activation at 10: %+v", s.Activations)
+	}
+}
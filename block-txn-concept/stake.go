@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// ErrNotProposer is returned by StakeMiner.Seal when the local validator was
+// not selected to produce the block at that height.
+var ErrNotProposer = errors.New("not the selected proposer for this block")
+
+// Validator is an account that has put its balance up as stake.
+type Validator struct {
+	Address string
+	PubKey  *ecdsa.PublicKey
+}
+
+// StakeMiner is a proof-of-stake engine. Instead of grinding nonces, each
+// block's producer is drawn pseudo-randomly from the validator set, weighted
+// by stake and seeded by the parent hash and height, and signs the header.
+// A validator's stake is its balance on Chain as of the block's parent, so
+// it moves with every payment to or from the validator.
+type StakeMiner struct {
+	Validators []Validator
+	Address    string            // the local validator, if any
+	Key        *ecdsa.PrivateKey // signs blocks when Address is selected
+
+	// Chain holds the balances stakes are read from. NewChain sets it if
+	// it is nil.
+	Chain *Chain
+}
+
+// Seal signs b if the local validator is the eligible proposer at its height.
+func (s *StakeMiner) Seal(b *Block) error {
+	proposer, err := s.Proposer(*b)
+	if err != nil {
+		return err
+	}
+	if proposer.Address != s.Address || s.Key == nil {
+		return ErrNotProposer
+	}
+
+	b.Proposer = s.Address
+	sig, err := SignDeterministic(s.Key, sealHash(*b))
+	if err != nil {
+		return err
+	}
+	b.Seal = sig
+	b.Hash = hashBlock(*b)
+	return nil
+}
+
+// VerifySeal checks that b was produced by the validator eligible at its
+// height and carries that validator's signature.
+func (s *StakeMiner) VerifySeal(b Block) error {
+	proposer, err := s.verifyProposer(b)
+	if err != nil {
+		return err
+	}
+	if !ecdsa.VerifyASN1(proposer.PubKey, sealHash(b), b.Seal) {
+		return fmt.Errorf("block %d: invalid proposer signature", b.Index)
+	}
+	return nil
+}
+
+// VerifyProposer is VerifySeal without the signature check.
+func (s *StakeMiner) VerifyProposer(b Block) error {
+	_, err := s.verifyProposer(b)
+	return err
+}
+
+func (s *StakeMiner) verifyProposer(b Block) (Validator, error) {
+	if err := verifyBlockHash(b); err != nil {
+		return Validator{}, err
+	}
+	proposer, err := s.Proposer(b)
+	if err != nil {
+		return Validator{}, err
+	}
+	if b.Proposer != proposer.Address {
+		return Validator{}, fmt.Errorf("block %d: proposer %s is not eligible, expected %s", b.Index, b.Proposer, proposer.Address)
+	}
+	return proposer, nil
+}
+
+// Stakes returns each validator's stake for producing b: its balance as
+// of b's parent.
+func (s *StakeMiner) Stakes(b Block) (map[string]Amount, error) {
+	if s.Chain == nil {
+		return nil, errors.New("stake engine has no chain to read balances from")
+	}
+	parent, ok := s.Chain.blocks[b.PrevHash]
+	if !ok {
+		return nil, fmt.Errorf("block %d: unknown parent %s", b.Index, b.PrevHash)
+	}
+	state, err := s.Chain.branchState(parent)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: stakes: %w", b.Index, err)
+	}
+	stakes := make(map[string]Amount, len(s.Validators))
+	for _, v := range s.Validators {
+		stakes[v.Address] = state.Balance(v.Address)
+	}
+	return stakes, nil
+}
+
+// Proposer returns the validator selected to produce b. The draw depends only
+// on the parent's hash, height, and balances, so every node computes the
+// same answer.
+func (s *StakeMiner) Proposer(b Block) (Validator, error) {
+	stakes, err := s.Stakes(b)
+	if err != nil {
+		return Validator{}, err
+	}
+	validators := make([]Validator, 0, len(s.Validators))
+	var total Amount
+	for _, v := range s.Validators {
+		if stake := stakes[v.Address]; stake > 0 {
+			validators = append(validators, v)
+			if total, err = total.Add(stake); err != nil {
+				return Validator{}, fmt.Errorf("block %d: total stake: %w", b.Index, err)
+			}
+		}
+	}
+	if len(validators) == 0 {
+		return Validator{}, errors.New("no validators with positive stake")
+	}
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].Address < validators[j].Address
+	})
+
+	seed := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", b.PrevHash, b.Index)))
+	r := Amount(new(big.Int).Mod(new(big.Int).SetBytes(seed[:]), big.NewInt(int64(total))).Int64())
+
+	for _, v := range validators {
+		if r < stakes[v.Address] {
+			return v, nil
+		}
+		r -= stakes[v.Address]
+	}
+	return validators[len(validators)-1], nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStakeFollowsBalance(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	e := &StakeMiner{
+		Validators: []Validator{
+			{Address: alice.Addr, PubKey: &alice.Key.PublicKey},
+			{Address: bob.Addr, PubKey: &bob.Key.PublicKey},
+		},
+		Address: alice.Addr,
+		Key:     alice.Key,
+	}
+	cfg := testConfig(map[string]Amount{alice.Addr: 100 * Coin})
+	cfg.Engine = e
+	genesis := Block{Version: CurrentBlockVersion, Timestamp: testStart, Bits: BitsForLeadingZeros(0), PrevHash: "0x" + strings.Repeat("0", 64)}
+	genesis.Hash = hashBlock(genesis)
+	c, err := NewChain(cfg, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bob holds nothing, so only alice can be drawn.
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 100 * Coin})
+	b, err := c.BuildBlock("", []Transaction{tx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// Now bob holds everything.
+	if _, err := c.BuildBlock("", nil); !errors.Is(err, ErrNotProposer) {
+		t.Fatalf("alice sealed with no stake: err = %v", err)
+	}
+	e.Address, e.Key = bob.Addr, bob.Key
+	b, err = c.BuildBlock("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Proposer != bob.Addr {
+		t.Errorf("proposer %s, want bob", b.Proposer)
+	}
+}
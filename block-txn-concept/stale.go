@@ -0,0 +1,49 @@
+package main
+
+import "sort"
+
+// StaleBlock is a valid block that is not on the best chain.
+type StaleBlock struct {
+	Block
+	// UncleOf is the hash of the best-chain block that referenced this one
+	// as an uncle, paying its miner a reduced reward (see MaxUncles). It is
+	// empty if no best-chain block has claimed it.
+	UncleOf string
+}
+
+// StaleBlocks returns every known block that lost out to the best chain,
+// ordered by height.
+func (c *Chain) StaleBlocks() []StaleBlock {
+	uncleOf := make(map[string]string)
+	for b := c.tip; b != nil; b = c.blocks[b.PrevHash] {
+		for _, u := range b.Uncles {
+			uncleOf[u] = b.Hash
+		}
+		if b == c.genesis {
+			break
+		}
+	}
+
+	var stale []StaleBlock
+	for _, b := range c.blocks {
+		if !c.onMainChain(b) {
+			stale = append(stale, StaleBlock{Block: *b, UncleOf: uncleOf[b.Hash]})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		if stale[i].Index != stale[j].Index {
+			return stale[i].Index < stale[j].Index
+		}
+		return stale[i].Hash < stale[j].Hash
+	})
+	return stale
+}
+
+// StaleRate is the fraction of all known non-genesis blocks that are stale.
+func (c *Chain) StaleRate() float64 {
+	total := len(c.blocks) - 1
+	if total <= 0 {
+		return 0
+	}
+	return float64(len(c.StaleBlocks())) / float64(total)
+}
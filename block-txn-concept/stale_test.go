@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestStaleBlocksRecordUncleInclusion(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.MaxUncles = 1
+	c := newTestChain(t, cfg)
+	var siblings []Block
+	for i := 0; i < 3; i++ {
+		b, err := c.BuildBlock(newTestAccount(t).Addr, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		siblings = append(siblings, b)
+	}
+	for _, b := range siblings {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rate := c.StaleRate(); rate != 2.0/3 {
+		t.Errorf("stale rate with three siblings = %v, want 2/3", rate)
+	}
+	nephew := mineBlocks(t, c, 1)[0]
+	if len(nephew.Uncles) != 1 {
+		t.Fatalf("nephew references %d uncles, want 1", len(nephew.Uncles))
+	}
+
+	stale := c.StaleBlocks()
+	if len(stale) != 2 {
+		t.Fatalf("%d stale blocks, want the 2 losing siblings", len(stale))
+	}
+	if stale[0].Hash > stale[1].Hash {
+		t.Error("stale blocks at the same height aren't ordered by hash")
+	}
+	for _, s := range stale {
+		if s.Index != 1 || s.Hash == c.BestChain()[1].Hash {
+			t.Errorf("block %d %s reported stale", s.Index, s.Hash)
+		}
+		want := ""
+		if s.Hash == nephew.Uncles[0] {
+			want = nephew.Hash
+		}
+		if s.UncleOf != want {
+			t.Errorf("%s: UncleOf = %q, want %q", s.Hash, s.UncleOf, want)
+		}
+	}
+	if rate := c.StaleRate(); rate != 0.5 {
+		t.Errorf("stale rate = %v, want 2 of 4", rate)
+	}
+}
+
+func TestStaleRateOnGenesisOnly(t *testing.T) {
+	c := newTestChain(t, testConfig(nil))
+	if len(c.StaleBlocks()) != 0 || c.StaleRate() != 0 {
+		t.Error("a bare chain reports stale blocks")
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// snapshotCacheDepth is how far below the tip Snapshot keeps the balances
+// it computes when the chain doesn't set PruneDepth.
+const snapshotCacheDepth = 128
+
+// Snapshot holds every account balance as of a given best-chain block.
+type Snapshot struct {
+	Height    int               `json:"height"`
+	BlockHash string            `json:"blockHash"`
+	Balances  map[string]Amount `json:"balances"`
+}
+
+// Balance returns addr's balance in the snapshot.
+func (s *Snapshot) Balance(addr string) Amount {
+	return s.Balances[addr]
+}
+
+// clone returns a deep copy so cached snapshots are never mutated.
+func (s *Snapshot) clone() *Snapshot {
+	balances := make(map[string]Amount, len(s.Balances))
+	for addr, bal := range s.Balances {
+		balances[addr] = bal
+	}
+	return &Snapshot{Height: s.Height, BlockHash: s.BlockHash, Balances: balances}
+}
+
+// applyBlock advances the snapshot by one block: the block's rewards are
+// credited and each transaction moves each leg's Amount from From to its
+// To, debiting From for the fee as well. The genesis
+// block also credits the configured allocations.
+func (c *Chain) applyBlock(s *Snapshot, b Block) error {
+	return c.applyBlockFunc(s, b, nil)
+}
+
+// applyBlockFunc is applyBlock, calling after, if set, with each
+// transaction's position once it has been applied.
+func (c *Chain) applyBlockFunc(s *Snapshot, b Block, after func(i int, tx Transaction)) error {
+	if b.IsPruned() {
+		return fmt.Errorf("block %d: %w; restore a snapshot at or above it", b.Index, ErrPruned)
+	}
+	if b.Hash == c.genesis.Hash {
+		for addr, amount := range c.config.Alloc {
+			s.Balances[addr] += amount
+		}
+	}
+	rewards, err := c.Rewards(b)
+	if err != nil {
+		return err
+	}
+	for addr, reward := range rewards {
+		balance, err := s.Balances[addr].Add(reward)
+		if err != nil {
+			return fmt.Errorf("block %d: reward for %s: %w", b.Index, addr, err)
+		}
+		s.Balances[addr] = balance
+	}
+	for i, tx := range b.Transactions {
+		if err := applyTxBalances(s.Balances, tx); err != nil {
+			return fmt.Errorf("block %d: tx %s: %w", b.Index, tx.Hash, err)
+		}
+		if after != nil {
+			after(i, tx)
+		}
+	}
+	s.Height = b.Index
+	s.BlockHash = b.Hash
+	return nil
+}
+
+// applyTxBalances debits tx's Cost from its sender and credits each leg,
+// with every sum checked, so a transaction whose amounts overflow fails
+// rather than wrapping a balance around. On error balances may hold some
+// of tx's changes; callers discard it.
+func applyTxBalances(balances map[string]Amount, tx Transaction) error {
+	cost, err := tx.Cost()
+	if err != nil {
+		return err
+	}
+	from, err := balances[tx.From].Sub(cost)
+	if err != nil {
+		return err
+	}
+	balances[tx.From] = from
+	for _, l := range tx.Legs() {
+		to, err := balances[l.To].Add(l.Amount)
+		if err != nil {
+			return err
+		}
+		balances[l.To] = to
+	}
+	return nil
+}
+
+// Snapshot returns the balances as of the best-chain block at height. It
+// starts from the nearest cached snapshot at or below height, replays the
+// blocks in between, and caches the result for later queries. The cache
+// only keeps recent heights; see evictSnapshots.
+func (c *Chain) Snapshot(height int) (*Snapshot, error) {
+	if height < 0 || height > c.tip.Index {
+		return nil, fmt.Errorf("height %d is outside the chain (tip %d)", height, c.tip.Index)
+	}
+	target := c.ancestorAt(c.tip, height)
+
+	// Find the closest snapshot on the best chain at or below height.
+	var base *Snapshot
+	for _, s := range c.snapshots {
+		if s.Height > height || (base != nil && s.Height <= base.Height) {
+			continue
+		}
+		if b, ok := c.blocks[s.BlockHash]; ok && c.onMainChain(b) {
+			base = s
+		}
+	}
+
+	var s *Snapshot
+	var from int
+	if base != nil {
+		s = base.clone()
+		from = base.Height + 1
+	} else {
+		s = &Snapshot{Height: -1, Balances: make(map[string]Amount)}
+	}
+
+	// Collect the blocks to replay by walking back from the target.
+	var replay []*Block
+	for b := target; b != nil && b.Index >= from; b = c.blocks[b.PrevHash] {
+		replay = append(replay, b)
+		if b == c.genesis {
+			break
+		}
+	}
+	for i := len(replay) - 1; i >= 0; i-- {
+		if err := c.applyBlock(s, *replay[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	c.snapshots[s.BlockHash] = s.clone()
+	c.evictSnapshots()
+	return s, nil
+}
+
+// evictSnapshots bounds the snapshot cache. It keeps snapshots within
+// PruneDepth blocks of the tip (snapshotCacheDepth if unset), the newest
+// best-chain snapshot below that, which older queries and replays past
+// pruned blocks start from, and any given to RestoreSnapshot.
+func (c *Chain) evictSnapshots() {
+	depth := c.config.PruneDepth
+	if depth <= 0 {
+		depth = snapshotCacheDepth
+	}
+	horizon := c.tip.Index - depth
+
+	var base *Snapshot
+	for _, s := range c.snapshots {
+		if s.Height >= horizon || (base != nil && s.Height <= base.Height) {
+			continue
+		}
+		if b, ok := c.blocks[s.BlockHash]; ok && c.onMainChain(b) {
+			base = s
+		}
+	}
+	for hash, s := range c.snapshots {
+		if s.Height < horizon && s != base && !c.restored[hash] {
+			delete(c.snapshots, hash)
+		}
+	}
+}
+
+// StateView is a read-only view of the chain as of one best-chain block.
+// Reads through it answer as if that block were the tip.
+type StateView struct {
+	chain *Chain
+	block *Block
+	snap  *Snapshot
+}
+
+// At returns a view of the state as of the best-chain block at height. It is
+// backed by Snapshot, so repeated views near the same height are cheap.
+func (c *Chain) At(height int) (*StateView, error) {
+	snap, err := c.Snapshot(height)
+	if err != nil {
+		return nil, err
+	}
+	return &StateView{chain: c, block: c.blocks[snap.BlockHash], snap: snap}, nil
+}
+
+// Height returns the height the view is at.
+func (v *StateView) Height() int {
+	return v.block.Index
+}
+
+// Head returns the block the view is at.
+func (v *StateView) Head() Block {
+	return *v.block
+}
+
+// Balance returns addr's balance as of the view's block.
+func (v *StateView) Balance(addr string) Amount {
+	return v.snap.Balance(addr)
+}
+
+// Accounts returns every address with a non-zero balance, sorted.
+func (v *StateView) Accounts() []string {
+	var addrs []string
+	for addr, bal := range v.snap.Balances {
+		if bal != 0 {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// TotalSupply is the sum of all balances as of the view's block.
+func (v *StateView) TotalSupply() Amount {
+	var total Amount
+	for _, bal := range v.snap.Balances {
+		total += bal
+	}
+	return total
+}
+
+// Block returns the best-chain block at height, if it is at or below the
+// view's height.
+func (v *StateView) Block(height int) (Block, bool) {
+	if height < 0 || height > v.block.Index {
+		return Block{}, false
+	}
+	return *v.chain.ancestorAt(v.block, height), true
+}
+
+// RestoreSnapshot adds a snapshot, for example one loaded from disk, to the
+// chain's cache. It must match the best-chain block at its height.
+func (c *Chain) RestoreSnapshot(s *Snapshot) error {
+	b, ok := c.blocks[s.BlockHash]
+	if !ok || b.Index != s.Height || !c.onMainChain(b) {
+		return fmt.Errorf("snapshot at height %d (%s) is not on the best chain", s.Height, s.BlockHash)
+	}
+	c.snapshots[s.BlockHash] = s.clone()
+	c.restored[s.BlockHash] = true
+	return nil
+}
+
+// SaveSnapshot writes s to path as JSON.
+func SaveSnapshot(path string, s *Snapshot) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Balances == nil {
+		s.Balances = make(map[string]Amount)
+	}
+	return &s, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+// queryEveryHeight asks for the balances at every height of c, as a
+// long-running explorer would.
+func queryEveryHeight(t *testing.T, c *Chain) {
+	t.Helper()
+	for h := 0; h <= c.Tip().Index; h++ {
+		if _, err := c.Snapshot(h); err != nil {
+			t.Fatalf("height %d: %v", h, err)
+		}
+	}
+}
+
+func TestSnapshotCacheStaysBounded(t *testing.T) {
+	c := newTestChain(t, testConfig(nil))
+	for i := 0; i < 3*snapshotCacheDepth; i++ {
+		mineBlocks(t, c, 1)
+		if _, err := c.Snapshot(c.Tip().Index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queryEveryHeight(t, c)
+	// The window below the tip, the tip itself, and one base under the
+	// window.
+	if n, max := len(c.snapshots), snapshotCacheDepth+2; n > max {
+		t.Errorf("%d cached snapshots after %d blocks, want at most %d", n, c.Tip().Index, max)
+	}
+	// Evicted heights are still answered, by replaying.
+	s, err := c.Snapshot(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Height != 1 || s.BlockHash != c.ancestorAt(c.tip, 1).Hash {
+		t.Errorf("snapshot for height 1 is at %d %s", s.Height, s.BlockHash)
+	}
+}
+
+func TestSnapshotCacheFollowsPruneDepth(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 100 * Coin})
+	cfg.PruneDepth = 5
+	c := newTestChain(t, cfg)
+	for i := 0; i < 40; i++ {
+		tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+		if err := mineTxs(t, c, tx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Snapshot(c.Tip().Index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, max := len(c.snapshots), cfg.PruneDepth+2; n > max {
+		t.Errorf("%d cached snapshots, want at most %d", n, max)
+	}
+	// With only the base under the window left, the tip replays from it:
+	// everything older is pruned.
+	for hash, s := range c.snapshots {
+		if s.Height >= c.Tip().Index-cfg.PruneDepth {
+			delete(c.snapshots, hash)
+		}
+	}
+	if got := tipBalance(t, c, bob.Addr); got != 40*Coin {
+		t.Errorf("bob has %s, want 40", got)
+	}
+}
+
+func TestStateViewAnswersAsOfItsHeight(t *testing.T) {
+	alice, bob, miner := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	b, err := c.BuildBlock(miner.Addr, []Transaction{signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 3 * Coin})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.At(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, c, 2)
+
+	if v.Height() != 0 || v.Head().Hash != c.BestChain()[0].Hash {
+		t.Errorf("view at 0 is at %d %s", v.Height(), v.Head().Hash)
+	}
+	if v.Balance(alice.Addr) != 10*Coin || v.Balance(bob.Addr) != 0 {
+		t.Errorf("view at 0: alice %s, bob %s", v.Balance(alice.Addr), v.Balance(bob.Addr))
+	}
+	if got := v.Accounts(); len(got) != 1 || got[0] != alice.Addr {
+		t.Errorf("accounts at 0 = %v", got)
+	}
+	if _, ok := v.Block(1); ok {
+		t.Error("view at 0 returned a block above it")
+	}
+
+	v, err = c.At(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Balance(alice.Addr) != 7*Coin || v.Balance(bob.Addr) != 3*Coin {
+		t.Errorf("view at 1: alice %s, bob %s", v.Balance(alice.Addr), v.Balance(bob.Addr))
+	}
+	if got := v.TotalSupply(); got != 10*Coin+cfg.BlockReward {
+		t.Errorf("supply at 1 = %s, want the allocation plus one reward", got)
+	}
+	if b, ok := v.Block(0); !ok || b.Hash != c.BestChain()[0].Hash {
+		t.Error("view at 1 doesn't return genesis")
+	}
+	if accts := v.Accounts(); len(accts) != 3 || !sort.StringsAreSorted(accts) {
+		t.Errorf("accounts at 1 = %v, want alice, bob and the miner, sorted", accts)
+	}
+
+	for _, h := range []int{-1, c.Tip().Index + 1} {
+		if _, err := c.At(h); err == nil {
+			t.Errorf("At(%d) succeeded", h)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// BalanceChange is one thing that moved an address's balance.
+type BalanceChange struct {
+	Height       int
+	BlockHash    string
+	TxHash       string // empty for rewards and allocations
+	Reason       string // "allocation", "mined", "uncle", "sent", or "received"
+	Counterparty string
+	Note         string
+	Delta        Amount
+}
+
+// StateDiff explains how an address's balance got from one height to
+// another.
+type StateDiff struct {
+	Address  string
+	From, To int
+	Before   Amount
+	After    Amount
+	Changes  []BalanceChange
+}
+
+// Diff lists every change to addr's balance in best-chain blocks above
+// height from, up to and including height to. Passing from = -1 includes
+// the genesis allocations.
+func (c *Chain) Diff(addr string, from, to int) (*StateDiff, error) {
+	if from > to {
+		return nil, fmt.Errorf("height %d is above %d", from, to)
+	}
+	after, err := c.At(to)
+	if err != nil {
+		return nil, err
+	}
+	d := &StateDiff{Address: addr, From: from, To: to, After: after.Balance(addr)}
+	if from >= 0 {
+		before, err := c.At(from)
+		if err != nil {
+			return nil, err
+		}
+		d.Before = before.Balance(addr)
+	}
+
+	for h := from + 1; h <= to; h++ {
+		b, _ := after.Block(h)
+		if b.IsPruned() {
+			return nil, fmt.Errorf("block %d is pruned", h)
+		}
+		if b.Hash == c.genesis.Hash {
+			if amount, ok := c.config.Alloc[addr]; ok {
+				d.Changes = append(d.Changes, BalanceChange{Height: h, BlockHash: b.Hash, Reason: "allocation", Delta: amount})
+			}
+		}
+		rewards, err := c.Rewards(b)
+		if err != nil {
+			return nil, err
+		}
+		if reward := rewards[addr]; reward != 0 {
+			reason := "uncle"
+			if b.Coinbase == addr {
+				reason = "mined"
+			}
+			d.Changes = append(d.Changes, BalanceChange{Height: h, BlockHash: b.Hash, Reason: reason, Delta: reward})
+		}
+		for _, tx := range b.Transactions {
+			change := BalanceChange{Height: h, BlockHash: b.Hash, TxHash: tx.Hash, Note: tx.Description}
+			switch {
+			case tx.From == addr:
+				// Whatever the transaction pays back to addr itself offsets it.
+				cost, _ := tx.Cost()
+				change.Reason, change.Counterparty, change.Delta = "sent", tx.payee(), tx.Pays(addr)-cost
+				if change.Delta == 0 {
+					continue // a free transfer to yourself changes nothing
+				}
+			case tx.sendsTo(addr):
+				change.Reason, change.Counterparty, change.Delta = "received", tx.From, tx.Pays(addr)
+			default:
+				continue
+			}
+			d.Changes = append(d.Changes, change)
+		}
+	}
+	return d, nil
+}
+
+// Print writes a human-readable explanation of d to w.
+func (d *StateDiff) Print(w io.Writer) {
+	fmt.Fprintf(w, "Balance of %s\n", d.Address)
+	fmt.Fprintf(w, "  at height %-4d %12.2f\n", d.From, d.Before)
+	for _, c := range d.Changes {
+		var what string
+		switch c.Reason {
+		case "allocation":
+			what = "genesis allocation"
+		case "mined":
+			what = "reward for mining the block"
+		case "uncle":
+			what = "reward for an uncle block"
+		case "sent":
+			what = fmt.Sprintf("sent to %s", c.Counterparty)
+		case "received":
+			what = fmt.Sprintf("received from %s", c.Counterparty)
+		}
+		if c.Note != "" {
+			what += fmt.Sprintf(" (%s)", c.Note)
+		}
+		fmt.Fprintf(w, "  %+15.2f  %s\n", c.Delta, what)
+		fmt.Fprintf(w, "                   block #%d %s\n", c.Height, c.BlockHash)
+		if c.TxHash != "" {
+			fmt.Fprintf(w, "                   tx %s\n", c.TxHash)
+		}
+	}
+	if len(d.Changes) == 0 {
+		fmt.Fprintln(w, "  (no changes)")
+	}
+	fmt.Fprintf(w, "  at height %-4d %12.2f  (%+.2f)\n", d.To, d.After, d.After-d.Before)
+}
+
+// runState implements "state diff <heightA> <heightB> -address ADDR". The
+// stored chain is replayed into a proof-of-work Chain to compute balances.
+func runState(args []string) error {
+	if len(args) == 0 || args[0] != "diff" {
+		return errors.New("usage: state diff <heightA> <heightB> -address ADDR")
+	}
+	fs := flag.NewFlagSet("state diff", flag.ContinueOnError)
+	path := fs.String("chain", "chain.json", "stored chain")
+	addr := fs.String("address", "", "address to explain")
+	reward := amountFlag(fs, "reward", 50*Coin, "block reward the chain was mined with")
+
+	// Allow flags before, between, or after the two heights.
+	var heights []int
+	rest := args[1:]
+	for {
+		if err := fs.Parse(rest); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		h, err := strconv.Atoi(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("bad height %q", fs.Arg(0))
+		}
+		heights = append(heights, h)
+		rest = fs.Args()[1:]
+	}
+	if len(heights) != 2 || *addr == "" {
+		return errors.New("usage: state diff <heightA> <heightB> -address ADDR")
+	}
+
+	blocks, err := LoadBlocks(*path)
+	if err != nil {
+		return err
+	}
+	alloc, err := LoadAlloc(*path)
+	if err != nil {
+		return err
+	}
+	upgrades, err := LoadUpgrades(*path)
+	if err != nil {
+		return err
+	}
+	chain, err := NewChain(ChainConfig{Engine: &PoWEngine{}, BlockReward: *reward, Alloc: alloc, MaxUncles: 2, Upgrades: upgrades}, blocks[0])
+	if err != nil {
+		return err
+	}
+	for _, b := range blocks[1:] {
+		if err := chain.AddBlock(b); err != nil {
+			return err
+		}
+	}
+
+	d, err := chain.Diff(*addr, heights[0], heights[1])
+	if err != nil {
+		return err
+	}
+	d.Print(os.Stdout)
+	return nil
+}
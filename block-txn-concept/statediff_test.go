@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffExplainsEveryBalanceChange(t *testing.T) {
+	alice, bob, miner := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	pay := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 2 * Coin, Fee: Coin / 2, Description: "books"})
+	b, err := c.BuildBlock(miner.Addr, []Transaction{pay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	back := signedTx(t, c, bob, Transaction{To: alice.Addr, Amount: Coin})
+	if err := mineTxs(t, c, back); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		addr     string
+		from, to int
+		reasons  []string
+	}{
+		{alice.Addr, -1, 2, []string{"allocation", "sent", "received"}},
+		{alice.Addr, 0, 1, []string{"sent"}},
+		{bob.Addr, -1, 2, []string{"received", "sent"}},
+		{miner.Addr, 0, 2, []string{"mined"}},
+		{miner.Addr, 1, 2, nil},
+	} {
+		d, err := c.Diff(tc.addr, tc.from, tc.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reasons []string
+		sum := d.Before
+		for _, ch := range d.Changes {
+			reasons = append(reasons, ch.Reason)
+			sum += ch.Delta
+		}
+		if strings.Join(reasons, ",") != strings.Join(tc.reasons, ",") {
+			t.Errorf("%s %d..%d: changes %v, want %v", tc.addr, tc.from, tc.to, reasons, tc.reasons)
+		}
+		if sum != d.After {
+			t.Errorf("%s %d..%d: %s plus changes is %s, but the balance is %s", tc.addr, tc.from, tc.to, d.Before, sum, d.After)
+		}
+	}
+
+	d, err := c.Diff(alice.Addr, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch := d.Changes[0]; ch.Delta != -(2*Coin+Coin/2) || ch.Counterparty != bob.Addr || ch.TxHash != pay.Hash || ch.Note != "books" {
+		t.Errorf("alice's payment shows as %+v", ch)
+	}
+	var out bytes.Buffer
+	d.Print(&out)
+	for _, want := range []string{"sent to " + bob.Addr + " (books)", "-2.50", "tx " + pay.Hash} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("printed diff lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	if _, err := c.Diff(alice.Addr, 2, 1); err == nil {
+		t.Error("a backwards range was accepted")
+	}
+	if _, err := c.Diff(alice.Addr, 0, 9); err == nil {
+		t.Error("a range past the tip was accepted")
+	}
+}
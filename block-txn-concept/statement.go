@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatementFormat selects how ExportStatement writes a statement.
+type StatementFormat string
+
+const (
+	StatementCSV  StatementFormat = "csv"
+	StatementJSON StatementFormat = "json"
+)
+
+// statementColumns are the CSV header, in order. They are part of the
+// format: add new columns at the end and never rename one.
+var statementColumns = []string{
+	"time", "hash", "id", "type", "from", "to", "amount", "fee", "change", "balance", "nonce", "description",
+	"category", "tags", "asset",
+}
+
+// StatementEntry is one transaction as exported, with its effect on the
+// account and the balance after it.
+type StatementEntry struct {
+	Time        time.Time       `json:"time"`
+	Hash        string          `json:"hash"`
+	ID          int             `json:"id,omitempty"`
+	Type        TransactionType `json:"type"`
+	From        string          `json:"from"`
+	To          []string        `json:"to"` // every leg's recipient
+	Amount      Amount          `json:"amount"`
+	Fee         Amount          `json:"fee"`
+	Change      Amount          `json:"change"` // signed effect on the balance
+	Balance     Amount          `json:"balance"`
+	Nonce       uint64          `json:"nonce"`
+	Description string          `json:"description"`
+	Category    string          `json:"category,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	Asset       Asset           `json:"asset,omitempty"` // Amount's token; Change and Balance are native
+}
+
+// Statement is an account's exported history. Opening is the balance
+// before the first entry, which is non-zero when funds arrived without a
+// transaction, such as genesis allocations and block rewards.
+type Statement struct {
+	Address string           `json:"address"`
+	Owner   string           `json:"owner"`
+	Opening Amount           `json:"openingBalance"`
+	Closing Amount           `json:"closingBalance"`
+	Entries []StatementEntry `json:"entries"`
+}
+
+// Statement returns a's history with a running balance.
+func (a *Account) Statement() Statement {
+	s := Statement{Address: a.Address, Owner: a.Owner, Closing: a.Balance, Entries: []StatementEntry{}}
+	var total Amount
+	for _, t := range a.Transactions {
+		amount, _ := t.Total()
+		e := StatementEntry{
+			Time:        t.Time,
+			Hash:        t.Hash,
+			ID:          t.ID,
+			Type:        t.Type,
+			From:        t.From,
+			Amount:      amount,
+			Fee:         t.Fee,
+			Nonce:       t.Nonce,
+			Description: t.Description,
+			Category:    t.Category,
+			Tags:        t.Tags,
+			Asset:       t.Asset,
+		}
+		for _, l := range t.Legs() {
+			e.To = append(e.To, l.To)
+		}
+		e.Change = t.change(NativeAsset)
+		total += e.Change
+		s.Entries = append(s.Entries, e)
+	}
+	s.Opening = a.Balance - total
+	bal := s.Opening
+	for i := range s.Entries {
+		bal += s.Entries[i].Change
+		s.Entries[i].Balance = bal
+	}
+	return s
+}
+
+// ExportStatement writes a's statement to w in format. CSV has one row per
+// transaction under statementColumns, with a multi-transfer's recipients
+// and a transaction's tags joined by ";"; JSON is a single Statement object. Amounts are plain
+// decimals in both.
+func (a *Account) ExportStatement(w io.Writer, format StatementFormat) error {
+	s := a.Statement()
+	switch format {
+	case StatementCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(statementColumns)
+		for _, e := range s.Entries {
+			cw.Write([]string{
+				e.Time.Format(time.RFC3339Nano),
+				e.Hash,
+				strconv.Itoa(e.ID),
+				string(e.Type),
+				e.From,
+				strings.Join(e.To, ";"),
+				e.Amount.String(),
+				e.Fee.String(),
+				e.Change.String(),
+				e.Balance.String(),
+				strconv.FormatUint(e.Nonce, 10),
+				e.Description,
+				e.Category,
+				strings.Join(e.Tags, ";"),
+				string(e.Asset),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case StatementJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	default:
+		return fmt.Errorf("unknown statement format %q (want csv or json)", format)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// statementLedger funds alice with 10 coins, then has her pay bob 3 with
+// a fee of 0.5 and bob pay her 1 back.
+func statementLedger(t *testing.T) (*Ledger, testAccount, testAccount) {
+	t.Helper()
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	l := NewLedger()
+	l.Mint(alice.Addr, 10*Coin)
+	pay, err := NewTxBuilder().From(alice.Addr).To(bob.Addr).Amount(3*Coin).Fee(Coin/2).NonceFrom(l).
+		Time(testStart).Description("rent, march").Category("housing").Tag("home", "monthly").SignWith(alice.Key).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Apply(pay); err != nil {
+		t.Fatal(err)
+	}
+	back, err := NewTxBuilder().From(bob.Addr).To(alice.Addr).Amount(Coin).NonceFrom(l).Time(testStart).SignWith(bob.Key).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Apply(back); err != nil {
+		t.Fatal(err)
+	}
+	return l, alice, bob
+}
+
+func TestStatementRunningBalance(t *testing.T) {
+	l, alice, bob := statementLedger(t)
+	s := l.Account(alice.Addr).Statement()
+	if s.Opening != 10*Coin || s.Closing != 7*Coin+Coin/2 {
+		t.Errorf("opening %s, closing %s; want 10 and 7.5", s.Opening, s.Closing)
+	}
+	if len(s.Entries) != 2 {
+		t.Fatalf("%d entries, want 2", len(s.Entries))
+	}
+	if e := s.Entries[0]; e.Change != -(3*Coin+Coin/2) || e.Balance != 6*Coin+Coin/2 || e.Fee != Coin/2 || len(e.To) != 1 || e.To[0] != bob.Addr {
+		t.Errorf("payment entry %+v", e)
+	}
+	if e := s.Entries[1]; e.Change != Coin || e.Balance != s.Closing || e.From != bob.Addr {
+		t.Errorf("refund entry %+v", e)
+	}
+
+	// Bob's opening balance is zero: everything he has came by transaction.
+	if s := l.Account(bob.Addr).Statement(); s.Opening != 0 || s.Closing != 2*Coin {
+		t.Errorf("bob: opening %s, closing %s", s.Opening, s.Closing)
+	}
+}
+
+func TestExportStatementFormats(t *testing.T) {
+	l, alice, _ := statementLedger(t)
+	a := l.Account(alice.Addr)
+
+	var buf bytes.Buffer
+	if err := a.ExportStatement(&buf, StatementCSV); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != strings.Join(statementColumns, ",") {
+		t.Fatalf("csv has %d rows, header %v", len(rows), rows[0])
+	}
+	row := make(map[string]string)
+	for i, col := range statementColumns {
+		row[col] = rows[1][i]
+	}
+	for col, want := range map[string]string{"change": "-3.5", "balance": "6.5", "description": "rent, march", "tags": "home;monthly", "category": "housing"} {
+		if row[col] != want {
+			t.Errorf("csv %s = %q, want %q", col, row[col], want)
+		}
+	}
+
+	buf.Reset()
+	if err := a.ExportStatement(&buf, StatementJSON); err != nil {
+		t.Fatal(err)
+	}
+	var s Statement
+	if err := json.Unmarshal(buf.Bytes(), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Address != alice.Addr || s.Opening != 10*Coin || len(s.Entries) != 2 || s.Entries[1].Balance != s.Closing {
+		t.Errorf("json statement %+v", s)
+	}
+
+	if err := a.ExportStatement(&buf, "xml"); err == nil {
+		t.Error("an unknown format was accepted")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrBadStateRoot is returned for a block whose StateRoot doesn't match the
+// balances its transactions leave, or for a state proof that doesn't lead
+// to a header's root.
+var ErrBadStateRoot = errors.New("state root mismatch")
+
+// Leaves and inner nodes are hashed with different prefixes, so no leaf
+// can pass for an inner node.
+const (
+	stateLeafPrefix = 0x00
+	stateNodePrefix = 0x01
+)
+
+// StateTree is a Merkle tree over every non-zero balance in a snapshot,
+// one leaf per address in address order. An odd node at the end of a level
+// moves up unpaired.
+type StateTree struct {
+	addrs    []string
+	balances map[string]Amount
+	levels   [][][]byte // levels[0] are the leaves, the last level the root
+}
+
+// NewStateTree builds the tree over s's balances.
+func NewStateTree(s *Snapshot) *StateTree {
+	t := &StateTree{balances: make(map[string]Amount)}
+	for addr, bal := range s.Balances {
+		if bal != 0 {
+			t.addrs = append(t.addrs, addr)
+			t.balances[addr] = bal
+		}
+	}
+	sort.Strings(t.addrs)
+	level := make([][]byte, len(t.addrs))
+	for i, addr := range t.addrs {
+		level[i] = stateLeaf(addr, t.balances[addr])
+	}
+	t.levels = append(t.levels, level)
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, stateNode(level[i], level[i+1]))
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t
+}
+
+// stateLeaf hashes one address's balance.
+func stateLeaf(addr string, bal Amount) []byte {
+	h := sha256.New()
+	h.Write([]byte{stateLeafPrefix})
+	h.Write([]byte(fmt.Sprintf("%d:%s", len(addr), addr)))
+	h.Write([]byte(bal.hashString()))
+	return h.Sum(nil)
+}
+
+// stateNode hashes two children into their parent.
+func stateNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{stateNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// Root returns the tree's root hash; an empty state has the SHA-256 of
+// nothing.
+func (t *StateTree) Root() string {
+	top := t.levels[len(t.levels)-1]
+	if len(top) == 0 {
+		sum := sha256.Sum256(nil)
+		return "0x" + hex.EncodeToString(sum[:])
+	}
+	return "0x" + hex.EncodeToString(top[0])
+}
+
+// ProofStep is one sibling on the path from a leaf to the root.
+type ProofStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"` // the sibling is on the left
+}
+
+// StateProof shows that Address held Balance as of the block at Height:
+// hashing its leaf up through Steps gives that block's StateRoot.
+type StateProof struct {
+	Address string      `json:"address"`
+	Balance Amount      `json:"balance"`
+	Height  int         `json:"height"`
+	Steps   []ProofStep `json:"steps"`
+}
+
+// Prove returns the proof of addr's balance. Addresses with a zero
+// balance have no leaf, so there is nothing to prove for them.
+func (t *StateTree) Prove(addr string) (*StateProof, error) {
+	i := sort.SearchStrings(t.addrs, addr)
+	if i == len(t.addrs) || t.addrs[i] != addr {
+		return nil, fmt.Errorf("%s has no balance in the state", addr)
+	}
+	p := &StateProof{Address: addr, Balance: t.balances[addr], Steps: []ProofStep{}}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := i ^ 1
+		if sibling < len(level) {
+			p.Steps = append(p.Steps, ProofStep{Hash: hex.EncodeToString(level[sibling]), Left: sibling < i})
+		}
+		i /= 2
+	}
+	return p, nil
+}
+
+// Root returns the root p's steps lead to.
+func (p *StateProof) Root() (string, error) {
+	node := stateLeaf(p.Address, p.Balance)
+	for _, s := range p.Steps {
+		sibling, err := hex.DecodeString(s.Hash)
+		if err != nil || len(sibling) != sha256.Size {
+			return "", fmt.Errorf("bad proof step %q", s.Hash)
+		}
+		if s.Left {
+			node = stateNode(sibling, node)
+		} else {
+			node = stateNode(node, sibling)
+		}
+	}
+	return "0x" + hex.EncodeToString(node), nil
+}
+
+// Verify checks p against header, which the caller trusts, e.g. because it
+// is on the heaviest header chain a light client has seen. It needs
+// nothing else from a full node: the header's hash must cover its state
+// root, and p must lead to that root. Under proof of authority or of stake
+// only a header from BlockVersion7 on has a seal that covers the root;
+// trusting an older one means trusting whoever relayed it.
+func (p *StateProof) Verify(header Block) error {
+	if header.Index != p.Height {
+		return fmt.Errorf("proof is for height %d, header is %d", p.Height, header.Index)
+	}
+	if header.Version < BlockVersion5 || header.StateRoot == "" {
+		return fmt.Errorf("block %d commits to no state root", header.Index)
+	}
+	if hashBlock(header) != header.Hash {
+		return fmt.Errorf("block %d: header does not match its hash", header.Index)
+	}
+	root, err := p.Root()
+	if err != nil {
+		return err
+	}
+	if root != header.StateRoot {
+		return fmt.Errorf("%w: proof for %s leads to %s, block %d has %s", ErrBadStateRoot, p.Address, root, header.Index, header.StateRoot)
+	}
+	return nil
+}
+
+// ProveBalance returns a proof of addr's balance as of the best-chain block
+// at height, to check with StateProof.Verify against that block's header.
+func (c *Chain) ProveBalance(addr string, height int) (*StateProof, error) {
+	s, err := c.Snapshot(height)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewStateTree(s).Prove(addr)
+	if err != nil {
+		return nil, err
+	}
+	p.Height = height
+	return p, nil
+}
+
+// stateRoot returns the root of the balances b leaves on top of parent.
+func (c *Chain) stateRoot(parent *Block, b Block) (string, error) {
+	s, err := c.branchState(parent)
+	if err != nil {
+		return "", err
+	}
+	if err := c.applyBlock(s, b); err != nil {
+		return "", err
+	}
+	return NewStateTree(s).Root(), nil
+}
+
+// validateStateRoot checks that a block from BlockVersion5 on commits to
+// the balances it leaves. If pruning has discarded the history needed to
+// recompute them, the root is taken on trust; any other failure to compute
+// them, such as a transaction that overflows a balance, rejects the block.
+func (c *Chain) validateStateRoot(b Block) error {
+	if b.Version < BlockVersion5 {
+		return nil
+	}
+	root, err := c.stateRoot(c.blocks[b.PrevHash], b)
+	if errors.Is(err, ErrPruned) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("block %d: state root: %w", b.Index, err)
+	}
+	if root != b.StateRoot {
+		return fmt.Errorf("block %d: %w: header has %q, balances give %s", b.Index, ErrBadStateRoot, b.StateRoot, root)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValidateStateRootRejectsUnappliableBlock(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: Coin}))
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: math.MaxInt64, Fee: 1})
+	b, err := c.buildBlock("", []Transaction{tx}, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Version = BlockVersion5
+	if err := c.validateStateRoot(b); err == nil {
+		t.Error("state root of a block that can't be applied was taken on trust")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math/big"
+	"time"
+)
+
+// ChainStats summarises the best chain.
+type ChainStats struct {
+	Blocks            int
+	Transactions      int
+	StaleBlocks       int // known blocks off the best chain
+	StaleRate         float64
+	AvgBlockInterval  time.Duration
+	TargetInterval    time.Duration // the configured TargetBlockInterval
+	AvgNonceAttempts  float64
+	CurrentBits       uint32
+	CurrentDifficulty float64
+	TotalWork         *big.Int // sum of expected hashes over every block
+}
+
+// ChainStats computes statistics over the best chain, from genesis to tip.
+func (c *Chain) ChainStats() ChainStats {
+	blocks := c.BestChain()
+	tip := blocks[len(blocks)-1]
+
+	stats := ChainStats{
+		Blocks:            len(blocks),
+		TargetInterval:    c.config.TargetBlockInterval,
+		CurrentBits:       tip.Bits,
+		CurrentDifficulty: Difficulty(tip.Bits),
+		TotalWork:         new(big.Int),
+		StaleBlocks:       len(c.StaleBlocks()),
+		StaleRate:         c.StaleRate(),
+	}
+
+	var attempts float64
+	for _, b := range blocks {
+		stats.Transactions += len(b.TxHashes())
+		stats.TotalWork.Add(stats.TotalWork, Work(b.Bits))
+		// Nonces start at zero, so a block found at nonce n took n+1 tries.
+		attempts += float64(b.Nonce + 1)
+	}
+	stats.AvgNonceAttempts = attempts / float64(len(blocks))
+
+	if len(blocks) > 1 {
+		span := tip.Timestamp.Sub(blocks[0].Timestamp)
+		stats.AvgBlockInterval = span / time.Duration(len(blocks)-1)
+	}
+	return stats
+}
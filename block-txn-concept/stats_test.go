@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestChainStatsSummarisesBestChain(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	cfg.TargetBlockInterval = 10 * time.Second
+	c := newTestChain(t, cfg)
+	if err := mineTxs(t, c, signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})); err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, c, 2)
+	// A losing sibling of block 1, built on a chain with its own clock.
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	stale := mineBlocks(t, newTestChain(t, rivalCfg), 1)[0]
+	if err := c.AddBlock(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	best := c.BestChain()
+	st := c.ChainStats()
+	if st.Blocks != 4 {
+		t.Errorf("Blocks = %d, want 4", st.Blocks)
+	}
+	var txs int
+	work := new(big.Int)
+	for _, b := range best {
+		txs += len(b.Transactions)
+		work.Add(work, Work(b.Bits))
+	}
+	if st.Transactions != txs || txs == 0 {
+		t.Errorf("Transactions = %d, want %d", st.Transactions, txs)
+	}
+	if st.TotalWork.Cmp(work) != 0 {
+		t.Errorf("TotalWork = %s, want %s", st.TotalWork, work)
+	}
+	if st.StaleBlocks != 1 || st.StaleRate != 0.25 {
+		t.Errorf("StaleBlocks = %d, StaleRate = %v; want 1 and 0.25", st.StaleBlocks, st.StaleRate)
+	}
+	if want := best[3].Timestamp.Sub(best[0].Timestamp) / 3; st.AvgBlockInterval != want {
+		t.Errorf("AvgBlockInterval = %v, want %v", st.AvgBlockInterval, want)
+	}
+	if st.TargetInterval != 10*time.Second {
+		t.Errorf("TargetInterval = %v, want the configured 10s", st.TargetInterval)
+	}
+	if tip := c.Tip(); st.CurrentBits != tip.Bits || st.CurrentDifficulty != Difficulty(tip.Bits) {
+		t.Errorf("current bits %08x, difficulty %v; tip has %08x", st.CurrentBits, st.CurrentDifficulty, tip.Bits)
+	}
+	if st.AvgNonceAttempts < 1 {
+		t.Errorf("AvgNonceAttempts = %v, every block takes at least one try", st.AvgNonceAttempts)
+	}
+}
+
+func TestChainStatsOnGenesisOnly(t *testing.T) {
+	st := newTestChain(t, testConfig(nil)).ChainStats()
+	if st.Blocks != 1 || st.AvgBlockInterval != 0 || st.StaleRate != 0 {
+		t.Errorf("stats for a bare chain = %+v", st)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AccountStatus restricts what an account may do.
+type AccountStatus string
+
+const (
+	// StatusActive is the default: no restrictions.
+	StatusActive AccountStatus = "active"
+	// StatusFrozen accounts can receive but not send.
+	StatusFrozen AccountStatus = "frozen"
+	// StatusClosed accounts can neither send nor receive, and stay closed.
+	StatusClosed AccountStatus = "closed"
+)
+
+var (
+	// ErrAccountFrozen is returned for a transaction sent from a frozen
+	// account.
+	ErrAccountFrozen = errors.New("account is frozen")
+	// ErrAccountClosed is returned for a transaction sent from or to a
+	// closed account.
+	ErrAccountClosed = errors.New("account is closed")
+	// ErrNotAuthority is returned for a status change not sent by
+	// ChainConfig.StatusAuthority.
+	ErrNotAuthority = errors.New("status changes must come from the status authority")
+)
+
+// valid reports whether s is a status a transaction can set.
+func (s AccountStatus) valid() bool {
+	return s == StatusActive || s == StatusFrozen || s == StatusClosed
+}
+
+// NewStatusChange builds an admin transaction from authority setting
+// target's status. Sign it with the authority's key like any other.
+func NewStatusChange(authority, target string, status AccountStatus, nonce uint64) *TxBuilder {
+	b := NewTxBuilder().From(authority).To(target).Nonce(nonce).Description(string(status))
+	b.tx.Status = status
+	return b
+}
+
+// statusAt returns addr's status on the branch ending at b, from the
+// status changes recorded when each block was added.
+func (c *Chain) statusAt(b *Block, addr string) AccountStatus {
+	for ; b != nil; b = c.blocks[b.PrevHash] {
+		if s, ok := c.statuses[b.Hash][addr]; ok {
+			return s
+		}
+		if b == c.genesis {
+			break
+		}
+	}
+	return StatusActive
+}
+
+// Status returns addr's account status.
+func (ctx *TxContext) Status(addr string) AccountStatus {
+	if s, ok := ctx.statuses[addr]; ok {
+		return s
+	}
+	return ctx.Chain.statusAt(ctx.parent, addr)
+}
+
+// checkStatus enforces account statuses: frozen and closed accounts can't
+// send, closed ones can't receive, and only ChainConfig.StatusAuthority
+// can change a status, with a transaction that moves no funds. A closed
+// account can't be reopened.
+func checkStatus(ctx *TxContext, tx Transaction) error {
+	switch ctx.Status(tx.From) {
+	case StatusFrozen:
+		return fmt.Errorf("%w: tx %s: %s", ErrAccountFrozen, tx.Hash, tx.From)
+	case StatusClosed:
+		return fmt.Errorf("%w: tx %s: %s", ErrAccountClosed, tx.Hash, tx.From)
+	}
+	for _, l := range tx.Legs() {
+		if ctx.Status(l.To) == StatusClosed {
+			return fmt.Errorf("%w: tx %s pays %s", ErrAccountClosed, tx.Hash, l.To)
+		}
+	}
+	if tx.Status == "" {
+		return nil
+	}
+	authority := ctx.Chain.config.StatusAuthority
+	if authority == "" || !strings.EqualFold(tx.From, authority) {
+		return fmt.Errorf("%w: tx %s is from %s", ErrNotAuthority, tx.Hash, tx.From)
+	}
+	if !tx.Status.valid() {
+		return fmt.Errorf("tx %s: unknown account status %q", tx.Hash, tx.Status)
+	}
+	if len(tx.Transfers) > 0 || tx.Amount != 0 {
+		return fmt.Errorf("tx %s: status change moves funds", tx.Hash)
+	}
+	return nil
+}
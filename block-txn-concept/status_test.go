@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAccountStatuses(t *testing.T) {
+	auth, alice, carol, mallory := newTestAccount(t), newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin, carol.Addr: 10 * Coin})
+	cfg.StatusAuthority = auth.Addr
+	c := newTestChain(t, cfg)
+	change := func(from testAccount, status AccountStatus) Transaction {
+		t.Helper()
+		tx, err := NewStatusChange(from.Addr, alice.Addr, status, c.NextNonce(from.Addr)).Bind("test", "").Time(testStart).SignWith(from.Key).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	pay := func(from, to testAccount) Transaction {
+		return signedTx(t, c, from, Transaction{To: to.Addr, Amount: Coin})
+	}
+
+	if err := mineTxs(t, c, change(mallory, StatusFrozen)); !errors.Is(err, ErrNotAuthority) {
+		t.Errorf("mallory's freeze: err = %v, want ErrNotAuthority", err)
+	}
+	withFunds := change(auth, StatusFrozen)
+	withFunds.Amount = Coin
+	if err := mineTxs(t, c, resign(t, withFunds, auth)); err == nil {
+		t.Error("status change moving funds was accepted")
+	}
+
+	if err := mineTxs(t, c, change(auth, StatusFrozen)); err != nil {
+		t.Fatalf("freeze: %v", err)
+	}
+	if err := mineTxs(t, c, pay(alice, carol)); !errors.Is(err, ErrAccountFrozen) {
+		t.Errorf("frozen sender: err = %v, want ErrAccountFrozen", err)
+	}
+	if err := mineTxs(t, c, pay(carol, alice)); err != nil {
+		t.Errorf("paying a frozen account: %v", err)
+	}
+
+	if err := mineTxs(t, c, change(auth, StatusClosed)); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := mineTxs(t, c, pay(carol, alice)); !errors.Is(err, ErrAccountClosed) {
+		t.Errorf("paying a closed account: err = %v, want ErrAccountClosed", err)
+	}
+	if err := mineTxs(t, c, pay(alice, carol)); !errors.Is(err, ErrAccountClosed) {
+		t.Errorf("closed sender: err = %v, want ErrAccountClosed", err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// chainFile is the on-disk format for a stored chain: the best chain's
+// blocks from genesis to tip, plus the genesis allocations and the
+// operator's attestation if there is one, as JSON.
+type chainFile struct {
+	Alloc       map[string]Amount   `json:"alloc,omitempty"`
+	Attestation *GenesisAttestation `json:"attestation,omitempty"`
+	Upgrades    []Upgrade           `json:"upgrades,omitempty"`
+	Blocks      []Block             `json:"blocks"`
+}
+
+// SaveBlocks writes blocks to path, replacing any existing file.
+func SaveBlocks(path string, blocks []Block) error {
+	return writeChainFile(path, chainFile{Blocks: blocks})
+}
+
+// SaveChain writes c's best chain, genesis allocations, attestation, and
+// upgrade schedule to path.
+func SaveChain(path string, c *Chain) error {
+	return writeChainFile(path, chainFile{Alloc: c.config.Alloc, Attestation: c.config.Attestation, Upgrades: c.config.Upgrades, Blocks: c.BestChain()})
+}
+
+func writeChainFile(path string, f chainFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadBlocks reads blocks previously written by SaveBlocks or SaveChain.
+func LoadBlocks(path string) ([]Block, error) {
+	f, err := readChainFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Blocks, nil
+}
+
+// LoadAlloc reads the genesis allocations stored by SaveChain.
+func LoadAlloc(path string) (map[string]Amount, error) {
+	f, err := readChainFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Alloc, nil
+}
+
+// LoadUpgrades reads the upgrade schedule stored by SaveChain, which is nil
+// for a chain that requires CurrentBlockVersion throughout.
+func LoadUpgrades(path string) ([]Upgrade, error) {
+	f, err := readChainFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Upgrades, nil
+}
+
+// LoadAttestation reads the genesis attestation stored with a chain, if any.
+func LoadAttestation(path string) (*GenesisAttestation, error) {
+	f, err := readChainFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Attestation, nil
+}
+
+func readChainFile(path string) (chainFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return chainFile{}, err
+	}
+	var f chainFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return chainFile{}, err
+	}
+	if len(f.Blocks) == 0 {
+		return chainFile{}, errors.New("stored chain has no blocks")
+	}
+	return f, nil
+}
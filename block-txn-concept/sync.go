@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Defaults for Syncer fields left at zero.
+const (
+	DefaultSyncWindow   = 16
+	DefaultStallTimeout = 5 * time.Second
+)
+
+// BlockSource is somewhere blocks can be downloaded from during initial sync,
+// in practice a remote peer. BlockByHeight should give up once ctx is done;
+// the Syncer cancels it when the source stalls or the sync ends.
+type BlockSource interface {
+	ID() string
+	BlockByHeight(ctx context.Context, height int) (Block, error)
+}
+
+// PeerSyncStats records how a single source performed during sync.
+type PeerSyncStats struct {
+	Blocks  int
+	Busy    time.Duration // total time spent serving requests
+	Stalled bool          // dropped for exceeding the stall timeout
+	Err     error         // set if the source was dropped for an error or an invalid block
+	Traffic TrafficStats  // the source's own counters, if it is a MeteredSource
+}
+
+// BlocksPerSecond is the source's observed download throughput.
+func (p PeerSyncStats) BlocksPerSecond() float64 {
+	if p.Busy <= 0 {
+		return 0
+	}
+	return float64(p.Blocks) / p.Busy.Seconds()
+}
+
+// Syncer downloads blocks from several sources in parallel. Requests are
+// spread over a moving window of heights just above the tip, so blocks may
+// arrive out of order, but they are validated and connected strictly in
+// order. A source that takes longer than StallTimeout for a block, fails a
+// request, or sends a block the chain rejects is dropped, and the heights it
+// was serving are handed to the remaining sources.
+type Syncer struct {
+	Chain        *Chain
+	Sources      []BlockSource
+	Window       int
+	StallTimeout time.Duration
+
+	stats map[string]*PeerSyncStats
+}
+
+type syncResult struct {
+	source BlockSource
+	height int
+	block  Block
+	err    error
+	took   time.Duration
+}
+
+type syncRequest struct {
+	source  BlockSource
+	started time.Time
+	cancel  context.CancelFunc
+}
+
+// Sync downloads and connects blocks until the chain tip reaches target. It
+// fails only if ctx ends or every source has been dropped; no request is
+// left running when it returns.
+func (s *Syncer) Sync(ctx context.Context, target int) error {
+	window := s.Window
+	if window <= 0 {
+		window = DefaultSyncWindow
+	}
+	stallTimeout := s.StallTimeout
+	if stallTimeout <= 0 {
+		stallTimeout = DefaultStallTimeout
+	}
+
+	s.stats = make(map[string]*PeerSyncStats)
+	idle := make([]BlockSource, 0, len(s.Sources))
+	for _, src := range s.Sources {
+		s.stats[src.ID()] = &PeerSyncStats{}
+		idle = append(idle, src)
+	}
+	dropped := make(map[string]bool)
+
+	next := s.Chain.Tip().Index + 1
+	requested := next // lowest height not yet requested from anyone
+	var retry []int   // heights whose source was dropped
+	downloaded := make(map[int]syncResult)
+	inflight := make(map[int]syncRequest)
+
+	// Cancelling ctx on return stops every request still running, stalled
+	// ones included.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan syncResult)
+	ticker := time.NewTicker(stallTimeout / 4)
+	defer ticker.Stop()
+
+	// drop stops using src: its request is cancelled, and heights it was
+	// serving or had delivered but not yet connected are asked for again.
+	drop := func(src BlockSource) {
+		id := src.ID()
+		dropped[id] = true
+		for height, req := range inflight {
+			if req.source.ID() == id {
+				req.cancel()
+				delete(inflight, height)
+				retry = append(retry, height)
+			}
+		}
+		for height, r := range downloaded {
+			if r.source.ID() == id {
+				delete(downloaded, height)
+				retry = append(retry, height)
+			}
+		}
+		kept := idle[:0]
+		for _, other := range idle {
+			if other.ID() != id {
+				kept = append(kept, other)
+			}
+		}
+		idle = kept
+	}
+
+	for next <= target {
+		// Hand out work to idle sources.
+		for len(idle) > 0 {
+			var height int
+			switch {
+			case len(retry) > 0:
+				height, retry = retry[0], retry[1:]
+			case requested <= target && requested < next+window:
+				height = requested
+				requested++
+			default:
+				height = -1
+			}
+			if height < 0 {
+				break
+			}
+			src := idle[0]
+			idle = idle[1:]
+			reqCtx, reqCancel := context.WithCancel(ctx)
+			inflight[height] = syncRequest{source: src, started: time.Now(), cancel: reqCancel}
+			go func(src BlockSource, height int) {
+				start := time.Now()
+				b, err := src.BlockByHeight(reqCtx, height)
+				r := syncResult{source: src, height: height, block: b, err: err, took: time.Since(start)}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+				}
+			}(src, height)
+		}
+
+		if len(inflight) == 0 {
+			return errors.New("sync: no usable sources left")
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("sync: %w", ctx.Err())
+
+		case r := <-results:
+			id := r.source.ID()
+			if dropped[id] {
+				continue
+			}
+			req, ok := inflight[r.height]
+			if !ok || req.source.ID() != id {
+				continue
+			}
+			delete(inflight, r.height)
+			req.cancel()
+
+			stats := s.stats[id]
+			stats.Busy += r.took
+			if r.err == nil && r.block.Index != r.height {
+				r.err = fmt.Errorf("sent block %d for height %d", r.block.Index, r.height)
+			}
+			if r.err != nil {
+				stats.Err = r.err
+				retry = append(retry, r.height)
+				drop(r.source)
+				continue
+			}
+			stats.Blocks++
+			downloaded[r.height] = r
+			idle = append(idle, r.source)
+
+		case now := <-ticker.C:
+			for _, req := range inflight {
+				if now.Sub(req.started) < stallTimeout {
+					continue
+				}
+				s.stats[req.source.ID()].Stalled = true
+				drop(req.source)
+			}
+		}
+
+		// Connect whatever is now contiguous with the tip. A block the
+		// chain rejects is the sender's fault, not the sync's: drop the
+		// sender and ask someone else for that height.
+		for {
+			r, ok := downloaded[next]
+			if !ok {
+				break
+			}
+			delete(downloaded, next)
+			if err := s.Chain.AddBlock(r.block); err != nil {
+				s.stats[r.source.ID()].Err = fmt.Errorf("invalid block %d: %w", next, err)
+				retry = append(retry, next)
+				drop(r.source)
+				break
+			}
+			next++
+		}
+	}
+	return nil
+}
+
+// Stats returns per-source statistics from the last Sync.
+func (s *Syncer) Stats() map[string]PeerSyncStats {
+	out := make(map[string]PeerSyncStats, len(s.stats))
+	for id, st := range s.stats {
+		out[id] = *st
+	}
+	for _, src := range s.Sources {
+		if m, ok := src.(*MeteredSource); ok {
+			if st, ok := out[m.ID()]; ok {
+				st.Traffic = m.Traffic()
+				out[m.ID()] = st
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSource serves a fixed chain's blocks, optionally after a per-height
+// delay, and gives up when its context ends.
+type testSource struct {
+	id     string
+	blocks []Block // indexed by height
+	delay  func(height int) time.Duration
+	tamper bool // send every block with its nonce changed
+}
+
+func (s *testSource) ID() string { return s.id }
+
+func (s *testSource) BlockByHeight(ctx context.Context, height int) (Block, error) {
+	if s.delay != nil {
+		select {
+		case <-time.After(s.delay(height)):
+		case <-ctx.Done():
+			return Block{}, ctx.Err()
+		}
+	}
+	b := s.blocks[height]
+	if s.tamper {
+		b.Nonce++
+	}
+	return b, nil
+}
+
+// stalledSource never answers; done is closed once its request has been
+// cancelled.
+type stalledSource struct {
+	done chan struct{}
+}
+
+func (s *stalledSource) ID() string { return "stalled" }
+
+func (s *stalledSource) BlockByHeight(ctx context.Context, height int) (Block, error) {
+	<-ctx.Done()
+	close(s.done)
+	return Block{}, ctx.Err()
+}
+
+// syncFixture returns a chain of n blocks past genesis to serve, and an
+// empty chain on the same genesis to sync into.
+func syncFixture(t *testing.T, n int) ([]Block, *Chain) {
+	t.Helper()
+	source := newTestChain(t, testConfig(nil))
+	blocks := append([]Block{*source.genesis}, mineBlocks(t, source, n)...)
+	return blocks, newTestChain(t, testConfig(nil))
+}
+
+func checkSynced(t *testing.T, c *Chain, blocks []Block) {
+	t.Helper()
+	want := blocks[len(blocks)-1]
+	if tip := c.Tip(); tip.Hash != want.Hash {
+		t.Errorf("tip is block %d %s, want %d %s", tip.Index, tip.Hash, want.Index, want.Hash)
+	}
+}
+
+func TestSyncConnectsOutOfOrderBlocks(t *testing.T) {
+	blocks, c := syncFixture(t, 20)
+	// Later heights in each window come back first.
+	reverse := func(height int) time.Duration { return time.Duration(8-height%8) * time.Millisecond }
+	s := &Syncer{
+		Chain:   c,
+		Sources: []BlockSource{&testSource{id: "a", blocks: blocks, delay: reverse}, &testSource{id: "b", blocks: blocks, delay: reverse}},
+		Window:  8,
+	}
+	if err := s.Sync(context.Background(), 20); err != nil {
+		t.Fatal(err)
+	}
+	checkSynced(t, c, blocks)
+	stats := s.Stats()
+	if got := stats["a"].Blocks + stats["b"].Blocks; got != 20 {
+		t.Errorf("sources served %d blocks, want 20", got)
+	}
+}
+
+func TestSyncDropsSourceOfInvalidBlocks(t *testing.T) {
+	blocks, c := syncFixture(t, 10)
+	slow := func(int) time.Duration { return time.Millisecond }
+	s := &Syncer{
+		Chain: c,
+		Sources: []BlockSource{
+			&testSource{id: "mallory", blocks: blocks, tamper: true},
+			&testSource{id: "honest", blocks: blocks, delay: slow},
+		},
+	}
+	if err := s.Sync(context.Background(), 10); err != nil {
+		t.Fatalf("one bad source failed the sync: %v", err)
+	}
+	checkSynced(t, c, blocks)
+	stats := s.Stats()
+	if err := stats["mallory"].Err; err == nil || !strings.Contains(err.Error(), "invalid block") {
+		t.Errorf("mallory's error = %v, want an invalid block", err)
+	}
+	if stats["honest"].Err != nil {
+		t.Errorf("honest source was dropped: %v", stats["honest"].Err)
+	}
+
+	// With nobody honest left there is nothing to fall back on.
+	_, c = syncFixture(t, 0)
+	s = &Syncer{Chain: c, Sources: []BlockSource{&testSource{id: "mallory", blocks: blocks, tamper: true}}}
+	if err := s.Sync(context.Background(), 10); err == nil || !strings.Contains(err.Error(), "no usable sources") {
+		t.Errorf("err = %v, want no usable sources", err)
+	}
+}
+
+func TestSyncDropsStalledSource(t *testing.T) {
+	blocks, c := syncFixture(t, 10)
+	stalled := &stalledSource{done: make(chan struct{})}
+	s := &Syncer{
+		Chain:        c,
+		Sources:      []BlockSource{stalled, &testSource{id: "honest", blocks: blocks}},
+		StallTimeout: 40 * time.Millisecond,
+	}
+	if err := s.Sync(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	checkSynced(t, c, blocks)
+	if !s.Stats()["stalled"].Stalled {
+		t.Error("stalled source not marked stalled")
+	}
+	// Its request was cancelled rather than left running.
+	select {
+	case <-stalled.done:
+	case <-time.After(time.Second):
+		t.Error("stalled request still running after Sync returned")
+	}
+}
+
+func TestSyncStopsWithContext(t *testing.T) {
+	blocks, c := syncFixture(t, 5)
+	stalled := &stalledSource{done: make(chan struct{})}
+	s := &Syncer{Chain: c, Sources: []BlockSource{stalled}, StallTimeout: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Sync(ctx, len(blocks)-1); err == nil {
+		t.Fatal("sync finished without any blocks")
+	}
+	select {
+	case <-stalled.done:
+	case <-time.After(time.Second):
+		t.Error("request still running after Sync returned")
+	}
+}
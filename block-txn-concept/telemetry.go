@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SoftwareVersion identifies this code in telemetry reports.
+const SoftwareVersion = "go-principals/block-txn-concept"
+
+// TelemetryReport is what a node sends to a collector. It is anonymous: the
+// node ID is random per client, and no addresses, keys, or transactions
+// are included.
+type TelemetryReport struct {
+	NodeID        string    `json:"nodeId"`
+	Version       string    `json:"version"`
+	HeaderVersion uint32    `json:"headerVersion"`
+	ChainID       string    `json:"chainId,omitempty"`
+	Height        int       `json:"height"`
+	Peers         int       `json:"peers"`
+	HashRate      float64   `json:"hashRate"` // hashes per second, 0 if not mining
+	Time          time.Time `json:"time"`
+}
+
+// TelemetryClient reports node statistics to a collector. Telemetry is
+// opt-in: nothing is sent unless a client is created with an endpoint.
+type TelemetryClient struct {
+	Endpoint string // collector URL, e.g. http://host:9090/report
+	Interval time.Duration
+	ChainID  string
+
+	// Height and Peers supply the current values for each report. Peers
+	// may be nil; there is no peer-to-peer layer to count yet.
+	Height func() int
+	Peers  func() int
+
+	nodeID string
+	client *http.Client
+
+	mu       sync.Mutex
+	hashRate float64
+}
+
+// NewTelemetryClient creates a client with a fresh random node ID.
+func NewTelemetryClient(endpoint string) (*TelemetryClient, error) {
+	if endpoint == "" {
+		return nil, errors.New("telemetry needs a collector endpoint")
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &TelemetryClient{
+		Endpoint: endpoint,
+		nodeID:   hex.EncodeToString(id),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// ObserveMining records the hash rate from a mining progress report. It has
+// the signature of Miner.OnProgress so it can be plugged in directly.
+func (t *TelemetryClient) ObserveMining(p MiningProgress) {
+	t.mu.Lock()
+	t.hashRate = p.HashRate
+	t.mu.Unlock()
+}
+
+// Snapshot builds a report from the current stats.
+func (t *TelemetryClient) Snapshot() TelemetryReport {
+	r := TelemetryReport{
+		NodeID:        t.nodeID,
+		Version:       SoftwareVersion,
+		HeaderVersion: CurrentBlockVersion,
+		ChainID:       t.ChainID,
+		Time:          time.Now().UTC(),
+	}
+	if t.Height != nil {
+		r.Height = t.Height()
+	}
+	if t.Peers != nil {
+		r.Peers = t.Peers()
+	}
+	t.mu.Lock()
+	r.HashRate = t.hashRate
+	t.mu.Unlock()
+	return r
+}
+
+// Report sends one report to the collector.
+func (t *TelemetryClient) Report() error {
+	data, err := json.Marshal(t.Snapshot())
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.Endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Run reports every Interval until a report fails. Failures are returned
+// rather than retried so a broken endpoint doesn't go unnoticed.
+func (t *TelemetryClient) Run() error {
+	interval := t.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	for {
+		if err := t.Report(); err != nil {
+			return err
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Collector receives telemetry reports and shows the latest one from each
+// node. It keeps no history and never records where a report came from.
+type Collector struct {
+	// Expiry drops nodes that haven't reported for this long from the
+	// summary. Zero keeps them forever.
+	Expiry time.Duration
+
+	mu    sync.Mutex
+	nodes map[string]TelemetryReport
+	seen  map[string]time.Time
+}
+
+// NewCollector creates an empty collector.
+func NewCollector(expiry time.Duration) *Collector {
+	return &Collector{
+		Expiry: expiry,
+		nodes:  make(map[string]TelemetryReport),
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// maxReportSize bounds a report body; real ones are a few hundred bytes.
+const maxReportSize = 4 << 10
+
+// ServeHTTP accepts reports with POST /report, and serves a plain-text
+// summary on GET / and the raw reports on GET /nodes.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/report":
+		var report TelemetryReport
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxReportSize)).Decode(&report); err != nil {
+			http.Error(w, "bad report: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if report.NodeID == "" {
+			http.Error(w, "report has no node ID", http.StatusBadRequest)
+			return
+		}
+		c.Add(report)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && r.URL.Path == "/nodes":
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(c.Nodes())
+	case r.Method == http.MethodGet && r.URL.Path == "/":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		c.WriteSummary(w)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Add records report as its node's latest.
+func (c *Collector) Add(report TelemetryReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes[report.NodeID] = report
+	c.seen[report.NodeID] = time.Now()
+}
+
+// Nodes returns the latest report from every live node, highest first.
+func (c *Collector) Nodes() []TelemetryReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nodes := make([]TelemetryReport, 0, len(c.nodes))
+	for id, r := range c.nodes {
+		if c.Expiry > 0 && time.Since(c.seen[id]) > c.Expiry {
+			delete(c.nodes, id)
+			delete(c.seen, id)
+			continue
+		}
+		nodes = append(nodes, r)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Height != nodes[j].Height {
+			return nodes[i].Height > nodes[j].Height
+		}
+		return nodes[i].NodeID < nodes[j].NodeID
+	})
+	return nodes
+}
+
+// WriteSummary writes a one-screen overview of the network: totals, then
+// one line per node.
+func (c *Collector) WriteSummary(w io.Writer) error {
+	nodes := c.Nodes()
+	var hashRate float64
+	best := 0
+	for _, n := range nodes {
+		hashRate += n.HashRate
+		best = max(best, n.Height)
+	}
+	if _, err := fmt.Fprintf(w, "%d nodes, best height %d, total hash rate %.0f H/s\n\n", len(nodes), best, hashRate); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%-16s %-10s %8s %6s %6s %12s  %s\n", "node", "chain", "height", "behind", "peers", "H/s", "last report")
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%-16s %-10s %8d %6d %6d %12.0f  %s\n",
+			n.NodeID, n.ChainID, n.Height, best-n.Height, n.Peers, n.HashRate, n.Time.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// runTelemetry implements the "telemetry" command:
+//
+//	telemetry serve [-addr :9090] [-expiry 5m]
+//	telemetry report -endpoint URL [-chain chain.json] [-interval 1m] [-once]
+//
+// report follows a stored chain file and reports its height.
+func runTelemetry(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: telemetry serve|report [flags]")
+	}
+	fs := flag.NewFlagSet("telemetry "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "serve":
+		addr := fs.String("addr", ":9090", "address to listen on")
+		expiry := fs.Duration("expiry", 5*time.Minute, "drop nodes silent for this long")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		fmt.Printf("Collecting telemetry on %s (POST /report, GET / for the summary)\n", *addr)
+		return http.ListenAndServe(*addr, NewCollector(*expiry))
+
+	case "report":
+		endpoint := fs.String("endpoint", "", "collector URL, e.g. http://host:9090/report")
+		chainPath := fs.String("chain", "chain.json", "stored chain to report on")
+		chainID := fs.String("chain-id", "", "chain ID to include in reports")
+		interval := fs.Duration("interval", time.Minute, "how often to report")
+		once := fs.Bool("once", false, "send a single report and exit")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		client, err := NewTelemetryClient(*endpoint)
+		if err != nil {
+			return err
+		}
+		client.ChainID = *chainID
+		client.Interval = *interval
+		client.Height = func() int {
+			blocks, err := LoadBlocks(*chainPath)
+			if err != nil || len(blocks) == 0 {
+				return 0
+			}
+			return blocks[len(blocks)-1].Index
+		}
+		if *once {
+			return client.Report()
+		}
+		return client.Run()
+
+	default:
+		return fmt.Errorf("unknown telemetry command %q", args[0])
+	}
+}
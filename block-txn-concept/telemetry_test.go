@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelemetryReportsReachTheCollector(t *testing.T) {
+	col := NewCollector(0)
+	srv := httptest.NewServer(col)
+	defer srv.Close()
+
+	var clients []*TelemetryClient
+	for i, height := range []int{12, 15} {
+		cl, err := NewTelemetryClient(srv.URL + "/report")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cl.ChainID = "test"
+		cl.Height = func() int { return height }
+		cl.ObserveMining(MiningProgress{HashRate: float64(1000 * (i + 1))})
+		if err := cl.Report(); err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, cl)
+	}
+	if clients[0].nodeID == clients[1].nodeID || len(clients[0].nodeID) != 16 {
+		t.Errorf("node IDs %q and %q, want distinct random ones", clients[0].nodeID, clients[1].nodeID)
+	}
+
+	resp, err := http.Get(srv.URL + "/nodes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var nodes []TelemetryReport
+	if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].Height != 15 || nodes[1].Height != 12 {
+		t.Fatalf("nodes %+v, want both, highest first", nodes)
+	}
+	if n := nodes[0]; n.NodeID != clients[1].nodeID || n.ChainID != "test" || n.HashRate != 2000 || n.Version != SoftwareVersion {
+		t.Errorf("report %+v", n)
+	}
+
+	// A newer report replaces the node's last one.
+	clients[0].Height = func() int { return 16 }
+	if err := clients[0].Report(); err != nil {
+		t.Fatal(err)
+	}
+	var summary bytes.Buffer
+	if err := col.WriteSummary(&summary); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(summary.String(), "2 nodes, best height 16, total hash rate 3000 H/s") {
+		t.Errorf("summary:\n%s", summary.String())
+	}
+}
+
+func TestCollectorRejectsBadReports(t *testing.T) {
+	col := NewCollector(0)
+	for _, body := range []string{"not json", `{"height":3}`} {
+		rec := httptest.NewRecorder()
+		col.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/report", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400", body, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	col.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/report", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE: status %d, want 404", rec.Code)
+	}
+	if len(col.Nodes()) != 0 {
+		t.Error("a rejected report was recorded")
+	}
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	cl, err := NewTelemetryClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cl.Report(); err == nil {
+		t.Error("a 404 from the collector wasn't reported")
+	}
+	if _, err := NewTelemetryClient(""); err == nil {
+		t.Error("a client without an endpoint was created")
+	}
+}
+
+func TestCollectorExpiresSilentNodes(t *testing.T) {
+	col := NewCollector(time.Minute)
+	col.Add(TelemetryReport{NodeID: "old", Height: 1})
+	col.Add(TelemetryReport{NodeID: "new", Height: 2})
+	col.seen["old"] = time.Now().Add(-2 * time.Minute)
+	if nodes := col.Nodes(); len(nodes) != 1 || nodes[0].NodeID != "new" {
+		t.Errorf("nodes %+v, want only the one that reported recently", nodes)
+	}
+}
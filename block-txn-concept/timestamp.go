@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxFutureBlockTime is how far past the local clock a block's timestamp
+// may be. It allows for clocks that disagree a little, not for proposers
+// that want a later time than they have.
+const MaxFutureBlockTime = 2 * time.Hour
+
+// ErrBadTimestamp is returned for a block whose timestamp is too far in
+// the future or breaks its engine's rules for following its parent.
+var ErrBadTimestamp = errors.New("bad block timestamp")
+
+// ParentEngine is implemented by engines with rules that relate a block to
+// its parent, such as whose turn it is. The chain calls VerifyParent for
+// every block it adds, including those whose signatures are assumed valid,
+// with now read from ChainConfig.Clock.
+type ParentEngine interface {
+	VerifyParent(parent, b Block, now time.Time) error
+}
+
+// validateTimestamp checks that b is no more than MaxFutureBlockTime ahead
+// of the chain's clock, then applies the engine's parent rules. Timestamps
+// are chosen by the proposer, and difficulty retargeting, the authority
+// rotation, and time-based expiry all read them, so none of those can
+// trust a timestamp that hasn't passed this.
+func (c *Chain) validateTimestamp(parent, b Block) error {
+	now := c.clockAt(b.Timestamp)
+	if limit := now.Add(MaxFutureBlockTime); b.Timestamp.After(limit) {
+		return fmt.Errorf("block %d: %w: %s is more than %v in the future", b.Index, ErrBadTimestamp,
+			b.Timestamp.Format(time.RFC3339Nano), MaxFutureBlockTime)
+	}
+	if e, ok := c.config.Engine.(ParentEngine); ok {
+		return e.VerifyParent(parent, b, now)
+	}
+	return nil
+}
+
+// readClock reads the chain's clock and remembers the reading.
+func (c *Chain) readClock() time.Time {
+	c.lastClock = c.config.Clock()
+	return c.lastClock
+}
+
+// clockAt returns the chain's time for judging whether t has arrived. A
+// clock only moves forward, so once an earlier reading is at or past t it
+// answers that as well as a new one would; reading the clock only when it
+// must leaves a StepClock advancing once per block built, not per block
+// checked.
+func (c *Chain) clockAt(t time.Time) time.Time {
+	if !t.After(c.lastClock) {
+		return c.lastClock
+	}
+	return c.readClock()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// peerBlock builds a block on cfg as a peer whose clock reads at would.
+func peerBlock(t *testing.T, cfg ChainConfig, at time.Time) Block {
+	t.Helper()
+	cfg.Clock = func() time.Time { return at }
+	b, err := newTestChain(t, cfg).BuildBlock("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestChainChecksTimestampsAgainstItsClock(t *testing.T) {
+	now := testStart.Add(time.Hour)
+	for _, tc := range []struct {
+		name  string
+		at    time.Time
+		valid bool
+	}{
+		{"within the allowed drift", now.Add(MaxFutureBlockTime), true},
+		{"past the allowed drift", now.Add(MaxFutureBlockTime + time.Second), false},
+		// Only engines with parent rules order timestamps.
+		{"same as parent", testStart, true},
+		{"before parent", testStart.Add(-time.Second), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig(nil)
+			b := peerBlock(t, cfg, tc.at)
+			cfg.Clock = func() time.Time { return now }
+			err := newTestChain(t, cfg).AddBlock(b)
+			if tc.valid && err != nil {
+				t.Error(err)
+			}
+			if !tc.valid && !errors.Is(err, ErrBadTimestamp) {
+				t.Errorf("err = %v, want ErrBadTimestamp", err)
+			}
+		})
+	}
+}
+
+// TestCheckingBlocksKeepsStepClockPace checks that adding a block the chain
+// built doesn't read the clock again, so a StepClock still moves one step a
+// block.
+func TestCheckingBlocksKeepsStepClockPace(t *testing.T) {
+	c := newTestChain(t, testConfig(nil))
+	for i, b := range mineBlocks(t, c, 3) {
+		if want := testStart.Add(time.Duration(i+1) * 10 * time.Second); !b.Timestamp.Equal(want) {
+			t.Errorf("block %d at %v, want %v", b.Index, b.Timestamp, want)
+		}
+	}
+}
+
+func TestAuthorityStepRules(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	e := &AuthorityEngine{
+		Authorities: []Authority{
+			{Address: alice.Addr, PubKey: &alice.Key.PublicKey},
+			{Address: bob.Addr, PubKey: &bob.Key.PublicKey},
+		},
+		StepDuration: 10 * time.Second,
+		Address:      alice.Addr,
+		Key:          alice.Key,
+	}
+	// aliceStep returns the start of the first of alice's steps at or
+	// after t.
+	aliceStep := func(t time.Time) time.Time {
+		s := e.Step(t)
+		if s%2 != 0 {
+			s++
+		}
+		return time.Unix(0, s*int64(e.StepDuration)).UTC()
+	}
+	start := aliceStep(testStart)
+
+	for _, tc := range []struct {
+		name  string
+		at    time.Time // the block's timestamp
+		now   time.Time // the checking chain's clock
+		valid bool
+	}{
+		{"next own step", start.Add(2 * e.StepDuration), start.Add(2 * e.StepDuration), true},
+		{"same step as parent", start.Add(time.Second), start.Add(time.Second), false},
+		{"step not begun", start.Add(2 * e.StepDuration), start.Add(e.StepDuration), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig(nil)
+			cfg.Engine = e
+			b := peerBlock(t, cfg, tc.at)
+			cfg.Clock = func() time.Time { return tc.now }
+			err := newTestChain(t, cfg).AddBlock(b)
+			if tc.valid && err != nil {
+				t.Error(err)
+			}
+			if !tc.valid && !errors.Is(err, ErrBadTimestamp) {
+				t.Errorf("err = %v, want ErrBadTimestamp", err)
+			}
+		})
+	}
+}
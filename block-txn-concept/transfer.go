@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxTransfers is the most legs a multi-transfer may have.
+const MaxTransfers = 1000
+
+// Transfer is one leg of a multi-transfer transaction: Amount paid to To.
+// A multi-transfer moves funds from its sender to every leg in one atomic
+// unit, applying all legs or none, and its hash covers every leg.
+type Transfer struct {
+	To     string
+	Amount Amount
+}
+
+// Legs returns what tx pays and to whom: its Transfers, or for an ordinary
+// transaction the single leg of To and Amount.
+func (t Transaction) Legs() []Transfer {
+	if len(t.Transfers) > 0 {
+		return t.Transfers
+	}
+	return []Transfer{{To: t.To, Amount: t.Amount}}
+}
+
+// Total is the sum of tx's legs, which its sender pays on top of the fee.
+// It fails with ErrAmountRange if the sum overflows, which checkLegs rules
+// out for any transaction that passes it.
+func (t Transaction) Total() (Amount, error) {
+	var total Amount
+	for _, l := range t.Legs() {
+		var err error
+		if total, err = total.Add(l.Amount); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// Cost is what tx debits its sender: its Total plus the fee.
+func (t Transaction) Cost() (Amount, error) {
+	total, err := t.Total()
+	if err != nil {
+		return 0, err
+	}
+	return total.Add(t.Fee)
+}
+
+// Pays returns how much tx pays addr across all its legs.
+func (t Transaction) Pays(addr string) Amount {
+	var total Amount
+	for _, l := range t.Legs() {
+		if l.To == addr {
+			total += l.Amount
+		}
+	}
+	return total
+}
+
+// sendsTo reports whether any of tx's legs pays addr. Addresses are
+// compared case-insensitively, for stored chains whose addresses may be
+// written in mixed case.
+func (t Transaction) sendsTo(addr string) bool {
+	for _, l := range t.Legs() {
+		if strings.EqualFold(l.To, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// receivedBy is Pays with addresses compared as in sendsTo.
+func (t Transaction) receivedBy(addr string) Amount {
+	var total Amount
+	for _, l := range t.Legs() {
+		if strings.EqualFold(l.To, addr) {
+			total += l.Amount
+		}
+	}
+	return total
+}
+
+// payee describes tx's recipient for display: To, or how many recipients a
+// multi-transfer pays.
+func (t Transaction) payee() string {
+	if len(t.Transfers) > 0 {
+		return fmt.Sprintf("%d recipients", len(t.Transfers))
+	}
+	return t.To
+}
+
+// checkLegs checks that every leg names a recipient and an amount from 0 to
+// MaxAmount, that there are at most MaxTransfers of them, and that a
+// multi-transfer leaves To and Amount empty so there is only one place its
+// recipients can be read from. It checks the fee is in the same range, so
+// that the sender's Cost can be summed without overflowing.
+func checkLegs(tx Transaction) error {
+	if len(tx.Transfers) > 0 && (tx.To != "" || tx.Amount != 0) {
+		return errors.New("multi-transfer with To or Amount set")
+	}
+	if len(tx.Transfers) > MaxTransfers {
+		return fmt.Errorf("%d legs, at most %d", len(tx.Transfers), MaxTransfers)
+	}
+	for i, l := range tx.Legs() {
+		if l.To == "" {
+			return fmt.Errorf("leg %d: missing recipient", i)
+		}
+		if l.Amount < 0 {
+			return fmt.Errorf("leg %d: negative amount", i)
+		}
+		if l.Amount > MaxAmount {
+			return fmt.Errorf("leg %d: %v is more than %v: %w", i, l.Amount, MaxAmount, ErrAmountRange)
+		}
+	}
+	if tx.Fee < 0 {
+		return errors.New("negative fee")
+	}
+	if tx.Fee > MaxAmount {
+		return fmt.Errorf("fee %v is more than %v: %w", tx.Fee, MaxAmount, ErrAmountRange)
+	}
+	if _, err := tx.Cost(); err != nil {
+		return err
+	}
+	return nil
+}
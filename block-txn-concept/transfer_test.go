@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAmountAddSubOverflow(t *testing.T) {
+	if _, err := Amount(math.MaxInt64).Add(1); !errors.Is(err, ErrAmountRange) {
+		t.Errorf("MaxInt64 + 1: err = %v, want ErrAmountRange", err)
+	}
+	if _, err := Amount(math.MinInt64).Sub(1); !errors.Is(err, ErrAmountRange) {
+		t.Errorf("MinInt64 - 1: err = %v, want ErrAmountRange", err)
+	}
+	if _, err := Amount(0).Sub(math.MinInt64); !errors.Is(err, ErrAmountRange) {
+		t.Errorf("0 - MinInt64: err = %v, want ErrAmountRange", err)
+	}
+	if got, err := Amount(5).Add(-7); err != nil || got != -2 {
+		t.Errorf("5 + -7 = %d, %v", got, err)
+	}
+}
+
+func TestCheckLegsRejectsOverflow(t *testing.T) {
+	half := Amount(math.MaxInt64/2 + 1)
+	many := make([]Transfer, MaxTransfers+1)
+	for i := range many {
+		many[i] = Transfer{To: "0xb0b", Amount: 1}
+	}
+	for _, tc := range []struct {
+		name string
+		tx   Transaction
+	}{
+		{"two halves", Transaction{From: "0xa11ce", Transfers: []Transfer{{"0xb0b", half}, {"0xca401", half}}}},
+		{"max leg and fee", Transaction{From: "0xa11ce", To: "0xb0b", Amount: math.MaxInt64, Fee: 1}},
+		{"leg over MaxAmount", Transaction{From: "0xa11ce", To: "0xb0b", Amount: MaxAmount + 1}},
+		{"fee over MaxAmount", Transaction{From: "0xa11ce", To: "0xb0b", Amount: 1, Fee: MaxAmount + 1}},
+		{"too many legs", Transaction{From: "0xa11ce", Transfers: many}},
+	} {
+		if err := checkLegs(tc.tx); err == nil {
+			t.Errorf("%s: accepted", tc.name)
+		}
+	}
+	if _, err := (Transaction{Transfers: []Transfer{{"0xb0b", half}, {"0xca401", half}}}).Total(); !errors.Is(err, ErrAmountRange) {
+		t.Errorf("Total of two halves: err = %v, want ErrAmountRange", err)
+	}
+}
+
+// TestChainRejectsOverflowMint is the exploit where an account holding one
+// coin sends legs whose sum wraps around, crediting the payees with coins
+// that never existed.
+func TestChainRejectsOverflowMint(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	half := Amount(math.MaxInt64/2 + 1)
+	for _, tc := range []struct {
+		name string
+		tx   Transaction
+	}{
+		{"wrapping legs", Transaction{Transfers: []Transfer{{bob.Addr, half}, {carol.Addr, half}}}},
+		{"wrapping fee", Transaction{To: bob.Addr, Amount: math.MaxInt64, Fee: 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: Coin}))
+			tx := signedTx(t, c, alice, tc.tx)
+
+			// A block without a state root, so building it doesn't apply
+			// the transaction first.
+			b, err := c.buildBlock("", []Transaction{tx}, BlockVersion4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.AddBlock(b); err == nil {
+				t.Fatal("block minting coins was accepted")
+			}
+			if _, err := c.BuildBlock("", []Transaction{tx}); err == nil {
+				t.Error("BuildBlock built a block minting coins")
+			}
+			if got := tipBalance(t, c, bob.Addr); got != 0 {
+				t.Errorf("bob holds %v", got)
+			}
+			if got := tipBalance(t, c, alice.Addr); got != Coin {
+				t.Errorf("alice holds %v, want %v", got, Coin)
+			}
+		})
+	}
+}
+
+func TestApplyTxBalancesChecksSums(t *testing.T) {
+	balances := map[string]Amount{"0xa11ce": Coin, "0xb0b": math.MaxInt64}
+	tx := Transaction{From: "0xa11ce", To: "0xb0b", Amount: 1}
+	if err := applyTxBalances(balances, tx); !errors.Is(err, ErrAmountRange) {
+		t.Errorf("credit past MaxInt64: err = %v, want ErrAmountRange", err)
+	}
+	wrapping := Transaction{From: "0xa11ce", To: "0xb0b", Amount: math.MaxInt64, Fee: 1}
+	if _, err := storedBalance([]Block{{Transactions: []Transaction{wrapping}}}, nil, "0xa11ce"); err == nil {
+		t.Error("storedBalance summed a wrapping transaction")
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// TxTemplate is a saved, reusable payment.
+type TxTemplate struct {
+	Name        string `json:"name"`
+	To          string `json:"to"` // an address, or "@label" for a saved payee
+	Amount      Amount `json:"amount"`
+	Fee         Amount `json:"fee,omitempty"`
+	Description string `json:"description"`
+	Category    string `json:"category,omitempty"`
+}
+
+// TxBook holds saved payees and transaction templates.
+type TxBook struct {
+	Payees    map[string]string     `json:"payees"` // label -> address
+	Templates map[string]TxTemplate `json:"templates"`
+}
+
+// LoadTxBook reads a book from path. A missing file is an empty book.
+func LoadTxBook(path string) (*TxBook, error) {
+	book := &TxBook{Payees: map[string]string{}, Templates: map[string]TxTemplate{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return book, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, book); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// Save writes the book to path.
+func (b *TxBook) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// ResolvePayee turns "@label" into the saved address; anything else is
+// returned unchanged.
+func (b *TxBook) ResolvePayee(to string) (string, error) {
+	label, ok := strings.CutPrefix(to, "@")
+	if !ok {
+		return to, nil
+	}
+	addr, ok := b.Payees[label]
+	if !ok {
+		return "", fmt.Errorf("no saved payee %q", label)
+	}
+	return addr, nil
+}
+
+// runTx implements the "tx" command:
+//
+//	tx payee add <label> <address>
+//	tx payee list
+//	tx template save -name N -to ADDR|@label -amount X [-fee F] [-note TEXT] [-category C]
+//	tx template list
+//	tx template use -name N -from ADDR|-multisig POLICY [-key KEY.pem] [-out tx.json] [-to ...] [-amount X] [-fee F] [-note TEXT] [-category C] [-chain-id ID] [-fork-id ID] [-yes]
+//	tx multisig create -threshold M -keys HEX,HEX,... [-out multisig.json]
+//	tx multisig cosign -in tx.json -key KEY.pem [-out tx.json]
+//	tx payout [flags] payees.csv
+func runTx(args []string) error {
+	if len(args) > 0 && args[0] == "payout" {
+		return runPayout(args[1:])
+	}
+	if len(args) < 2 {
+		return errors.New("usage: tx payee add|list ... | tx template save|list|use ... | tx multisig create|cosign ... | tx payout ...")
+	}
+	group, action, rest := args[0], args[1], args[2:]
+
+	fs := flag.NewFlagSet("tx "+group+" "+action, flag.ContinueOnError)
+	store := fs.String("store", "txbook.json", "file holding payees and templates")
+	name := fs.String("name", "", "template name")
+	from := fs.String("from", "", "sender address")
+	to := fs.String("to", "", "recipient address or @payee")
+	amount := amountFlag(fs, "amount", 0, "amount")
+	fee := amountFlag(fs, "fee", 0, "fee paid to the miner")
+	note := fs.String("note", "", "description")
+	category := fs.String("category", "", "reporting category")
+	chainID := fs.String("chain-id", "", "chain the transaction is for")
+	forkID := fs.String("fork-id", "", "fork the transaction is for (see spec dump)")
+	nonce := fs.Uint64("nonce", 0, "number of transactions the sender has sent before this one")
+	keyPath := fs.String("key", "", "sender's private key to sign with (see genesis keygen)")
+	policyPath := fs.String("multisig", "", "multisig policy to send from (see tx multisig create)")
+	threshold := fs.Int("threshold", 0, "signatures a multisig policy requires")
+	keys := fs.String("keys", "", "comma-separated hex public keys of a multisig policy's participants")
+	in := fs.String("in", "", "transaction JSON to cosign")
+	out := fs.String("out", "", "file to write to")
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	if err := fs.Parse(rest); err != nil {
+		return err
+	}
+
+	book, err := LoadTxBook(*store)
+	if err != nil {
+		return err
+	}
+
+	switch group + " " + action {
+	case "payee add":
+		if fs.NArg() != 2 {
+			return errors.New("usage: tx payee add <label> <address>")
+		}
+		if _, err := canonicalAddress(fs.Arg(1)); err != nil {
+			return err
+		}
+		book.Payees[fs.Arg(0)] = fs.Arg(1)
+		return book.Save(*store)
+
+	case "payee list":
+		labels := make([]string, 0, len(book.Payees))
+		for label := range book.Payees {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			fmt.Printf("@%-12s %s\n", label, book.Payees[label])
+		}
+		return nil
+
+	case "template save":
+		if *name == "" || *to == "" || *amount <= 0 {
+			return errors.New("template save needs -name, -to, and a positive -amount")
+		}
+		if *fee < 0 {
+			return errors.New("-fee can't be negative")
+		}
+		book.Templates[*name] = TxTemplate{Name: *name, To: *to, Amount: *amount, Fee: *fee, Description: *note, Category: *category}
+		return book.Save(*store)
+
+	case "template list":
+		names := make([]string, 0, len(book.Templates))
+		for n := range book.Templates {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			t := book.Templates[n]
+			fmt.Printf("%-12s -> %s  %.2f  %s\n", t.Name, t.To, t.Amount, t.Description)
+		}
+		return nil
+
+	case "template use":
+		t, ok := book.Templates[*name]
+		if !ok {
+			return fmt.Errorf("no template named %q", *name)
+		}
+		var policy *MultisigPolicy
+		if *policyPath != "" {
+			if policy, err = LoadMultisigPolicy(*policyPath); err != nil {
+				return err
+			}
+			if *from == "" {
+				addr, err := policy.Address()
+				if err != nil {
+					return err
+				}
+				*from = addr.String()
+			}
+		}
+		if *from == "" {
+			return errors.New("template use needs -from or -multisig")
+		}
+		// Flags given on the command line override the template.
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "to":
+				t.To = *to
+			case "amount":
+				t.Amount = *amount
+			case "fee":
+				t.Fee = *fee
+			case "note":
+				t.Description = *note
+			case "category":
+				t.Category = *category
+			}
+		})
+		if t.Fee < 0 {
+			return errors.New("-fee can't be negative")
+		}
+		tx, err := book.buildTx(t, *from, *chainID, *forkID, *nonce)
+		if err != nil {
+			return err
+		}
+		tx.Multisig = policy
+		if *keyPath != "" {
+			key, err := readKeyFile(*keyPath)
+			if err != nil {
+				return err
+			}
+			sign := SignTx
+			if policy != nil {
+				sign = CosignTx
+			}
+			if err := sign(&tx, key); err != nil {
+				return err
+			}
+		}
+		if *out == "" {
+			return confirmAndEmit(tx, *yes, os.Stdin, os.Stdout, os.Stdout)
+		}
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		if err := confirmAndEmit(tx, *yes, os.Stdin, os.Stdout, f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+
+	case "multisig create":
+		var pubs []*ecdsa.PublicKey
+		for _, k := range strings.Split(*keys, ",") {
+			pub, err := ParsePublicKey(strings.TrimSpace(k))
+			if err != nil {
+				return fmt.Errorf("-keys: %w", err)
+			}
+			pubs = append(pubs, pub)
+		}
+		policy, err := NewMultisigPolicy(*threshold, pubs...)
+		if err != nil {
+			return err
+		}
+		addr, err := policy.Address()
+		if err != nil {
+			return err
+		}
+		if *out == "" {
+			*out = "multisig.json"
+		}
+		data, err := json.MarshalIndent(policy, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*out, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("%d-of-%d policy written to %s\nAddress: %s\n", policy.Threshold, len(policy.Keys), *out, addr.Checksum())
+		return nil
+
+	case "multisig cosign":
+		if *in == "" || *keyPath == "" {
+			return errors.New("multisig cosign needs -in and -key")
+		}
+		data, err := os.ReadFile(*in)
+		if err != nil {
+			return err
+		}
+		var tx Transaction
+		if err := json.Unmarshal(data, &tx); err != nil {
+			return err
+		}
+		key, err := readKeyFile(*keyPath)
+		if err != nil {
+			return err
+		}
+		if err := CosignTx(&tx, key); err != nil {
+			return err
+		}
+		if data, err = json.MarshalIndent(tx, "", "  "); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		if *out == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(*out, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d of %d signatures\n", len(tx.Cosigs), tx.Multisig.Threshold)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown tx command %q", group+" "+action)
+	}
+}
+
+// buildTx resolves a template into a concrete transaction from sender on
+// the given chain and fork, carrying the sender's next nonce.
+func (b *TxBook) buildTx(t TxTemplate, sender, chainID, forkID string, nonce uint64) (Transaction, error) {
+	to, err := b.ResolvePayee(t.To)
+	if err != nil {
+		return Transaction{}, err
+	}
+	return NewTxBuilder().
+		From(sender).To(to).Amount(t.Amount).Fee(t.Fee).
+		Bind(chainID, forkID).Nonce(nonce).
+		Description(t.Description).Category(t.Category).
+		Build()
+}
+
+// confirmAndEmit shows the fully resolved transaction on out, asks for
+// confirmation unless yes is set, and writes it to dst as JSON.
+func confirmAndEmit(tx Transaction, yes bool, in io.Reader, out, dst io.Writer) error {
+	fmt.Fprintln(out, "About to create:")
+	fmt.Fprintf(out, "  From   : %s\n", tx.From)
+	fmt.Fprintf(out, "  To     : %s\n", tx.To)
+	fmt.Fprintf(out, "  Amount : %.2f\n", tx.Amount)
+	if tx.Fee > 0 {
+		fmt.Fprintf(out, "  Fee    : %.2f\n", tx.Fee)
+	}
+	fmt.Fprintf(out, "  Note   : %s\n", tx.Description)
+	if tx.ChainID != "" {
+		fmt.Fprintf(out, "  Chain  : %s\n", tx.ChainID)
+	}
+	if tx.ForkID != "" {
+		fmt.Fprintf(out, "  Fork   : %s\n", tx.ForkID)
+	}
+	fmt.Fprintf(out, "  Nonce  : %d\n", tx.Nonce)
+	fmt.Fprintf(out, "  Hash   : %s\n", tx.Hash)
+	switch {
+	case tx.Multisig != nil:
+		fmt.Fprintf(out, "  Signed : %d of %d\n", len(tx.Cosigs), tx.Multisig.Threshold)
+	case len(tx.Signature) == 0:
+		fmt.Fprintln(out, "  Signed : no")
+	default:
+		fmt.Fprintln(out, "  Signed : yes")
+	}
+
+	if !yes {
+		fmt.Fprint(out, "Proceed? [y/N] ")
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("cancelled")
+		}
+	}
+
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tx)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTxTemplateUseResolvesPayeeAndOverrides(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	dir := t.TempDir()
+	store := filepath.Join(dir, "txbook.json")
+	out := filepath.Join(dir, "tx.json")
+
+	for _, args := range [][]string{
+		{"payee", "add", "-store", store, "bob", bob.Addr},
+		{"template", "save", "-store", store, "-name", "rent", "-to", "@bob", "-amount", "12.50", "-fee", "0.10", "-note", "rent", "-category", "housing"},
+		{"template", "use", "-store", store, "-name", "rent", "-from", alice.Addr, "-amount", "13", "-nonce", "4", "-chain-id", "test", "-out", out, "-yes"},
+	} {
+		if err := runTx(args); err != nil {
+			t.Fatalf("tx %s: %v", strings.Join(args[:2], " "), err)
+		}
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.To != bob.Addr || tx.From != alice.Addr {
+		t.Errorf("tx from %s to %s, want %s to the saved payee %s", tx.From, tx.To, alice.Addr, bob.Addr)
+	}
+	if tx.Amount != 13*Coin || tx.Fee != Coin/10 || tx.Description != "rent" || tx.Category != "housing" {
+		t.Errorf("amount %s fee %s note %q category %q; want the -amount override and the template's other fields", tx.Amount, tx.Fee, tx.Description, tx.Category)
+	}
+	if tx.Nonce != 4 || tx.ChainID != "test" || tx.Hash != computeTxHash(tx) {
+		t.Errorf("nonce %d, chain %q, hash %s", tx.Nonce, tx.ChainID, tx.Hash)
+	}
+
+	book, err := LoadTxBook(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Templates["rent"].Amount != 12*Coin+Coin/2 {
+		t.Errorf("using a template with overrides changed the saved one: %+v", book.Templates["rent"])
+	}
+}
+
+func TestTxBookErrors(t *testing.T) {
+	dir := t.TempDir()
+	store := filepath.Join(dir, "txbook.json")
+	book, err := LoadTxBook(store)
+	if err != nil || len(book.Payees) != 0 || len(book.Templates) != 0 {
+		t.Fatalf("missing file: book %+v, err %v; want an empty book", book, err)
+	}
+	if _, err := book.ResolvePayee("@nobody"); err == nil {
+		t.Error("an unknown payee label resolved")
+	}
+	if addr, _ := book.ResolvePayee("0xabc"); addr != "0xabc" {
+		t.Errorf("a plain address resolved to %q", addr)
+	}
+	for _, args := range [][]string{
+		{"payee", "add", "-store", store, "bob", "0x1234"},
+		{"template", "save", "-store", store, "-name", "x", "-to", "@bob"},
+		{"template", "use", "-store", store, "-name", "missing", "-from", "0xabc"},
+	} {
+		if err := runTx(args); err == nil {
+			t.Errorf("tx %v: no error", args)
+		}
+	}
+}
+
+func TestConfirmAndEmitAsksFirst(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	tx, err := NewTxBuilder().From(alice.Addr).To(bob.Addr).Amount(Coin).SignWith(alice.Key).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shown, dst bytes.Buffer
+	if err := confirmAndEmit(tx, false, strings.NewReader("n\n"), &shown, &dst); err == nil || dst.Len() != 0 {
+		t.Errorf("declined: err %v, wrote %d bytes", err, dst.Len())
+	}
+	for _, want := range []string{tx.Hash, "Signed : yes", "Proceed?"} {
+		if !strings.Contains(shown.String(), want) {
+			t.Errorf("prompt lacks %q:\n%s", want, shown.String())
+		}
+	}
+	if err := confirmAndEmit(tx, false, strings.NewReader("yes\n"), &shown, &dst); err != nil {
+		t.Fatal(err)
+	}
+	var got Transaction
+	if err := json.Unmarshal(dst.Bytes(), &got); err != nil || got.Hash != tx.Hash {
+		t.Errorf("emitted %s (err %v), want the transaction", dst.String(), err)
+	}
+}
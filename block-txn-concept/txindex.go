@@ -0,0 +1,124 @@
+package main
+
+// TxLocation says where a transaction sits on the best chain.
+type TxLocation struct {
+	BlockHash string
+	Height    int
+	Position  int // index within the block's transactions
+}
+
+// updateTxIndex removes the transactions of blocks that left the best chain
+// from the transaction, address, and receipt indexes, and adds those of
+// blocks that joined it.
+func (c *Chain) updateTxIndex(connected, disconnected []Block) {
+	// Disconnected blocks are the newest on the old branch, so their
+	// transactions are at the end of each address's history. Undo them
+	// newest first.
+	for i := len(disconnected) - 1; i >= 0; i-- {
+		b := disconnected[i]
+		hashes := b.TxHashes()
+		for _, hash := range hashes {
+			delete(c.txIndex, hash)
+			delete(c.receipts, hash)
+		}
+		if b.IsPruned() {
+			c.unindexPrunedHistory(hashes)
+			continue
+		}
+		for j := len(b.Transactions) - 1; j >= 0; j-- {
+			tx := b.Transactions[j]
+			if tx.Reverses != "" {
+				delete(c.reversals, tx.Reverses)
+			}
+			for _, addr := range txAddresses(tx) {
+				h := c.addrIndex[addr]
+				if n := len(h); n > 0 && h[n-1] == tx.Hash {
+					h = h[:n-1]
+				}
+				if len(h) == 0 {
+					delete(c.addrIndex, addr)
+				} else {
+					c.addrIndex[addr] = h
+				}
+			}
+		}
+	}
+	for _, b := range connected {
+		for i, hash := range b.TxHashes() {
+			c.txIndex[hash] = TxLocation{BlockHash: b.Hash, Height: b.Index, Position: i}
+		}
+		for _, tx := range b.Transactions {
+			if tx.Reverses != "" {
+				c.reversals[tx.Reverses] = tx.Hash
+			}
+			for _, addr := range txAddresses(tx) {
+				c.addrIndex[addr] = append(c.addrIndex[addr], tx.Hash)
+			}
+		}
+	}
+	c.indexReceipts(connected)
+}
+
+// unindexPrunedHistory drops hashes from every address history. It is the
+// slow path for disconnecting a pruned block, whose senders and recipients
+// are no longer known.
+func (c *Chain) unindexPrunedHistory(hashes []string) {
+	drop := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		drop[hash] = true
+	}
+	for addr, h := range c.addrIndex {
+		kept := h[:0]
+		for _, hash := range h {
+			if !drop[hash] {
+				kept = append(kept, hash)
+			}
+		}
+		if len(kept) == 0 {
+			delete(c.addrIndex, addr)
+		} else {
+			c.addrIndex[addr] = kept
+		}
+	}
+}
+
+// txAddresses returns the distinct addresses a transaction touches.
+func txAddresses(tx Transaction) []string {
+	addrs := []string{tx.From}
+	seen := map[string]bool{tx.From: true}
+	for _, l := range tx.Legs() {
+		if !seen[l.To] {
+			seen[l.To] = true
+			addrs = append(addrs, l.To)
+		}
+	}
+	return addrs
+}
+
+// LocateTransaction returns where the best chain includes the transaction
+// with the given hash. It works for pruned blocks too.
+func (c *Chain) LocateTransaction(hash string) (TxLocation, bool) {
+	loc, ok := c.txIndex[hash]
+	return loc, ok
+}
+
+// GetTransaction returns a best-chain transaction by hash along with its
+// location. It reports false if the transaction isn't on the best chain or
+// its block has been pruned.
+func (c *Chain) GetTransaction(hash string) (Transaction, TxLocation, bool) {
+	loc, ok := c.txIndex[hash]
+	if !ok {
+		return Transaction{}, TxLocation{}, false
+	}
+	b := c.blocks[loc.BlockHash]
+	if b.IsPruned() {
+		return Transaction{}, loc, false
+	}
+	return b.Transactions[loc.Position], loc, true
+}
+
+// GetHistory returns the hashes of every best-chain transaction sent from or
+// to addr, oldest first.
+func (c *Chain) GetHistory(addr string) []string {
+	return append([]string(nil), c.addrIndex[addr]...)
+}
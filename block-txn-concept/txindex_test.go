@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTxIndexFollowsTheBestChain(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	cfg.PruneDepth = 3
+	c := newTestChain(t, cfg)
+	first := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	if err := mineTxs(t, c, first); err != nil {
+		t.Fatal(err)
+	}
+	second := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 2 * Coin})
+	if err := mineTxs(t, c, second); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, loc, ok := c.GetTransaction(second.Hash)
+	if !ok || tx.Hash != second.Hash || loc != (TxLocation{BlockHash: c.Tip().Hash, Height: 2, Position: 0}) {
+		t.Errorf("GetTransaction = %s at %+v, %v", tx.Hash, loc, ok)
+	}
+	if _, ok := c.LocateTransaction("0xdead"); ok {
+		t.Error("an unknown hash was located")
+	}
+
+	// Once block 1 is pruned its transaction can still be located, but not
+	// read.
+	mineBlocks(t, c, cfg.PruneDepth)
+	loc, ok = c.LocateTransaction(first.Hash)
+	if !ok || loc.Height != 1 {
+		t.Errorf("pruned transaction located at %+v, %v", loc, ok)
+	}
+	if _, _, ok := c.GetTransaction(first.Hash); ok {
+		t.Error("GetTransaction returned a pruned transaction")
+	}
+
+	// A longer branch without block 2 drops its transaction from the index.
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	rival := newTestChain(t, rivalCfg)
+	if err := mineTxs(t, rival, first); err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, rival, c.Tip().Index)
+	for _, b := range rival.BestChain()[1:] {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Tip().Hash != rival.Tip().Hash {
+		t.Fatal("the rival branch didn't become the tip")
+	}
+	if _, ok := c.LocateTransaction(second.Hash); ok {
+		t.Error("a transaction from a disconnected block is still indexed")
+	}
+	if loc, ok := c.LocateTransaction(first.Hash); !ok || loc.BlockHash != rival.BestChain()[1].Hash {
+		t.Errorf("first transaction located at %+v, want the rival's block 1", loc)
+	}
+}
+
+func TestAddressHistory(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	toBob := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 3 * Coin})
+	if err := mineTxs(t, c, toBob); err != nil {
+		t.Fatal(err)
+	}
+	split := signedTx(t, c, bob, Transaction{Transfers: []Transfer{{To: carol.Addr, Amount: Coin}, {To: alice.Addr, Amount: Coin}, {To: carol.Addr, Amount: Coin}}})
+	if err := mineTxs(t, c, split); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		addr string
+		want []string
+	}{
+		{alice.Addr, []string{toBob.Hash, split.Hash}},
+		{bob.Addr, []string{toBob.Hash, split.Hash}},
+		{carol.Addr, []string{split.Hash}}, // once, though paid by two legs
+		{newTestAccount(t).Addr, nil},
+	} {
+		if got := c.GetHistory(tc.addr); !sameHashes(got, tc.want) {
+			t.Errorf("%s: history %v, want %v", tc.addr, got, tc.want)
+		}
+	}
+
+	// The history is a copy.
+	c.GetHistory(alice.Addr)[0] = "changed"
+	if c.GetHistory(alice.Addr)[0] != toBob.Hash {
+		t.Error("changing a returned history changed the index")
+	}
+
+	// Replacing block 2 with a longer branch unwinds only its entries.
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	rival := newTestChain(t, rivalCfg)
+	if err := rival.AddBlock(c.BestChain()[1]); err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, rival, 2)
+	for _, b := range rival.BestChain()[2:] {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Tip().Hash != rival.Tip().Hash {
+		t.Fatal("the rival branch didn't become the tip")
+	}
+	if got := c.GetHistory(bob.Addr); !sameHashes(got, []string{toBob.Hash}) {
+		t.Errorf("bob's history after the reorg: %v", got)
+	}
+	if got := c.GetHistory(carol.Addr); got != nil {
+		t.Errorf("carol's history after the reorg: %v, want none", got)
+	}
+}
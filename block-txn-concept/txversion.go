@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Transaction formats. A transaction's Version decides how its hash is
+// computed, so a hash never changes once signed: a new field goes into a
+// new version, whose hash covers it, while older versions keep hashing as
+// they always did and can't set it.
+const (
+	// TxVersion0 is the original format. Its hash concatenates the first
+	// fields and writes each field added since only when it is set, so
+	// transactions from before a field existed keep their hashes.
+	TxVersion0 uint32 = 0
+	// TxVersion1 hashes a version prefix and then every field, set or not,
+	// each tagged and length-prefixed, with times in UTC.
+	TxVersion1 uint32 = 1
+	// TxVersion2 is TxVersion1 plus Conditions.
+	TxVersion2 uint32 = 2
+	// TxVersion3 is TxVersion2 plus Reverses.
+	TxVersion3 uint32 = 3
+
+	// CurrentTxVersion is the format TxBuilder and the tx command write.
+	CurrentTxVersion = TxVersion3
+)
+
+// ErrTxVersion is returned for a transaction in a format this node doesn't
+// know.
+var ErrTxVersion = errors.New("unknown transaction version")
+
+// txFormat is how one version is hashed and which fields it may set.
+type txFormat struct {
+	hash func(w io.Writer, t Transaction)
+
+	// check, if set, rejects fields the version can't carry.
+	check func(t Transaction) error
+}
+
+// txFormats holds the rules for every version this node understands. A
+// version added later must also have laterFields reject its new fields in
+// the earlier ones, since their hashes wouldn't cover them.
+var txFormats = map[uint32]txFormat{
+	TxVersion0: {hash: hashTxV0, check: checkTxV0},
+	TxVersion1: {hash: hashTxV1, check: laterFields},
+	TxVersion2: {hash: hashTxV1, check: laterFields},
+	TxVersion3: {hash: hashTxV1},
+}
+
+// laterFields rejects fields added in versions after t's, which t's hash
+// doesn't cover: Conditions before TxVersion2 and Reverses before
+// TxVersion3.
+func laterFields(t Transaction) error {
+	if len(t.Conditions) > 0 && t.Version < TxVersion2 {
+		return fmt.Errorf("conditions need transaction version %d, not %d", TxVersion2, t.Version)
+	}
+	if t.Reverses != "" && t.Version < TxVersion3 {
+		return fmt.Errorf("reversals need transaction version %d, not %d", TxVersion3, t.Version)
+	}
+	return nil
+}
+
+// checkTxV0 is laterFields plus a rule for the fork ID. TxVersion0 hashes
+// the chain ID and fork ID back to back with no lengths, so a transaction
+// for chain "ab" on fork "c" would hash, and verify, exactly like one for
+// chain "a" on fork "bc". Binding to a fork needs TxVersion1 or later.
+func checkTxV0(t Transaction) error {
+	if t.ForkID != "" {
+		return fmt.Errorf("fork IDs need transaction version %d, not %d", TxVersion1, t.Version)
+	}
+	return laterFields(t)
+}
+
+// checkTxVersion checks that t's version is known and that t sets only
+// fields its version carries.
+func checkTxVersion(t Transaction) error {
+	f, ok := txFormats[t.Version]
+	if !ok {
+		return fmt.Errorf("%w %d", ErrTxVersion, t.Version)
+	}
+	if f.check != nil {
+		return f.check(t)
+	}
+	return nil
+}
+
+// hashTxV1 writes t in the TxVersion1 format, the canonical encoding, or
+// for TxVersion2 and later in that format with the fields each version
+// added after the rest.
+func hashTxV1(w io.Writer, t Transaction) {
+	field := canonicalWriter{w}.field
+	field("v", strconv.FormatUint(uint64(t.Version), 10))
+	field("i", strconv.Itoa(t.ID))
+	field("f", t.From)
+	field("t", t.To)
+	field("T", t.Time.UTC().Format(time.RFC3339Nano))
+	field("d", t.Description)
+	field("a", t.Amount.hashString())
+	field("F", t.Fee.hashString())
+	field("y", string(t.Type))
+	field("C", t.ChainID)
+	field("K", t.ForkID)
+	field("n", strconv.FormatUint(t.Nonce, 10))
+	field("L", strconv.Itoa(len(t.Transfers)))
+	for _, l := range t.Transfers {
+		field("l", l.To)
+		field("a", l.Amount.hashString())
+	}
+	field("c", t.Category)
+	field("G", strconv.Itoa(len(t.Tags)))
+	for _, tag := range t.Tags {
+		field("g", tag)
+	}
+	field("s", string(t.Status))
+	field("A", string(t.Asset))
+	if t.Expires == nil {
+		field("e", "")
+	} else {
+		field("e", strconv.Itoa(t.Expires.Height)+"@"+t.Expires.Time.UTC().Format(time.RFC3339Nano))
+	}
+	if t.Version < TxVersion2 {
+		return
+	}
+	field("W", strconv.Itoa(len(t.Conditions)))
+	for _, c := range t.Conditions {
+		field("w", string(c.Kind))
+		field("a", c.Address)
+		field("h", strconv.Itoa(c.Height))
+		field("m", c.Amount.hashString())
+	}
+	if t.Version < TxVersion3 {
+		return
+	}
+	field("R", t.Reverses)
+}
+
+// UnmarshalJSON decodes a transaction and applies its version's decoding
+// rules, so a transaction in an unknown format is refused when it is read
+// rather than hashed wrongly later.
+func (t *Transaction) UnmarshalJSON(data []byte) error {
+	type plain Transaction // without this method
+	if err := json.Unmarshal(data, (*plain)(t)); err != nil {
+		return err
+	}
+	if err := checkTxVersion(*t); err != nil {
+		return fmt.Errorf("tx %s: %w", t.Hash, err)
+	}
+	return nil
+}
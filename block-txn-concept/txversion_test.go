@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestTxVersions(t *testing.T) {
+	v0 := Transaction{From: "ab", To: "c", Amount: Coin, Time: testStart, Type: Debit}
+	v1 := v0
+	v1.Version = TxVersion1
+	if computeTxHash(v0) == computeTxHash(v1) {
+		t.Error("versions 0 and 1 hash alike")
+	}
+
+	unknown := v0
+	unknown.Version = CurrentTxVersion + 1
+	if err := checkTxVersion(unknown); !errors.Is(err, ErrTxVersion) {
+		t.Errorf("unknown version: err = %v, want ErrTxVersion", err)
+	}
+	data, err := json.Marshal(unknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Transaction
+	if err := json.Unmarshal(data, &decoded); !errors.Is(err, ErrTxVersion) {
+		t.Errorf("decoding an unknown version: err = %v, want ErrTxVersion", err)
+	}
+
+	// Fields a version's hash doesn't cover can't be set in it.
+	conditional := v1
+	conditional.Conditions = []Condition{WhenHeightAbove(1)}
+	reversal := v1
+	reversal.Version, reversal.Reverses = TxVersion2, "0x01"
+	for name, tx := range map[string]Transaction{"conditions in v1": conditional, "reversal in v2": reversal} {
+		if err := checkTxVersion(tx); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestBlocksRejectUnknownTxVersions(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	tx.Version = CurrentTxVersion + 1
+	tx.Hash = computeTxHash(tx)
+	if err := mineTxs(t, c, tx); !errors.Is(err, ErrTxVersion) {
+		t.Errorf("block: err = %v, want ErrTxVersion", err)
+	}
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The UTXO model is the other way to keep a ledger. Instead of a balance
+// per account, there is a set of unspent transaction outputs, each owned by
+// an address. A transaction consumes whole outputs as inputs and creates
+// new ones; whatever the inputs hold beyond the new outputs is the fee. An
+// address's balance is just the sum of the outputs it owns.
+//
+// It runs alongside the account model used by Chain rather than replacing
+// it, so the two can be compared on the same payments (see runUTXO).
+
+// ErrSpent is returned when a transaction spends an output that doesn't
+// exist or was already spent.
+var ErrSpent = errors.New("output is spent or never existed")
+
+// ErrDuplicateUTXOTx is returned for a transaction whose outputs are still
+// unspent from an earlier transaction with the same hash.
+var ErrDuplicateUTXOTx = errors.New("transaction outputs already exist")
+
+// OutPoint names an output: the transaction that created it and its
+// position among that transaction's outputs.
+type OutPoint struct {
+	TxHash string
+	Index  int
+}
+
+func (o OutPoint) String() string {
+	return fmt.Sprintf("%s:%d", o.TxHash, o.Index)
+}
+
+// TxOutput locks Amount to Owner, an address. Only the holder of the key
+// Owner is derived from may spend it.
+type TxOutput struct {
+	Owner  string
+	Amount Amount
+}
+
+// TxInput spends a previous output. PubKey must be the key the output's
+// owner is derived from, and Signature its signature over the spending
+// transaction's hash; see SignUTXOInput.
+type TxInput struct {
+	Prev      OutPoint
+	PubKey    []byte
+	Signature []byte
+}
+
+// UTXOTx is a transaction in the UTXO model. A transaction with no inputs
+// is a coinbase, which mints the block reward plus the block's fees.
+type UTXOTx struct {
+	Hash    string
+	Inputs  []TxInput
+	Outputs []TxOutput
+	Note    string
+}
+
+// IsCoinbase reports whether tx mints new coins.
+func (tx UTXOTx) IsCoinbase() bool {
+	return len(tx.Inputs) == 0
+}
+
+// computeUTXOTxHash hashes every input's outpoint, every output, and the
+// note. It leaves out the inputs' keys and signatures, which sign it.
+func computeUTXOTxHash(tx UTXOTx) string {
+	h := sha256.New()
+	for _, in := range tx.Inputs {
+		h.Write([]byte(in.Prev.String()))
+	}
+	h.Write([]byte{0})
+	for _, out := range tx.Outputs {
+		h.Write([]byte(out.Owner))
+		h.Write([]byte(out.Amount.hashString()))
+	}
+	h.Write([]byte(tx.Note))
+	return "0x" + hex.EncodeToString(h.Sum(nil))
+}
+
+// UTXOSet holds every unspent output.
+type UTXOSet struct {
+	outputs map[OutPoint]TxOutput
+}
+
+// NewUTXOSet creates an empty set.
+func NewUTXOSet() *UTXOSet {
+	return &UTXOSet{outputs: make(map[OutPoint]TxOutput)}
+}
+
+// Get returns an unspent output.
+func (s *UTXOSet) Get(op OutPoint) (TxOutput, bool) {
+	out, ok := s.outputs[op]
+	return out, ok
+}
+
+// Balance sums the unspent outputs owned by owner.
+func (s *UTXOSet) Balance(owner string) Amount {
+	var total Amount
+	for _, out := range s.outputs {
+		if out.Owner == owner {
+			total += out.Amount
+		}
+	}
+	return total
+}
+
+// Unspent returns the outpoints owned by owner, in a stable order.
+func (s *UTXOSet) Unspent(owner string) []OutPoint {
+	var ops []OutPoint
+	for op, out := range s.outputs {
+		if out.Owner == owner {
+			ops = append(ops, op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].TxHash != ops[j].TxHash {
+			return ops[i].TxHash < ops[j].TxHash
+		}
+		return ops[i].Index < ops[j].Index
+	})
+	return ops
+}
+
+// Len returns the number of unspent outputs.
+func (s *UTXOSet) Len() int {
+	return len(s.outputs)
+}
+
+// Fee returns how much tx's inputs hold beyond its outputs. It errors if an
+// input is unknown, if either sum overflows, or if the outputs hold more
+// than the inputs.
+func (s *UTXOSet) Fee(tx UTXOTx) (Amount, error) {
+	var in, out Amount
+	var err error
+	for _, input := range tx.Inputs {
+		prev, ok := s.outputs[input.Prev]
+		if !ok {
+			return 0, fmt.Errorf("input %s: %w", input.Prev, ErrSpent)
+		}
+		if in, err = in.Add(prev.Amount); err != nil {
+			return 0, fmt.Errorf("inputs: %w", err)
+		}
+	}
+	if out, err = sumOutputs(tx); err != nil {
+		return 0, err
+	}
+	if out > in {
+		return 0, fmt.Errorf("outputs exceed inputs by %v", out-in)
+	}
+	return in - out, nil
+}
+
+// sumOutputs is the checked sum of tx's outputs.
+func sumOutputs(tx UTXOTx) (Amount, error) {
+	var out Amount
+	for _, o := range tx.Outputs {
+		var err error
+		if out, err = out.Add(o.Amount); err != nil {
+			return 0, fmt.Errorf("outputs: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// SignUTXOInput signs tx's input i with key, filling in its PubKey and
+// Signature, and recomputes tx's hash. key must be the one the spent
+// output's owner is derived from.
+func SignUTXOInput(tx *UTXOTx, i int, key *ecdsa.PrivateKey) error {
+	tx.Hash = computeUTXOTxHash(*tx)
+	digest, err := hex.DecodeString(strings.TrimPrefix(tx.Hash, "0x"))
+	if err != nil {
+		return err
+	}
+	sig, err := SignDeterministic(key, digest)
+	if err != nil {
+		return err
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return err
+	}
+	tx.Inputs[i].PubKey, tx.Inputs[i].Signature = pub, sig
+	return nil
+}
+
+// verifyUTXOInput checks that in carries a valid signature over tx's hash
+// by the key owner is derived from.
+func verifyUTXOInput(tx UTXOTx, in TxInput, owner string) error {
+	pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), in.PubKey)
+	if err != nil {
+		return fmt.Errorf("%w: public key: %v", ErrBadSignature, err)
+	}
+	digest, err := hex.DecodeString(strings.TrimPrefix(tx.Hash, "0x"))
+	if err != nil {
+		return err
+	}
+	if !ecdsa.VerifyASN1(pub, digest, in.Signature) {
+		return ErrBadSignature
+	}
+	signer, err := AddressFromPubKey(pub)
+	if err != nil {
+		return err
+	}
+	if want, err := ParseAddress(owner); err != nil || want != signer {
+		return fmt.Errorf("%w: key belongs to %s, not %s", ErrBadSignature, signer, owner)
+	}
+	return nil
+}
+
+// validate checks tx against the set: its outputs are positive, at most
+// MaxAmount, and new, and
+// unless it is a coinbase, every input exists, is unspent, is signed by
+// its owner, and is used once, and the inputs cover the outputs.
+func (s *UTXOSet) validate(tx UTXOTx) error {
+	if got := computeUTXOTxHash(tx); got != tx.Hash {
+		return fmt.Errorf("tx %s: hash does not match computed %s", tx.Hash, got)
+	}
+	if len(tx.Outputs) == 0 {
+		return fmt.Errorf("tx %s: no outputs", tx.Hash)
+	}
+	for i, out := range tx.Outputs {
+		if out.Amount <= 0 {
+			return fmt.Errorf("tx %s: output %d is not positive", tx.Hash, i)
+		}
+		if out.Amount > MaxAmount {
+			return fmt.Errorf("tx %s: output %d: %v is more than %v: %w", tx.Hash, i, out.Amount, MaxAmount, ErrAmountRange)
+		}
+		if _, ok := s.outputs[OutPoint{TxHash: tx.Hash, Index: i}]; ok {
+			return fmt.Errorf("tx %s: %w", tx.Hash, ErrDuplicateUTXOTx)
+		}
+	}
+	if tx.IsCoinbase() {
+		return nil
+	}
+	seen := make(map[OutPoint]bool, len(tx.Inputs))
+	for _, in := range tx.Inputs {
+		if seen[in.Prev] {
+			return fmt.Errorf("tx %s: input %s: %w", tx.Hash, in.Prev, ErrDoubleSpend)
+		}
+		seen[in.Prev] = true
+		prev, ok := s.outputs[in.Prev]
+		if !ok {
+			return fmt.Errorf("tx %s: input %s: %w", tx.Hash, in.Prev, ErrSpent)
+		}
+		if err := verifyUTXOInput(tx, in, prev.Owner); err != nil {
+			return fmt.Errorf("tx %s: input %s: %w", tx.Hash, in.Prev, err)
+		}
+	}
+	if _, err := s.Fee(tx); err != nil {
+		return fmt.Errorf("tx %s: %w", tx.Hash, err)
+	}
+	return nil
+}
+
+// apply spends tx's inputs and adds its outputs. tx must be valid.
+func (s *UTXOSet) apply(tx UTXOTx) {
+	for _, in := range tx.Inputs {
+		delete(s.outputs, in.Prev)
+	}
+	for i, out := range tx.Outputs {
+		s.outputs[OutPoint{TxHash: tx.Hash, Index: i}] = out
+	}
+}
+
+// ApplyBlock validates and applies a block's transactions in order, so a
+// transaction may spend outputs created earlier in the same block. At most
+// one coinbase is allowed, and it may mint no more than reward plus the
+// fees of the block's other transactions. Either every transaction is
+// applied or, on error, none is.
+func (s *UTXOSet) ApplyBlock(txs []UTXOTx, reward Amount) error {
+	next := &UTXOSet{outputs: make(map[OutPoint]TxOutput, len(s.outputs))}
+	for op, out := range s.outputs {
+		next.outputs[op] = out
+	}
+
+	var fees, minted Amount
+	coinbases := 0
+	for _, tx := range txs {
+		if err := next.validate(tx); err != nil {
+			return err
+		}
+		var err error
+		if tx.IsCoinbase() {
+			coinbases++
+			minted, err = sumOutputs(tx)
+		} else {
+			fee, _ := next.Fee(tx)
+			fees, err = fees.Add(fee)
+		}
+		if err != nil {
+			return fmt.Errorf("tx %s: %w", tx.Hash, err)
+		}
+		next.apply(tx)
+	}
+	if coinbases > 1 {
+		return fmt.Errorf("block has %d coinbase transactions", coinbases)
+	}
+	allowed, err := reward.Add(fees)
+	if err != nil {
+		return fmt.Errorf("block reward plus fees: %w", err)
+	}
+	if minted > allowed {
+		return fmt.Errorf("%w: %v, at most %v", ErrCoinbaseOverclaim, minted, allowed)
+	}
+	s.outputs = next.outputs
+	return nil
+}
+
+// NewUTXOCoinbase mints amount to owner. note should differ between blocks
+// so coinbases don't share a hash.
+func NewUTXOCoinbase(owner string, amount Amount, note string) UTXOTx {
+	tx := UTXOTx{Outputs: []TxOutput{{Owner: owner, Amount: amount}}, Note: note}
+	tx.Hash = computeUTXOTxHash(tx)
+	return tx
+}
+
+// BuildUTXOPayment builds a transaction paying amount from key's address
+// to another: it selects the address's outputs, largest first, until they
+// cover amount plus fee, returns anything left over to it as change, and
+// signs every input with key.
+func (s *UTXOSet) BuildUTXOPayment(key *ecdsa.PrivateKey, to string, amount, fee Amount, note string) (UTXOTx, error) {
+	addr, err := AddressFromPubKey(&key.PublicKey)
+	if err != nil {
+		return UTXOTx{}, err
+	}
+	from := addr.String()
+	ops := s.Unspent(from)
+	sort.SliceStable(ops, func(i, j int) bool { return s.outputs[ops[i]].Amount > s.outputs[ops[j]].Amount })
+
+	need, err := amount.Add(fee)
+	if err != nil {
+		return UTXOTx{}, err
+	}
+	tx := UTXOTx{Note: note}
+	var gathered Amount
+	for _, op := range ops {
+		if gathered >= need {
+			break
+		}
+		tx.Inputs = append(tx.Inputs, TxInput{Prev: op})
+		if gathered, err = gathered.Add(s.outputs[op].Amount); err != nil {
+			return UTXOTx{}, err
+		}
+	}
+	if gathered < need {
+		return UTXOTx{}, fmt.Errorf("%s holds %.2f, needs %.2f", from, gathered, need)
+	}
+	tx.Outputs = append(tx.Outputs, TxOutput{Owner: to, Amount: amount})
+	if change := gathered - amount - fee; change > 0 {
+		tx.Outputs = append(tx.Outputs, TxOutput{Owner: from, Amount: change})
+	}
+	for i := range tx.Inputs {
+		if err := SignUTXOInput(&tx, i, key); err != nil {
+			return UTXOTx{}, err
+		}
+	}
+	return tx, nil
+}
+
+// runUTXO implements the "utxo" command: it replays the demo's payments in
+// the UTXO model and prints the unspent outputs after each block.
+func runUTXO(args []string) error {
+	fs := flag.NewFlagSet("utxo", flag.ContinueOnError)
+	reward := amountFlag(fs, "reward", 50*Coin, "block reward")
+	fee := amountFlag(fs, "fee", 0, "fee to attach to each payment")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The account's address comes from a fresh key, which signs its inputs
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	addr, err := AddressFromPubKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	account := addr.String()
+	const (
+		coffee    = "0xC0Ffee000000000000000000000000000000003"
+		bookStore = "0xB00k000000000000000000000000000000000004"
+		miner     = "0x5A11e5000000000000000000000000000000000"
+	)
+	set := NewUTXOSet()
+	steps := []struct {
+		to     string
+		amount Amount
+		note   string
+	}{
+		{coffee, MustParseAmount("4.50"), "Coffee"},
+		{bookStore, MustParseAmount("25.00"), "Book"},
+	}
+
+	// The genesis coinbase stands in for the account model's allocation.
+	if err := set.ApplyBlock([]UTXOTx{NewUTXOCoinbase(account, 1000*Coin, "genesis")}, 1000*Coin); err != nil {
+		return err
+	}
+	printUTXOSet(set, 0)
+	for i, step := range steps {
+		height := i + 1
+		pay, err := set.BuildUTXOPayment(key, step.to, step.amount, *fee, step.note)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nBlock %d: %s pays %.2f to %s (%d in, %d out, fee %.2f)\n",
+			height, account[:10]+"...", step.amount, step.to[:10]+"...", len(pay.Inputs), len(pay.Outputs), *fee)
+		coinbase := NewUTXOCoinbase(miner, *reward+*fee, fmt.Sprintf("block %d", height))
+		if err := set.ApplyBlock([]UTXOTx{coinbase, pay}, *reward); err != nil {
+			return err
+		}
+		printUTXOSet(set, height)
+	}
+	fmt.Printf("\nFinal balance of %s: %.2f\n", account, set.Balance(account))
+	return nil
+}
+
+func printUTXOSet(set *UTXOSet, height int) {
+	fmt.Printf("Unspent outputs after block %d:\n", height)
+	var ops []OutPoint
+	for op := range set.outputs {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].String() < ops[j].String() })
+	for _, op := range ops {
+		out := set.outputs[op]
+		fmt.Printf("  %s:%d  %-44s %10.2f\n", op.TxHash[:18]+"...", op.Index, out.Owner, out.Amount)
+	}
+}
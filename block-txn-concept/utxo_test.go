@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestUTXORejectsWrappingOutputs is the exploit where outputs summing past
+// MaxInt64 wrap around, so a small input seems to pay them with a fee.
+func TestUTXORejectsWrappingOutputs(t *testing.T) {
+	alice := newTestAccount(t)
+	set := NewUTXOSet()
+	coinbase := NewUTXOCoinbase(alice.Addr, 100, "genesis")
+	if err := set.ApplyBlock([]UTXOTx{coinbase}, 100); err != nil {
+		t.Fatal(err)
+	}
+	tx := UTXOTx{
+		Inputs:  []TxInput{{Prev: OutPoint{coinbase.Hash, 0}}},
+		Outputs: []TxOutput{{"bob", math.MaxInt64}, {"carol", math.MaxInt64}},
+	}
+	if err := SignUTXOInput(&tx, 0, alice.Key); err != nil {
+		t.Fatal(err)
+	}
+	if fee, err := set.Fee(tx); err == nil {
+		t.Errorf("Fee = %v, want an error", fee)
+	}
+	if err := set.ApplyBlock([]UTXOTx{tx}, 0); err == nil {
+		t.Fatal("block with wrapping outputs was accepted")
+	}
+	if got := set.Balance("bob"); got != 0 {
+		t.Errorf("bob holds %v", got)
+	}
+	if got := set.Balance(alice.Addr); got != 100 {
+		t.Errorf("alice holds %v, want 100", got)
+	}
+}
+
+func TestUTXORejectsOutputsAboveInputs(t *testing.T) {
+	alice := newTestAccount(t)
+	set := NewUTXOSet()
+	coinbase := NewUTXOCoinbase(alice.Addr, 100, "genesis")
+	if err := set.ApplyBlock([]UTXOTx{coinbase}, 100); err != nil {
+		t.Fatal(err)
+	}
+	tx := UTXOTx{
+		Inputs:  []TxInput{{Prev: OutPoint{coinbase.Hash, 0}}},
+		Outputs: []TxOutput{{"bob", 60}, {"carol", 41}},
+	}
+	if err := SignUTXOInput(&tx, 0, alice.Key); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.ApplyBlock([]UTXOTx{tx}, 0); err == nil {
+		t.Fatal("outputs above inputs were accepted")
+	}
+}
+
+// TestUTXORequiresOwnerSignature is the exploit where anyone spends an
+// output by naming its owner: the input has to be signed by the owner's
+// key, over this transaction.
+func TestUTXORequiresOwnerSignature(t *testing.T) {
+	alice, mallory := newTestAccount(t), newTestAccount(t)
+	set := NewUTXOSet()
+	coinbase := NewUTXOCoinbase(alice.Addr, 100, "genesis")
+	if err := set.ApplyBlock([]UTXOTx{coinbase}, 100); err != nil {
+		t.Fatal(err)
+	}
+	steal := func() UTXOTx {
+		return UTXOTx{
+			Inputs:  []TxInput{{Prev: OutPoint{coinbase.Hash, 0}}},
+			Outputs: []TxOutput{{mallory.Addr, 100}},
+		}
+	}
+
+	unsigned := steal()
+	unsigned.Hash = computeUTXOTxHash(unsigned)
+	forged := steal()
+	if err := SignUTXOInput(&forged, 0, mallory.Key); err != nil {
+		t.Fatal(err)
+	}
+	// Alice's key and signature, lifted from a payment she signed to someone else.
+	paid := UTXOTx{
+		Inputs:  []TxInput{{Prev: OutPoint{coinbase.Hash, 0}}},
+		Outputs: []TxOutput{{newTestAccount(t).Addr, 100}},
+	}
+	if err := SignUTXOInput(&paid, 0, alice.Key); err != nil {
+		t.Fatal(err)
+	}
+	replayed := steal()
+	replayed.Inputs[0].PubKey, replayed.Inputs[0].Signature = paid.Inputs[0].PubKey, paid.Inputs[0].Signature
+	replayed.Hash = computeUTXOTxHash(replayed)
+
+	for name, tx := range map[string]UTXOTx{"unsigned": unsigned, "wrong key": forged, "replayed": replayed} {
+		if err := set.ApplyBlock([]UTXOTx{tx}, 0); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s: err = %v, want ErrBadSignature", name, err)
+		}
+	}
+	if got := set.Balance(mallory.Addr); got != 0 {
+		t.Errorf("mallory holds %v", got)
+	}
+	if err := set.ApplyBlock([]UTXOTx{paid}, 0); err != nil {
+		t.Fatalf("payment signed by alice: %v", err)
+	}
+}
+
+func TestBuildUTXOPaymentSignsInputs(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	set := NewUTXOSet()
+	if err := set.ApplyBlock([]UTXOTx{NewUTXOCoinbase(alice.Addr, 100, "genesis")}, 100); err != nil {
+		t.Fatal(err)
+	}
+	pay, err := set.BuildUTXOPayment(alice.Key, bob.Addr, 60, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := set.ApplyBlock([]UTXOTx{pay}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := set.Balance(bob.Addr); got != 60 {
+		t.Errorf("bob holds %v, want 60", got)
+	}
+	if got := set.Balance(alice.Addr); got != 39 {
+		t.Errorf("alice holds %v, want 39", got)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// TxValidator is one rule a transaction must pass to enter a block or the
+// mempool. Rules see the transaction against a TxContext, which already
+// reflects every earlier transaction in the same block.
+type TxValidator interface {
+	ValidateTx(ctx *TxContext, tx Transaction) error
+}
+
+// TxValidatorFunc adapts a function to TxValidator.
+type TxValidatorFunc func(ctx *TxContext, tx Transaction) error
+
+// ValidateTx calls f.
+func (f TxValidatorFunc) ValidateTx(ctx *TxContext, tx Transaction) error {
+	return f(ctx, tx)
+}
+
+// TxContext is where a transaction is being validated: the block it would
+// go into, on top of parent, and the state as of just before it.
+type TxContext struct {
+	Chain   *Chain
+	Height  int       // height of the block
+	Version uint32    // header version of the block
+	Time    time.Time // timestamp of the block, or of the tip for the mempool
+	Pool    bool      // validating for the mempool rather than a block
+
+	parent   *Block
+	nonces   map[string]uint64        // next nonce of senders earlier in the block
+	deltas   map[string]Amount        // balance changes made earlier in the block
+	spent    map[string]Amount        // native coin senders sent earlier in the block
+	statuses map[string]AccountStatus // status changes made earlier in the block
+	reversed map[string]bool          // hashes reversed earlier in the block
+
+	state    *Snapshot // balances as of parent, loaded on first use
+	stateErr error
+}
+
+// newTxContext starts a context for a block of the given version and
+// timestamp on top of parent.
+func (c *Chain) newTxContext(parent *Block, version uint32, at time.Time, pool bool) *TxContext {
+	return &TxContext{
+		Chain:    c,
+		Height:   parent.Index + 1,
+		Version:  version,
+		Time:     at,
+		Pool:     pool,
+		parent:   parent,
+		nonces:   make(map[string]uint64),
+		deltas:   make(map[string]Amount),
+		spent:    make(map[string]Amount),
+		statuses: make(map[string]AccountStatus),
+		reversed: make(map[string]bool),
+	}
+}
+
+// Nonce returns the nonce addr's next transaction must carry.
+func (ctx *TxContext) Nonce(addr string) uint64 {
+	if n, ok := ctx.nonces[addr]; ok {
+		return n
+	}
+	return ctx.Chain.nextNonce(ctx.parent, addr)
+}
+
+// Balance returns addr's balance. ok is false if it can't be known
+// because blocks it depends on have been pruned.
+func (ctx *TxContext) Balance(addr string) (bal Amount, ok bool) {
+	if ctx.state == nil && ctx.stateErr == nil {
+		ctx.state, ctx.stateErr = ctx.Chain.branchState(ctx.parent)
+	}
+	if ctx.stateErr != nil {
+		return 0, false
+	}
+	return ctx.state.Balance(addr) + ctx.deltas[addr], true
+}
+
+// apply records tx's effects, so the transactions after it in the block
+// are checked against them. tx has passed checkLegs, so its Cost can't
+// overflow.
+func (ctx *TxContext) apply(tx Transaction) {
+	ctx.nonces[tx.From] = ctx.Nonce(tx.From) + 1
+	cost, _ := tx.Cost()
+	ctx.deltas[tx.From] -= cost
+	ctx.spent[tx.From] -= tx.change(NativeAsset)
+	for _, l := range tx.Legs() {
+		ctx.deltas[l.To] += l.Amount
+	}
+	if tx.Status != "" {
+		ctx.statuses[tx.To] = tx.Status
+	}
+	if tx.Reverses != "" {
+		ctx.reversed[tx.Reverses] = true
+	}
+}
+
+// branchState returns the balances as of b, which needn't be on the best
+// chain: it starts from the best-chain snapshot where b's branch forks off
+// and replays the branch from there.
+func (c *Chain) branchState(b *Block) (*Snapshot, error) {
+	fork := c.forkPoint(b)
+	s, err := c.Snapshot(fork.Index)
+	if err != nil {
+		return nil, err
+	}
+	var replay []*Block
+	for p := b; p != fork; p = c.blocks[p.PrevHash] {
+		replay = append(replay, p)
+	}
+	for i := len(replay) - 1; i >= 0; i-- {
+		if err := c.applyBlock(s, *replay[i]); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// TxRules returns the rules every transaction is validated with, in
+// order: the built-in ones, then ChainConfig.TxRules.
+func (c *Chain) TxRules() []TxValidator {
+	rules := []TxValidator{
+		TxValidatorFunc(checkWellFormed),
+		TxValidatorFunc(checkSignature),
+		TxValidatorFunc(checkEscrow),
+		TxValidatorFunc(checkReversal),
+		TxValidatorFunc(checkBinding),
+		TxValidatorFunc(checkAsset),
+		TxValidatorFunc(checkExpiry),
+		TxValidatorFunc(checkConditions),
+		TxValidatorFunc(checkStatus),
+		TxValidatorFunc(checkNonce),
+		TxValidatorFunc(checkBalance),
+		TxValidatorFunc(checkSpendingLimit),
+		TxValidatorFunc(checkMinFee),
+	}
+	return append(rules, c.config.TxRules...)
+}
+
+// validateTx runs tx through TxRules, stopping at the first failure.
+func (c *Chain) validateTx(ctx *TxContext, tx Transaction) error {
+	for _, rule := range c.TxRules() {
+		if err := rule.ValidateTx(ctx, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkWellFormed checks tx's own fields: its format is known, its hash is
+// correct, it names a sender and a recipient for every leg, and its legs
+// and fee are in range; see checkLegs.
+func checkWellFormed(ctx *TxContext, tx Transaction) error {
+	if err := checkTxVersion(tx); err != nil {
+		return fmt.Errorf("tx %s: %w", tx.Hash, err)
+	}
+	if got := computeTxHash(tx); got != tx.Hash {
+		return fmt.Errorf("tx %s: hash does not match computed %s", tx.Hash, got)
+	}
+	if tx.From == "" {
+		return fmt.Errorf("tx %s: missing sender", tx.Hash)
+	}
+	if err := checkLegs(tx); err != nil {
+		return fmt.Errorf("tx %s: %w", tx.Hash, err)
+	}
+	return nil
+}
+
+// checkBinding checks that tx is bound to this chain's ID and, if it names
+// a fork, to the one in force at the block's height.
+func checkBinding(ctx *TxContext, tx Transaction) error {
+	cfg := ctx.Chain.config
+	if tx.ChainID != cfg.ChainID {
+		return fmt.Errorf("%w: %s is for chain %q, not %q", ErrWrongChain, tx.Hash, tx.ChainID, cfg.ChainID)
+	}
+	if tx.ForkID == "" {
+		return nil
+	}
+	if want := ctx.Chain.ForkID(ctx.Height); tx.ForkID != want {
+		return fmt.Errorf("%w: %s is for fork %s, not %s", ErrWrongFork, tx.Hash, tx.ForkID, want)
+	}
+	return nil
+}
+
+// checkNonce checks that tx carries its sender's next nonce, from
+// BlockVersion2 on. The mempool also takes later nonces, which wait there
+// until the gap is filled.
+func checkNonce(ctx *TxContext, tx Transaction) error {
+	if ctx.Version < BlockVersion2 {
+		return nil
+	}
+	want := ctx.Nonce(tx.From)
+	if tx.Nonce < want {
+		return fmt.Errorf("%w: tx %s reuses nonce %d of %s (next is %d)", ErrBadNonce, tx.Hash, tx.Nonce, tx.From, want)
+	}
+	if tx.Nonce > want && !ctx.Pool {
+		return fmt.Errorf("%w: tx %s has nonce %d, %s is at %d", ErrBadNonce, tx.Hash, tx.Nonce, tx.From, want)
+	}
+	return nil
+}
+
+// checkBalance checks that tx's sender can afford its amount and fee under
+// its ChainConfig.Overdraft policy, from BlockVersion3 on.
+func checkBalance(ctx *TxContext, tx Transaction) error {
+	if ctx.Version < BlockVersion3 {
+		return nil
+	}
+	bal, ok := ctx.Balance(tx.From)
+	if !ok {
+		return nil // pruned history; nothing to check against
+	}
+	cost, err := tx.Cost()
+	if err != nil {
+		return fmt.Errorf("tx %s: %w", tx.Hash, err)
+	}
+	if p := ctx.Chain.config.overdraft(tx.From); !p.Allows(bal, cost) {
+		return fmt.Errorf("%w: tx %s: %s spends %v but holds %v (%v)", ErrInsufficientFunds, tx.Hash, tx.From, cost, bal, p)
+	}
+	return nil
+}
+
+// checkMinFee checks that tx pays at least ChainConfig.MinFee. Legacy
+// headers can't carry fees, so it doesn't apply to them.
+func checkMinFee(ctx *TxContext, tx Transaction) error {
+	min := ctx.Chain.config.MinFee
+	if min == 0 || ctx.Version == LegacyBlockVersion {
+		return nil
+	}
+	if tx.Fee < min {
+		return fmt.Errorf("%w: tx %s pays %v, at least %v", ErrUnderpriced, tx.Hash, tx.Fee, min)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// mineTxs builds a version 4 block of txs on c's tip, without checking
+// them first, and adds it.
+func mineTxs(t *testing.T, c *Chain, txs ...Transaction) error {
+	t.Helper()
+	b, err := c.buildBlock("", txs, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.AddBlock(b)
+}
+
+// resign signs tx again as from after a test has changed its fields.
+func resign(t *testing.T, tx Transaction, from testAccount) Transaction {
+	t.Helper()
+	if err := SignTx(&tx, from.Key); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestTxRulesRejectInvalidTransactions(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	cfg.MinFee = 1
+	c := newTestChain(t, cfg)
+	base := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin, Fee: 1})
+
+	wrongChain := base
+	wrongChain.ChainID = "other"
+	future := base
+	future.Nonce = 1
+	tooMuch := base
+	tooMuch.Amount = 10 * Coin
+	cheap := base
+	cheap.Fee = 0
+	noSender := base
+	noSender.From = ""
+	noSender.Hash = computeTxHash(noSender)
+	badHash := base
+	badHash.Hash = computeTxHash(tooMuch)
+
+	for _, tc := range []struct {
+		name string
+		tx   Transaction
+		want error
+	}{
+		{"wrong chain", resign(t, wrongChain, alice), ErrWrongChain},
+		{"nonce gap", resign(t, future, alice), ErrBadNonce},
+		{"overspend", resign(t, tooMuch, alice), ErrInsufficientFunds},
+		{"below min fee", resign(t, cheap, alice), ErrUnderpriced},
+		{"no sender", noSender, nil},
+		{"wrong hash", badHash, nil},
+	} {
+		err := mineTxs(t, c, tc.tx)
+		if err == nil {
+			t.Errorf("%s: block was accepted", tc.name)
+		} else if tc.want != nil && !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+	if c.Tip().Index != 0 {
+		t.Fatalf("tip moved to %d", c.Tip().Index)
+	}
+	if err := mineTxs(t, c, base); err != nil {
+		t.Fatalf("valid tx: %v", err)
+	}
+}
+
+// TestTxRulesSeeEarlierTransactionsInBlock is the exploit where a sender
+// puts two transactions with one nonce, or more than it holds in total,
+// into a single block, so each passes on its own.
+func TestTxRulesSeeEarlierTransactionsInBlock(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	c := newTestChain(t, testConfig(map[string]Amount{alice.Addr: 10 * Coin}))
+	first := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 6 * Coin})
+	replay := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 6 * Coin, Description: "again"})
+	if err := mineTxs(t, c, first, replay); !errors.Is(err, ErrBadNonce) {
+		t.Errorf("same nonce twice: err = %v, want ErrBadNonce", err)
+	}
+	second := replay
+	second.Nonce = 1
+	if err := mineTxs(t, c, first, resign(t, second, alice)); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("overspend across two txs: err = %v, want ErrInsufficientFunds", err)
+	}
+	second.Amount = 4 * Coin
+	if err := mineTxs(t, c, first, resign(t, second, alice)); err != nil {
+		t.Fatalf("two affordable txs: %v", err)
+	}
+	if got := tipBalance(t, c, bob.Addr); got != 10*Coin {
+		t.Errorf("bob holds %v, want 10", got)
+	}
+}
+
+func TestConfigTxRulesRunAfterBuiltIns(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	errBlocked := errors.New("recipient blocked")
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	cfg.TxRules = []TxValidator{TxValidatorFunc(func(ctx *TxContext, tx Transaction) error {
+		if tx.To == bob.Addr {
+			return errBlocked
+		}
+		return nil
+	})}
+	c := newTestChain(t, cfg)
+	tx := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	if err := mineTxs(t, c, tx); !errors.Is(err, errBlocked) {
+		t.Errorf("block: err = %v, want the custom rule's error", err)
+	}
+	m := NewMempool(c)
+	defer m.Close()
+	if err := m.Add(tx); !errors.Is(err, errBlocked) {
+		t.Errorf("mempool: err = %v, want the custom rule's error", err)
+	}
+	tx.ChainID = "other"
+	if err := m.Add(resign(t, tx, alice)); !errors.Is(err, ErrWrongChain) {
+		t.Errorf("built-in rules should fail first: err = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestVDFEvaluate(t *testing.T) {
+	// A product of two Mersenne primes: its factors are public, which
+	// doesn't matter for checking the arithmetic.
+	one := big.NewInt(1)
+	p := new(big.Int).Sub(new(big.Int).Lsh(one, 127), one)
+	q := new(big.Int).Sub(new(big.Int).Lsh(one, 89), one)
+	n := new(big.Int).Mul(p, q)
+	e := &VDFEngine{Modulus: n, Iterations: 300}
+	x := big.NewInt(5)
+	want := new(big.Int).Exp(x, new(big.Int).Lsh(big.NewInt(1), 300), n)
+	if got := e.evaluate(x); got.Cmp(want) != 0 {
+		t.Errorf("evaluate = %v, want 5^(2^300) mod N = %v", got, want)
+	}
+
+	// The proof is x^floor(2^T / l).
+	l := vdfChallenge(x, want)
+	quot := new(big.Int).Div(new(big.Int).Lsh(big.NewInt(1), 300), l)
+	if got := e.prove(x, l); got.Cmp(new(big.Int).Exp(x, quot, n)) != 0 {
+		t.Errorf("prove = %v, want x^floor(2^T/l)", got)
+	}
+}
+
+func TestVDFSeal(t *testing.T) {
+	e, err := NewVDFEngine(512, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestChain(t, testConfig(nil))
+	b, err := c.buildBlock("", nil, BlockVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Seal(&b); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.VerifySeal(b); err != nil {
+		t.Fatalf("sealed block: %v", err)
+	}
+
+	forged := b
+	forged.Seal = append([]byte(nil), b.Seal...)
+	forged.Seal[len(forged.Seal)/2-1] ^= 1 // y
+	forged.Hash = hashBlock(forged)
+	short := b
+	short.Seal = b.Seal[1:]
+	short.Hash = hashBlock(short)
+	faster := *e
+	faster.Iterations--
+	for name, check := range map[string]func() error{
+		"altered output":   func() error { return e.VerifySeal(forged) },
+		"short seal":       func() error { return e.VerifySeal(short) },
+		"fewer iterations": func() error { return faster.VerifySeal(b) },
+	} {
+		if check() == nil {
+			t.Errorf("%s: seal verified", name)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// VerifyFailure locates the first problem found in a stored chain.
+type VerifyFailure struct {
+	Height int
+	Hash   string
+	Check  string // which check failed: genesis, link, hash, pow, tx-hash, consensus, or balance
+	Err    error
+}
+
+// VerifyReport summarises a full validation of a stored chain.
+type VerifyReport struct {
+	Blocks       int
+	Transactions int
+	Supply       Amount         // total balance at the last verified block
+	Failure      *VerifyFailure // nil if the whole chain is valid
+	Balances     map[string]Amount
+}
+
+// VerifyChain fully validates blocks as a chain under cfg, stopping at the
+// first failure. Beyond what AddBlock checks, it confirms that no sender
+// ever spends more than it holds.
+func VerifyChain(blocks []Block, cfg ChainConfig) VerifyReport {
+	r := VerifyReport{Balances: make(map[string]Amount)}
+	if len(blocks) == 0 {
+		r.Failure = &VerifyFailure{Check: "genesis", Err: errors.New("chain is empty")}
+		return r
+	}
+	fail := func(b Block, check string, err error) VerifyReport {
+		r.Failure = &VerifyFailure{Height: b.Index, Hash: b.Hash, Check: check, Err: err}
+		return r
+	}
+
+	g := blocks[0]
+	if g.Index != 0 {
+		return fail(g, "genesis", fmt.Errorf("first block has index %d", g.Index))
+	}
+	if err := verifyBlockHash(g); err != nil {
+		return fail(g, "hash", err)
+	}
+	if err := cfg.Engine.VerifySeal(g); err != nil {
+		return fail(g, "pow", err)
+	}
+	chain, err := NewChain(cfg, g)
+	if err != nil {
+		return fail(g, "genesis", err)
+	}
+
+	for i, b := range blocks {
+		if i > 0 {
+			prev := blocks[i-1]
+			if b.PrevHash != prev.Hash || b.Index != prev.Index+1 {
+				return fail(b, "link", fmt.Errorf("block %d does not follow block %d (%s)", b.Index, prev.Index, prev.Hash))
+			}
+			if err := verifyBlockHash(b); err != nil {
+				return fail(b, "hash", err)
+			}
+			if err := cfg.Engine.VerifySeal(b); err != nil {
+				return fail(b, "pow", err)
+			}
+			for _, tx := range b.Transactions {
+				if got := computeTxHash(tx); got != tx.Hash {
+					return fail(b, "tx-hash", fmt.Errorf("tx %s: computed hash %s", tx.Hash, got))
+				}
+			}
+			if err := chain.AddBlock(b); err != nil {
+				return fail(b, "consensus", err)
+			}
+		}
+
+		if i == 0 {
+			for addr, amount := range cfg.Alloc {
+				r.Balances[addr] += amount
+			}
+		}
+		rewards, err := chain.Rewards(b)
+		if err != nil {
+			return fail(b, "balance", err)
+		}
+		for addr, amount := range rewards {
+			r.Balances[addr] += amount
+		}
+		for _, tx := range b.Transactions {
+			cost, err := tx.Cost()
+			if err != nil {
+				return fail(b, "balance", fmt.Errorf("tx %s: %w", tx.Hash, err))
+			}
+			if r.Balances[tx.From] < cost {
+				return fail(b, "balance", fmt.Errorf("tx %s: %s spends %v but holds %v", tx.Hash, tx.From, cost, r.Balances[tx.From]))
+			}
+			if err := applyTxBalances(r.Balances, tx); err != nil {
+				return fail(b, "balance", fmt.Errorf("tx %s: %w", tx.Hash, err))
+			}
+		}
+		r.Blocks++
+		r.Transactions += len(b.TxHashes())
+	}
+	for _, bal := range r.Balances {
+		r.Supply += bal
+	}
+	return r
+}
+
+// Print writes the report, ending with the failure if there is one.
+func (r VerifyReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "Verified %d blocks, %d transactions\n", r.Blocks, r.Transactions)
+	if r.Failure != nil {
+		f := r.Failure
+		fmt.Fprintf(w, "FAIL at block %d (%s)\n", f.Height, f.Hash)
+		fmt.Fprintf(w, "  check : %s\n", f.Check)
+		fmt.Fprintf(w, "  error : %v\n", f.Err)
+		return
+	}
+	addrs := make([]string, 0, len(r.Balances))
+	for addr, bal := range r.Balances {
+		if bal != 0 {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+	fmt.Fprintf(w, "Total supply %.2f across %d accounts\n", r.Supply, len(addrs))
+	for _, addr := range addrs {
+		fmt.Fprintf(w, "  %-44s %12.2f\n", addr, r.Balances[addr])
+	}
+	fmt.Fprintln(w, "OK")
+}
+
+// runVerify implements the "verify" command. It exits non-zero if the chain
+// is invalid, so it can gate scripts.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	path := fs.String("chain", "chain.json", "stored chain to verify")
+	reward := amountFlag(fs, "reward", 50*Coin, "block reward the chain was mined with")
+	chainID := fs.String("chain-id", "", "chain ID its transactions must carry")
+	genesisKey := fs.String("genesis-key", "", "operator public key the genesis must be attested by")
+	quiet := fs.Bool("q", false, "print nothing; report only through the exit code")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 1 {
+		*path = fs.Arg(0)
+	}
+
+	blocks, err := LoadBlocks(*path)
+	if err != nil {
+		return err
+	}
+	alloc, err := LoadAlloc(*path)
+	if err != nil {
+		return err
+	}
+	cfg := ChainConfig{ChainID: *chainID, Engine: &PoWEngine{}, BlockReward: *reward, Alloc: alloc, MaxUncles: 2}
+	if cfg.Upgrades, err = LoadUpgrades(*path); err != nil {
+		return err
+	}
+	if *genesisKey != "" {
+		if cfg.GenesisKey, err = ParsePublicKey(*genesisKey); err != nil {
+			return fmt.Errorf("-genesis-key: %w", err)
+		}
+		if cfg.Attestation, err = LoadAttestation(*path); err != nil {
+			return err
+		}
+	}
+	report := VerifyChain(blocks, cfg)
+	if !*quiet {
+		report.Print(os.Stdout)
+	}
+	if f := report.Failure; f != nil {
+		return fmt.Errorf("%s check failed at block %d", f.Check, f.Height)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func verifyFixture(t *testing.T) (ChainConfig, *Chain, testAccount) {
+	t.Helper()
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	if err := mineTxs(t, c, signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: 4 * Coin, Fee: Coin / 4})); err != nil {
+		t.Fatal(err)
+	}
+	mineBlocks(t, c, 2)
+	return cfg, c, bob
+}
+
+func TestVerifyChainAcceptsAValidChain(t *testing.T) {
+	cfg, c, bob := verifyFixture(t)
+	r := VerifyChain(c.BestChain(), cfg)
+	if r.Failure != nil {
+		t.Fatalf("failure: %+v", r.Failure)
+	}
+	if r.Blocks != 4 || r.Transactions != 1 {
+		t.Errorf("verified %d blocks and %d transactions, want 4 and 1", r.Blocks, r.Transactions)
+	}
+	v, err := c.At(c.Tip().Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Supply != v.TotalSupply() || r.Balances[bob.Addr] != 4*Coin {
+		t.Errorf("supply %s, bob %s; the chain says %s and 4", r.Supply, r.Balances[bob.Addr], v.TotalSupply())
+	}
+	var out bytes.Buffer
+	r.Print(&out)
+	if !strings.HasSuffix(out.String(), "OK\n") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
+
+func TestVerifyChainNamesTheFailedCheck(t *testing.T) {
+	cfg, c, _ := verifyFixture(t)
+	for _, tc := range []struct {
+		check  string
+		height int
+		tamper func([]Block) []Block
+	}{
+		{"genesis", 0, func([]Block) []Block { return nil }},
+		{"link", 3, func(b []Block) []Block { return append(b[:2], b[3]) }},
+		{"hash", 2, func(b []Block) []Block { b[2].Nonce++; return b }},
+		{"tx-hash", 1, func(b []Block) []Block {
+			b[1].Transactions = append([]Transaction(nil), b[1].Transactions...)
+			b[1].Transactions[0].Amount = 9 * Coin
+			return b
+		}},
+	} {
+		blocks := tc.tamper(c.BestChain())
+		r := VerifyChain(blocks, cfg)
+		if r.Failure == nil {
+			t.Errorf("%s: tampered chain verified", tc.check)
+			continue
+		}
+		if r.Failure.Check != tc.check || r.Failure.Height != tc.height {
+			t.Errorf("failed %s at %d (%v), want %s at %d", r.Failure.Check, r.Failure.Height, r.Failure.Err, tc.check, tc.height)
+		}
+		var out bytes.Buffer
+		r.Print(&out)
+		if !strings.Contains(out.String(), "check : "+tc.check) {
+			t.Errorf("%s: report:\n%s", tc.check, out.String())
+		}
+	}
+
+	// Blocks that are well-formed but break a rule of the config fail on
+	// consensus.
+	other := cfg
+	other.MinFee = Coin
+	if r := VerifyChain(c.BestChain(), other); r.Failure == nil || r.Failure.Check != "consensus" || r.Failure.Height != 1 {
+		t.Errorf("higher minimum fee: failure %+v, want consensus at 1", r.Failure)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Block header versions. Each version fixes how a header is hashed and which
+// consensus rules apply to the block, so a rule change is a new version that
+// activates at a height rather than an edit that invalidates old chains.
+const (
+	// LegacyBlockVersion is a header from before versioning. Its hash leaves
+	// out the version and coinbase amount, and it can't carry fees: the
+	// coinbase is paid the block reward and uncle bonuses implicitly.
+	LegacyBlockVersion uint32 = 0
+	// BlockVersion1 hashes the version and coinbase amount, and pays the
+	// coinbase what it claims, fees included.
+	BlockVersion1 uint32 = 1
+	// BlockVersion2 requires every transaction to carry its sender's next
+	// nonce.
+	BlockVersion2 uint32 = 2
+	// BlockVersion3 requires every sender to afford the amount and fee of
+	// each transaction it sends.
+	BlockVersion3 uint32 = 3
+	// BlockVersion4 requires every transaction to be signed by the key its
+	// sender's address is derived from.
+	BlockVersion4 uint32 = 4
+	// BlockVersion5 commits to the state root, the Merkle root of every
+	// balance after the block; see StateTree.
+	BlockVersion5 uint32 = 5
+	// BlockVersion6 accepts transactions signed with a recoverable
+	// signature and no public key; see SignTxRecoverable.
+	BlockVersion6 uint32 = 6
+	// BlockVersion7 puts the state root in the seal hash, so the signature
+	// of a proof-of-authority or proof-of-stake block covers it too.
+	BlockVersion7 uint32 = 7
+
+	// CurrentBlockVersion is the version new blocks are built with.
+	CurrentBlockVersion = BlockVersion7
+)
+
+// Upgrade activates a header version: from Height on, every block must have
+// at least Version.
+type Upgrade struct {
+	Version uint32
+	Height  int
+}
+
+// LegacyUpgrades is the schedule for a chain that keeps accepting every
+// header version, legacy ones included, which check neither nonces,
+// balances nor signatures. Only chains rebuilt from data that predates
+// those rules, such as Migrate's, should run it.
+var LegacyUpgrades = []Upgrade{{LegacyBlockVersion, 0}}
+
+// MinBlockVersion returns the lowest header version allowed at height under
+// the chain's upgrade schedule. A chain without one requires
+// CurrentBlockVersion from genesis on: older headers skip checks, so a
+// chain must opt in to them explicitly.
+func (c *Chain) MinBlockVersion(height int) uint32 {
+	if len(c.config.Upgrades) == 0 {
+		return CurrentBlockVersion
+	}
+	required := LegacyBlockVersion
+	for _, u := range c.config.Upgrades {
+		if height >= u.Height && u.Version > required {
+			required = u.Version
+		}
+	}
+	return required
+}
+
+// validateVersion checks that b's header version is one this code knows and
+// that the upgrade schedule allows at its height.
+func (c *Chain) validateVersion(b Block) error {
+	if b.Version > CurrentBlockVersion {
+		return fmt.Errorf("block %d: unknown header version %d (this node knows up to %d)", b.Index, b.Version, CurrentBlockVersion)
+	}
+	if required := c.MinBlockVersion(b.Index); b.Version < required {
+		return fmt.Errorf("block %d: header version %d, at least %d required from height %d", b.Index, b.Version, required, c.activationHeight(required))
+	}
+	return nil
+}
+
+// activationHeight returns the height at which version first became
+// required.
+func (c *Chain) activationHeight(version uint32) int {
+	if len(c.config.Upgrades) == 0 {
+		return 0
+	}
+	height := -1
+	for _, u := range c.config.Upgrades {
+		if u.Version == version && (height < 0 || u.Height < height) {
+			height = u.Height
+		}
+	}
+	return height
+}
+
+// validateUpgrades checks an upgrade schedule: versions must be known and
+// activate in order.
+func validateUpgrades(upgrades []Upgrade) error {
+	sorted := append([]Upgrade(nil), upgrades...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Height < sorted[j].Height })
+	for i, u := range sorted {
+		if u.Version > CurrentBlockVersion {
+			return fmt.Errorf("upgrade to unknown header version %d", u.Version)
+		}
+		if u.Height < 0 {
+			return fmt.Errorf("upgrade to version %d at negative height", u.Version)
+		}
+		if i > 0 && u.Version <= sorted[i-1].Version {
+			return fmt.Errorf("upgrade to version %d at height %d does not raise the version", u.Version, u.Height)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestMinBlockVersion(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.Upgrades = []Upgrade{{BlockVersion5, 4}, {BlockVersion4, 2}}
+	c := newTestChain(t, cfg)
+	for height, want := range []uint32{LegacyBlockVersion, LegacyBlockVersion, BlockVersion4, BlockVersion4, BlockVersion5, BlockVersion5} {
+		if got := c.MinBlockVersion(height); got != want {
+			t.Errorf("MinBlockVersion(%d) = %d, want %d", height, got, want)
+		}
+	}
+
+	cfg.Upgrades = nil
+	c = newTestChain(t, cfg)
+	for _, height := range []int{0, 1, 1000} {
+		if got := c.MinBlockVersion(height); got != CurrentBlockVersion {
+			t.Errorf("no schedule: MinBlockVersion(%d) = %d, want %d", height, got, CurrentBlockVersion)
+		}
+	}
+}
+
+func TestNewChainRejectsBadUpgrades(t *testing.T) {
+	for name, upgrades := range map[string][]Upgrade{
+		"unknown version":   {{CurrentBlockVersion + 1, 1}},
+		"negative height":   {{BlockVersion4, -1}},
+		"lowers version":    {{BlockVersion5, 1}, {BlockVersion4, 2}},
+		"repeats a version": {{BlockVersion4, 1}, {BlockVersion4, 2}},
+	} {
+		cfg := testConfig(nil)
+		cfg.Upgrades = upgrades
+		genesis := newTestChain(t, testConfig(nil)).Tip()
+		if _, err := NewChain(cfg, genesis); err == nil {
+			t.Errorf("%s: schedule accepted", name)
+		}
+	}
+}
+
+func TestVersionSchedule(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.Upgrades = []Upgrade{{BlockVersion4, 2}}
+	c := newTestChain(t, cfg)
+	build := func(version uint32) Block {
+		b, err := c.buildBlock("", nil, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	if err := c.AddBlock(build(BlockVersion3)); err != nil {
+		t.Fatalf("version 3 before the upgrade: %v", err)
+	}
+	if err := c.AddBlock(build(BlockVersion3)); err == nil {
+		t.Error("version 3 accepted once version 4 is required")
+	}
+	unknown := build(BlockVersion4)
+	unknown.Version = CurrentBlockVersion + 1
+	if err := cfg.Engine.Seal(&unknown); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(unknown); err == nil {
+		t.Error("unknown header version accepted")
+	}
+	if err := c.AddBlock(build(BlockVersion4)); err != nil {
+		t.Fatalf("version 4 after the upgrade: %v", err)
+	}
+}
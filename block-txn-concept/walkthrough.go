@@ -0,0 +1,332 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// walkthrough narrates a scripted run of the package and checks every
+// outcome along the way, so it doubles as an end-to-end test: any
+// expectation that doesn't hold fails the run.
+type walkthrough struct {
+	out   io.Writer
+	steps int
+	err   error
+}
+
+func (w *walkthrough) step(title string) {
+	w.steps++
+	fmt.Fprintf(w.out, "\n== %d. %s\n", w.steps, title)
+}
+
+func (w *walkthrough) say(format string, args ...any) {
+	fmt.Fprintf(w.out, "   "+format+"\n", args...)
+}
+
+// expect records a failure unless ok holds. Only the first failure is
+// kept; the run carries on so the log shows how far things got.
+func (w *walkthrough) expect(ok bool, format string, args ...any) {
+	if ok {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintf(w.out, "   !! %s\n", msg)
+	if w.err == nil {
+		w.err = fmt.Errorf("step %d: %s", w.steps, msg)
+	}
+}
+
+// check is expect for an error that should be nil.
+func (w *walkthrough) check(err error, what string) {
+	w.expect(err == nil, "%s: %v", what, err)
+}
+
+func short(hash string) string {
+	if len(hash) <= 14 {
+		return hash
+	}
+	return hash[:14] + "..."
+}
+
+// Walkthrough runs the tutorial, writing the narration to out. It sets up
+// a genesis attested by an operator key, funds accounts from it, submits
+// signed transactions through a mempool, mines them, follows them with a light
+// client's bloom filter, a state proof, and a state diff, forces a reorg from
+// a second node, verifies the result from scratch, and shows a tampered copy
+// being rejected.
+//
+// Blocks commit to their transactions by hash list and bloom filter rather
+// than a Merkle root, so only balances have proof steps; the merkle module
+// shows transaction proofs on their own.
+func Walkthrough(out io.Writer) error {
+	w := &walkthrough{out: out}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w.step("Accounts and an operator key")
+	keys := make(map[string]*ecdsa.PrivateKey)
+	account := func(name string) (string, error) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		addr, err := AddressFromPubKey(&key.PublicKey)
+		if err != nil {
+			return "", err
+		}
+		keys[addr.String()] = key
+		return addr.String(), nil
+	}
+	var alice, bob, carol, dave string
+	for _, a := range []struct {
+		name string
+		addr *string
+	}{{"alice", &alice}, {"bob", &bob}, {"carol", &carol}, {"dave", &dave}} {
+		addr, err := account(a.name)
+		if err != nil {
+			return err
+		}
+		*a.addr = addr
+	}
+	w.say("alice %s", alice)
+	w.say("bob   %s", bob)
+	w.say("carol %s", carol)
+	w.say("dave  %s (mines on a second node)", dave)
+	w.say("Each address is derived from its owner's key, which signs its payments.")
+	operator, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	w.say("The operator key will attest the genesis.")
+
+	w.step("Genesis, funded and attested")
+	// No uncles: VerifyChain only sees the best chain, so it couldn't check
+	// the orphaned blocks a later block would reference.
+	cfg := ChainConfig{
+		ChainID:     "walkthrough",
+		Engine:      &FakePoWEngine{Seed: 1},
+		BlockReward: 50 * Coin,
+		Alloc:       map[string]Amount{alice: 100 * Coin, bob: 50 * Coin},
+		Clock:       StepClock(start.Add(10*time.Second), 10*time.Second),
+	}
+	genesis := Block{Version: CurrentBlockVersion, Timestamp: start, Bits: BitsForLeadingZeros(0), PrevHash: "0x" + strings.Repeat("0", 64)}
+	if err := cfg.Engine.Seal(&genesis); err != nil {
+		return err
+	}
+	unsigned, err := NewChain(cfg, genesis)
+	if err != nil {
+		return err
+	}
+	if cfg.Attestation, err = unsigned.Attest(operator); err != nil {
+		return err
+	}
+	cfg.GenesisKey = &operator.PublicKey
+	chain, err := NewChain(cfg, genesis)
+	if err != nil {
+		return err
+	}
+	w.say("Genesis %s, chain ID %q, fork ID %s", short(genesis.Hash), cfg.ChainID, chain.ForkID(0))
+	w.say("alice starts with %.2f and bob with %.2f", cfg.Alloc[alice], cfg.Alloc[bob])
+	tampered := cfg
+	tampered.BlockReward = 500 * Coin
+	_, err = NewChain(tampered, genesis)
+	w.say("A node that raises the block reward refuses to start: %v", err)
+	w.expect(errors.Is(err, ErrBadAttestation), "tampered config was not rejected")
+
+	w.step("Submitting transactions to the mempool")
+	pool := NewMempool(chain)
+	defer pool.Close()
+	pay := func(from, to, amount, fee, note string) Transaction {
+		tx := Transaction{
+			From: from, To: to, Time: start, Description: note, Amount: MustParseAmount(amount), Fee: MustParseAmount(fee),
+			Type: Debit, ChainID: cfg.ChainID, Nonce: chain.NextNonce(from),
+		}
+		for _, pending := range pool.txs {
+			if pending.From == from && pending.Nonce >= tx.Nonce {
+				tx.Nonce = pending.Nonce + 1
+			}
+		}
+		w.check(SignTx(&tx, keys[from]), "signing "+note)
+		w.check(pool.Add(tx), "adding "+note)
+		w.say("%-6s %6.2f fee %.2f nonce %d  %s", note, tx.Amount, tx.Fee, tx.Nonce, short(tx.Hash))
+		return tx
+	}
+	rent := pay(alice, bob, "10", "0.10", "rent")
+	pay(alice, carol, "5", "0.50", "lunch")
+	pay(bob, carol, "20", "1.00", "bike")
+	theft := Transaction{From: dave, To: dave, Time: start, Description: "theft", Amount: 50 * Coin, Type: Debit, ChainID: cfg.ChainID, Nonce: 2}
+	w.check(SignTx(&theft, keys[dave]), "signing the theft")
+	theft.From = alice
+	theft.Hash = computeTxHash(theft)
+	err = pool.Add(theft)
+	w.say("dave can't spend alice's coins with their own key: %v", err)
+	w.expect(errors.Is(err, ErrBadSignature), "forged signature was accepted")
+	order := pool.Select(0)
+	w.say("The builder will take them by fee rate, each sender in nonce order:")
+	for _, tx := range order {
+		w.say("  %-6s rate %.2f", tx.Description, FeeRate(tx))
+	}
+	w.expect(len(order) == 3 && order[0].Description == "bike", "bike should go first")
+
+	w.step("Mining")
+	for _, limit := range []int{2, 0} {
+		b, err := pool.BuildBlock(carol, limit)
+		w.check(err, "building a block")
+		w.check(chain.AddBlock(b), "adding a block")
+		w.say("Block %d %s: %d txs, carol claims %.2f", b.Index, short(b.Hash), len(b.Transactions), b.CoinbaseAmount)
+	}
+	w.expect(pool.Len() == 0, "mempool should be empty, holds %d", pool.Len())
+	tip, _ := chain.At(chain.Tip().Index)
+	w.say("Balances: alice %.2f, bob %.2f, carol %.2f", tip.Balance(alice), tip.Balance(bob), tip.Balance(carol))
+	w.expect(tip.Balance(alice) == MustParseAmount("84.40"), "alice should hold 84.40, holds %.2f", tip.Balance(alice))
+
+	w.step("Replaying a transaction")
+	err = pool.Add(rent)
+	w.say("The mempool turns the rent payment away: %v", err)
+	w.expect(err != nil, "replay was accepted by the mempool")
+	replay, err := chain.BuildBlock(carol, []Transaction{rent})
+	w.check(err, "building the replay block")
+	err = chain.AddBlock(replay)
+	w.say("So does the chain: %v", err)
+	w.expect(errors.Is(err, ErrDoubleSpend) || errors.Is(err, ErrBadNonce), "replayed block was accepted")
+
+	w.step("A light client looks for carol")
+	var headers []Block
+	for _, b := range chain.BestChain() {
+		b.PrunedTxHashes, b.Transactions = b.TxHashes(), nil
+		headers = append(headers, b)
+	}
+	matches := FilterBlocks(headers, carol)
+	for _, b := range matches {
+		w.say("Block %d may mention carol; fetch its body", b.Index)
+	}
+	w.expect(len(matches) > 0, "bloom filters found nothing for carol")
+	header := headers[len(headers)-1]
+	proof, err := chain.ProveBalance(carol, header.Index)
+	w.check(err, "proving carol's balance")
+	if proof != nil {
+		err = proof.Verify(header)
+		w.say("A full node proves carol holds %.2f at block %d with %d sibling hash(es); the header's state root confirms it", proof.Balance, proof.Height, len(proof.Steps))
+		w.expect(err == nil, "a true balance failed its proof: %v", err)
+		forged := *proof
+		forged.Balance += Coin
+		err = forged.Verify(header)
+		w.say("Claiming one coin more breaks the proof: %v", err)
+		w.expect(errors.Is(err, ErrBadStateRoot), "an inflated balance passed its proof")
+	}
+
+	w.step("Explaining carol's balance")
+	diff, err := chain.Diff(carol, -1, chain.Tip().Index)
+	w.check(err, "diffing carol's balance")
+	if diff != nil {
+		diff.Print(indent{w.out})
+	}
+
+	w.step("A second node mines a longer branch")
+	cfg2 := cfg
+	cfg2.Clock = StepClock(start.Add(11*time.Second), 10*time.Second)
+	node2, err := NewChain(cfg2, genesis)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		b, err := node2.BuildBlock(dave, nil)
+		w.check(err, "building on the second node")
+		w.check(node2.AddBlock(b), "adding on the second node")
+	}
+	ledger := NewLedger()
+	for _, b := range chain.BestChain() {
+		w.check(ledger.ApplyBlock(chain, b), "building a ledger")
+	}
+	unfollow := ledger.Follow(chain, func(err error) { w.check(err, "following the chain") })
+	reorgs := 0
+	unsubscribe := chain.Subscribe(ReorgEvent, func(e Event) {
+		reorgs++
+		w.say("Reorg: %d blocks disconnected, %d connected, tip now %s", len(e.Disconnected), len(e.Connected), short(e.Block.Hash))
+	})
+	for _, b := range node2.BestChain()[1:] {
+		w.check(chain.AddBlock(b), "relaying a block")
+	}
+	unsubscribe()
+	unfollow()
+	w.expect(reorgs == 1, "expected one reorg, saw %d", reorgs)
+	tip, _ = chain.At(chain.Tip().Index)
+	w.say("A ledger following the chain unapplied the old branch: carol holds %.2f", ledger.Account(carol).Balance)
+	w.expect(ledger.Account(carol).Balance == tip.Balance(carol), "ledger says carol holds %.2f, the chain says %.2f", ledger.Account(carol).Balance, tip.Balance(carol))
+	for h := 0; h <= chain.Tip().Index; h++ {
+		view, _ := chain.At(h)
+		got, err := ledger.BalanceAt(dave, h)
+		w.check(err, "asking the ledger for a past balance")
+		w.expect(got == view.Balance(dave), "ledger says dave held %.2f at height %d, the chain says %.2f", got, h, view.Balance(dave))
+	}
+	w.say("Its per-block history agrees with the chain on dave's balance at every height")
+	w.say("The orphaned payments are back in the mempool: %d pending", pool.Len())
+	w.expect(pool.Len() == 3, "mempool should hold 3, holds %d", pool.Len())
+	b, err := pool.BuildBlock(carol, 0)
+	w.check(err, "rebuilding")
+	w.check(chain.AddBlock(b), "re-mining the orphaned payments")
+	w.say("Block %d puts them back on the best chain", b.Index)
+
+	w.step("Verifying the chain from scratch")
+	best := chain.BestChain()
+	report := VerifyChain(best, cfg)
+	report.Print(indent{w.out})
+	w.expect(report.Failure == nil, "verification failed")
+	view, _ := chain.At(chain.Tip().Index)
+	want := view.TotalSupply()
+	w.expect(report.Supply == want, "replayed supply %.2f, the chain's own state says %.2f", report.Supply, want)
+
+	w.step("Tampering with history")
+	forged := append([]Block(nil), best...)
+	forged[len(forged)-1].Transactions = append([]Transaction(nil), forged[len(forged)-1].Transactions...)
+	forged[len(forged)-1].Transactions[0].Amount = 1000 * Coin
+	report = VerifyChain(forged, cfg)
+	w.expect(report.Failure != nil, "forged chain verified")
+	if report.Failure != nil {
+		w.say("Raising one amount fails the %q check at block %d", report.Failure.Check, report.Failure.Height)
+	}
+
+	if w.err != nil {
+		return w.err
+	}
+	fmt.Fprintf(out, "\nAll %d steps behaved as expected.\n", w.steps)
+	return nil
+}
+
+// indent prefixes every line written through it, to nest other output in
+// the narration.
+type indent struct{ w io.Writer }
+
+func (i indent) Write(p []byte) (int, error) {
+	start := 0
+	for j, c := range p {
+		if c == '\n' {
+			if _, err := fmt.Fprintf(i.w, "   %s\n", p[start:j]); err != nil {
+				return 0, err
+			}
+			start = j + 1
+		}
+	}
+	if start < len(p) {
+		if _, err := fmt.Fprintf(i.w, "   %s", p[start:]); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// runWalkthrough implements the "walkthrough" command.
+func runWalkthrough(args []string) error {
+	fs := flag.NewFlagSet("walkthrough", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return Walkthrough(os.Stdout)
+}
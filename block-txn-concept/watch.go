@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// watchRecord is one line of "watch -json" output.
+type watchRecord struct {
+	Kind         EventKind     `json:"kind"`
+	Height       int           `json:"height"`
+	Hash         string        `json:"hash"`
+	Time         time.Time     `json:"time"`
+	Transactions []Transaction `json:"transactions,omitempty"`
+	Disconnected []string      `json:"disconnected,omitempty"` // reorg only: hashes of blocks dropped
+}
+
+// Watcher follows a stored chain file, like tail -f, and reports blocks that
+// join the best chain as the file is rewritten. If the file's chain switches
+// branches, the dropped blocks are reported as a reorg first.
+type Watcher struct {
+	Path     string
+	Interval time.Duration
+	Address  string // if set, only transactions to or from it are shown
+	JSON     bool   // write one JSON record per line instead of text
+
+	seen []Block
+}
+
+// Run polls the file until it fails to read or out fails. With backlog > 0
+// the last backlog blocks already in the file are shown first.
+func (w *Watcher) Run(out io.Writer, backlog int) error {
+	blocks, err := LoadBlocks(w.Path)
+	if err != nil {
+		return err
+	}
+	start := max(len(blocks)-backlog, 0)
+	w.seen = blocks[:start]
+	if err := w.update(out, blocks); err != nil {
+		return err
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for {
+		time.Sleep(interval)
+		blocks, err := LoadBlocks(w.Path)
+		if err != nil {
+			// The file may be mid-rewrite; try again next tick.
+			if errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if err := w.update(out, blocks); err != nil {
+			return err
+		}
+	}
+}
+
+// update reports the difference between the blocks seen so far and blocks.
+func (w *Watcher) update(out io.Writer, blocks []Block) error {
+	common := 0
+	for common < len(w.seen) && common < len(blocks) && w.seen[common].Hash == blocks[common].Hash {
+		common++
+	}
+	if common < len(w.seen) {
+		if err := w.emitReorg(out, w.seen[common:], blocks); err != nil {
+			return err
+		}
+	}
+	for _, b := range blocks[common:] {
+		if err := w.emitBlock(out, b); err != nil {
+			return err
+		}
+	}
+	w.seen = blocks
+	return nil
+}
+
+func (w *Watcher) emitReorg(out io.Writer, dropped, blocks []Block) error {
+	tip := blocks[len(blocks)-1]
+	if w.JSON {
+		rec := watchRecord{Kind: ReorgEvent, Height: tip.Index, Hash: tip.Hash, Time: tip.Timestamp}
+		for _, b := range dropped {
+			rec.Disconnected = append(rec.Disconnected, b.Hash)
+		}
+		return json.NewEncoder(out).Encode(rec)
+	}
+	_, err := fmt.Fprintf(out, "reorg: %d block(s) from #%d dropped\n", len(dropped), dropped[0].Index)
+	return err
+}
+
+func (w *Watcher) emitBlock(out io.Writer, b Block) error {
+	var txs []Transaction
+	for _, tx := range b.Transactions {
+		if w.Address == "" || strings.EqualFold(tx.From, w.Address) || tx.sendsTo(w.Address) {
+			txs = append(txs, tx)
+		}
+	}
+
+	if w.JSON {
+		return json.NewEncoder(out).Encode(watchRecord{
+			Kind: NewBlockEvent, Height: b.Index, Hash: b.Hash, Time: b.Timestamp, Transactions: txs,
+		})
+	}
+	if _, err := fmt.Fprintf(out, "#%-5d %s  %s  %d tx\n", b.Index, b.Hash[:18]+"...", b.Timestamp.Format(time.RFC3339), len(b.TxHashes())); err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		total, _ := tx.Total()
+		if _, err := fmt.Fprintf(out, "       %s %s -> %s | %.2f  %s\n", tx.Hash[:18]+"...", tx.From, tx.payee(), total, tx.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runWatch implements the "watch" command.
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	w := &Watcher{}
+	fs.StringVar(&w.Path, "chain", "chain.json", "stored chain to follow")
+	fs.DurationVar(&w.Interval, "interval", time.Second, "how often to check for new blocks")
+	fs.StringVar(&w.Address, "address", "", "only show transactions to or from this address")
+	fs.BoolVar(&w.JSON, "json", false, "write one JSON record per line")
+	backlog := fs.Int("n", 5, "number of existing blocks to show first")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return w.Run(os.Stdout, *backlog)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func decodeWatch(t *testing.T, out *bytes.Buffer) []watchRecord {
+	t.Helper()
+	var recs []watchRecord
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var r watchRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, r)
+	}
+	return recs
+}
+
+func TestWatcherReportsNewBlocksAndReorgs(t *testing.T) {
+	alice, bob, carol := newTestAccount(t), newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(map[string]Amount{alice.Addr: 10 * Coin, carol.Addr: 10 * Coin})
+	c := newTestChain(t, cfg)
+	toBob := signedTx(t, c, alice, Transaction{To: bob.Addr, Amount: Coin})
+	toAlice := signedTx(t, c, carol, Transaction{To: alice.Addr, Amount: Coin})
+	if err := mineTxs(t, c, toBob, toAlice); err != nil {
+		t.Fatal(err)
+	}
+	before := c.BestChain()
+
+	w := &Watcher{Address: bob.Addr, JSON: true}
+	var out bytes.Buffer
+	if err := w.update(&out, before); err != nil {
+		t.Fatal(err)
+	}
+	recs := decodeWatch(t, &out)
+	if len(recs) != 2 || recs[1].Kind != NewBlockEvent || recs[1].Hash != before[1].Hash {
+		t.Fatalf("first read: %+v", recs)
+	}
+	if txs := recs[1].Transactions; len(txs) != 1 || txs[0].Hash != toBob.Hash {
+		t.Errorf("block 1 shows %d transactions, want only the one to bob", len(txs))
+	}
+
+	// Nothing changed: nothing to report.
+	if err := w.update(&out, before); err != nil || out.Len() != 0 {
+		t.Errorf("unchanged file reported %q (err %v)", out.String(), err)
+	}
+
+	rivalCfg := cfg
+	rivalCfg.Clock = StepClock(testStart.Add(7*time.Second), 7*time.Second)
+	after := append([]Block{before[0]}, mineBlocks(t, newTestChain(t, rivalCfg), 2)...)
+	if err := w.update(&out, after); err != nil {
+		t.Fatal(err)
+	}
+	recs = decodeWatch(t, &out)
+	if len(recs) != 3 || recs[0].Kind != ReorgEvent {
+		t.Fatalf("after the reorg: %+v", recs)
+	}
+	if recs[0].Hash != after[2].Hash || len(recs[0].Disconnected) != 1 || recs[0].Disconnected[0] != before[1].Hash {
+		t.Errorf("reorg record %+v", recs[0])
+	}
+	for i, r := range recs[1:] {
+		if r.Kind != NewBlockEvent || r.Hash != after[i+1].Hash {
+			t.Errorf("record %d: %s %s, want new-block %s", i+1, r.Kind, r.Hash, after[i+1].Hash)
+		}
+	}
+
+	w.JSON = false
+	w.seen = before
+	if err := w.update(&out, after); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "reorg: 1 block(s) from #1 dropped\n#1 ") {
+		t.Errorf("text output:\n%s", out.String())
+	}
+}
+
+// lockedBuffer lets a test read what a Watcher running in another goroutine
+// has written.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatcherRunShowsBacklogAndStopsWhenFileGoes(t *testing.T) {
+	c := newTestChain(t, testConfig(nil))
+	blocks := append([]Block{*c.genesis}, mineBlocks(t, c, 3)...)
+	path := filepath.Join(t.TempDir(), "chain.json")
+	if err := SaveBlocks(path, blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	var out lockedBuffer
+	w := &Watcher{Path: path, Interval: time.Millisecond}
+	done := make(chan error, 1)
+	go func() { done <- w.Run(&out, 2) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Count(out.String(), "\n") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("backlog not shown:\n%s", out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Run returned %v, want ErrNotExist", err)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "#2 ") || !strings.Contains(got, "\n#3 ") || strings.Contains(got, "#1 ") {
+		t.Errorf("want only the last two blocks:\n%s", got)
+	}
+}
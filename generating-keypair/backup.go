@@ -0,0 +1,288 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// BackupVersion is the backup format this code reads and writes. A reader
+// refuses any other version rather than guess at a format it doesn't know;
+// a change that older readers could safely ignore doesn't need a new one.
+const BackupVersion = 1
+
+// pbkdf2Iterations is the work factor for turning a passphrase into the
+// backup encryption key. Files may use anything from minBackupIterations
+// to maxBackupIterations: below that the passphrase is too cheap to guess,
+// and above it a crafted file could keep restore busy for hours.
+const (
+	pbkdf2Iterations    = 600_000
+	minBackupIterations = 100_000
+	maxBackupIterations = 10_000_000
+)
+
+// Backup is everything a user needs to restore a wallet, in one file: the
+// keys and the phrase they came from, encrypted, and the wallet's metadata
+// in the clear. The metadata is the encryption's additional data, so it
+// can't be edited without the passphrase either.
+type Backup struct {
+	Version     int               `json:"version"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	Secrets     EncryptedSecret   `json:"secrets"` // backupSecrets, as JSON
+	Accounts    []BackupAccount   `json:"accounts"`
+	AddressBook []Contact         `json:"addressBook,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"` // free-form notes keyed by address or tx hash
+}
+
+// BackupAccount is the public half of one key in a backup, in the order
+// of the encrypted keys.
+type BackupAccount struct {
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	PublicKey string `json:"publicKey"` // uncompressed point, hex
+}
+
+// Contact is a saved payee.
+type Contact struct {
+	Label   string `json:"label"`
+	Address string `json:"address"`
+}
+
+// EncryptedSecret is a secret sealed with AES-256-GCM under a key derived
+// from a passphrase with PBKDF2-SHA256.
+type EncryptedSecret struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Wallet is what a backup holds once opened.
+type Wallet struct {
+	Mnemonic    string // phrase the first key was derived from, if any
+	Keys        []NamedKey
+	AddressBook []Contact
+	Labels      map[string]string
+}
+
+// NamedKey is a private key and the name the user knows it by.
+type NamedKey struct {
+	Name string
+	Key  *ecdsa.PrivateKey
+}
+
+// backupSecrets is the plaintext of Backup.Secrets.
+type backupSecrets struct {
+	Mnemonic string   `json:"mnemonic,omitempty"`
+	Keys     []string `json:"keys"` // SEC1 DER, hex, in Accounts order
+}
+
+// NewBackup seals w under passphrase.
+func NewBackup(w Wallet, passphrase string) (*Backup, error) {
+	if len(w.Keys) == 0 {
+		return nil, errors.New("nothing to back up")
+	}
+	b := &Backup{
+		Version:     BackupVersion,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		AddressBook: w.AddressBook,
+		Labels:      w.Labels,
+	}
+	secrets := backupSecrets{Mnemonic: w.Mnemonic}
+	for _, k := range w.Keys {
+		der, err := x509.MarshalECPrivateKey(k.Key)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := k.Key.PublicKey.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		addr, err := AddressFromPubKey(&k.Key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		secrets.Keys = append(secrets.Keys, hex.EncodeToString(der))
+		b.Accounts = append(b.Accounts, BackupAccount{Name: k.Name, Address: addr, PublicKey: hex.EncodeToString(pub)})
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := b.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	if b.Secrets, err = encryptSecret(plaintext, ad, passphrase); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Open decrypts the backup. It fails if the passphrase is wrong or if
+// anything in the file, metadata included, was changed since NewBackup.
+func (b *Backup) Open(passphrase string) (Wallet, error) {
+	ad, err := b.additionalData()
+	if err != nil {
+		return Wallet{}, err
+	}
+	plaintext, err := decryptSecret(b.Secrets, ad, passphrase)
+	if err != nil {
+		return Wallet{}, err
+	}
+	var secrets backupSecrets
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return Wallet{}, err
+	}
+	if len(secrets.Keys) != len(b.Accounts) {
+		return Wallet{}, fmt.Errorf("backup holds %d keys for %d accounts", len(secrets.Keys), len(b.Accounts))
+	}
+	w := Wallet{Mnemonic: secrets.Mnemonic, AddressBook: b.AddressBook, Labels: b.Labels}
+	for i, s := range secrets.Keys {
+		der, err := hex.DecodeString(s)
+		if err != nil {
+			return Wallet{}, err
+		}
+		priv, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return Wallet{}, err
+		}
+		w.Keys = append(w.Keys, NamedKey{Name: b.Accounts[i].Name, Key: priv})
+	}
+	return w, nil
+}
+
+// additionalData is the metadata the encryption authenticates: everything
+// but the secret itself.
+func (b *Backup) additionalData() ([]byte, error) {
+	// Empty and missing mean the same once saved without omitempty fields.
+	book, labels := b.AddressBook, b.Labels
+	if len(book) == 0 {
+		book = nil
+	}
+	if len(labels) == 0 {
+		labels = nil
+	}
+	return json.Marshal(struct {
+		Version     int               `json:"version"`
+		CreatedAt   time.Time         `json:"createdAt"`
+		Accounts    []BackupAccount   `json:"accounts"`
+		AddressBook []Contact         `json:"addressBook"`
+		Labels      map[string]string `json:"labels"`
+	}{b.Version, b.CreatedAt, b.Accounts, book, labels})
+}
+
+// UnmarshalJSON reads a backup, rejecting versions other than
+// BackupVersion.
+func (b *Backup) UnmarshalJSON(data []byte) error {
+	type plain Backup
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if p.Version != BackupVersion {
+		return fmt.Errorf("unsupported backup version %d (this tool reads version %d)", p.Version, BackupVersion)
+	}
+	*b = Backup(p)
+	return nil
+}
+
+// SaveBackup writes b to path, readable only by the owner.
+func SaveBackup(path string, b *Backup) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// LoadBackup reads a backup written by SaveBackup.
+func LoadBackup(path string) (*Backup, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Backup
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func encryptSecret(secret, ad []byte, passphrase string) (EncryptedSecret, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return EncryptedSecret{}, err
+	}
+	gcm, err := backupCipher(passphrase, salt, pbkdf2Iterations)
+	if err != nil {
+		return EncryptedSecret{}, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return EncryptedSecret{}, err
+	}
+	return EncryptedSecret{
+		KDF:        "pbkdf2-sha256",
+		Iterations: pbkdf2Iterations,
+		Salt:       hex.EncodeToString(salt),
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(gcm.Seal(nil, nonce, secret, ad)),
+	}, nil
+}
+
+func decryptSecret(s EncryptedSecret, ad []byte, passphrase string) ([]byte, error) {
+	if s.KDF != "pbkdf2-sha256" {
+		return nil, fmt.Errorf("unsupported key derivation %q", s.KDF)
+	}
+	if s.Iterations < minBackupIterations || s.Iterations > maxBackupIterations {
+		return nil, fmt.Errorf("%d PBKDF2 iterations is outside %d to %d", s.Iterations, minBackupIterations, maxBackupIterations)
+	}
+	salt, err := hex.DecodeString(s.Salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(s.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(s.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := backupCipher(passphrase, salt, s.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+	secret, err := gcm.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted backup")
+	}
+	return secret, nil
+}
+
+func backupCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testWallet(t *testing.T) Wallet {
+	t.Helper()
+	const phrase = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	s, err := MnemonicSigner(phrase, "", SchemeECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Wallet{
+		Mnemonic:    phrase,
+		Keys:        []NamedKey{{"main", s.(ECDSASigner).Key}, {"savings", other}},
+		AddressBook: []Contact{{Label: "shop", Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"}},
+		Labels:      map[string]string{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed": "groceries"},
+	}
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	w := testWallet(t)
+	b, err := NewBackup(w, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "wallet.backup")
+	if err := SaveBackup(path, b); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadBackup(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := loaded.Open("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Mnemonic != w.Mnemonic {
+		t.Errorf("mnemonic = %q", got.Mnemonic)
+	}
+	if len(got.Keys) != 2 {
+		t.Fatalf("%d keys restored, want 2", len(got.Keys))
+	}
+	for i, k := range got.Keys {
+		if k.Name != w.Keys[i].Name || !k.Key.Equal(w.Keys[i].Key) {
+			t.Errorf("key %d: %q differs", i, k.Name)
+		}
+		if want, _ := AddressFromPubKey(&w.Keys[i].Key.PublicKey); loaded.Accounts[i].Address != want {
+			t.Errorf("account %d address %s, want %s", i, loaded.Accounts[i].Address, want)
+		}
+	}
+	if len(got.AddressBook) != 1 || got.AddressBook[0] != w.AddressBook[0] {
+		t.Errorf("address book = %+v", got.AddressBook)
+	}
+	if got.Labels["0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"] != "groceries" {
+		t.Errorf("labels = %v", got.Labels)
+	}
+	if _, err := loaded.Open("wrong horse"); err == nil {
+		t.Error("wrong passphrase opened the backup")
+	}
+}
+
+// TestBackupAuthenticatesMetadata edits each part of a saved backup in
+// turn; none of the edits may go unnoticed.
+func TestBackupAuthenticatesMetadata(t *testing.T) {
+	b, err := NewBackup(testWallet(t), "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, edit := range map[string]func(*Backup){
+		"ciphertext": func(b *Backup) {
+			ct, _ := hex.DecodeString(b.Secrets.Ciphertext)
+			ct[0] ^= 1
+			b.Secrets.Ciphertext = hex.EncodeToString(ct)
+		},
+		"contact address": func(b *Backup) { b.AddressBook[0].Address = "0x0000000000000000000000000000000000000bad" },
+		"added contact":   func(b *Backup) { b.AddressBook = append(b.AddressBook, Contact{"mallory", "0x0bad"}) },
+		"label":           func(b *Backup) { b.Labels["0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"] = "refund" },
+		"dropped labels":  func(b *Backup) { b.Labels = nil },
+		"account name":    func(b *Backup) { b.Accounts[0].Name = "spending" },
+		"account order":   func(b *Backup) { b.Accounts[0], b.Accounts[1] = b.Accounts[1], b.Accounts[0] },
+		"created at":      func(b *Backup) { b.CreatedAt = b.CreatedAt.Add(1) },
+	} {
+		var edited Backup
+		if err := json.Unmarshal(data, &edited); err != nil {
+			t.Fatal(err)
+		}
+		edit(&edited)
+		if _, err := edited.Open("correct horse"); err == nil {
+			t.Errorf("%s: edited backup opened", name)
+		}
+	}
+}
+
+func TestBackupRejectsBadParameters(t *testing.T) {
+	b, err := NewBackup(testWallet(t), "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Out-of-range counts fail before any PBKDF2 work is done.
+	for _, n := range []int{0, -1, minBackupIterations - 1, maxBackupIterations + 1, 1 << 40} {
+		edited := *b
+		edited.Secrets.Iterations = n
+		_, err := edited.Open("correct horse")
+		if err == nil || !strings.Contains(err.Error(), "iterations") {
+			t.Errorf("%d iterations: err = %v", n, err)
+		}
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{`"version":0`, `"version":2`} {
+		in := strings.Replace(string(data), `"version":1`, version, 1)
+		var got Backup
+		if err := json.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("%s: backup accepted", version)
+		}
+	}
+
+	if _, err := NewBackup(Wallet{}, "correct horse"); err == nil {
+		t.Error("backed up a wallet with no keys")
+	}
+}
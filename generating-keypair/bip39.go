@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// bip39English is the BIP-39 English wordlist, 2048 words in order, one
+// per line.
+//
+//go:embed bip39_english.txt
+var bip39English string
+
+var (
+	bip39Words = strings.Fields(bip39English)
+	bip39Index = func() map[string]int {
+		m := make(map[string]int, len(bip39Words))
+		for i, w := range bip39Words {
+			m[w] = i
+		}
+		return m
+	}()
+)
+
+// ErrMnemonicChecksum is returned for a phrase whose words are all in the
+// list but whose checksum doesn't match, usually a mistyped or swapped
+// word.
+var ErrMnemonicChecksum = errors.New("mnemonic checksum mismatch")
+
+// NewMnemonic returns a phrase over fresh random entropy: 128 bits give 12
+// words, 256 give 24.
+func NewMnemonic(bits int) (string, error) {
+	if bits%32 != 0 || bits < 128 || bits > 256 {
+		return "", fmt.Errorf("mnemonic entropy must be 128 to 256 bits in steps of 32, not %d", bits)
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic encodes entropy as words: the entropy followed by the
+// first len(entropy)/4 bits of its SHA-256, split into 11-bit indexes into
+// the wordlist.
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	n := len(entropy)
+	if n%4 != 0 || n < 16 || n > 32 {
+		return "", fmt.Errorf("mnemonic entropy must be 16 to 32 bytes in steps of 4, not %d", n)
+	}
+	sum := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), sum[0])
+	words := make([]string, (n*8+n/4)/11)
+	for i := range words {
+		var idx int
+		for b := i * 11; b < i*11+11; b++ {
+			idx = idx<<1 | int(data[b/8]>>(7-b%8)&1)
+		}
+		words[i] = bip39Words[idx]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a phrase back to its entropy, checking that
+// every word is in the list and that the checksum matches.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, fmt.Errorf("mnemonic must have 12, 15, 18, 21, or 24 words, not %d", len(words))
+	}
+	bits := len(words) * 11
+	data := make([]byte, (bits+7)/8)
+	for i, w := range words {
+		idx, ok := bip39Index[strings.ToLower(w)]
+		if !ok {
+			return nil, fmt.Errorf("word %d, %q, is not in the BIP-39 English list", i+1, w)
+		}
+		for j := 0; j < 11; j++ {
+			if idx>>(10-j)&1 == 1 {
+				b := i*11 + j
+				data[b/8] |= 1 << (7 - b%8)
+			}
+		}
+	}
+	n := bits * 32 / 33 / 8
+	entropy := data[:n]
+	sum := sha256.Sum256(entropy)
+	csBits := n / 4
+	if data[n]>>(8-csBits) != sum[0]>>(8-csBits) {
+		return nil, ErrMnemonicChecksum
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic reports whether mnemonic is a well-formed phrase.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// MnemonicSeed derives the 64-byte seed from a phrase and an optional
+// passphrase: PBKDF2-HMAC-SHA512 over the words joined by single spaces,
+// salted with "mnemonic" plus the passphrase, 2048 rounds. BIP-39 asks for
+// both to be NFKD-normalized first; English words need none, so only a
+// passphrase with accented or other non-ASCII letters could differ from
+// other wallets'. Any passphrase gives a valid seed, so a wrong one
+// recovers a different wallet rather than failing.
+func MnemonicSeed(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+	return pbkdf2.Key(sha512.New, normalized, []byte("mnemonic"+passphrase), 2048, 64)
+}
+
+// MasterKeyFromSeed derives the master key for scheme from a BIP-39 seed
+// the way SLIP-10 does: HMAC-SHA512 keyed with the curve's name, whose
+// left half is the key. For P-256 a half that isn't a valid scalar is
+// hashed again until one is.
+func MasterKeyFromSeed(seed []byte, scheme string) (Signer, error) {
+	switch scheme {
+	case SchemeECDSA:
+		data := seed
+		for {
+			mac := hmac.New(sha512.New, []byte("Nist256p1 seed"))
+			mac.Write(data)
+			i := mac.Sum(nil)
+			if priv, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), i[:32]); err == nil {
+				return ECDSASigner{priv}, nil
+			}
+			data = i
+		}
+	case SchemeEd25519:
+		mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+		mac.Write(seed)
+		return Ed25519Signer{ed25519.NewKeyFromSeed(mac.Sum(nil)[:32])}, nil
+	default:
+		return nil, fmt.Errorf("unknown signature scheme %q (want %s or %s)", scheme, SchemeECDSA, SchemeEd25519)
+	}
+}
+
+// MnemonicSigner recovers the master key for scheme from a phrase and
+// passphrase.
+func MnemonicSigner(mnemonic, passphrase, scheme string) (Signer, error) {
+	seed, err := MnemonicSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return MasterKeyFromSeed(seed, scheme)
+}
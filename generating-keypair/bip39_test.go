@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Vectors from the reference implementation, python-mnemonic, whose seeds
+// all use the passphrase "TREZOR".
+var trezorVectors = []struct{ entropy, mnemonic, seed string }{
+	{"00000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607"},
+	{"80808080808080808080808080808080",
+		"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8"},
+	{"ffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069"},
+	{"000000000000000000000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent",
+		"035895f2f481b1b0f01fcf8c289c794660b289981a78f8106447707fdd9666ca06da5a9a565181599b79f53b844d8a71dd9f439c52a3d7b3e8a79c906ac845fa"},
+	{"0000000000000000000000000000000000000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		"bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8"},
+	{"9e885d952ad362caeb4efe34a8e91bd2",
+		"ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+		"274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028"},
+	{"f585c11aec520db57dd353c69554b21a89b20fb0650966fa0a9d6f74fd989d8f",
+		"void come effort suffer camp survey warrior heavy shoot primary clutch crush open amazing screen patrol group space point ten exist slush involve unfold",
+		"01f5bced59dec48e362f2c45b5de68b9fd6c92c6634f44d6d40aab69056506f0e35524a518034ddc1192e1dacd32c1ed3eaa3c3b131c88ed8e7e54c49a5d0998"},
+}
+
+func TestBIP39Vectors(t *testing.T) {
+	for _, v := range trezorVectors {
+		entropy, err := hex.DecodeString(v.entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mnemonic, err := EntropyToMnemonic(entropy)
+		if err != nil || mnemonic != v.mnemonic {
+			t.Errorf("EntropyToMnemonic(%s) = %q, %v, want %q", v.entropy, mnemonic, err, v.mnemonic)
+		}
+		back, err := MnemonicToEntropy(v.mnemonic)
+		if err != nil || !bytes.Equal(back, entropy) {
+			t.Errorf("MnemonicToEntropy(%q) = %x, %v", v.mnemonic, back, err)
+		}
+		seed, err := MnemonicSeed(v.mnemonic, "TREZOR")
+		if err != nil || hex.EncodeToString(seed) != v.seed {
+			t.Errorf("MnemonicSeed(%q) = %x, %v, want %s", v.mnemonic, seed, err, v.seed)
+		}
+	}
+}
+
+func TestMnemonicRejectsBadPhrases(t *testing.T) {
+	good := trezorVectors[1].mnemonic
+	words := strings.Fields(good)
+	swapped := append([]string(nil), words...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+	if err := ValidateMnemonic(strings.Join(swapped, " ")); !errors.Is(err, ErrMnemonicChecksum) {
+		t.Errorf("swapped words: err = %v, want ErrMnemonicChecksum", err)
+	}
+	for name, bad := range map[string]string{
+		"unknown word": strings.Replace(good, "winner", "winnner", 1),
+		"11 words":     strings.Join(words[:11], " "),
+		"13 words":     good + " legal",
+	} {
+		if err := ValidateMnemonic(bad); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	// Case and spacing don't change the phrase.
+	seed, err := MnemonicSeed("  "+strings.ToUpper(good)+"\n", "TREZOR")
+	if err != nil || hex.EncodeToString(seed) != trezorVectors[1].seed {
+		t.Errorf("reformatted phrase: seed = %x, %v", seed, err)
+	}
+}
+
+// TestMasterKeyFromSeedVectors checks the master keys of SLIP-10's first
+// test vector, seed 000102…0f, on both curves.
+func TestMasterKeyFromSeedVectors(t *testing.T) {
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := MasterKeyFromSeed(seed, SchemeECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := s.(ECDSASigner).Key.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(d); got != "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2" {
+		t.Errorf("nist256p1 master key = %s", got)
+	}
+	s, err = MasterKeyFromSeed(seed, SchemeEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(s.(Ed25519Signer).Key.Seed()); got != "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7" {
+		t.Errorf("ed25519 master key = %s", got)
+	}
+}
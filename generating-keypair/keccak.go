@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// keccak256 is the original Keccak-256 that Ethereum uses, which pads
+// differently from the standardized SHA3-256 in crypto/sha3 and so gives
+// different hashes. The keystore format needs it for its MAC.
+func keccak256(data ...[]byte) []byte {
+	const rate = 136
+	var st [25]uint64
+	var buf []byte
+	for _, d := range data {
+		buf = append(buf, d...)
+	}
+	for len(buf) >= rate {
+		keccakAbsorb(&st, buf[:rate])
+		buf = buf[rate:]
+	}
+	last := make([]byte, rate)
+	copy(last, buf)
+	last[len(buf)] ^= 0x01
+	last[rate-1] ^= 0x80
+	keccakAbsorb(&st, last)
+
+	out := make([]byte, 32)
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], st[i])
+	}
+	return out
+}
+
+// keccakAbsorb XORs one rate-sized block into the state and permutes it.
+func keccakAbsorb(st *[25]uint64, block []byte) {
+	for i := 0; i < len(block)/8; i++ {
+		st[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+	}
+	keccakF1600(st)
+}
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRotations and keccakLanes drive the combined rho and pi steps:
+// each lane in turn moves to the next position in keccakLanes, rotated by
+// the matching offset.
+var (
+	keccakRotations = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+	keccakLanes     = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+)
+
+// keccakF1600 is the Keccak-f[1600] permutation.
+func keccakF1600(st *[25]uint64) {
+	var bc [5]uint64
+	for round := 0; round < 24; round++ {
+		// theta
+		for i := 0; i < 5; i++ {
+			bc[i] = st[i] ^ st[i+5] ^ st[i+10] ^ st[i+15] ^ st[i+20]
+		}
+		for i := 0; i < 5; i++ {
+			t := bc[(i+4)%5] ^ bits.RotateLeft64(bc[(i+1)%5], 1)
+			for j := 0; j < 25; j += 5 {
+				st[j+i] ^= t
+			}
+		}
+		// rho and pi
+		t := st[1]
+		for i, j := range keccakLanes {
+			st[j], t = bits.RotateLeft64(t, keccakRotations[i]), st[j]
+		}
+		// chi
+		for j := 0; j < 25; j += 5 {
+			copy(bc[:], st[j:j+5])
+			for i := 0; i < 5; i++ {
+				st[j+i] ^= ^bc[(i+1)%5] & bc[(i+2)%5]
+			}
+		}
+		// iota
+		st[0] ^= keccakRoundConstants[round]
+	}
+}
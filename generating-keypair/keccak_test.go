@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha3"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKeccak256Vectors(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	} {
+		if got := hex.EncodeToString(keccak256([]byte(tc.in))); got != tc.want {
+			t.Errorf("keccak256(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+// TestKeccakSpongeMatchesSHA3 checks the permutation and the absorbing of
+// inputs longer than a block, which the vectors above don't reach, against
+// crypto/sha3: SHA3-256 is the same sponge with 0x06 padding instead of
+// 0x01.
+func TestKeccakSpongeMatchesSHA3(t *testing.T) {
+	const rate = 136
+	sha3Sponge := func(msg []byte) []byte {
+		var st [25]uint64
+		for len(msg) >= rate {
+			keccakAbsorb(&st, msg[:rate])
+			msg = msg[rate:]
+		}
+		last := make([]byte, rate)
+		copy(last, msg)
+		last[len(msg)] ^= 0x06
+		last[rate-1] ^= 0x80
+		keccakAbsorb(&st, last)
+		out := make([]byte, 32)
+		for i := 0; i < 4; i++ {
+			binary.LittleEndian.PutUint64(out[i*8:], st[i])
+		}
+		return out
+	}
+	msg := make([]byte, 3*rate+1)
+	for i := range msg {
+		msg[i] = byte(i * 7)
+	}
+	for n := 0; n <= len(msg); n++ {
+		want := sha3.Sum256(msg[:n])
+		if got := sha3Sponge(msg[:n]); !bytes.Equal(got, want[:]) {
+			t.Fatalf("%d bytes: %x, want %x", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrKeystorePassphrase is returned when a keystore file's MAC doesn't
+// match, which almost always means the passphrase is wrong.
+var ErrKeystorePassphrase = errors.New("could not decrypt key with given passphrase")
+
+// ErrKeystoreAddress is returned when a keystore file decrypts to a key
+// whose address isn't the one the file names, such as a secp256k1 key from
+// geth read as P-256.
+var ErrKeystoreAddress = errors.New("decrypted key does not match keystore address")
+
+// Scrypt costs for new keystore files, as geth names them. The standard
+// cost takes 256 MB and about a second; the light one is for tests and
+// small devices.
+const (
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+	LightScryptN    = 1 << 12
+	LightScryptP    = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+)
+
+// KeystoreFile is one key in the JSON layout of the Web3 Secret Storage
+// format (version 3): the private key encrypted with AES-128-CTR under a
+// key derived from the passphrase with scrypt, or PBKDF2 in older files,
+// and a Keccak-256 MAC to detect a wrong passphrase. Only the layout is
+// shared with Ethereum wallets. The key inside is a P-256 scalar named by
+// its SHA-256-derived address, not a secp256k1 key and Keccak address, so
+// geth refuses these files and DecryptKey refuses geth's.
+type KeystoreFile struct {
+	Address string         `json:"address"` // hex, no 0x
+	Crypto  KeystoreCrypto `json:"crypto"`
+	ID      string         `json:"id"`
+	Version int            `json:"version"`
+}
+
+// KeystoreCrypto is the encrypted key and how to decrypt it.
+type KeystoreCrypto struct {
+	Cipher       string               `json:"cipher"`
+	CipherText   string               `json:"ciphertext"`
+	CipherParams KeystoreCipherParams `json:"cipherparams"`
+	KDF          string               `json:"kdf"`
+	KDFParams    KeystoreKDFParams    `json:"kdfparams"`
+	MAC          string               `json:"mac"`
+}
+
+// KeystoreCipherParams holds the AES-CTR initial counter.
+type KeystoreCipherParams struct {
+	IV string `json:"iv"`
+}
+
+// KeystoreKDFParams holds the parameters of either key derivation: N, R,
+// and P for scrypt, C and PRF for PBKDF2.
+type KeystoreKDFParams struct {
+	DKLen int    `json:"dklen"`
+	N     int    `json:"n,omitempty"`
+	R     int    `json:"r,omitempty"`
+	P     int    `json:"p,omitempty"`
+	C     int    `json:"c,omitempty"`
+	PRF   string `json:"prf,omitempty"`
+	Salt  string `json:"salt"`
+}
+
+// EncryptKey seals priv under passphrase with scrypt cost N and
+// parallelism p.
+func EncryptKey(priv *ecdsa.PrivateKey, passphrase string, N, p int) (*KeystoreFile, error) {
+	secret, err := priv.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	addr, err := AddressFromPubKey(&priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	id := make([]byte, 16)
+	for _, b := range [][]byte{salt, iv, id} {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+	}
+	derived, err := scryptKey([]byte(passphrase), salt, N, scryptR, p, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := aesCTR(derived[:16], iv, secret)
+	if err != nil {
+		return nil, err
+	}
+	id[6] = id[6]&0x0f | 0x40 // random UUID, version 4
+	id[8] = id[8]&0x3f | 0x80
+	return &KeystoreFile{
+		Address: strings.TrimPrefix(addr, "0x"),
+		Crypto: KeystoreCrypto{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(ciphertext),
+			CipherParams: KeystoreCipherParams{IV: hex.EncodeToString(iv)},
+			KDF:          "scrypt",
+			KDFParams:    KeystoreKDFParams{DKLen: scryptDKLen, N: N, R: scryptR, P: p, Salt: hex.EncodeToString(salt)},
+			MAC:          hex.EncodeToString(keccak256(derived[16:32], ciphertext)),
+		},
+		ID:      fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		Version: 3,
+	}, nil
+}
+
+// DecryptKey opens f with passphrase. The key is read as a P-256 scalar,
+// the curve the rest of these demos use, and its address must be the one
+// f names, if it names one; a key for another curve decrypts fine but
+// fails that check with ErrKeystoreAddress.
+func DecryptKey(f *KeystoreFile, passphrase string) (*ecdsa.PrivateKey, error) {
+	if f.Version != 3 {
+		return nil, fmt.Errorf("unsupported keystore version %d", f.Version)
+	}
+	c := f.Crypto
+	if c.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported keystore cipher %q", c.Cipher)
+	}
+	var raw [4][]byte // ciphertext, iv, mac, salt
+	for i, s := range []string{c.CipherText, c.CipherParams.IV, c.MAC, c.KDFParams.Salt} {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("keystore: %w", err)
+		}
+		raw[i] = b
+	}
+	ciphertext, iv, mac, salt := raw[0], raw[1], raw[2], raw[3]
+
+	kp := c.KDFParams
+	if kp.DKLen < 32 {
+		return nil, fmt.Errorf("keystore: derived key length %d is below 32", kp.DKLen)
+	}
+	var derived []byte
+	var err error
+	switch c.KDF {
+	case "scrypt":
+		derived, err = scryptKey([]byte(passphrase), salt, kp.N, kp.R, kp.P, kp.DKLen)
+	case "pbkdf2":
+		if kp.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported keystore PRF %q", kp.PRF)
+		}
+		derived, err = pbkdf2.Key(sha256.New, passphrase, salt, kp.C, kp.DKLen)
+	default:
+		return nil, fmt.Errorf("unsupported keystore key derivation %q", c.KDF)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(keccak256(derived[16:32], ciphertext), mac) != 1 {
+		return nil, ErrKeystorePassphrase
+	}
+	secret, err := aesCTR(derived[:16], iv, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), secret)
+	if err != nil {
+		return nil, err
+	}
+	if f.Address != "" {
+		addr, err := AddressFromPubKey(&priv.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(strings.TrimPrefix(addr, "0x"), strings.TrimPrefix(f.Address, "0x")) {
+			return nil, fmt.Errorf("%w: file names %s, key is %s", ErrKeystoreAddress, f.Address, addr)
+		}
+	}
+	return priv, nil
+}
+
+// aesCTR encrypts or decrypts data with AES in counter mode.
+func aesCTR(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("keystore: invalid IV length")
+	}
+	out := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(out, data)
+	return out, nil
+}
+
+// Keystore is a directory of keystore files, one key each, with geth's
+// UTC--<time>--<address> file names. The names are the only thing the two
+// directories have in common; see KeystoreFile.
+type Keystore struct {
+	Dir     string
+	ScryptN int
+	ScryptP int
+}
+
+// KeystoreAccount is one key in a Keystore.
+type KeystoreAccount struct {
+	Address string
+	Path    string
+}
+
+// NewKeystore returns the keystore in dir, writing new files at the
+// standard scrypt cost.
+func NewKeystore(dir string) *Keystore {
+	return &Keystore{Dir: dir, ScryptN: StandardScryptN, ScryptP: StandardScryptP}
+}
+
+// Create generates a new key and stores it under passphrase.
+func (ks *Keystore) Create(passphrase string) (KeystoreAccount, error) {
+	priv, _ := generateKeys()
+	return ks.Import(priv, passphrase)
+}
+
+// Import stores priv under passphrase.
+func (ks *Keystore) Import(priv *ecdsa.PrivateKey, passphrase string) (KeystoreAccount, error) {
+	f, err := EncryptKey(priv, passphrase, ks.ScryptN, ks.ScryptP)
+	if err != nil {
+		return KeystoreAccount{}, err
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return KeystoreAccount{}, err
+	}
+	if err := os.MkdirAll(ks.Dir, 0o700); err != nil {
+		return KeystoreAccount{}, err
+	}
+	name := fmt.Sprintf("UTC--%s--%s", time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), f.Address)
+	path := filepath.Join(ks.Dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return KeystoreAccount{}, err
+	}
+	return KeystoreAccount{Address: f.Address, Path: path}, nil
+}
+
+// List returns the accounts in the keystore, oldest first by file name.
+// Files that aren't keystore files are skipped.
+func (ks *Keystore) List() ([]KeystoreAccount, error) {
+	entries, err := os.ReadDir(ks.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []KeystoreAccount
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(ks.Dir, e.Name())
+		f, err := readKeystoreFile(path)
+		if err != nil || f.Address == "" {
+			continue
+		}
+		accounts = append(accounts, KeystoreAccount{Address: f.Address, Path: path})
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Path < accounts[j].Path })
+	return accounts, nil
+}
+
+// Unlock decrypts the key for address, with or without 0x, in any case.
+func (ks *Keystore) Unlock(address, passphrase string) (*ecdsa.PrivateKey, error) {
+	accounts, err := ks.List()
+	if err != nil {
+		return nil, err
+	}
+	want := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	for _, a := range accounts {
+		if strings.ToLower(a.Address) != want {
+			continue
+		}
+		f, err := readKeystoreFile(a.Path)
+		if err != nil {
+			return nil, err
+		}
+		return DecryptKey(f, passphrase)
+	}
+	return nil, fmt.Errorf("no key for %s in %s", address, ks.Dir)
+}
+
+func readKeystoreFile(path string) (*KeystoreFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f KeystoreFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
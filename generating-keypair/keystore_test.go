@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// The Web3 Secret Storage test vectors: one secp256k1 key, encrypted with
+// PBKDF2 and with scrypt under the passphrase "testpassword".
+const (
+	web3Key      = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+	web3Address  = "008aeeda4d805471df9b2a5b0f38a0c3bcba786b"
+	web3PBKDF2   = `{"address":"008aeeda4d805471df9b2a5b0f38a0c3bcba786b","crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"6087dab2f9fdbbfaddc31a909735c1e6"},"ciphertext":"5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46","kdf":"pbkdf2","kdfparams":{"c":262144,"dklen":32,"prf":"hmac-sha256","salt":"ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},"mac":"517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+	web3Scrypt   = `{"address":"008aeeda4d805471df9b2a5b0f38a0c3bcba786b","crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"83dbcc02d8ccb40e466191a123791e0e"},"ciphertext":"d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c","kdf":"scrypt","kdfparams":{"dklen":32,"n":262144,"p":8,"r":1,"salt":"ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"},"mac":"2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+	web3Password = "testpassword"
+)
+
+func TestDecryptKeyWeb3Vectors(t *testing.T) {
+	for name, file := range map[string]string{"pbkdf2": web3PBKDF2, "scrypt": web3Scrypt} {
+		t.Run(name, func(t *testing.T) {
+			var f KeystoreFile
+			if err := json.Unmarshal([]byte(file), &f); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := DecryptKey(&f, "wrong"); !errors.Is(err, ErrKeystorePassphrase) {
+				t.Errorf("wrong passphrase: err = %v", err)
+			}
+			// The secp256k1 key's address isn't the P-256 one.
+			if _, err := DecryptKey(&f, web3Password); !errors.Is(err, ErrKeystoreAddress) {
+				t.Fatalf("geth key read as P-256: err = %v, want ErrKeystoreAddress", err)
+			}
+			// Without an address to check, the secret still decrypts to the
+			// vector's 32 bytes.
+			f.Address = ""
+			priv, err := DecryptKey(&f, web3Password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			secret, err := priv.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(secret); got != web3Key {
+				t.Errorf("key %s, want %s", got, web3Key)
+			}
+		})
+	}
+}
+
+func TestEncryptDecryptKey(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := EncryptKey(priv, "pass", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecryptKey(f, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(priv) {
+		t.Error("decrypted a different key")
+	}
+	f.Address = web3Address
+	if _, err := DecryptKey(f, "pass"); !errors.Is(err, ErrKeystoreAddress) {
+		t.Errorf("mismatched address: err = %v, want ErrKeystoreAddress", err)
+	}
+}
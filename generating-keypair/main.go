@@ -6,7 +6,12 @@ import (
 	"crypto/rand"
 	"crypto/x509"
 	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 )
 
 func generateKeys() (*ecdsa.PrivateKey, *ecdsa.PublicKey) {
@@ -19,14 +24,326 @@ func generateKeys() (*ecdsa.PrivateKey, *ecdsa.PublicKey) {
 }
 
 func main() {
-	priv, pub := generateKeys()
+	if len(os.Args) > 2 && os.Args[1] == "wallet" {
+		if err := runWallet(os.Args[2], os.Args[3:]); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 2 && os.Args[1] == "keystore" {
+		if err := runKeystore(os.Args[2], os.Args[3:]); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 2 && os.Args[1] == "mnemonic" {
+		if err := runMnemonic(os.Args[2], os.Args[3:]); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Marshal private key to DER bytes
-	privBytes, err := x509.MarshalECPrivateKey(priv)
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	scheme := fs.String("scheme", SchemeECDSA, "signature scheme: ecdsa or ed25519")
+	in := fs.String("in", "", "load the private key from this PEM file instead of generating one")
+	out := fs.String("out", "", "save the private key to this PEM file, and the public key to FILE.pub")
+	format := fs.String("format", "", "private key PEM format: sec1 or pkcs8 (default sec1 for ecdsa, pkcs8 otherwise)")
+	fs.Parse(os.Args[1:])
+
+	if err := runKeygen(*scheme, *in, *out, *format); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// runKeygen generates or loads a key, prints it, and optionally saves it
+// as PEM.
+func runKeygen(scheme, in, out, format string) error {
+	var s Signer
+	var err error
+	if in != "" {
+		s, err = LoadPrivateKeyPEM(in)
+	} else {
+		s, err = GenerateSigner(scheme)
+	}
 	if err != nil {
-		panic(err)
+		return err
 	}
+	return showKey(s, out, format)
+}
 
+// showKey prints s and, if out is set, saves it as PEM in format, or the
+// scheme's usual format if that is empty.
+func showKey(s Signer, out, format string) error {
+	// Marshal private key to DER bytes
+	privBytes, err := s.PrivateBytes()
+	if err != nil {
+		return err
+	}
 	fmt.Println("Private Key (hex):", hex.EncodeToString(privBytes))
-	fmt.Printf("Public Key:\n  X: %x\n  Y: %x\n", pub.X, pub.Y)
+	if k, ok := s.(ECDSASigner); ok {
+		fmt.Printf("Public Key:\n  X: %x\n  Y: %x\n", k.Key.X, k.Key.Y)
+	} else {
+		fmt.Println("Public Key (hex):", hex.EncodeToString(s.Public().Bytes()))
+	}
+	fmt.Println("Address:", VerifierAddress(s.Public()))
+
+	if out == "" {
+		return nil
+	}
+	if format == "" {
+		format = FormatPKCS8
+		if s.Scheme() == SchemeECDSA {
+			format = FormatSEC1
+		}
+	}
+	if err := SavePrivateKeyPEM(out, s, format); err != nil {
+		return err
+	}
+	if err := SavePublicKeyPEM(out+".pub", s.Public()); err != nil {
+		return err
+	}
+	fmt.Printf("Saved %s (%s) and %s.pub\n", out, format, out)
+	return nil
+}
+
+// runWallet implements "wallet backup" and "wallet restore". A backup
+// collects the user's existing keys from any of a PEM file, a keystore
+// directory, and a mnemonic, along with contacts and labels given as
+// flags, into one file encrypted under WALLET_PASSPHRASE. Keystore files
+// are unlocked with KEYSTORE_PASSPHRASE, or WALLET_PASSPHRASE if it is
+// unset, and a mnemonic's own passphrase comes from MNEMONIC_PASSPHRASE.
+func runWallet(cmd string, args []string) error {
+	fs := flag.NewFlagSet("wallet "+cmd, flag.ContinueOnError)
+	file := fs.String("file", "wallet-backup.json", "backup file")
+	name := fs.String("name", "default", "account name for the -pem or -mnemonic key (backup only)")
+	pemPath := fs.String("pem", "", "back up the ECDSA key in this PEM file")
+	keystoreDir := fs.String("keystore", "", "back up every key in this keystore directory")
+	mnemonic := fs.String("mnemonic", "", "back up the ECDSA key derived from this phrase, and the phrase")
+	contacts := make(map[string]string)
+	fs.Func("contact", "saved payee as LABEL=ADDRESS (repeatable)", keyValueFlag(contacts))
+	labels := make(map[string]string)
+	fs.Func("label", "note as ADDRESS_OR_TX=TEXT (repeatable)", keyValueFlag(labels))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	passphrase := os.Getenv("WALLET_PASSPHRASE")
+	if passphrase == "" {
+		return errors.New("set WALLET_PASSPHRASE to the backup passphrase")
+	}
+
+	switch cmd {
+	case "backup":
+		w := Wallet{Labels: labels}
+		if *mnemonic != "" {
+			s, err := MnemonicSigner(*mnemonic, os.Getenv("MNEMONIC_PASSPHRASE"), SchemeECDSA)
+			if err != nil {
+				return err
+			}
+			w.Mnemonic = *mnemonic
+			w.Keys = append(w.Keys, NamedKey{Name: *name, Key: s.(ECDSASigner).Key})
+		}
+		if *pemPath != "" {
+			s, err := LoadPrivateKeyPEM(*pemPath)
+			if err != nil {
+				return err
+			}
+			k, ok := s.(ECDSASigner)
+			if !ok {
+				return fmt.Errorf("%s: backups hold ECDSA keys, not %s", *pemPath, s.Scheme())
+			}
+			w.Keys = append(w.Keys, NamedKey{Name: *name, Key: k.Key})
+		}
+		if *keystoreDir != "" {
+			ksPassphrase := os.Getenv("KEYSTORE_PASSPHRASE")
+			if ksPassphrase == "" {
+				ksPassphrase = passphrase
+			}
+			ks := NewKeystore(*keystoreDir)
+			accounts, err := ks.List()
+			if err != nil {
+				return err
+			}
+			for _, a := range accounts {
+				priv, err := ks.Unlock(a.Address, ksPassphrase)
+				if err != nil {
+					return fmt.Errorf("%s: %w", a.Path, err)
+				}
+				w.Keys = append(w.Keys, NamedKey{Name: "0x" + a.Address, Key: priv})
+			}
+		}
+		if len(w.Keys) == 0 {
+			return errors.New("pass the keys to back up with -pem, -keystore, or -mnemonic")
+		}
+		for label, addr := range contacts {
+			w.AddressBook = append(w.AddressBook, Contact{Label: label, Address: addr})
+		}
+		sort.Slice(w.AddressBook, func(i, j int) bool { return w.AddressBook[i].Label < w.AddressBook[j].Label })
+
+		b, err := NewBackup(w, passphrase)
+		if err != nil {
+			return err
+		}
+		if err := SaveBackup(*file, b); err != nil {
+			return err
+		}
+		fmt.Printf("Backed up %d keys, %d contacts, and %d labels to %s (format v%d)\n", len(b.Accounts), len(b.AddressBook), len(b.Labels), *file, b.Version)
+		return nil
+
+	case "restore":
+		b, err := LoadBackup(*file)
+		if err != nil {
+			return err
+		}
+		w, err := b.Open(passphrase)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Backup v%d created %s\n", b.Version, b.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+		if w.Mnemonic != "" {
+			fmt.Println("Mnemonic:", w.Mnemonic)
+		}
+		for i, k := range w.Keys {
+			privBytes, err := x509.MarshalECPrivateKey(k.Key)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Account %q: %s\n", k.Name, b.Accounts[i].Address)
+			fmt.Println("  Private Key (hex):", hex.EncodeToString(privBytes))
+		}
+		for _, c := range w.AddressBook {
+			fmt.Printf("Contact %q: %s\n", c.Label, c.Address)
+		}
+		keys := make([]string, 0, len(w.Labels))
+		for k := range w.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("Label %s: %s\n", k, w.Labels[k])
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown wallet command %q (want backup or restore)", cmd)
+	}
+}
+
+// keyValueFlag parses a repeatable KEY=VALUE flag into m.
+func keyValueFlag(m map[string]string) func(string) error {
+	return func(s string) error {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("%q is not KEY=VALUE", s)
+		}
+		m[k] = v
+		return nil
+	}
+}
+
+// runKeystore implements "keystore create", "keystore list", and
+// "keystore unlock".
+func runKeystore(cmd string, args []string) error {
+	fs := flag.NewFlagSet("keystore "+cmd, flag.ContinueOnError)
+	dir := fs.String("dir", "keystore", "keystore directory")
+	address := fs.String("address", "", "account to unlock")
+	light := fs.Bool("light", false, "use the light scrypt cost for new keys")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ks := NewKeystore(*dir)
+	if *light {
+		ks.ScryptN, ks.ScryptP = LightScryptN, LightScryptP
+	}
+
+	switch cmd {
+	case "create":
+		passphrase := os.Getenv("WALLET_PASSPHRASE")
+		if passphrase == "" {
+			return errors.New("set WALLET_PASSPHRASE to the key's passphrase")
+		}
+		a, err := ks.Create(passphrase)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("New key %s stored in %s\n", a.Address, a.Path)
+		return nil
+
+	case "list":
+		accounts, err := ks.List()
+		if err != nil {
+			return err
+		}
+		for _, a := range accounts {
+			fmt.Printf("%s  %s\n", a.Address, a.Path)
+		}
+		return nil
+
+	case "unlock":
+		passphrase := os.Getenv("WALLET_PASSPHRASE")
+		if passphrase == "" {
+			return errors.New("set WALLET_PASSPHRASE to the key's passphrase")
+		}
+		priv, err := ks.Unlock(*address, passphrase)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Unlocked %s\n", *address)
+		fmt.Printf("Public Key:\n  X: %x\n  Y: %x\n", priv.X, priv.Y)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown keystore command %q (want create, list, or unlock)", cmd)
+	}
+}
+
+// runMnemonic implements "mnemonic new" and "mnemonic recover". The
+// optional BIP-39 passphrase comes from WALLET_PASSPHRASE; without it the
+// passphrase is empty, as most wallets default to.
+func runMnemonic(cmd string, args []string) error {
+	fs := flag.NewFlagSet("mnemonic "+cmd, flag.ContinueOnError)
+	words := fs.Int("words", 12, "phrase length for a new phrase: 12 or 24 words")
+	phrase := fs.String("phrase", "", "phrase to recover (recover only)")
+	scheme := fs.String("scheme", SchemeECDSA, "signature scheme: ecdsa or ed25519")
+	out := fs.String("out", "", "save the private key to this PEM file, and the public key to FILE.pub")
+	format := fs.String("format", "", "private key PEM format: sec1 or pkcs8")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	passphrase := os.Getenv("WALLET_PASSPHRASE")
+
+	var mnemonic string
+	switch cmd {
+	case "new":
+		if *words != 12 && *words != 24 {
+			return fmt.Errorf("-words must be 12 or 24, not %d", *words)
+		}
+		m, err := NewMnemonic(*words / 3 * 32)
+		if err != nil {
+			return err
+		}
+		mnemonic = m
+		fmt.Println("Mnemonic:", mnemonic)
+		fmt.Println("Write these words down in order; anyone who has them has the key.")
+
+	case "recover":
+		if *phrase == "" {
+			return errors.New("pass the phrase to recover with -phrase")
+		}
+		mnemonic = *phrase
+
+	default:
+		return fmt.Errorf("unknown mnemonic command %q (want new or recover)", cmd)
+	}
+
+	s, err := MnemonicSigner(mnemonic, passphrase, *scheme)
+	if err != nil {
+		return err
+	}
+	return showKey(s, *out, *format)
 }
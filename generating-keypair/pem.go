@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Private key encodings EncodePrivateKeyPEM can write.
+const (
+	FormatSEC1  = "sec1"  // "EC PRIVATE KEY", ECDSA only
+	FormatPKCS8 = "pkcs8" // "PRIVATE KEY", any scheme
+)
+
+// PEM block types.
+const (
+	pemECPrivateKey = "EC PRIVATE KEY"
+	pemPrivateKey   = "PRIVATE KEY"
+	pemPublicKey    = "PUBLIC KEY"
+)
+
+// EncodePrivateKeyPEM encodes s's private key as PEM in format, SEC1 or
+// PKCS#8. Ed25519 keys have no SEC1 form.
+func EncodePrivateKeyPEM(s Signer, format string) ([]byte, error) {
+	var block *pem.Block
+	switch format {
+	case FormatSEC1:
+		k, ok := s.(ECDSASigner)
+		if !ok {
+			return nil, fmt.Errorf("%s keys have no SEC1 encoding; use %s", s.Scheme(), FormatPKCS8)
+		}
+		der, err := x509.MarshalECPrivateKey(k.Key)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: pemECPrivateKey, Bytes: der}
+	case FormatPKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(privateKey(s))
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: pemPrivateKey, Bytes: der}
+	default:
+		return nil, fmt.Errorf("unknown private key format %q (want %s or %s)", format, FormatSEC1, FormatPKCS8)
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// EncodePublicKeyPEM encodes v's public key as a PKIX "PUBLIC KEY" block.
+func EncodePublicKeyPEM(v Verifier) ([]byte, error) {
+	var pub any
+	switch v := v.(type) {
+	case ECDSAVerifier:
+		pub = v.Key
+	case Ed25519Verifier:
+		pub = v.Key
+	default:
+		return nil, fmt.Errorf("unsupported public key scheme %s", v.Scheme())
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemPublicKey, Bytes: der}), nil
+}
+
+// privateKey returns the crypto key behind s.
+func privateKey(s Signer) any {
+	switch s := s.(type) {
+	case ECDSASigner:
+		return s.Key
+	case Ed25519Signer:
+		return s.Key
+	}
+	return nil
+}
+
+// DecodePrivateKeyPEM reads the first private key block in data, SEC1 or
+// PKCS#8, as a Signer.
+func DecodePrivateKeyPEM(data []byte) (Signer, error) {
+	block, err := decodePEM(data, pemECPrivateKey, pemPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == pemECPrivateKey {
+		k, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return ECDSASigner{k}, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return ECDSASigner{k}, nil
+	case ed25519.PrivateKey:
+		return Ed25519Signer{k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// DecodePublicKeyPEM reads the first "PUBLIC KEY" block in data as a
+// Verifier.
+func DecodePublicKeyPEM(data []byte) (Verifier, error) {
+	block, err := decodePEM(data, pemPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ECDSAVerifier{k}, nil
+	case ed25519.PublicKey:
+		return Ed25519Verifier{k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// decodePEM returns the first block in data of one of types, skipping any
+// others, such as EC PARAMETERS blocks written by openssl.
+func decodePEM(data []byte, types ...string) (*pem.Block, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no key found in PEM data")
+		}
+		for _, t := range types {
+			if block.Type == t {
+				return block, nil
+			}
+		}
+	}
+}
+
+// SavePrivateKeyPEM writes s's private key to path in format, readable only
+// by the owner.
+func SavePrivateKeyPEM(path string, s Signer, format string) error {
+	data, err := EncodePrivateKeyPEM(s, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// SavePublicKeyPEM writes v's public key to path.
+func SavePublicKeyPEM(path string, v Verifier) error {
+	data, err := EncodePublicKeyPEM(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadPrivateKeyPEM reads a private key written by SavePrivateKeyPEM or
+// by openssl.
+func LoadPrivateKeyPEM(path string) (Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := DecodePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// LoadPublicKeyPEM reads a public key written by SavePublicKeyPEM.
+func LoadPublicKeyPEM(path string) (Verifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	v, err := DecodePublicKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/pem"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrivateKeyPEMRoundTrip(t *testing.T) {
+	for _, tc := range []struct{ scheme, format, blockType string }{
+		{SchemeECDSA, FormatSEC1, pemECPrivateKey},
+		{SchemeECDSA, FormatPKCS8, pemPrivateKey},
+		{SchemeEd25519, FormatPKCS8, pemPrivateKey},
+	} {
+		s, err := GenerateSigner(tc.scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := EncodePrivateKeyPEM(s, tc.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block, _ := pem.Decode(data); block == nil || block.Type != tc.blockType {
+			t.Errorf("%s %s: not a %q block", tc.scheme, tc.format, tc.blockType)
+		}
+		// openssl writes the curve parameters first.
+		params := pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0x06, 0x08}})
+		got, err := DecodePrivateKeyPEM(append(params, data...))
+		if err != nil {
+			t.Fatalf("%s %s: %v", tc.scheme, tc.format, err)
+		}
+		if got.Scheme() != tc.scheme || !bytes.Equal(got.Public().Bytes(), s.Public().Bytes()) {
+			t.Errorf("%s %s: decoded a different key", tc.scheme, tc.format)
+		}
+	}
+
+	ed, err := GenerateSigner(SchemeEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EncodePrivateKeyPEM(ed, FormatSEC1); err == nil {
+		t.Error("encoded an Ed25519 key as SEC1")
+	}
+	if _, err := DecodePrivateKeyPEM([]byte("not PEM")); err == nil {
+		t.Error("decoded a key from nothing")
+	}
+}
+
+func TestPublicKeyPEMFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, scheme := range []string{SchemeECDSA, SchemeEd25519} {
+		s, err := GenerateSigner(scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, scheme+".pub")
+		if err := SavePublicKeyPEM(path, s.Public()); err != nil {
+			t.Fatal(err)
+		}
+		v, err := LoadPublicKeyPEM(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := []byte("round trip")
+		sig, err := s.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Scheme() != scheme || !v.Verify(msg, sig) {
+			t.Errorf("%s: loaded key doesn't verify the signer's signature", scheme)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// scryptKey derives a keyLen-byte key from password and salt with scrypt
+// (RFC 7914). N is the CPU and memory cost, a power of two; r the block
+// size; p the parallelism. Memory use is 128*N*r bytes.
+func scryptKey(password, salt []byte, N, r, p, keyLen int) ([]byte, error) {
+	if N <= 1 || N&(N-1) != 0 {
+		return nil, errors.New("scrypt: N must be a power of two above 1")
+	}
+	if r <= 0 || p <= 0 || uint64(r)*uint64(p) >= 1<<30 || r > (1<<31-1)/128/p || N > (1<<31-1)/128/r {
+		return nil, errors.New("scrypt: parameters are too large")
+	}
+
+	b, err := pbkdf2.Key(sha256.New, string(password), salt, 1, p*128*r)
+	if err != nil {
+		return nil, err
+	}
+	xy := make([]uint32, 64*r)
+	v := make([]uint32, 32*N*r)
+	for i := 0; i < p; i++ {
+		scryptROMix(b[i*128*r:], r, N, v, xy)
+	}
+	return pbkdf2.Key(sha256.New, string(password), b, 1, keyLen)
+}
+
+// scryptROMix mixes one 128*r-byte block of b in place, using v as the
+// N-entry scratch table and xy as two working blocks.
+func scryptROMix(b []byte, r, N int, v, xy []uint32) {
+	var tmp [16]uint32
+	R := 32 * r
+	x, y := xy[:R], xy[R:]
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	for i := 0; i < N; i += 2 {
+		copy(v[i*R:], x)
+		scryptBlockMix(&tmp, x, y, r)
+		copy(v[(i+1)*R:], y)
+		scryptBlockMix(&tmp, y, x, r)
+	}
+	for i := 0; i < N; i += 2 {
+		j := int(x[(2*r-1)*16] & uint32(N-1))
+		for k := range x {
+			x[k] ^= v[j*R+k]
+		}
+		scryptBlockMix(&tmp, x, y, r)
+		j = int(y[(2*r-1)*16] & uint32(N-1))
+		for k := range y {
+			y[k] ^= v[j*R+k]
+		}
+		scryptBlockMix(&tmp, y, x, r)
+	}
+	for i, w := range x {
+		binary.LittleEndian.PutUint32(b[4*i:], w)
+	}
+}
+
+// scryptBlockMix is BlockMix with Salsa20/8: out takes the even 64-byte
+// blocks of the chain first, then the odd ones.
+func scryptBlockMix(tmp *[16]uint32, in, out []uint32, r int) {
+	copy(tmp[:], in[(2*r-1)*16:])
+	for i := 0; i < 2*r; i += 2 {
+		salsa208XOR(tmp, in[i*16:], out[i*8:])
+		salsa208XOR(tmp, in[i*16+16:], out[i*8+r*16:])
+	}
+}
+
+// salsa208XOR sets tmp and out to Salsa20/8 of tmp XOR in.
+func salsa208XOR(tmp *[16]uint32, in, out []uint32) {
+	var w, x [16]uint32
+	for i := range w {
+		w[i] = tmp[i] ^ in[i]
+	}
+	x = w
+	quarter := func(a, b, c, d int) {
+		x[b] ^= bits.RotateLeft32(x[a]+x[d], 7)
+		x[c] ^= bits.RotateLeft32(x[b]+x[a], 9)
+		x[d] ^= bits.RotateLeft32(x[c]+x[b], 13)
+		x[a] ^= bits.RotateLeft32(x[d]+x[c], 18)
+	}
+	for i := 0; i < 8; i += 2 {
+		quarter(0, 4, 8, 12) // columns
+		quarter(5, 9, 13, 1)
+		quarter(10, 14, 2, 6)
+		quarter(15, 3, 7, 11)
+		quarter(0, 1, 2, 3) // rows
+		quarter(5, 6, 7, 4)
+		quarter(10, 11, 8, 9)
+		quarter(15, 12, 13, 14)
+	}
+	for i := range x {
+		out[i] = x[i] + w[i]
+		tmp[i] = out[i]
+	}
+}
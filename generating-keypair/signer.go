@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+)
+
+// Signature schemes GenerateSigner knows.
+const (
+	SchemeECDSA   = "ecdsa"   // ECDSA on P-256 over SHA-256, ASN.1 signatures
+	SchemeEd25519 = "ed25519" // Ed25519, 64-byte signatures
+)
+
+// Signer signs messages with a private key under one signature scheme.
+type Signer interface {
+	Scheme() string
+	Sign(msg []byte) ([]byte, error)
+	Public() Verifier
+	// PrivateBytes is the private key as DER: SEC1 for ECDSA, PKCS#8 for
+	// Ed25519.
+	PrivateBytes() ([]byte, error)
+}
+
+// Verifier checks signatures made by one Signer's key.
+type Verifier interface {
+	Scheme() string
+	Verify(msg, sig []byte) bool
+	// Bytes is the public key: an uncompressed point for ECDSA, 32 bytes
+	// for Ed25519.
+	Bytes() []byte
+}
+
+// GenerateSigner creates a new key for scheme.
+func GenerateSigner(scheme string) (Signer, error) {
+	switch scheme {
+	case SchemeECDSA:
+		priv, _ := generateKeys()
+		return ECDSASigner{priv}, nil
+	case SchemeEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return Ed25519Signer{priv}, nil
+	default:
+		return nil, fmt.Errorf("unknown signature scheme %q (want %s or %s)", scheme, SchemeECDSA, SchemeEd25519)
+	}
+}
+
+// ECDSASigner signs the SHA-256 of a message with a P-256 key. Its nonces
+// are derived as RFC 6979 describes, so signing is deterministic.
+type ECDSASigner struct{ Key *ecdsa.PrivateKey }
+
+func (s ECDSASigner) Scheme() string { return SchemeECDSA }
+
+func (s ECDSASigner) Sign(msg []byte) ([]byte, error) {
+	hash := sha256.Sum256(msg)
+	return s.Key.Sign(nil, hash[:], crypto.SHA256)
+}
+
+func (s ECDSASigner) Public() Verifier { return ECDSAVerifier{&s.Key.PublicKey} }
+
+func (s ECDSASigner) PrivateBytes() ([]byte, error) { return x509.MarshalECPrivateKey(s.Key) }
+
+// ECDSAVerifier checks ECDSASigner signatures.
+type ECDSAVerifier struct{ Key *ecdsa.PublicKey }
+
+func (v ECDSAVerifier) Scheme() string { return SchemeECDSA }
+
+func (v ECDSAVerifier) Verify(msg, sig []byte) bool {
+	hash := sha256.Sum256(msg)
+	return ecdsa.VerifyASN1(v.Key, hash[:], sig)
+}
+
+func (v ECDSAVerifier) Bytes() []byte {
+	b, _ := v.Key.Bytes() // only fails for keys on no standard curve
+	return b
+}
+
+// Ed25519Signer signs messages with an Ed25519 key, which hashes them
+// itself.
+type Ed25519Signer struct{ Key ed25519.PrivateKey }
+
+func (s Ed25519Signer) Scheme() string { return SchemeEd25519 }
+
+func (s Ed25519Signer) Sign(msg []byte) ([]byte, error) { return ed25519.Sign(s.Key, msg), nil }
+
+func (s Ed25519Signer) Public() Verifier {
+	return Ed25519Verifier{s.Key.Public().(ed25519.PublicKey)}
+}
+
+func (s Ed25519Signer) PrivateBytes() ([]byte, error) { return x509.MarshalPKCS8PrivateKey(s.Key) }
+
+// Ed25519Verifier checks Ed25519Signer signatures.
+type Ed25519Verifier struct{ Key ed25519.PublicKey }
+
+func (v Ed25519Verifier) Scheme() string { return SchemeEd25519 }
+
+func (v Ed25519Verifier) Verify(msg, sig []byte) bool { return ed25519.Verify(v.Key, msg, sig) }
+
+func (v Ed25519Verifier) Bytes() []byte { return v.Key }
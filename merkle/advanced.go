@@ -5,9 +5,9 @@ import (
 	"fmt"
 )
 
-func main() {
+func runAdvanced() {
 	fmt.Println("🌳 Advanced Merkle Tree Features")
-	fmt.Println("=================================\n")
+	fmt.Print("=================================\n\n")
 
 	// Create transactions
 	transactions := []*Transaction{
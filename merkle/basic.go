@@ -5,9 +5,9 @@ import (
 	"log"
 )
 
-func main() {
+func runBasic() {
 	fmt.Println("🌳 Merkle Tree - Basic Example")
-	fmt.Println("===============================\n")
+	fmt.Print("===============================\n\n")
 
 	// Create sample transactions
 	transactions := []*Transaction{
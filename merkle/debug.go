@@ -6,9 +6,9 @@ import (
 	"fmt"
 )
 
-func main() {
+func runDebug() {
 	fmt.Println("🔍 Debugging Merkle Tree")
-	fmt.Println("========================\n")
+	fmt.Print("========================\n\n")
 
 	// Create simple 2-tx tree
 	txs := []*Transaction{
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ProofFormatVersion is the first byte of every encoded proof.
+//
+// Encoded proofs are byte-exact and language-neutral so that clients in
+// other languages can verify proofs produced here:
+//
+//	byte 0        version (0x01)
+//	byte 1        depth n, 0..64
+//	then n steps, leaf to root, each 33 bytes:
+//	  byte 0      sibling side: 0x00 = left, 0x01 = right
+//	  bytes 1-32  sibling hash
+//
+// A leaf hash is SHA-256 over the transaction's UTF-8 string form
+// "<id>:<from>-><to>:<amount with two decimals>". Each step hashes
+// SHA-256(left || right), where the running hash goes on the side opposite
+// the sibling. The proof is valid if the final hash equals the root.
+const ProofFormatVersion = 0x01
+
+const (
+	proofHeaderSize = 2
+	proofStepSize   = 1 + sha256.Size
+)
+
+// MarshalBinary encodes the proof in the format described at
+// ProofFormatVersion.
+func (p *MerkleProof) MarshalBinary() ([]byte, error) {
+	if len(p.Hashes) != len(p.Positions) {
+		return nil, errors.New("proof has mismatched hashes and positions")
+	}
+	if len(p.Hashes) > MaxProofDepth {
+		return nil, fmt.Errorf("proof depth %d exceeds %d", len(p.Hashes), MaxProofDepth)
+	}
+	out := make([]byte, 0, proofHeaderSize+len(p.Hashes)*proofStepSize)
+	out = append(out, ProofFormatVersion, byte(len(p.Hashes)))
+	for i, h := range p.Hashes {
+		if len(h) != sha256.Size {
+			return nil, fmt.Errorf("proof step %d: hash is %d bytes, want %d", i, len(h), sha256.Size)
+		}
+		side := byte(0x00)
+		if p.Positions[i] {
+			side = 0x01
+		}
+		out = append(out, side)
+		out = append(out, h...)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary decodes a proof written by MarshalBinary, rejecting
+// anything that isn't exactly in that format.
+func (p *MerkleProof) UnmarshalBinary(data []byte) error {
+	if len(data) < proofHeaderSize {
+		return errors.New("proof too short")
+	}
+	if data[0] != ProofFormatVersion {
+		return fmt.Errorf("unsupported proof version 0x%02x", data[0])
+	}
+	depth := int(data[1])
+	if depth > MaxProofDepth {
+		return fmt.Errorf("proof depth %d exceeds %d", depth, MaxProofDepth)
+	}
+	if want := proofHeaderSize + depth*proofStepSize; len(data) != want {
+		return fmt.Errorf("proof is %d bytes, want %d for depth %d", len(data), want, depth)
+	}
+
+	hashes := make([][]byte, depth)
+	positions := make([]bool, depth)
+	for i := range depth {
+		step := data[proofHeaderSize+i*proofStepSize:]
+		switch step[0] {
+		case 0x00:
+		case 0x01:
+			positions[i] = true
+		default:
+			return fmt.Errorf("proof step %d: bad side byte 0x%02x", i, step[0])
+		}
+		hashes[i] = append([]byte(nil), step[1:proofStepSize]...)
+	}
+	p.Hashes, p.Positions = hashes, positions
+	return nil
+}
+
+// VerifyEncodedProof checks hex-encoded leaf hash, encoded proof, and root.
+func VerifyEncodedProof(leafHex, proofHex, rootHex string) (bool, error) {
+	leaf, err := hex.DecodeString(leafHex)
+	if err != nil {
+		return false, fmt.Errorf("leaf: %w", err)
+	}
+	root, err := hex.DecodeString(rootHex)
+	if err != nil {
+		return false, fmt.Errorf("root: %w", err)
+	}
+	raw, err := hex.DecodeString(proofHex)
+	if err != nil {
+		return false, fmt.Errorf("proof: %w", err)
+	}
+	var proof MerkleProof
+	if err := proof.UnmarshalBinary(raw); err != nil {
+		return false, err
+	}
+	return VerifyProof(leaf, &proof, root), nil
+}
+
+// BlockHeader is what a light client keeps of a block: enough to chain
+// blocks together and to check inclusion proofs against MerkleRoot.
+type BlockHeader struct {
+	Height     uint64
+	Timestamp  int64  // Unix seconds
+	PrevHash   []byte // hash of the previous header; all zeros for the first
+	MerkleRoot []byte // root of the block's transaction tree
+}
+
+// HeaderFormatVersion is the first byte of every encoded header.
+//
+// Encoded headers are byte-exact, like proofs, and always 81 bytes:
+//
+//	byte 0        version (0x01)
+//	bytes 1-8     height, unsigned, big-endian
+//	bytes 9-16    timestamp in Unix seconds, signed, big-endian
+//	bytes 17-48   previous header hash
+//	bytes 49-80   Merkle root
+//
+// A header's hash is SHA-256 over its encoding.
+const HeaderFormatVersion = 0x01
+
+const headerSize = 1 + 8 + 8 + sha256.Size + sha256.Size
+
+// MarshalBinary encodes the header in the format described at
+// HeaderFormatVersion.
+func (h *BlockHeader) MarshalBinary() ([]byte, error) {
+	if len(h.PrevHash) != sha256.Size {
+		return nil, fmt.Errorf("previous hash is %d bytes, want %d", len(h.PrevHash), sha256.Size)
+	}
+	if len(h.MerkleRoot) != sha256.Size {
+		return nil, fmt.Errorf("merkle root is %d bytes, want %d", len(h.MerkleRoot), sha256.Size)
+	}
+	out := make([]byte, 0, headerSize)
+	out = append(out, HeaderFormatVersion)
+	out = binary.BigEndian.AppendUint64(out, h.Height)
+	out = binary.BigEndian.AppendUint64(out, uint64(h.Timestamp))
+	out = append(out, h.PrevHash...)
+	out = append(out, h.MerkleRoot...)
+	return out, nil
+}
+
+// UnmarshalBinary decodes a header written by MarshalBinary, rejecting
+// anything that isn't exactly in that format.
+func (h *BlockHeader) UnmarshalBinary(data []byte) error {
+	if len(data) != headerSize {
+		return fmt.Errorf("header is %d bytes, want %d", len(data), headerSize)
+	}
+	if data[0] != HeaderFormatVersion {
+		return fmt.Errorf("unsupported header version 0x%02x", data[0])
+	}
+	h.Height = binary.BigEndian.Uint64(data[1:9])
+	h.Timestamp = int64(binary.BigEndian.Uint64(data[9:17]))
+	h.PrevHash = append([]byte(nil), data[17:49]...)
+	h.MerkleRoot = append([]byte(nil), data[49:81]...)
+	return nil
+}
+
+// Hash returns SHA-256 of the header's encoding.
+func (h *BlockHeader) Hash() ([]byte, error) {
+	data, err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// VerifyEncodedHeader decodes a hex-encoded header and reports whether it
+// hashes to hashHex.
+func VerifyEncodedHeader(headerHex, hashHex string) (*BlockHeader, bool, error) {
+	raw, err := hex.DecodeString(headerHex)
+	if err != nil {
+		return nil, false, fmt.Errorf("header: %w", err)
+	}
+	want, err := hex.DecodeString(hashHex)
+	if err != nil {
+		return nil, false, fmt.Errorf("hash: %w", err)
+	}
+	var h BlockHeader
+	if err := h.UnmarshalBinary(raw); err != nil {
+		return nil, false, err
+	}
+	got := sha256.Sum256(raw)
+	return &h, bytes.Equal(got[:], want), nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	name, args := "basic", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	var err error
+	switch name {
+	case "basic":
+		runBasic()
+	case "advanced":
+		runAdvanced()
+	case "debug":
+		runDebug()
+	case "verify":
+		err = runVerify(args)
+	case "vectors":
+		err = runVectors(args)
+	default:
+		err = fmt.Errorf("unknown command %q (want basic, advanced, debug, verify, or vectors)", name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
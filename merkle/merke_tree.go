@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -186,26 +187,44 @@ func (mt *MerkleTree) GenerateProof(txIndex int) (*MerkleProof, error) {
 	return proof, nil
 }
 
-// VerifyProof verifies a Merkle proof
+// MaxProofDepth is the deepest proof VerifyProof accepts, enough for a tree
+// of 2^64 leaves.
+const MaxProofDepth = 64
+
+// VerifyProof verifies a Merkle proof. It runs in constant memory whatever
+// the proof depth: each level is hashed out of one fixed 64-byte buffer
+// holding left || right into one fixed 32-byte running hash. Proofs deeper
+// than MaxProofDepth, with mismatched Hashes and Positions, or with hashes
+// that aren't 32 bytes are rejected.
 func VerifyProof(txHash []byte, proof *MerkleProof, rootHash []byte) bool {
-	currentHash := txHash
+	if proof == nil || len(proof.Hashes) != len(proof.Positions) || len(proof.Hashes) > MaxProofDepth {
+		return false
+	}
+	if len(txHash) != sha256.Size {
+		return false
+	}
 
-	for i, siblingHash := range proof.Hashes {
-		var combined []byte
+	var pair [2 * sha256.Size]byte
+	var current [sha256.Size]byte
+	copy(current[:], txHash)
 
+	for i, siblingHash := range proof.Hashes {
+		if len(siblingHash) != sha256.Size {
+			return false
+		}
 		if proof.Positions[i] {
 			// Sibling is on the right
-			combined = append(currentHash, siblingHash...)
+			copy(pair[:sha256.Size], current[:])
+			copy(pair[sha256.Size:], siblingHash)
 		} else {
 			// Sibling is on the left
-			combined = append(siblingHash, currentHash...)
+			copy(pair[:sha256.Size], siblingHash)
+			copy(pair[sha256.Size:], current[:])
 		}
-
-		hash := sha256.Sum256(combined)
-		currentHash = hash[:]
+		current = sha256.Sum256(pair[:])
 	}
 
-	return hex.EncodeToString(currentHash) == hex.EncodeToString(rootHash)
+	return bytes.Equal(current[:], rootHash)
 }
 
 // PrintTree prints the tree structure (for debugging)
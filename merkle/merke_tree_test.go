@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func testTree(t *testing.T, size int) *MerkleTree {
+	t.Helper()
+	txs := make([]*Transaction, size)
+	for i := range txs {
+		txs[i] = &Transaction{ID: fmt.Sprintf("tx%d", i), From: "alice", To: "bob", Amount: float64(i)}
+	}
+	tree, err := NewMerkleTree(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestEveryProofVerifies(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		tree := testTree(t, size)
+		for i, tx := range tree.Transactions {
+			proof, err := tree.GenerateProof(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !VerifyProof(tx.Hash(), proof, tree.Root.Hash) {
+				t.Errorf("size %d, leaf %d: proof rejected", size, i)
+			}
+			other := tree.Transactions[(i+1)%size].Hash()
+			if size > 1 && VerifyProof(other, proof, tree.Root.Hash) {
+				t.Errorf("size %d, leaf %d: proof accepted for another leaf", size, i)
+			}
+		}
+	}
+}
+
+func TestVerifyProofRejectsMalformed(t *testing.T) {
+	tree := testTree(t, 5)
+	leaf := tree.Transactions[2].Hash()
+	root := tree.Root.Hash
+	proof, err := tree.GenerateProof(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := func() *MerkleProof {
+		p := &MerkleProof{Positions: append([]bool(nil), proof.Positions...)}
+		for _, h := range proof.Hashes {
+			p.Hashes = append(p.Hashes, append([]byte(nil), h...))
+		}
+		return p
+	}
+
+	flipped := clone()
+	flipped.Hashes[0][0] ^= 1
+	swapped := clone()
+	swapped.Positions[0] = !swapped.Positions[0]
+	uneven := clone()
+	uneven.Positions = uneven.Positions[1:]
+	short := clone()
+	short.Hashes[0] = short.Hashes[0][:31]
+
+	for name, p := range map[string]*MerkleProof{
+		"nil":            nil,
+		"flipped hash":   flipped,
+		"swapped sides":  swapped,
+		"uneven lengths": uneven,
+		"short hash":     short,
+	} {
+		if VerifyProof(leaf, p, root) {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if VerifyProof(leaf[:31], proof, root) {
+		t.Error("short leaf: accepted")
+	}
+	badRoot := bytes.Clone(root)
+	badRoot[31] ^= 1
+	if VerifyProof(leaf, proof, badRoot) {
+		t.Error("wrong root: accepted")
+	}
+	if VerifyProof(leaf, proof, []byte(tree.GetRootHash())) {
+		t.Error("hex root compared as bytes: accepted")
+	}
+}
+
+// TestVerifyProofDepthLimit folds a leaf up a chain of zero siblings, so
+// the proof is correct at any depth; only the limit tells them apart.
+func TestVerifyProofDepthLimit(t *testing.T) {
+	leaf := sha256.Sum256([]byte("leaf"))
+	proof := &MerkleProof{}
+	root := leaf
+	var zero [sha256.Size]byte
+	for depth := 1; depth <= MaxProofDepth+1; depth++ {
+		proof.Hashes = append(proof.Hashes, zero[:])
+		proof.Positions = append(proof.Positions, true)
+		root = sha256.Sum256(append(root[:], zero[:]...))
+		if got, want := VerifyProof(leaf[:], proof, root[:]), depth <= MaxProofDepth; got != want {
+			t.Errorf("depth %d: VerifyProof = %v, want %v", depth, got, want)
+		}
+	}
+}
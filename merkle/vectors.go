@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// TestVector is one case in the cross-language test-vector pack. A
+// verifier passes a vector when it accepts the proof exactly if Valid is
+// true; Malformed vectors must be rejected while decoding.
+type TestVector struct {
+	Name        string `json:"name"`
+	Transaction string `json:"transaction,omitempty"` // string form the leaf is hashed from
+	Leaf        string `json:"leaf"`                  // hex
+	Proof       string `json:"proof"`                 // hex, see ProofFormatVersion
+	Root        string `json:"root"`                  // hex
+	Valid       bool   `json:"valid"`
+	Malformed   bool   `json:"malformed,omitempty"`
+}
+
+// HeaderVector is one header case in the test-vector pack. A verifier
+// passes it when decoding Encoding gives exactly the listed fields and its
+// SHA-256 matches Hash if and only if Valid is true; Malformed headers must
+// be rejected while decoding.
+type HeaderVector struct {
+	Name       string `json:"name"`
+	Height     uint64 `json:"height"`
+	Timestamp  int64  `json:"timestamp"`
+	PrevHash   string `json:"prevHash"`   // hex
+	MerkleRoot string `json:"merkleRoot"` // hex
+	Encoding   string `json:"encoding"`   // hex, see HeaderFormatVersion
+	Hash       string `json:"hash"`       // hex
+	Valid      bool   `json:"valid"`
+	Malformed  bool   `json:"malformed,omitempty"`
+}
+
+// VectorPack is the file written by "vectors".
+type VectorPack struct {
+	FormatVersion       int            `json:"formatVersion"`
+	HeaderFormatVersion int            `json:"headerFormatVersion"`
+	Vectors             []TestVector   `json:"vectors"`
+	Headers             []HeaderVector `json:"headers"`
+}
+
+// GenerateVectors builds the test-vector pack: every proof from trees of
+// several sizes, a chain of headers committing to those trees' roots, and
+// tampered and malformed cases of both. The output is deterministic.
+func GenerateVectors() (*VectorPack, error) {
+	pack := &VectorPack{FormatVersion: ProofFormatVersion, HeaderFormatVersion: HeaderFormatVersion}
+
+	var sample TestVector
+	var roots [][]byte
+	for _, size := range []int{1, 2, 3, 4, 5, 8} {
+		txs := make([]*Transaction, size)
+		for i := range txs {
+			txs[i] = &Transaction{
+				ID:     fmt.Sprintf("tx%d", i+1),
+				From:   fmt.Sprintf("addr%d", i),
+				To:     fmt.Sprintf("addr%d", i+1),
+				Amount: float64(10*(i+1)) + 0.25,
+			}
+		}
+		tree, err := NewMerkleTree(txs)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, tree.Root.Hash)
+		for i, tx := range txs {
+			proof, err := tree.GenerateProof(i)
+			if err != nil {
+				return nil, err
+			}
+			raw, err := proof.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			v := TestVector{
+				Name:        fmt.Sprintf("tree of %d, leaf %d", size, i),
+				Transaction: tx.String(),
+				Leaf:        hex.EncodeToString(tx.Hash()),
+				Proof:       hex.EncodeToString(raw),
+				Root:        hex.EncodeToString(tree.Root.Hash),
+				Valid:       true,
+			}
+			pack.Vectors = append(pack.Vectors, v)
+			if size == 5 && i == 2 {
+				sample = v
+			}
+		}
+	}
+
+	// Negative cases, all derived from one valid proof of depth 3.
+	raw, _ := hex.DecodeString(sample.Proof)
+	mutate := func(name string, valid, malformed bool, edit func(v *TestVector, raw []byte) []byte) {
+		v := sample
+		v.Name, v.Valid, v.Malformed = name, valid, malformed
+		v.Proof = hex.EncodeToString(edit(&v, append([]byte(nil), raw...)))
+		pack.Vectors = append(pack.Vectors, v)
+	}
+	mutate("tampered leaf", false, false, func(v *TestVector, p []byte) []byte {
+		v.Transaction = ""
+		v.Leaf = flipHex(v.Leaf)
+		return p
+	})
+	mutate("wrong root", false, false, func(v *TestVector, p []byte) []byte {
+		v.Root = flipHex(v.Root)
+		return p
+	})
+	mutate("sibling side flipped", false, false, func(v *TestVector, p []byte) []byte {
+		p[proofHeaderSize] ^= 0x01
+		return p
+	})
+	mutate("sibling hash altered", false, false, func(v *TestVector, p []byte) []byte {
+		p[proofHeaderSize+1] ^= 0x80
+		return p
+	})
+	mutate("last step dropped", false, false, func(v *TestVector, p []byte) []byte {
+		p[1]--
+		return p[:len(p)-proofStepSize]
+	})
+	mutate("truncated", false, true, func(v *TestVector, p []byte) []byte {
+		return p[:len(p)-1]
+	})
+	mutate("trailing byte", false, true, func(v *TestVector, p []byte) []byte {
+		return append(p, 0x00)
+	})
+	mutate("unknown version", false, true, func(v *TestVector, p []byte) []byte {
+		p[0] = 0x02
+		return p
+	})
+	mutate("bad side byte", false, true, func(v *TestVector, p []byte) []byte {
+		p[proofHeaderSize] = 0x02
+		return p
+	})
+	mutate("depth over 64", false, true, func(v *TestVector, p []byte) []byte {
+		deep := []byte{ProofFormatVersion, MaxProofDepth + 1}
+		for range MaxProofDepth + 1 {
+			deep = append(deep, p[proofHeaderSize:proofHeaderSize+proofStepSize]...)
+		}
+		return deep
+	})
+
+	headers, err := headerVectors(roots)
+	if err != nil {
+		return nil, err
+	}
+	pack.Headers = headers
+	return pack, nil
+}
+
+// headerVectors chains one header per root, a block every ten minutes,
+// then adds negative cases derived from the last of them.
+func headerVectors(roots [][]byte) ([]HeaderVector, error) {
+	var vectors []HeaderVector
+	prev := make([]byte, 32)
+	for i, root := range roots {
+		h := BlockHeader{Height: uint64(i), Timestamp: 1_700_000_000 + 600*int64(i), PrevHash: prev, MerkleRoot: root}
+		raw, err := h.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := h.Hash()
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, HeaderVector{
+			Name:       fmt.Sprintf("header %d", i),
+			Height:     h.Height,
+			Timestamp:  h.Timestamp,
+			PrevHash:   hex.EncodeToString(h.PrevHash),
+			MerkleRoot: hex.EncodeToString(h.MerkleRoot),
+			Encoding:   hex.EncodeToString(raw),
+			Hash:       hex.EncodeToString(hash),
+			Valid:      true,
+		})
+		prev = hash
+	}
+
+	sample := vectors[len(vectors)-1]
+	raw, _ := hex.DecodeString(sample.Encoding)
+	mutate := func(name string, malformed bool, edit func(v *HeaderVector, raw []byte) []byte) {
+		v := sample
+		v.Name, v.Valid, v.Malformed = name, false, malformed
+		v.Encoding = hex.EncodeToString(edit(&v, append([]byte(nil), raw...)))
+		vectors = append(vectors, v)
+	}
+	mutate("header with altered root", false, func(v *HeaderVector, p []byte) []byte {
+		p[len(p)-1] ^= 0x01
+		v.MerkleRoot = hex.EncodeToString(p[len(p)-32:])
+		return p
+	})
+	mutate("header with altered timestamp", false, func(v *HeaderVector, p []byte) []byte {
+		p[16]++
+		v.Timestamp++
+		return p
+	})
+	mutate("header truncated", true, func(v *HeaderVector, p []byte) []byte {
+		return p[:len(p)-1]
+	})
+	mutate("header with trailing byte", true, func(v *HeaderVector, p []byte) []byte {
+		return append(p, 0x00)
+	})
+	mutate("header with unknown version", true, func(v *HeaderVector, p []byte) []byte {
+		p[0] = 0x02
+		return p
+	})
+	return vectors, nil
+}
+
+// checkHeaderVector reports whether a verifier agrees with v: decoding
+// Encoding fails exactly if v is malformed, and otherwise gives v's fields
+// and matches Hash exactly if v is valid.
+func checkHeaderVector(v HeaderVector) error {
+	h, ok, err := VerifyEncodedHeader(v.Encoding, v.Hash)
+	if (err != nil) != v.Malformed {
+		return fmt.Errorf("decode error = %v", err)
+	}
+	if err != nil {
+		return nil
+	}
+	if h.Height != v.Height || h.Timestamp != v.Timestamp ||
+		hex.EncodeToString(h.PrevHash) != v.PrevHash || hex.EncodeToString(h.MerkleRoot) != v.MerkleRoot {
+		return fmt.Errorf("decoded %d %d %x %x", h.Height, h.Timestamp, h.PrevHash, h.MerkleRoot)
+	}
+	if ok != v.Valid {
+		return fmt.Errorf("hash matches = %v", ok)
+	}
+	return nil
+}
+
+// flipHex flips the lowest bit of a hex-encoded value.
+func flipHex(s string) string {
+	b, _ := hex.DecodeString(s)
+	b[len(b)-1] ^= 0x01
+	return hex.EncodeToString(b)
+}
+
+// runVectors implements the "vectors" command.
+func runVectors(args []string) error {
+	fs := flag.NewFlagSet("vectors", flag.ContinueOnError)
+	out := fs.String("out", "testdata/proof-vectors.json", "file to write the vector pack to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	pack, err := GenerateVectors()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) // keep "->" in transactions readable
+	if err := enc.Encode(pack); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d proof and %d header vectors to %s\n", len(pack.Vectors), len(pack.Headers), *out)
+	return nil
+}
+
+// runVerify implements the "verify" command. It checks either a single
+// proof given on the command line or every vector in a pack.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	leaf := fs.String("leaf", "", "leaf hash, hex")
+	proof := fs.String("proof", "", "encoded proof, hex")
+	root := fs.String("root", "", "root hash, hex")
+	vectors := fs.String("vectors", "", "vector pack to check instead of a single proof")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *vectors == "" {
+		ok, err := VerifyEncodedProof(*leaf, *proof, *root)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("proof is INVALID")
+		}
+		fmt.Println("proof is valid")
+		return nil
+	}
+
+	data, err := os.ReadFile(*vectors)
+	if err != nil {
+		return err
+	}
+	var pack VectorPack
+	if err := json.Unmarshal(data, &pack); err != nil {
+		return err
+	}
+	if pack.FormatVersion != ProofFormatVersion {
+		return fmt.Errorf("pack is format version %d, this verifier reads %d", pack.FormatVersion, ProofFormatVersion)
+	}
+	if pack.HeaderFormatVersion != HeaderFormatVersion {
+		return fmt.Errorf("pack is header format version %d, this verifier reads %d", pack.HeaderFormatVersion, HeaderFormatVersion)
+	}
+	failed := 0
+	for _, v := range pack.Vectors {
+		ok, err := VerifyEncodedProof(v.Leaf, v.Proof, v.Root)
+		if ok != v.Valid || (err != nil) != v.Malformed {
+			failed++
+			fmt.Printf("FAIL %s: got valid=%v err=%v\n", v.Name, ok, err)
+		}
+	}
+	for _, v := range pack.Headers {
+		if err := checkHeaderVector(v); err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", v.Name, err)
+		}
+	}
+	total := len(pack.Vectors) + len(pack.Headers)
+	fmt.Printf("%d/%d vectors passed\n", total-failed, total)
+	if failed > 0 {
+		return fmt.Errorf("%d vectors failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestVectorPack runs every vector in testdata the way "verify -vectors"
+// does.
+func TestVectorPack(t *testing.T) {
+	data, err := os.ReadFile("testdata/proof-vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pack VectorPack
+	if err := json.Unmarshal(data, &pack); err != nil {
+		t.Fatal(err)
+	}
+	if pack.FormatVersion != ProofFormatVersion {
+		t.Fatalf("pack is format version %d, want %d", pack.FormatVersion, ProofFormatVersion)
+	}
+	var valid, malformed int
+	for _, v := range pack.Vectors {
+		ok, err := VerifyEncodedProof(v.Leaf, v.Proof, v.Root)
+		if ok != v.Valid || (err != nil) != v.Malformed {
+			t.Errorf("%s: valid = %v, err = %v", v.Name, ok, err)
+		}
+		if v.Valid {
+			valid++
+		}
+		if v.Malformed {
+			malformed++
+		}
+	}
+	if valid == 0 || malformed == 0 {
+		t.Errorf("pack has %d valid and %d malformed vectors, want some of each", valid, malformed)
+	}
+
+	if pack.HeaderFormatVersion != HeaderFormatVersion {
+		t.Fatalf("pack is header format version %d, want %d", pack.HeaderFormatVersion, HeaderFormatVersion)
+	}
+	valid, malformed = 0, 0
+	roots := make(map[string]bool)
+	for _, v := range pack.Vectors {
+		if v.Valid {
+			roots[v.Root] = true
+		}
+	}
+	prev := strings.Repeat("00", 32)
+	for _, v := range pack.Headers {
+		if err := checkHeaderVector(v); err != nil {
+			t.Errorf("%s: %v", v.Name, err)
+		}
+		if v.Malformed {
+			malformed++
+		}
+		if !v.Valid {
+			continue
+		}
+		// Valid headers form a chain over the proof vectors' roots.
+		valid++
+		if v.PrevHash != prev {
+			t.Errorf("%s: previous hash %s, want %s", v.Name, v.PrevHash, prev)
+		}
+		if !roots[v.MerkleRoot] {
+			t.Errorf("%s: root %s is in no proof vector", v.Name, v.MerkleRoot)
+		}
+		prev = v.Hash
+	}
+	if valid == 0 || malformed == 0 {
+		t.Errorf("pack has %d valid and %d malformed headers, want some of each", valid, malformed)
+	}
+}
+
+// TestHeaderEncoding pins one header's bytes and hash, computed outside
+// Go, and checks that anything but the exact format is refused.
+func TestHeaderEncoding(t *testing.T) {
+	prev, _ := hex.DecodeString("ae3a975a8558b312a9a8ffe61eab730ccbbd36f892619934acb9d8297e287bfd")
+	root, _ := hex.DecodeString("f22867460a6308b52fc4468bc2633eece5cfc2d26832784c3254933560f341e0")
+	h := BlockHeader{Height: 1, Timestamp: 1_700_000_600, PrevHash: prev, MerkleRoot: root}
+	raw, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "01" + "0000000000000001" + "000000006553f358" +
+		"ae3a975a8558b312a9a8ffe61eab730ccbbd36f892619934acb9d8297e287bfd" +
+		"f22867460a6308b52fc4468bc2633eece5cfc2d26832784c3254933560f341e0"
+	if got := hex.EncodeToString(raw); got != want {
+		t.Errorf("encoding = %s, want %s", got, want)
+	}
+	if hash, _ := h.Hash(); hex.EncodeToString(hash) != "f884037dc227c8114aca72c3c11fee74bc679348c8b4c0cf4ca4dc3800e96e15" {
+		t.Errorf("hash = %x", hash)
+	}
+
+	var back BlockHeader
+	if err := back.UnmarshalBinary(raw); err != nil {
+		t.Fatal(err)
+	}
+	if back.Height != h.Height || back.Timestamp != h.Timestamp || !bytes.Equal(back.PrevHash, prev) || !bytes.Equal(back.MerkleRoot, root) {
+		t.Errorf("round trip gave %+v", back)
+	}
+	// Timestamps before 1970 survive the unsigned field.
+	h.Timestamp = -1
+	raw, _ = h.MarshalBinary()
+	if err := back.UnmarshalBinary(raw); err != nil || back.Timestamp != -1 {
+		t.Errorf("negative timestamp decoded as %d, %v", back.Timestamp, err)
+	}
+
+	for name, bad := range map[string]BlockHeader{
+		"short previous hash": {PrevHash: prev[:31], MerkleRoot: root},
+		"long merkle root":    {PrevHash: prev, MerkleRoot: append(root, 0)},
+		"missing hashes":      {},
+	} {
+		if _, err := bad.MarshalBinary(); err == nil {
+			t.Errorf("%s: encoded", name)
+		}
+	}
+}
+
+// TestGenerateVectorsMatchesTestdata checks that the encoding hasn't
+// drifted from the published pack: regenerating it must give the same
+// file, byte for byte.
+func TestGenerateVectorsMatchesTestdata(t *testing.T) {
+	want, err := os.ReadFile("testdata/proof-vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack, err := GenerateVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	enc := json.NewEncoder(&got)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(pack); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Error("GenerateVectors differs from testdata/proof-vectors.json; run \"go run . vectors\" only if the change is intended")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// AddressLength is the size of an address in bytes.
+const AddressLength = 20
+
+// AddressFromPubKey derives the address controlled by pub the way
+// block-txn-concept does, so a key made here can hold funds there: 0x and
+// the last 20 bytes of the SHA-256 of its uncompressed encoding, as
+// Ethereum takes them from Keccak-256.
+func AddressFromPubKey(pub *ecdsa.PublicKey) (string, error) {
+	raw, err := pub.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return addressOf(raw), nil
+}
+
+// VerifierAddress derives the address for a public key of any scheme the
+// same way, from v.Bytes(). For ECDSA it is AddressFromPubKey's.
+func VerifierAddress(v Verifier) string {
+	return addressOf(v.Bytes())
+}
+
+func addressOf(pub []byte) string {
+	sum := sha256.Sum256(pub)
+	return "0x" + hex.EncodeToString(sum[len(sum)-AddressLength:])
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"testing"
+)
+
+// TestAddressFromPubKeyVector pins the address of RFC 6979's P-256 key,
+// A.2.5, to the one block-txn-concept derives for it, so funds sent there
+// from a key made here can be spent.
+func TestAddressFromPubKeyVector(t *testing.T) {
+	d, err := hex.DecodeString("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "0x131ce83c1160fa33c087ab15b863574d31d8ff3c"
+	if got, err := AddressFromPubKey(&key.PublicKey); err != nil || got != want {
+		t.Errorf("AddressFromPubKey = %s, %v, want %s", got, err, want)
+	}
+	if got := VerifierAddress(ECDSAVerifier{&key.PublicKey}); got != want {
+		t.Errorf("VerifierAddress = %s, want %s", got, want)
+	}
+}
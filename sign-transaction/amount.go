@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Amount is a quantity of coins in minor units, as block-txn-concept's
+// ledger counts them: 1 coin is 10^AmountDecimals units. Signing the same
+// integer the ledger stores is what lets a signature made here verify
+// there.
+type Amount int64
+
+// AmountDecimals is the number of decimal places an Amount holds.
+const AmountDecimals = 8
+
+// Coin is one whole coin.
+const Coin Amount = 100_000_000
+
+// decimal writes a with exactly places decimals, rounding half away from
+// zero.
+func (a Amount) decimal(places int) string {
+	neg := a < 0
+	u := new(big.Int).Abs(big.NewInt(int64(a)))
+	if places < AmountDecimals {
+		unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(AmountDecimals-places)), nil)
+		u.Add(u, new(big.Int).Quo(unit, big.NewInt(2)))
+		u.Quo(u, unit)
+	} else if places > AmountDecimals {
+		u.Mul(u, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places-AmountDecimals)), nil))
+	}
+	digits := u.String()
+	if places > 0 {
+		if len(digits) <= places {
+			digits = strings.Repeat("0", places-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+	}
+	if neg && strings.Trim(digits, "0.") != "" {
+		digits = "-" + digits
+	}
+	return digits
+}
+
+// String returns a as a decimal with no trailing zeros, e.g. "4.5" or "25".
+func (a Amount) String() string {
+	s := strings.TrimRight(a.decimal(AmountDecimals), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// hashString is how the payload writes a, as the ledger does: with six
+// decimals when the last two of the eight are zero, and all eight
+// otherwise.
+func (a Amount) hashString() string {
+	if a%100 == 0 {
+		return a.decimal(6)
+	}
+	return a.decimal(AmountDecimals)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// payloadVectors are transactions with their expected payload and SHA-256,
+// which any implementation of the encoding must reproduce.
+var payloadVectors = []struct {
+	tx      Transaction
+	payload string
+	hash    string
+}{
+	{Transaction{From: "ab", To: "c", Amount: 42 * Coin},
+		"v1:1i1:0f2:abt1:cT20:0001-01-01T00:00:00Zd0:a9:42.000000F8:0.000000y0:C0:K0:n1:0L1:0c0:G1:0s0:A0:e0:",
+		"a2872fd91f8e7d2c3b24de507a2276c885d2508a69a93cfd7a9ffe5b62dcbe64"},
+	{Transaction{From: "a", To: "bc", Amount: 42 * Coin},
+		"v1:1i1:0f1:at2:bcT20:0001-01-01T00:00:00Zd0:a9:42.000000F8:0.000000y0:C0:K0:n1:0L1:0c0:G1:0s0:A0:e0:",
+		"147ec36a066f063f370e3a5d25e9401957d22120ba93e8d5bec890a9491f5556"},
+	{Transaction{From: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", To: "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359", Amount: Coin / 10},
+		"v1:1i1:0f42:0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaedt42:0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359T20:0001-01-01T00:00:00Zd0:a8:0.100000F8:0.000000y0:C0:K0:n1:0L1:0c0:G1:0s0:A0:e0:",
+		"49eefffda90c744dca25f680b196be50995d48afe34a11c8361cf3c824909fe8"},
+	{Transaction{From: "alice", To: "bob", Amount: 1},
+		"v1:1i1:0f5:alicet3:bobT20:0001-01-01T00:00:00Zd0:a10:0.00000001F8:0.000000y0:C0:K0:n1:0L1:0c0:G1:0s0:A0:e0:",
+		"6cb2ea1ecdbc8bd929402d027f4ab6da1bfcac70a031894431a657a50c6208d8"},
+	{Transaction{From: "0x131ce83c1160fa33c087ab15b863574d31d8ff3c", To: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Description: "rent", Amount: 4_250_000_000, Fee: 100_000, Type: "debit", ChainID: "test", Nonce: 3},
+		"v1:1i1:0f42:0x131ce83c1160fa33c087ab15b863574d31d8ff3ct42:0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaedT20:2024-01-01T00:00:00Zd4:renta9:42.500000F8:0.001000y5:debitC4:testK0:n1:3L1:0c0:G1:0s0:A0:e0:",
+		"9e27ee445efcbc70c13dd2f767315a8baf2cd70ce2be8730babe3bec0f556184"},
+}
+
+func TestPayloadVectors(t *testing.T) {
+	for i, v := range payloadVectors {
+		if got := string(transactionBytes(v.tx)); got != v.payload {
+			t.Errorf("vector %d: payload %q, want %q", i, got, v.payload)
+		}
+		if got := hex.EncodeToString(hashTransaction(v.tx)); got != v.hash {
+			t.Errorf("vector %d: hash %s, want %s", i, got, v.hash)
+		}
+	}
+}
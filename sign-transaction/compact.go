@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Fixed-length signature sizes: r and s, 32 bytes each and big-endian,
+// and optionally a recovery id v, as Ethereum and most other chains write
+// them on the wire.
+const (
+	CompactSigLength     = 64 // r || s
+	RecoverableSigLength = 65 // r || s || v
+)
+
+// ErrSignatureEncoding is returned for a signature that isn't well formed
+// in the encoding it claims: the wrong length, trailing or non-DER bytes,
+// or an r or s outside 1 to N-1.
+var ErrSignatureEncoding = errors.New("malformed signature")
+
+// ASN1ToCompact converts an ASN.1 DER signature, as ecdsa.SignASN1 writes,
+// to the fixed-length r || s most wire formats use. The DER must be
+// strict: a signature with padding, trailing bytes, or another encoding of
+// the same numbers is refused rather than normalized.
+func ASN1ToCompact(der []byte) ([]byte, error) {
+	var rs struct{ R, S *big.Int }
+	rest, err := asn1.Unmarshal(der, &rs)
+	if err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("%w: not an ASN.1 signature", ErrSignatureEncoding)
+	}
+	if again, err := asn1.Marshal(rs); err != nil || !bytes.Equal(again, der) {
+		return nil, fmt.Errorf("%w: not DER", ErrSignatureEncoding)
+	}
+	if err := checkSigRange(rs.R, rs.S); err != nil {
+		return nil, err
+	}
+	out := make([]byte, CompactSigLength)
+	rs.R.FillBytes(out[:32])
+	rs.S.FillBytes(out[32:])
+	return out, nil
+}
+
+// CompactToASN1 converts r || s, or r || s || v with the recovery id
+// dropped, to ASN.1 DER for ecdsa.VerifyASN1.
+func CompactToASN1(sig []byte) ([]byte, error) {
+	r, s, err := parseCompact(sig)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{r, s})
+}
+
+// parseCompact splits a compact or recoverable signature into r and s,
+// checking its length, their range, and that a recovery id is 0 to 3.
+func parseCompact(sig []byte) (r, s *big.Int, err error) {
+	if len(sig) != CompactSigLength && len(sig) != RecoverableSigLength {
+		return nil, nil, fmt.Errorf("%w: %d bytes, want %d or %d", ErrSignatureEncoding, len(sig), CompactSigLength, RecoverableSigLength)
+	}
+	if len(sig) == RecoverableSigLength && sig[64] > 3 {
+		return nil, nil, fmt.Errorf("%w: recovery id %d", ErrSignatureEncoding, sig[64])
+	}
+	r = new(big.Int).SetBytes(sig[:32])
+	s = new(big.Int).SetBytes(sig[32:64])
+	if err := checkSigRange(r, s); err != nil {
+		return nil, nil, err
+	}
+	return r, s, nil
+}
+
+// checkSigRange checks that r and s are in 1 to N-1 for P-256, the only
+// values a signature can hold.
+func checkSigRange(r, s *big.Int) error {
+	n := elliptic.P256().Params().N
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return fmt.Errorf("%w: r or s out of range", ErrSignatureEncoding)
+	}
+	return nil
+}
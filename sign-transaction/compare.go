@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// compareSchemes signs and verifies the demo transaction n times under each
+// scheme and writes a table of key and signature sizes and the average time
+// per operation.
+func compareSchemes(w io.Writer, n int) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCHEME\tPUBLIC KEY\tSIGNATURE\tKEYGEN\tSIGN\tVERIFY")
+	for _, scheme := range []string{SchemeECDSA, SchemeEd25519, SchemeSchnorr} {
+		start := time.Now()
+		var s Signer
+		for i := 0; i < n; i++ {
+			var err error
+			if s, err = GenerateSigner(scheme); err != nil {
+				return err
+			}
+		}
+		keygen := time.Since(start) / time.Duration(n)
+		tx, err := demoTransaction(s.Public())
+		if err != nil {
+			return err
+		}
+
+		var sig []byte
+		start = time.Now()
+		for i := 0; i < n; i++ {
+			var err error
+			if sig, err = signTransactionWith(tx, s); err != nil {
+				return err
+			}
+		}
+		sign := time.Since(start) / time.Duration(n)
+
+		v := s.Public()
+		start = time.Now()
+		for i := 0; i < n; i++ {
+			if !verifyTransactionWith(tx, sig, v) {
+				return fmt.Errorf("%s signature did not verify", scheme)
+			}
+		}
+		verify := time.Since(start) / time.Duration(n)
+
+		fmt.Fprintf(tw, "%s\t%d bytes\t%d bytes\t%s\t%s\t%s\n", scheme, len(v.Bytes()), len(sig), keygen, sign, verify)
+	}
+	return tw.Flush()
+}
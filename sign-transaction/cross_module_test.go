@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+// signedPayment is ../testdata/signed-payment.json, which block-txn-concept's
+// tests read too: a payment, its payload, and its signature.
+type signedPayment struct {
+	Key string `json:"key"`
+	Tx  struct {
+		From        string    `json:"from"`
+		To          string    `json:"to"`
+		Time        time.Time `json:"time"`
+		Description string    `json:"description"`
+		Amount      int64     `json:"amount"` // minor units
+		Fee         int64     `json:"fee"`
+		Type        string    `json:"type"`
+		ChainID     string    `json:"chainID"`
+		Nonce       uint64    `json:"nonce"`
+	} `json:"tx"`
+	Payload   string `json:"payload"`
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+}
+
+// TestSignaturesVerifyInTheLedger signs the shared payment and checks the
+// result against the signature block-txn-concept's ledger verifies, and
+// the other way around.
+func TestSignaturesVerifyInTheLedger(t *testing.T) {
+	data, err := os.ReadFile("../testdata/signed-payment.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v signedPayment
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	d, err := hex.DecodeString(v.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := hex.DecodeString(v.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := Transaction{
+		From: v.Tx.From, To: v.Tx.To, Time: v.Tx.Time, Description: v.Tx.Description,
+		Amount: Amount(v.Tx.Amount), Fee: Amount(v.Tx.Fee), Type: v.Tx.Type, ChainID: v.Tx.ChainID, Nonce: v.Tx.Nonce,
+	}
+	if got := string(transactionBytes(tx)); got != v.Payload {
+		t.Fatalf("payload %q, want %q", got, v.Payload)
+	}
+	if got := hex.EncodeToString(hashTransaction(tx)); got != v.Hash {
+		t.Errorf("hash %s, want %s", got, v.Hash)
+	}
+	sig, err := signTransaction(tx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Errorf("signature %x, want the ledger's %x", sig, want)
+	}
+	if !VerifyTransaction(tx, want, &key.PublicKey) {
+		t.Error("the ledger's signature does not verify")
+	}
+}
@@ -1,23 +1,37 @@
 package main
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"flag"
 	"fmt"
+	"os"
+	"time"
 )
 
+// Transaction is a payment in the form block-txn-concept's ledger takes,
+// with only the fields a plain payment needs; see transactionBytes.
 type Transaction struct {
-	From   string
-	To     string
-	Amount float64
+	ID          int // optional user label; not unique
+	From        string
+	To          string
+	Time        time.Time
+	Description string
+	Amount      Amount
+	Fee         Amount // paid by From on top of Amount
+	Type        string // "debit" for a payment
+	ChainID     string // chain the payment is valid on
+	ForkID      string // rule set the payment is valid under, if pinned
+	Nonce       uint64 // number of transactions From sent before this one
 }
 
 func hashTransaction(tx Transaction) []byte {
-	data := fmt.Sprintf("%s%s%f", tx.From, tx.To, tx.Amount)
-	hash := sha256.Sum256([]byte(data))
+	hash := sha256.Sum256(transactionBytes(tx))
 	return hash[:]
 }
 
@@ -25,31 +39,162 @@ func signTransaction(tx Transaction, priv *ecdsa.PrivateKey) ([]byte, error) {
 	hash := hashTransaction(tx)
 
 	// ASN.1 encoded ECDSA signature (r,s) -> []byte
-	sig, err := ecdsa.SignASN1(rand.Reader, priv, hash)
+	sig, err := SignDeterministic(priv, hash)
 	if err != nil {
 		return nil, err
 	}
 	return sig, nil
 }
 
+// SignDeterministic signs digest, a SHA-256 hash, with priv and returns the
+// ASN.1 signature. Its nonce k comes from RFC 6979, an HMAC-DRBG seeded
+// with the key and the digest, rather than from the random source, so the
+// same key and transaction always sign the same way. A repeated or biased
+// random k would give the private key away; a derived one can't repeat
+// for different messages.
+func SignDeterministic(priv *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	return priv.Sign(nil, digest, crypto.SHA256)
+}
+
+// signTransactionWith signs tx under s's scheme. For ECDSA the signature
+// is the same as signTransaction's.
+func signTransactionWith(tx Transaction, s Signer) ([]byte, error) {
+	return s.Sign(transactionBytes(tx))
+}
+
+// verifyTransactionWith reports whether sig is v's key's signature of tx
+// and tx is from v's address.
+func verifyTransactionWith(tx Transaction, sig []byte, v Verifier) bool {
+	return tx.From == VerifierAddress(v) && v.Verify(transactionBytes(tx), sig)
+}
+
+// VerifyTransaction reports whether sig is a valid ASN.1 ECDSA signature
+// of tx by the key pub, and tx is from pub's address. A signature alone
+// only shows some key signed; the address ties it to the sender.
+func VerifyTransaction(tx Transaction, sig []byte, pub *ecdsa.PublicKey) bool {
+	from, err := AddressFromPubKey(pub)
+	if err != nil || tx.From != from {
+		return false
+	}
+	return ecdsa.VerifyASN1(pub, hashTransaction(tx), sig)
+}
+
+// demoTransaction is the payment the demo signs: 42 from the signer's
+// address to a fresh key's, as its first payment, on a chain without a
+// chain ID like block-txn-concept's demo.
+func demoTransaction(from Verifier) (Transaction, error) {
+	bob, err := GenerateSigner(from.Scheme())
+	if err != nil {
+		return Transaction{}, err
+	}
+	return Transaction{
+		From:   VerifierAddress(from),
+		To:     VerifierAddress(bob.Public()),
+		Time:   time.Now().UTC(),
+		Amount: 42 * Coin,
+		Type:   "debit",
+	}, nil
+}
+
 func main() {
+	scheme := flag.String("scheme", SchemeECDSA, "signature scheme: ecdsa, ed25519, or schnorr")
+	compare := flag.Bool("compare", false, "compare key and signature sizes and timings of every scheme")
+	keyFile := flag.String("key", "", "sign with the private key in this PEM file, e.g. one saved by generating-keypair")
+	flag.Parse()
+	if *compare {
+		if err := compareSchemes(os.Stdout, 1000); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *scheme != SchemeECDSA || *keyFile != "" {
+		if err := signWithScheme(*scheme, *keyFile); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// generate a keypair
 	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		panic(err)
 	}
 
-	tx := Transaction{
-		From:   "alice",
-		To:     "bob",
-		Amount: 42.0,
+	tx, err := demoTransaction(ECDSAVerifier{&priv.PublicKey})
+	if err != nil {
+		panic(err)
 	}
+	fmt.Printf("%s pays %s %s\n", tx.From, tx.To, tx.Amount)
 
 	sig, err := signTransaction(tx, priv)
 	if err != nil {
 		panic(err)
 	}
 
-	// print ONLY the signature, hex encoded
+	// print the signature, hex encoded
+	fmt.Println(hex.EncodeToString(sig))
+
+	// signing again gives the same signature, since the nonce is derived
+	again, err := signTransaction(tx, priv)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("deterministic:", bytes.Equal(sig, again))
+
+	// the same signature in the fixed-length form, and back
+	compact, err := ASN1ToCompact(sig)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("compact:", hex.EncodeToString(compact))
+	der, err := CompactToASN1(compact)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("round trip:", bytes.Equal(der, sig))
+
+	// check it, and check it no longer matches once the amount changes
+	fmt.Println("valid:", VerifyTransaction(tx, sig, &priv.PublicKey))
+	tampered := tx
+	tampered.Amount = 4200 * Coin
+	fmt.Println("tampered valid:", VerifyTransaction(tampered, sig, &priv.PublicKey))
+	impostor := tx
+	impostor.From = tx.To
+	fmt.Println("wrong sender valid:", VerifyTransaction(impostor, sig, &priv.PublicKey))
+}
+
+// signWithScheme is main for a scheme other than ECDSA or a saved key: it
+// signs the demo transaction with the key in keyFile, or a new key for
+// scheme if keyFile is empty, and checks the signature.
+func signWithScheme(scheme, keyFile string) error {
+	var s Signer
+	var err error
+	if keyFile != "" {
+		s, err = LoadPrivateKeyPEM(keyFile)
+	} else {
+		s, err = GenerateSigner(scheme)
+	}
+	if err != nil {
+		return err
+	}
+	tx, err := demoTransaction(s.Public())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s pays %s %s\n", tx.From, tx.To, tx.Amount)
+	sig, err := signTransactionWith(tx, s)
+	if err != nil {
+		return err
+	}
 	fmt.Println(hex.EncodeToString(sig))
+	fmt.Println("valid:", verifyTransactionWith(tx, sig, s.Public()))
+	tampered := tx
+	tampered.Amount = 4200 * Coin
+	fmt.Println("tampered valid:", verifyTransactionWith(tampered, sig, s.Public()))
+	impostor := tx
+	impostor.From = tx.To
+	fmt.Println("wrong sender valid:", verifyTransactionWith(impostor, sig, s.Public()))
+	return nil
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// LoadPrivateKeyPEM reads a private key PEM file, SEC1 ("EC PRIVATE KEY")
+// or PKCS#8 ("PRIVATE KEY"), such as generating-keypair -out writes, as a
+// Signer. A SEC1 key on secp256k1 is a Schnorr key. Other blocks, like
+// openssl's EC PARAMETERS, are skipped.
+func LoadPrivateKeyPEM(path string) (Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no private key found", path)
+		}
+		switch block.Type {
+		case "EC PRIVATE KEY":
+			k, err := x509.ParseECPrivateKey(block.Bytes)
+			if err != nil {
+				if s, serr := parseSchnorrPrivateKey(block.Bytes); serr == nil {
+					return s, nil
+				}
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			return ECDSASigner{k}, nil
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			switch k := key.(type) {
+			case *ecdsa.PrivateKey:
+				return ECDSASigner{k}, nil
+			case ed25519.PrivateKey:
+				return Ed25519Signer{k}, nil
+			}
+			return nil, fmt.Errorf("%s: unsupported private key type %T", path, key)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Schnorr signatures as BIP-340 defines them, the scheme Bitcoin's taproot
+// outputs are signed with. They are on secp256k1, Bitcoin's curve, which
+// neither crypto/ecdsa nor crypto/elliptic has, so the curve arithmetic is
+// here, on big.Int. It is written to be read, not to be fast or constant
+// time, and shouldn't guard real funds.
+//
+// A public key is only the x coordinate of its point, 32 bytes: of the two
+// points with that x, the one with even y is meant. A signature is 64
+// bytes, the x coordinate of the nonce point R and the scalar s. Because s
+// is linear in the key and the nonce, keys and signatures can be added
+// together, which is what MuSig key aggregation and taproot's key tweaking
+// build on.
+
+var (
+	secp256k1P  = hexInt("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f")
+	secp256k1N  = hexInt("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+	secp256k1Gx = hexInt("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	secp256k1Gy = hexInt("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8")
+
+	// oidSecp256k1 names the curve in SEC1 key files, as openssl writes
+	// them for "ecparam -name secp256k1".
+	oidSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+func hexInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("bad constant " + s)
+	}
+	return n
+}
+
+// ErrSchnorrKey is returned for a secret key outside 1 to N-1, or an x-only
+// public key that is no point's x coordinate.
+var ErrSchnorrKey = errors.New("invalid secp256k1 key")
+
+// k1Point is a secp256k1 point in Jacobian coordinates, (X/Z², Y/Z³); Z of
+// zero is the point at infinity. Working without affine x and y saves a
+// modular inverse on every addition.
+type k1Point struct{ X, Y, Z *big.Int }
+
+func k1Infinity() k1Point { return k1Point{new(big.Int), new(big.Int), new(big.Int)} }
+
+func k1Affine(x, y *big.Int) k1Point {
+	return k1Point{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+func (p k1Point) infinite() bool { return p.Z.Sign() == 0 }
+
+// affine returns p's x and y. p must not be the point at infinity.
+func (p k1Point) affine() (x, y *big.Int) {
+	zInv := new(big.Int).ModInverse(p.Z, secp256k1P)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+	x = new(big.Int).Mul(p.X, zInv2)
+	x.Mod(x, secp256k1P)
+	y = new(big.Int).Mul(p.Y, zInv2.Mul(zInv2, zInv))
+	y.Mod(y, secp256k1P)
+	return x, y
+}
+
+// double returns 2p, using that secp256k1's a is zero.
+func (p k1Point) double() k1Point {
+	if p.infinite() || p.Y.Sign() == 0 {
+		return k1Infinity()
+	}
+	mod := func(z *big.Int) *big.Int { return z.Mod(z, secp256k1P) }
+	a := mod(new(big.Int).Mul(p.X, p.X))
+	b := mod(new(big.Int).Mul(p.Y, p.Y))
+	c := mod(new(big.Int).Mul(b, b))
+	d := new(big.Int).Add(p.X, b)
+	d = mod(d.Mul(d, d).Sub(d, a).Sub(d, c).Lsh(d, 1))
+	e := new(big.Int).Mul(a, big.NewInt(3))
+	f := mod(new(big.Int).Mul(e, e))
+	x := mod(new(big.Int).Sub(f, new(big.Int).Lsh(d, 1)))
+	y := new(big.Int).Sub(d, x)
+	y = mod(y.Mul(y, e).Sub(y, c.Lsh(c, 3)))
+	z := new(big.Int).Mul(p.Y, p.Z)
+	z = mod(z.Lsh(z, 1))
+	return k1Point{x, y, z}
+}
+
+// add returns p + q.
+func (p k1Point) add(q k1Point) k1Point {
+	switch {
+	case p.infinite():
+		return q
+	case q.infinite():
+		return p
+	}
+	mod := func(z *big.Int) *big.Int { return z.Mod(z, secp256k1P) }
+	pz2 := mod(new(big.Int).Mul(p.Z, p.Z))
+	qz2 := mod(new(big.Int).Mul(q.Z, q.Z))
+	u1 := mod(new(big.Int).Mul(p.X, qz2))
+	u2 := mod(new(big.Int).Mul(q.X, pz2))
+	s1 := mod(new(big.Int).Mul(p.Y, qz2.Mul(qz2, q.Z)))
+	s2 := mod(new(big.Int).Mul(q.Y, pz2.Mul(pz2, p.Z)))
+	if u1.Cmp(u2) == 0 {
+		if s1.Cmp(s2) != 0 {
+			return k1Infinity()
+		}
+		return p.double()
+	}
+	h := mod(new(big.Int).Sub(u2, u1))
+	r := mod(new(big.Int).Sub(s2, s1))
+	h2 := mod(new(big.Int).Mul(h, h))
+	h3 := mod(new(big.Int).Mul(h2, h))
+	u1h2 := mod(new(big.Int).Mul(u1, h2))
+	x := new(big.Int).Mul(r, r)
+	x = mod(x.Sub(x, h3).Sub(x, new(big.Int).Lsh(u1h2, 1)))
+	y := new(big.Int).Sub(u1h2, x)
+	y = mod(y.Mul(y, r).Sub(y, s1.Mul(s1, h3)))
+	z := new(big.Int).Mul(h, p.Z)
+	z = mod(z.Mul(z, q.Z))
+	return k1Point{x, y, z}
+}
+
+// mul returns kp by double-and-add.
+func (p k1Point) mul(k *big.Int) k1Point {
+	out := k1Infinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		out = out.double()
+		if k.Bit(i) == 1 {
+			out = out.add(p)
+		}
+	}
+	return out
+}
+
+func k1BaseMul(k *big.Int) k1Point { return k1Affine(secp256k1Gx, secp256k1Gy).mul(k) }
+
+// liftX returns the point with x coordinate x and even y, or false if
+// there is none: y² = x³ + 7.
+func liftX(x *big.Int) (k1Point, bool) {
+	if x.Cmp(secp256k1P) >= 0 {
+		return k1Point{}, false
+	}
+	c := new(big.Int).Exp(x, big.NewInt(3), secp256k1P)
+	c.Add(c, big.NewInt(7)).Mod(c, secp256k1P)
+	y := new(big.Int).ModSqrt(c, secp256k1P)
+	if y == nil {
+		return k1Point{}, false
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(secp256k1P, y)
+	}
+	return k1Affine(x, y), true
+}
+
+// taggedHash is BIP-340's domain-separated hash: SHA-256 over the SHA-256
+// of tag, twice, then the data. A hash made for one purpose can't be
+// passed off as one for another.
+func taggedHash(tag string, data ...[]byte) []byte {
+	t := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(t[:])
+	h.Write(t[:])
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+func bytes32(n *big.Int) []byte { return n.FillBytes(make([]byte, 32)) }
+
+// SchnorrSecretKey returns the secret key for the 32 bytes b, which must
+// be in 1 to N-1.
+func SchnorrSecretKey(b []byte) (*big.Int, error) {
+	d := new(big.Int).SetBytes(b)
+	if len(b) != 32 || d.Sign() == 0 || d.Cmp(secp256k1N) >= 0 {
+		return nil, fmt.Errorf("%w: secret key must be 32 bytes in 1 to N-1", ErrSchnorrKey)
+	}
+	return d, nil
+}
+
+// SchnorrPublicKey returns the x-only public key for secret key d.
+func SchnorrPublicKey(d *big.Int) []byte {
+	x, _ := k1BaseMul(d).affine()
+	return bytes32(x)
+}
+
+// SchnorrSign signs msg with secret key d as BIP-340 does. aux is 32
+// bytes of fresh randomness mixed into the nonce. The nonce is derived from
+// the key and the message as well, so a weak or repeated aux can't give
+// the key away; aux only guards against attacks on the computation itself,
+// and all zeros still signs safely.
+func SchnorrSign(d *big.Int, msg, aux []byte) ([]byte, error) {
+	if d.Sign() == 0 || d.Cmp(secp256k1N) >= 0 {
+		return nil, fmt.Errorf("%w: secret key out of range", ErrSchnorrKey)
+	}
+	if len(aux) != 32 {
+		return nil, fmt.Errorf("aux randomness is %d bytes, want 32", len(aux))
+	}
+	// The key is negated if need be so its point has even y, the one its
+	// x-only public key stands for.
+	px, py := k1BaseMul(d).affine()
+	if py.Bit(0) == 1 {
+		d = new(big.Int).Sub(secp256k1N, d)
+	}
+	pub := bytes32(px)
+
+	t := bytes32(d)
+	for i, b := range taggedHash("BIP0340/aux", aux) {
+		t[i] ^= b
+	}
+	k := new(big.Int).SetBytes(taggedHash("BIP0340/nonce", t, pub, msg))
+	k.Mod(k, secp256k1N)
+	if k.Sign() == 0 {
+		return nil, errors.New("schnorr nonce is zero")
+	}
+	rx, ry := k1BaseMul(k).affine()
+	if ry.Bit(0) == 1 {
+		k.Sub(secp256k1N, k)
+	}
+	r := bytes32(rx)
+
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", r, pub, msg))
+	e.Mod(e, secp256k1N)
+	s := e.Mul(e, d)
+	s.Add(s, k).Mod(s, secp256k1N)
+	sig := append(r, bytes32(s)...)
+
+	// Check the signature before letting it out, as BIP-340 recommends: a
+	// fault while signing could otherwise leak the key.
+	if !SchnorrVerify(pub, msg, sig) {
+		return nil, errors.New("schnorr signature failed to verify")
+	}
+	return sig, nil
+}
+
+// SchnorrVerify reports whether sig is a BIP-340 signature of msg by the
+// x-only public key pub: whether sG − eP is a point with even y and x
+// coordinate r, where e is the challenge hash of r, pub, and msg.
+func SchnorrVerify(pub, msg, sig []byte) bool {
+	if len(pub) != 32 || len(sig) != 64 {
+		return false
+	}
+	p, ok := liftX(new(big.Int).SetBytes(pub))
+	if !ok {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(secp256k1P) >= 0 || s.Cmp(secp256k1N) >= 0 {
+		return false
+	}
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", sig[:32], pub, msg))
+	e.Mod(e, secp256k1N)
+	e.Sub(secp256k1N, e)
+	R := k1BaseMul(s).add(p.mul(e))
+	if R.infinite() {
+		return false
+	}
+	x, y := R.affine()
+	return y.Bit(0) == 0 && x.Cmp(r) == 0
+}
+
+// SchnorrSigner signs the SHA-256 of a message with a secp256k1 key, as
+// BIP-340 signatures in Bitcoin sign a 32-byte transaction digest. The aux
+// randomness is fresh for each signature, so unlike ECDSASigner's, its
+// signatures of the same message differ.
+type SchnorrSigner struct{ Key *big.Int }
+
+// GenerateSchnorrSigner creates a new secp256k1 key.
+func GenerateSchnorrSigner() (SchnorrSigner, error) {
+	for {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return SchnorrSigner{}, err
+		}
+		if d, err := SchnorrSecretKey(b); err == nil {
+			return SchnorrSigner{d}, nil
+		}
+	}
+}
+
+func (s SchnorrSigner) Scheme() string { return SchemeSchnorr }
+
+func (s SchnorrSigner) Sign(msg []byte) ([]byte, error) {
+	aux := make([]byte, 32)
+	if _, err := rand.Read(aux); err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(msg)
+	return SchnorrSign(s.Key, hash[:], aux)
+}
+
+func (s SchnorrSigner) Public() Verifier { return SchnorrVerifier{SchnorrPublicKey(s.Key)} }
+
+// ecPrivateKey is SEC1's ECPrivateKey, which x509 only marshals for the
+// curves crypto/elliptic has.
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// PrivateBytes is the key as SEC1 DER naming secp256k1, the form openssl
+// writes such keys in.
+func (s SchnorrSigner) PrivateBytes() ([]byte, error) {
+	x, y := k1BaseMul(s.Key).affine()
+	pub := append(append([]byte{4}, bytes32(x)...), bytes32(y)...)
+	return asn1.Marshal(ecPrivateKey{
+		Version:       1,
+		PrivateKey:    bytes32(s.Key),
+		NamedCurveOID: oidSecp256k1,
+		PublicKey:     asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+	})
+}
+
+// parseSchnorrPrivateKey parses SEC1 DER for a secp256k1 key, as
+// PrivateBytes or openssl writes it.
+func parseSchnorrPrivateKey(der []byte) (SchnorrSigner, error) {
+	var k ecPrivateKey
+	rest, err := asn1.Unmarshal(der, &k)
+	if err != nil || len(rest) > 0 {
+		return SchnorrSigner{}, errors.New("not a SEC1 private key")
+	}
+	if !k.NamedCurveOID.Equal(oidSecp256k1) {
+		return SchnorrSigner{}, fmt.Errorf("curve %v is not secp256k1", k.NamedCurveOID)
+	}
+	if len(k.PrivateKey) > 32 {
+		return SchnorrSigner{}, fmt.Errorf("%w: secret key is %d bytes", ErrSchnorrKey, len(k.PrivateKey))
+	}
+	b := make([]byte, 32)
+	copy(b[32-len(k.PrivateKey):], k.PrivateKey)
+	d, err := SchnorrSecretKey(b)
+	if err != nil {
+		return SchnorrSigner{}, err
+	}
+	// The public key may be compressed or not; either way its x
+	// coordinate follows the first byte.
+	s := SchnorrSigner{d}
+	if pub := k.PublicKey.RightAlign(); len(pub) >= 33 && !bytes.Equal(pub[1:33], SchnorrPublicKey(d)) {
+		return SchnorrSigner{}, fmt.Errorf("%w: public key does not match the secret key", ErrSchnorrKey)
+	}
+	return s, nil
+}
+
+// SchnorrVerifier checks SchnorrSigner signatures against an x-only public
+// key.
+type SchnorrVerifier struct{ Key []byte }
+
+func (v SchnorrVerifier) Scheme() string { return SchemeSchnorr }
+
+func (v SchnorrVerifier) Verify(msg, sig []byte) bool {
+	hash := sha256.Sum256(msg)
+	return SchnorrVerify(v.Key, hash[:], sig)
+}
+
+func (v SchnorrVerifier) Bytes() []byte { return v.Key }
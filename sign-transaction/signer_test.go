@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// TestECDSASignerVectors checks SignDeterministic, through ECDSASigner,
+// against RFC 6979's P-256 and SHA-256 vectors, A.2.5.
+func TestECDSASignerVectors(t *testing.T) {
+	d, err := hex.DecodeString("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := ECDSASigner{key}
+	for _, tc := range []struct{ msg, r, s string }{
+		{"sample", "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716", "F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"},
+		{"test", "F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367", "019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083"},
+	} {
+		der, err := s.Sign([]byte(tc.msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := ASN1ToCompact(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := hex.EncodeToString(sig), strings.ToLower(tc.r+tc.s); got != want {
+			t.Errorf("%q: r || s = %s, want %s", tc.msg, got, want)
+		}
+		if !s.Public().Verify([]byte(tc.msg), der) {
+			t.Errorf("%q: signature does not verify", tc.msg)
+		}
+	}
+}
+
+func TestSignTransactionIsDeterministic(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := AddressFromPubKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := Transaction{From: from, To: "bob", Amount: 10}
+	first, err := signTransaction(tx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := signTransaction(tx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("signing the same transaction twice gave different signatures")
+	}
+	if !VerifyTransaction(tx, first, &key.PublicKey) {
+		t.Error("signature does not verify")
+	}
+	tx.Amount = 11
+	if VerifyTransaction(tx, first, &key.PublicKey) {
+		t.Error("signature verifies for a changed transaction")
+	}
+}
+
+// TestEd25519SignerVectors checks Ed25519Signer against RFC 8032's test
+// vectors 1 and 2, section 7.1.
+func TestEd25519SignerVectors(t *testing.T) {
+	for _, tc := range []struct{ seed, pub, msg, sig string }{
+		{
+			"9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+			"d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+			"",
+			"e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+		},
+		{
+			"4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+			"3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			"72",
+			"92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+		},
+	} {
+		seed, _ := hex.DecodeString(tc.seed)
+		msg, _ := hex.DecodeString(tc.msg)
+		s := Ed25519Signer{ed25519.NewKeyFromSeed(seed)}
+		if got := hex.EncodeToString(s.Public().Bytes()); got != tc.pub {
+			t.Errorf("public key %s, want %s", got, tc.pub)
+		}
+		sig, err := s.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(sig); got != tc.sig {
+			t.Errorf("msg %q: signature %s, want %s", tc.msg, got, tc.sig)
+		}
+		if !s.Public().Verify(msg, sig) {
+			t.Errorf("msg %q: signature does not verify", tc.msg)
+		}
+		sig[0] ^= 1
+		if s.Public().Verify(msg, sig) {
+			t.Errorf("msg %q: altered signature verifies", tc.msg)
+		}
+	}
+}